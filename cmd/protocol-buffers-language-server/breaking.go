@@ -0,0 +1,59 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+var (
+	breakingCmd = kingpin.Command("breaking", "Detect breaking changes of proto files against a git revision.")
+
+	breakingAgainst = breakingCmd.Flag("against", "Git revision to compare with. Defaults to the one in the project configuration or HEAD.").String()
+	breakingPaths   = breakingCmd.Arg("paths", "Files or directories to check. Defaults to the current directory.").Strings()
+)
+
+// runBreaking prints the breaking changes of the proto files in the paths given on the command line.
+// It returns true if any breaking change is found.
+func runBreaking(ctx context.Context, session source.Session, w io.Writer) (bool, error) {
+	view, wd, err := newWorkingDirView(ctx, session)
+	if err != nil {
+		return false, err
+	}
+	filenames, err := protoFiles(view, *breakingPaths)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	for _, filename := range filenames {
+		diagnostics, err := source.BreakingDiagnostics(ctx, view, uri.File(filename), *breakingAgainst)
+		if err != nil {
+			return found, fmt.Errorf("failed to check %s: %w", filename, err)
+		}
+		for _, d := range diagnostics {
+			found = true
+			fmt.Fprintf(w, "%s:%d:%d: %s (%v)\n", relPath(wd, filename), int(d.Range.Start.Line)+1, int(d.Range.Start.Character)+1, d.Message, d.Code)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+var (
+	checkCmd = kingpin.Command("check", "Report the diagnostics of proto files the same as the server does.")
+
+	checkFormat = checkCmd.Flag("format", "Output format.").Default(formatHuman).Enum(formatHuman, formatJSON, formatSARIF, formatGitHub)
+	checkFailOn = checkCmd.Flag("fail-on", "Lowest severity of diagnostics which makes the command fail.").Default("error").Enum("error", "warning", "info", "hint")
+	checkPaths  = checkCmd.Arg("paths", "Files or directories to check. Defaults to the current directory.").Strings()
+)
+
+// runCheck prints the diagnostics of the proto files in the paths given on the command line.
+// It returns true if any diagnostic as severe as --fail-on or more is found.
+func runCheck(ctx context.Context, session source.Session, w io.Writer) (bool, error) {
+	view, wd, err := newWorkingDirView(ctx, session)
+	if err != nil {
+		return false, err
+	}
+	filenames, err := protoFiles(view, *checkPaths)
+	if err != nil {
+		return false, err
+	}
+
+	failOn := map[string]protocol.DiagnosticSeverity{
+		"error":   protocol.SeverityError,
+		"warning": protocol.SeverityWarning,
+		"info":    protocol.SeverityInformation,
+		"hint":    protocol.SeverityHint,
+	}[*checkFailOn]
+
+	var files []fileDiagnostics
+	failed := false
+	for _, filename := range filenames {
+		diagnostics, err := source.Diagnostics(ctx, view, uri.File(filename))
+		if err != nil {
+			return false, fmt.Errorf("failed to check %s: %w", filename, err)
+		}
+		for _, d := range diagnostics {
+			if d.Severity <= failOn {
+				failed = true
+			}
+		}
+		files = append(files, fileDiagnostics{
+			filename:    relPath(wd, filename),
+			diagnostics: diagnostics,
+		})
+	}
+
+	if err := writeDiagnostics(w, *checkFormat, files); err != nil {
+		return false, err
+	}
+	return failed, nil
+}
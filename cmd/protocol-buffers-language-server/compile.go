@@ -0,0 +1,82 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/go-language-server/uri"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/compiler"
+)
+
+var (
+	compileCmd = kingpin.Command("compile", "Compile proto files into a FileDescriptorSet. The set is written to stdout by default.")
+
+	compileOutput            = compileCmd.Flag("output", "File to write the FileDescriptorSet to.").Short('o').String()
+	compileFormat            = compileCmd.Flag("format", "Format of the FileDescriptorSet.").Default("binary").Enum("binary", "json")
+	compileIncludeImports    = compileCmd.Flag("include-imports", "Include all files imported by the root files in the set.").Bool()
+	compileIncludeSourceInfo = compileCmd.Flag("include-source-info", "Include source code info in the set.").Bool()
+	compilePaths             = compileCmd.Arg("paths", "Root files or directories to compile. Defaults to the current directory.").Strings()
+)
+
+// runCompile compiles the proto files in the paths given on the command line and writes the FileDescriptorSet.
+func runCompile(ctx context.Context, session source.Session, w io.Writer) error {
+	view, _, err := newWorkingDirView(ctx, session)
+	if err != nil {
+		return err
+	}
+	filenames, err := protoFiles(view, *compilePaths)
+	if err != nil {
+		return err
+	}
+	uris := make([]uri.URI, 0, len(filenames))
+	for _, filename := range filenames {
+		uris = append(uris, uri.File(filename))
+	}
+
+	set, err := source.Compile(ctx, view, uris, compiler.Options{
+		IncludeImports:    *compileIncludeImports,
+		IncludeSourceInfo: *compileIncludeSourceInfo,
+	})
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch *compileFormat {
+	case "json":
+		data, err = protojson.MarshalOptions{Multiline: true}.Marshal(set)
+		data = append(data, '\n')
+	default:
+		data, err = proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal the FileDescriptorSet: %w", err)
+	}
+
+	if *compileOutput != "" {
+		return ioutil.WriteFile(*compileOutput, data, 0644)
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// newWorkingDirView returns a view rooted at the working directory with its project configurations loaded
+// in the same way as the server does for a workspace folder.
+func newWorkingDirView(ctx context.Context, session source.Session) (source.View, string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, "", err
+	}
+	view := source.NewView(session, filepath.Base(wd), uri.File(wd))
+	if err := view.ReloadConfig(ctx); err != nil {
+		return nil, "", err
+	}
+	return view, wd, nil
+}
+
+// protoFiles returns the absolute paths to the proto files in given paths.
+// Directories are walked except for hidden ones and the files excluded by project configurations.
+// No paths means the folder of the view.
+func protoFiles(view source.View, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{view.Folder().Filename()}
+	}
+
+	var filenames []string
+	for _, path := range paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.Walk(path, func(filename string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if filename != path && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(filename) != ".proto" {
+				return nil
+			}
+			if filename != path && view.Config(uri.File(filename)).Excluded(filename) {
+				return nil
+			}
+			filenames = append(filenames, filename)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filenames, nil
+}
+
+// relPath returns the path to a file relative to a directory if the file is in the directory.
+func relPath(dir, filename string) string {
+	if rel, err := filepath.Rel(dir, filename); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return filename
+}
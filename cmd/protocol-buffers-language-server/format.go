@@ -0,0 +1,100 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/diff"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+var (
+	formatCmd = kingpin.Command("format", "Format proto files the same as the server does. Formatted files are printed to stdout by default.")
+
+	formatWrite = formatCmd.Flag("write", "Write the result to the files instead of stdout.").Short('w').Bool()
+	formatDiff  = formatCmd.Flag("diff", "Print unified diffs instead of the formatted files.").Short('d').Bool()
+	formatList  = formatCmd.Flag("list", "List the files whose formatting differs instead of printing the formatted files.").Short('l').Bool()
+	formatPaths = formatCmd.Arg("paths", "Files or directories to format. Defaults to the current directory.").Strings()
+)
+
+// runFormat formats the proto files in the paths given on the command line.
+// It returns true if any file can't be formatted, or if any file is unformatted with --list or --diff
+// unless the file is rewritten with --write, so that the command can gate CI.
+func runFormat(ctx context.Context, session source.Session, stdout, stderr io.Writer) (bool, error) {
+	view, wd, err := newWorkingDirView(ctx, session)
+	if err != nil {
+		return false, err
+	}
+	filenames, err := protoFiles(view, *formatPaths)
+	if err != nil {
+		return false, err
+	}
+
+	failed := false
+	for _, filename := range filenames {
+		name := relPath(wd, filename)
+		data, formatted, err := source.Format(ctx, view, uri.File(filename))
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		changed := !bytes.Equal(data, formatted)
+
+		if !*formatWrite && !*formatDiff && !*formatList {
+			if _, err := stdout.Write(formatted); err != nil {
+				return failed, err
+			}
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if *formatList {
+			fmt.Fprintln(stdout, name)
+		}
+		if *formatDiff {
+			slashed := filepath.ToSlash(name)
+			fmt.Fprint(stdout, diff.Unified("a/"+slashed, "b/"+slashed, string(data), string(formatted)))
+		}
+		if *formatWrite {
+			if err := writeFile(filename, formatted); err != nil {
+				return failed, err
+			}
+			continue
+		}
+		failed = true
+	}
+	return failed, nil
+}
+
+// writeFile replaces the content of a file keeping its permission.
+func writeFile(filename string, data []byte) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, info.Mode().Perm())
+}
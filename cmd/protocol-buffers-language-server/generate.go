@@ -0,0 +1,70 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+var (
+	generateCmd = kingpin.Command("generate", "Preview the files which protoc plugins generate for proto files. The files are printed to stdout by default.")
+
+	generateOutputDir = generateCmd.Flag("output-dir", "Directory to write the generated files to instead of stdout.").Short('o').String()
+	generatePaths     = generateCmd.Arg("paths", "Files or directories to generate files for. Defaults to the current directory.").Strings()
+)
+
+// runGenerate runs the protoc plugins configured for the proto files in the paths given on the command line.
+// Each generated file is printed after a header line with its name unless --output-dir is given.
+func runGenerate(ctx context.Context, session source.Session, w io.Writer) error {
+	view, wd, err := newWorkingDirView(ctx, session)
+	if err != nil {
+		return err
+	}
+	filenames, err := protoFiles(view, *generatePaths)
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range filenames {
+		files, err := source.GeneratedCode(ctx, view, uri.File(filename))
+		if err != nil {
+			return fmt.Errorf("%s: %w", relPath(wd, filename), err)
+		}
+		for _, f := range files {
+			if *generateOutputDir == "" {
+				fmt.Fprintf(w, "==> %s (protoc-gen-%s) <==\n%s\n", f.Name, f.Plugin, f.Content)
+				continue
+			}
+			path := filepath.Join(*generateOutputDir, filepath.FromSlash(f.Name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path, []byte(f.Content), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
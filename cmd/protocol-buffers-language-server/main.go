@@ -29,7 +29,11 @@ import (
 	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
 )
 
-var cfg config.Config
+var (
+	cfg config.Config
+
+	serveCmd = kingpin.Command("serve", "Run the language server. This is the default command.").Default()
+)
 
 func init() {
 	kingpin.Flag("logfile", "Filename to log.").StringVar(&cfg.Log.File)
@@ -39,9 +43,9 @@ func init() {
 }
 
 func main() {
-	kingpin.Parse()
+	command := kingpin.Parse()
 
-	logger, err := logging.NewLogger(cfg.Log)
+	logger, level, err := logging.NewLoggerWithLevel(cfg.Log)
 	if err != nil {
 		fmt.Fprint(os.Stderr, err)
 		os.Exit(1)
@@ -53,9 +57,49 @@ func main() {
 	defer cancel()
 	session := source.NewSession()
 
-	if err := runServer(ctx, session, server.WithLogger(logger)); err != nil {
-		logger.Error("failed to run server", zap.Error(err))
-		os.Exit(1)
+	switch command {
+	case serveCmd.FullCommand():
+		if err := runServer(ctx, session, server.WithLogger(logger), server.WithLogLevel(level)); err != nil {
+			logger.Error("failed to run server", zap.Error(err))
+			os.Exit(1)
+		}
+	case checkCmd.FullCommand():
+		failed, err := runCheck(ctx, session, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	case formatCmd.FullCommand():
+		failed, err := runFormat(ctx, session, os.Stdout, os.Stderr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	case compileCmd.FullCommand():
+		if err := runCompile(ctx, session, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case generateCmd.FullCommand():
+		if err := runGenerate(ctx, session, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case breakingCmd.FullCommand():
+		found, err := runBreaking(ctx, session, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if found {
+			os.Exit(1)
+		}
 	}
 }
 
@@ -0,0 +1,297 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-language-server/protocol"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/lint"
+)
+
+// Output formats of diagnostics.
+const (
+	formatHuman  = "human"
+	formatJSON   = "json"
+	formatSARIF  = "sarif"
+	formatGitHub = "github"
+)
+
+// fileDiagnostics is the diagnostics of a file.
+type fileDiagnostics struct {
+	// filename is the path to the file to print, which is relative to the working directory if possible.
+	filename    string
+	diagnostics []protocol.Diagnostic
+}
+
+// writeDiagnostics writes the diagnostics of files in a given format.
+func writeDiagnostics(w io.Writer, format string, files []fileDiagnostics) error {
+	switch format {
+	case formatHuman:
+		return writeHuman(w, files)
+	case formatJSON:
+		return writeJSON(w, files)
+	case formatSARIF:
+		return writeSARIF(w, files)
+	case formatGitHub:
+		return writeGitHub(w, files)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// writeHuman writes diagnostics like "foo.proto:1:2: warning: message (CODE)".
+func writeHuman(w io.Writer, files []fileDiagnostics) error {
+	for _, f := range files {
+		for _, d := range f.diagnostics {
+			msg := fmt.Sprintf("%s:%d:%d: %s: %s", f.filename, int(d.Range.Start.Line)+1, int(d.Range.Start.Character)+1, severityName(d.Severity), d.Message)
+			if d.Code != nil {
+				msg += fmt.Sprintf(" (%v)", d.Code)
+			}
+			if _, err := fmt.Fprintln(w, msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonDiagnostic is a diagnostic in the JSON format.
+// Lines and columns are 1-based, and the end is exclusive.
+type jsonDiagnostic struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+}
+
+func writeJSON(w io.Writer, files []fileDiagnostics) error {
+	diagnostics := []jsonDiagnostic{}
+	for _, f := range files {
+		for _, d := range f.diagnostics {
+			diagnostics = append(diagnostics, jsonDiagnostic{
+				File:      filepath.ToSlash(f.filename),
+				Line:      int(d.Range.Start.Line) + 1,
+				Column:    int(d.Range.Start.Character) + 1,
+				EndLine:   int(d.Range.End.Line) + 1,
+				EndColumn: int(d.Range.End.Character) + 1,
+				Severity:  severityName(d.Severity),
+				Code:      code(d),
+				Message:   d.Message,
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diagnostics)
+}
+
+// sarifLog is a subset of the Static Analysis Results Interchange Format (SARIF) v2.1.0.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func writeSARIF(w io.Writer, files []fileDiagnostics) error {
+	results := []sarifResult{}
+	ruleIDs := make(map[string]struct{})
+	for _, f := range files {
+		for _, d := range f.diagnostics {
+			id := code(d)
+			if id != "" {
+				ruleIDs[id] = struct{}{}
+			}
+			results = append(results, sarifResult{
+				RuleID:  id,
+				Level:   sarifLevel(d.Severity),
+				Message: sarifMessage{Text: d.Message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.filename)},
+						Region: sarifRegion{
+							StartLine:   int(d.Range.Start.Line) + 1,
+							StartColumn: int(d.Range.Start.Character) + 1,
+							EndLine:     int(d.Range.End.Line) + 1,
+							EndColumn:   int(d.Range.End.Character) + 1,
+						},
+					},
+				}},
+			})
+		}
+	}
+
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for id := range ruleIDs {
+		rule := sarifRule{ID: id}
+		if r, ok := lint.LookupRule(id); ok {
+			rule.ShortDescription.Text = r.Description
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "protocol-buffers-language-server",
+				InformationURI: "https://github.com/micnncim/protocol-buffers-language-server",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	})
+}
+
+func sarifLevel(s protocol.DiagnosticSeverity) string {
+	switch s {
+	case protocol.SeverityError:
+		return "error"
+	case protocol.SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// writeGitHub writes diagnostics as workflow commands which GitHub Actions shows as annotations.
+func writeGitHub(w io.Writer, files []fileDiagnostics) error {
+	for _, f := range files {
+		for _, d := range f.diagnostics {
+			command := "notice"
+			switch d.Severity {
+			case protocol.SeverityError:
+				command = "error"
+			case protocol.SeverityWarning:
+				command = "warning"
+			}
+			props := []string{
+				"file=" + escapeGitHubProperty(filepath.ToSlash(f.filename)),
+				fmt.Sprintf("line=%d", int(d.Range.Start.Line)+1),
+				fmt.Sprintf("col=%d", int(d.Range.Start.Character)+1),
+				fmt.Sprintf("endLine=%d", int(d.Range.End.Line)+1),
+				fmt.Sprintf("endColumn=%d", int(d.Range.End.Character)+1),
+			}
+			if c := code(d); c != "" {
+				props = append(props, "title="+escapeGitHubProperty(c))
+			}
+			if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(props, ","), escapeGitHubData(d.Message)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	gitHubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	gitHubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeGitHubData(s string) string {
+	return gitHubDataEscaper.Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return gitHubPropertyEscaper.Replace(s)
+}
+
+func severityName(s protocol.DiagnosticSeverity) string {
+	switch s {
+	case protocol.SeverityError:
+		return "error"
+	case protocol.SeverityWarning:
+		return "warning"
+	case protocol.SeverityInformation:
+		return "info"
+	case protocol.SeverityHint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// code returns the code of a diagnostic as a string, which is empty if the diagnostic has no code.
+func code(d protocol.Diagnostic) string {
+	if d.Code == nil {
+		return ""
+	}
+	return fmt.Sprint(d.Code)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	files := []fileDiagnostics{
+		{
+			filename: "foo.proto",
+			diagnostics: []protocol.Diagnostic{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 1, Character: 8},
+						End:   protocol.Position{Line: 1, Character: 15},
+					},
+					Severity: protocol.SeverityWarning,
+					Code:     "MESSAGE_PASCAL_CASE",
+					Message:  "Message name should be PascalCase.",
+				},
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 2, Character: 0},
+						End:   protocol.Position{Line: 2, Character: 1},
+					},
+					Severity: protocol.SeverityError,
+					Message:  "100% invalid,\nsyntax",
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "human",
+			format: formatHuman,
+			want: "foo.proto:2:9: warning: Message name should be PascalCase. (MESSAGE_PASCAL_CASE)\n" +
+				"foo.proto:3:1: error: 100% invalid,\nsyntax\n",
+		},
+		{
+			name:   "github",
+			format: formatGitHub,
+			want: "::warning file=foo.proto,line=2,col=9,endLine=2,endColumn=16,title=MESSAGE_PASCAL_CASE::Message name should be PascalCase.\n" +
+				"::error file=foo.proto,line=3,col=1,endLine=3,endColumn=2::100%25 invalid,%0Asyntax\n",
+		},
+		{
+			name:   "json",
+			format: formatJSON,
+			want: `[
+  {
+    "file": "foo.proto",
+    "line": 2,
+    "column": 9,
+    "endLine": 2,
+    "endColumn": 16,
+    "severity": "warning",
+    "code": "MESSAGE_PASCAL_CASE",
+    "message": "Message name should be PascalCase."
+  },
+  {
+    "file": "foo.proto",
+    "line": 3,
+    "column": 1,
+    "endLine": 3,
+    "endColumn": 2,
+    "severity": "error",
+    "message": "100% invalid,\nsyntax"
+  }
+]
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeDiagnostics(&buf, tt.format, files); err != nil {
+				t.Fatalf("writeDiagnostics() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeDiagnostics() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
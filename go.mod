@@ -15,6 +15,8 @@ require (
 	github.com/kelseyhightower/envconfig v1.4.0
 	go.uber.org/atomic v1.4.0
 	go.uber.org/zap v1.10.1-0.20190430155229-8a2ee5670ced
+	google.golang.org/protobuf v1.32.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 // https://thrift.apache.org/lib/go suggests using github
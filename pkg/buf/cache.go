@@ -0,0 +1,129 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheLayouts is the list of directories relative to the cache directory
+// which contain modules laid out as <remote>/<owner>/<repository>/<commit>.
+var cacheLayouts = []string{
+	".",
+	filepath.Join("v1", "module", "data"),
+	filepath.Join("v3", "modules", "b5"),
+	filepath.Join("v3", "modules", "shake256"),
+}
+
+// CacheDir returns the directory of the local buf module cache.
+// It respects BUF_CACHE_DIR and XDG_CACHE_HOME in the same way as buf.
+func CacheDir() string {
+	if dir := os.Getenv("BUF_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "buf")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cache", "buf")
+}
+
+// ResolveDep finds the directory of a module in a local module cache.
+// It never accesses the network. If commit is empty, the most recently cached commit is used.
+func ResolveDep(cacheDir, ref, commit string) (string, bool) {
+	if cacheDir == "" {
+		return "", false
+	}
+	for _, layout := range cacheLayouts {
+		dir := filepath.Join(cacheDir, layout, filepath.FromSlash(ref))
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if commit != "" {
+			if d := filepath.Join(dir, commit); isDir(d) {
+				return moduleRoot(d), true
+			}
+			continue
+		}
+		if d, ok := latestCommitDir(dir); ok {
+			return moduleRoot(d), true
+		}
+		// The module is laid out without commits.
+		return dir, true
+	}
+	return "", false
+}
+
+// parseReference splits a module reference like "buf.build/owner/repository:ref" into the module and the ref.
+func parseReference(dep string) (ref, commit string) {
+	if i := strings.LastIndex(dep, ":"); i >= 0 {
+		return dep[:i], dep[i+1:]
+	}
+	return dep, ""
+}
+
+// moduleRoot returns the directory which contains the files of a cached module.
+func moduleRoot(dir string) string {
+	if d := filepath.Join(dir, "files"); isDir(d) {
+		return d
+	}
+	return dir
+}
+
+// latestCommitDir returns the most recently modified directory named after a commit.
+func latestCommitDir(dir string) (string, bool) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	var latest os.FileInfo
+	for _, info := range infos {
+		if !info.IsDir() || !isCommit(info.Name()) {
+			continue
+		}
+		if latest == nil || info.ModTime().After(latest.ModTime()) {
+			latest = info
+		}
+	}
+	if latest == nil {
+		return "", false
+	}
+	return filepath.Join(dir, latest.Name()), true
+}
+
+// isCommit returns true if a given name looks like a commit or a digest of a module.
+func isCommit(name string) bool {
+	if len(name) < 12 {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// ConfigFilename is the name of a buf module or workspace configuration file.
+	ConfigFilename = "buf.yaml"
+
+	// WorkFilename is the name of a buf workspace configuration file of v1.
+	WorkFilename = "buf.work.yaml"
+
+	// LockFilename is the name of a file which pins the dependencies of buf modules.
+	LockFilename = "buf.lock"
+)
+
+const (
+	versionV1Beta1 = "v1beta1"
+	versionV1      = "v1"
+	versionV2      = "v2"
+)
+
+// Config represents a content of buf.yaml of v1beta1, v1 and v2.
+// Fields which are not related to the server are not declared.
+type Config struct {
+	Version string `yaml:"version"`
+
+	// Name is the name of the module in v1.
+	Name string `yaml:"name"`
+
+	// Deps is a list of module references the module depends on.
+	Deps []string `yaml:"deps"`
+
+	// Build is the build configuration of v1beta1 and v1.
+	Build BuildConfig `yaml:"build"`
+
+	// Modules is the list of modules in the workspace of v2.
+	Modules []ModuleConfig `yaml:"modules"`
+
+	Lint     Checks `yaml:"lint"`
+	Breaking Checks `yaml:"breaking"`
+}
+
+// BuildConfig represents a build configuration of v1beta1 and v1.
+type BuildConfig struct {
+	// Roots is a list of module roots in v1beta1.
+	Roots []string `yaml:"roots"`
+
+	// Excludes is a list of directories to exclude relative to the module root.
+	Excludes []string `yaml:"excludes"`
+}
+
+// ModuleConfig represents a module declared in buf.yaml of v2.
+type ModuleConfig struct {
+	// Path is the directory of the module relative to buf.yaml.
+	Path string `yaml:"path"`
+	Name string `yaml:"name"`
+
+	// Excludes is a list of directories to exclude relative to buf.yaml.
+	Excludes []string `yaml:"excludes"`
+
+	Lint     *Checks `yaml:"lint"`
+	Breaking *Checks `yaml:"breaking"`
+}
+
+// Checks represents a configuration of lint or breaking change detection.
+type Checks struct {
+	Use        []string            `yaml:"use"`
+	Except     []string            `yaml:"except"`
+	Ignore     []string            `yaml:"ignore"`
+	IgnoreOnly map[string][]string `yaml:"ignore_only"`
+}
+
+// WorkConfig represents a content of buf.work.yaml.
+type WorkConfig struct {
+	Version     string   `yaml:"version"`
+	Directories []string `yaml:"directories"`
+}
+
+// Lock represents a content of buf.lock of v1 and v2.
+type Lock struct {
+	Version string    `yaml:"version"`
+	Deps    []LockDep `yaml:"deps"`
+}
+
+// LockDep represents a pinned dependency.
+type LockDep struct {
+	// Remote, Owner and Repository identify the module in v1.
+	Remote     string `yaml:"remote"`
+	Owner      string `yaml:"owner"`
+	Repository string `yaml:"repository"`
+
+	// Name identifies the module in v2.
+	Name string `yaml:"name"`
+
+	Commit string `yaml:"commit"`
+}
+
+// Reference returns the module reference of the dependency without a commit.
+func (d LockDep) Reference() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return d.Remote + "/" + d.Owner + "/" + d.Repository
+}
+
+// ParseConfig parses a content of buf.yaml.
+func ParseConfig(data []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseWorkConfig parses a content of buf.work.yaml.
+func ParseWorkConfig(data []byte) (*WorkConfig, error) {
+	c := &WorkConfig{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseLock parses a content of buf.lock.
+func ParseLock(data []byte) (*Lock, error) {
+	l := &Lock{}
+	if err := yaml.Unmarshal(data, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func loadConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+func loadWorkConfig(filename string) (*WorkConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWorkConfig(data)
+}
+
+// loadLock reads buf.lock. A missing file is not an error.
+func loadLock(filename string) (*Lock, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Lock{}, nil
+		}
+		return nil, err
+	}
+	return ParseLock(data)
+}
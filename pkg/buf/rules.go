@@ -0,0 +1,198 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"sort"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+// lintRules maps buf lint rule IDs to the lint rule IDs of the server.
+// Only the rules which the server checks are listed.
+var lintRules = map[string]string{
+	"ENUM_FIRST_VALUE_ZERO":       "ENUM_FIRST_VALUE_ZERO",
+	"ENUM_PASCAL_CASE":            "ENUM_PASCAL_CASE",
+	"ENUM_VALUE_PREFIX":           "ENUM_VALUE_PREFIX",
+	"ENUM_VALUE_UPPER_SNAKE_CASE": "ENUM_VALUE_UPPER_SNAKE_CASE",
+	"ENUM_ZERO_VALUE_SUFFIX":      "ENUM_ZERO_VALUE_SUFFIX",
+	"FIELD_LOWER_SNAKE_CASE":      "FIELD_LOWER_SNAKE_CASE",
+	"MESSAGE_PASCAL_CASE":         "MESSAGE_PASCAL_CASE",
+	"PACKAGE_DIRECTORY_MATCH":     "PACKAGE_DIRECTORY_MATCH",
+	"RPC_PASCAL_CASE":             "RPC_PASCAL_CASE",
+	"RPC_REQUEST_STANDARD_NAME":   "RPC_REQUEST_STANDARD_NAME",
+	"RPC_RESPONSE_STANDARD_NAME":  "RPC_RESPONSE_STANDARD_NAME",
+	"SERVICE_PASCAL_CASE":         "SERVICE_PASCAL_CASE",
+}
+
+// defaultLintRules is the buf lint rule IDs of DEFAULT in v1 and STANDARD in v2.
+var defaultLintRules = []string{
+	"ENUM_FIRST_VALUE_ZERO",
+	"ENUM_PASCAL_CASE",
+	"ENUM_VALUE_PREFIX",
+	"ENUM_VALUE_UPPER_SNAKE_CASE",
+	"ENUM_ZERO_VALUE_SUFFIX",
+	"FIELD_LOWER_SNAKE_CASE",
+	"MESSAGE_PASCAL_CASE",
+	"PACKAGE_DIRECTORY_MATCH",
+	"RPC_PASCAL_CASE",
+	"RPC_REQUEST_STANDARD_NAME",
+	"RPC_RESPONSE_STANDARD_NAME",
+	"SERVICE_PASCAL_CASE",
+}
+
+// lintCategories maps buf lint categories to the buf lint rule IDs they contain.
+// Only the rules listed in lintRules are contained.
+var lintCategories = map[string][]string{
+	"MINIMAL": {
+		"PACKAGE_DIRECTORY_MATCH",
+	},
+	"BASIC": {
+		"ENUM_FIRST_VALUE_ZERO",
+		"ENUM_PASCAL_CASE",
+		"ENUM_VALUE_UPPER_SNAKE_CASE",
+		"FIELD_LOWER_SNAKE_CASE",
+		"MESSAGE_PASCAL_CASE",
+		"PACKAGE_DIRECTORY_MATCH",
+		"RPC_PASCAL_CASE",
+		"SERVICE_PASCAL_CASE",
+	},
+	"DEFAULT":  defaultLintRules,
+	"STANDARD": defaultLintRules,
+}
+
+// breakingRules maps buf breaking rule IDs to the breaking rule IDs of the server.
+// Only the rules which the server checks are listed.
+var breakingRules = map[string]string{
+	"ENUM_VALUE_SAME_NAME":                   "ENUM_VALUE_SAME_NAME",
+	"FIELD_NO_DELETE":                        "FIELD_NO_DELETE",
+	"FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED": "FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED",
+	"FIELD_SAME_CARDINALITY":                 "FIELD_SAME_CARDINALITY",
+	"FIELD_SAME_LABEL":                       "FIELD_SAME_CARDINALITY",
+	"FIELD_SAME_TYPE":                        "FIELD_SAME_TYPE",
+	"FIELD_WIRE_COMPATIBLE_CARDINALITY":      "FIELD_SAME_CARDINALITY",
+	"FIELD_WIRE_COMPATIBLE_TYPE":             "FIELD_SAME_TYPE",
+	"FIELD_WIRE_JSON_COMPATIBLE_CARDINALITY": "FIELD_SAME_CARDINALITY",
+	"FIELD_WIRE_JSON_COMPATIBLE_TYPE":        "FIELD_SAME_TYPE",
+	"RPC_NO_DELETE":                          "RPC_NO_DELETE",
+	"RPC_SAME_CLIENT_STREAMING":              "RPC_SAME_CLIENT_STREAMING",
+	"RPC_SAME_SERVER_STREAMING":              "RPC_SAME_SERVER_STREAMING",
+}
+
+// breakingCategories maps buf breaking categories to the buf breaking rule IDs they contain.
+// Only the rules listed in breakingRules are contained.
+var breakingCategories = map[string][]string{
+	"FILE": {
+		"ENUM_VALUE_SAME_NAME",
+		"FIELD_NO_DELETE",
+		"FIELD_SAME_CARDINALITY",
+		"FIELD_SAME_TYPE",
+		"RPC_NO_DELETE",
+		"RPC_SAME_CLIENT_STREAMING",
+		"RPC_SAME_SERVER_STREAMING",
+	},
+	"PACKAGE": {
+		"ENUM_VALUE_SAME_NAME",
+		"FIELD_NO_DELETE",
+		"FIELD_SAME_CARDINALITY",
+		"FIELD_SAME_TYPE",
+		"RPC_NO_DELETE",
+		"RPC_SAME_CLIENT_STREAMING",
+		"RPC_SAME_SERVER_STREAMING",
+	},
+	"WIRE_JSON": {
+		"ENUM_VALUE_SAME_NAME",
+		"FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED",
+		"FIELD_WIRE_JSON_COMPATIBLE_CARDINALITY",
+		"FIELD_WIRE_JSON_COMPATIBLE_TYPE",
+		"RPC_SAME_CLIENT_STREAMING",
+		"RPC_SAME_SERVER_STREAMING",
+	},
+	"WIRE": {
+		"FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED",
+		"FIELD_WIRE_COMPATIBLE_CARDINALITY",
+		"FIELD_WIRE_COMPATIBLE_TYPE",
+		"RPC_SAME_CLIENT_STREAMING",
+		"RPC_SAME_SERVER_STREAMING",
+	},
+}
+
+// Lint converts buf lint checks to a lint configuration of the server.
+// Rules which the server doesn't check are dropped.
+func (c Checks) Lint() config.Lint {
+	use := c.Use
+	if len(use) == 0 {
+		use = []string{"DEFAULT"}
+	}
+	ignoreOnly := make(map[string][]string)
+	for id, paths := range c.IgnoreOnly {
+		for _, rule := range expand(id, lintRules, lintCategories) {
+			ignoreOnly[rule] = append(ignoreOnly[rule], paths...)
+		}
+	}
+	return config.Lint{
+		Use:        mapRules(use, lintRules, lintCategories),
+		Disable:    mapRules(c.Except, lintRules, lintCategories),
+		Ignore:     c.Ignore,
+		IgnoreOnly: ignoreOnly,
+	}
+}
+
+// Breaking converts buf breaking checks to a breaking change detection configuration of the server.
+// Rules which the server doesn't check are dropped.
+func (c Checks) Breaking() config.Breaking {
+	use := c.Use
+	if len(use) == 0 {
+		use = []string{"FILE"}
+	}
+	return config.Breaking{
+		Use:     mapRules(use, breakingRules, breakingCategories),
+		Disable: mapRules(c.Except, breakingRules, breakingCategories),
+		Ignore:  c.Ignore,
+	}
+}
+
+// mapRules expands categories and maps buf rule IDs to the rule IDs of the server.
+// The result is never nil so that an empty result means no rules.
+func mapRules(ids []string, rules map[string]string, categories map[string][]string) []string {
+	set := make(map[string]struct{})
+	for _, id := range ids {
+		for _, rule := range expand(id, rules, categories) {
+			set[rule] = struct{}{}
+		}
+	}
+	mapped := make([]string, 0, len(set))
+	for rule := range set {
+		mapped = append(mapped, rule)
+	}
+	sort.Strings(mapped)
+	return mapped
+}
+
+func expand(id string, rules map[string]string, categories map[string][]string) []string {
+	if ids, ok := categories[id]; ok {
+		expanded := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if rule, ok := rules[id]; ok {
+				expanded = append(expanded, rule)
+			}
+		}
+		return expanded
+	}
+	if rule, ok := rules[id]; ok {
+		return []string{rule}
+	}
+	return nil
+}
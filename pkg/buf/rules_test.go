@@ -0,0 +1,120 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+func TestChecks_Lint(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks Checks
+		want   config.Lint
+	}{
+		{
+			name:   "default",
+			checks: Checks{},
+			want: config.Lint{
+				Use:        []string{"ENUM_FIRST_VALUE_ZERO", "ENUM_PASCAL_CASE", "ENUM_VALUE_PREFIX", "ENUM_VALUE_UPPER_SNAKE_CASE", "ENUM_ZERO_VALUE_SUFFIX", "FIELD_LOWER_SNAKE_CASE", "MESSAGE_PASCAL_CASE", "PACKAGE_DIRECTORY_MATCH", "RPC_PASCAL_CASE", "RPC_REQUEST_STANDARD_NAME", "RPC_RESPONSE_STANDARD_NAME", "SERVICE_PASCAL_CASE"},
+				Disable:    []string{},
+				IgnoreOnly: map[string][]string{},
+			},
+		},
+		{
+			name: "category with exceptions and unknown rules",
+			checks: Checks{
+				Use:    []string{"MINIMAL", "COMMENT_FIELD", "FIELD_LOWER_SNAKE_CASE"},
+				Except: []string{"PACKAGE_DIRECTORY_MATCH", "PACKAGE_SAME_DIRECTORY"},
+				Ignore: []string{"vendor"},
+				IgnoreOnly: map[string][]string{
+					"BASIC": {"legacy"},
+				},
+			},
+			want: config.Lint{
+				Use:     []string{"FIELD_LOWER_SNAKE_CASE", "PACKAGE_DIRECTORY_MATCH"},
+				Disable: []string{"PACKAGE_DIRECTORY_MATCH"},
+				Ignore:  []string{"vendor"},
+				IgnoreOnly: map[string][]string{
+					"ENUM_FIRST_VALUE_ZERO":       {"legacy"},
+					"ENUM_PASCAL_CASE":            {"legacy"},
+					"ENUM_VALUE_UPPER_SNAKE_CASE": {"legacy"},
+					"FIELD_LOWER_SNAKE_CASE":      {"legacy"},
+					"MESSAGE_PASCAL_CASE":         {"legacy"},
+					"PACKAGE_DIRECTORY_MATCH":     {"legacy"},
+					"RPC_PASCAL_CASE":             {"legacy"},
+					"SERVICE_PASCAL_CASE":         {"legacy"},
+				},
+			},
+		},
+		{
+			name: "only unknown rules",
+			checks: Checks{
+				Use: []string{"COMMENTS"},
+			},
+			want: config.Lint{
+				Use:        []string{},
+				Disable:    []string{},
+				IgnoreOnly: map[string][]string{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.checks.Lint(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecks_Breaking(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks Checks
+		want   config.Breaking
+	}{
+		{
+			name: "default",
+			want: config.Breaking{
+				Use:     []string{"ENUM_VALUE_SAME_NAME", "FIELD_NO_DELETE", "FIELD_SAME_CARDINALITY", "FIELD_SAME_TYPE", "RPC_NO_DELETE", "RPC_SAME_CLIENT_STREAMING", "RPC_SAME_SERVER_STREAMING"},
+				Disable: []string{},
+			},
+		},
+		{
+			name: "wire",
+			checks: Checks{
+				Use:    []string{"WIRE"},
+				Except: []string{"FIELD_SAME_LABEL"},
+			},
+			want: config.Breaking{
+				Use:     []string{"FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED", "FIELD_SAME_CARDINALITY", "FIELD_SAME_TYPE", "RPC_SAME_CLIENT_STREAMING", "RPC_SAME_SERVER_STREAMING"},
+				Disable: []string{"FIELD_SAME_CARDINALITY"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.checks.Breaking(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Breaking() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+// Module represents a buf module. The directory of a module is an import root.
+type Module struct {
+	// Dir is the absolute path to the root of the module.
+	Dir  string
+	Name string
+
+	// Excludes is a list of directories to exclude relative to Dir.
+	Excludes []string
+
+	Lint     Checks
+	Breaking Checks
+}
+
+// Workspace represents a set of buf modules which can import each other.
+type Workspace struct {
+	// Dir is the absolute path to the directory which contains buf.work.yaml or buf.yaml.
+	Dir     string
+	Modules []*Module
+
+	// Deps is a list of module references the modules depend on.
+	Deps []string
+
+	// Commits maps module references to the commits pinned in buf.lock.
+	Commits map[string]string
+}
+
+// Load reads buf.work.yaml or buf.yaml in a given directory and returns Workspace.
+// buf.work.yaml takes precedence over buf.yaml.
+func Load(dir string) (*Workspace, error) {
+	w := &Workspace{
+		Dir:     dir,
+		Commits: make(map[string]string),
+	}
+
+	work, err := loadWorkConfig(filepath.Join(dir, WorkFilename))
+	switch {
+	case err == nil:
+		for _, d := range work.Directories {
+			if err := w.loadModuleDir(filepath.Join(dir, filepath.FromSlash(d))); err != nil {
+				return nil, err
+			}
+		}
+		return w, nil
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to load %s: %w", filepath.Join(dir, WorkFilename), err)
+	}
+
+	c, err := loadConfig(filepath.Join(dir, ConfigFilename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", filepath.Join(dir, ConfigFilename), err)
+	}
+	if err := w.addConfig(dir, c); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// loadModuleDir loads a module listed in buf.work.yaml.
+// A directory without buf.yaml is a module with the default configuration.
+func (w *Workspace) loadModuleDir(dir string) error {
+	c, err := loadConfig(filepath.Join(dir, ConfigFilename))
+	switch {
+	case os.IsNotExist(err):
+		c = &Config{Version: versionV1}
+	case err != nil:
+		return fmt.Errorf("failed to load %s: %w", filepath.Join(dir, ConfigFilename), err)
+	}
+	return w.addConfig(dir, c)
+}
+
+// addConfig adds the modules declared in buf.yaml placed in a given directory.
+func (w *Workspace) addConfig(dir string, c *Config) error {
+	switch c.Version {
+	case versionV2:
+		modules := c.Modules
+		if len(modules) == 0 {
+			modules = []ModuleConfig{{Path: "."}}
+		}
+		for _, mc := range modules {
+			path := filepath.Clean(filepath.FromSlash(mc.Path))
+			m := &Module{
+				Dir:      filepath.Join(dir, path),
+				Name:     mc.Name,
+				Excludes: relPaths(path, mc.Excludes),
+				Lint:     c.Lint,
+				Breaking: c.Breaking,
+			}
+			if mc.Lint != nil {
+				m.Lint = *mc.Lint
+			}
+			if mc.Breaking != nil {
+				m.Breaking = *mc.Breaking
+			}
+			m.Lint = m.Lint.relTo(path)
+			m.Breaking = m.Breaking.relTo(path)
+			w.Modules = append(w.Modules, m)
+		}
+
+	case versionV1Beta1, versionV1, "":
+		roots := c.Build.Roots
+		if len(roots) == 0 || c.Version != versionV1Beta1 {
+			roots = []string{"."}
+		}
+		for _, root := range roots {
+			root = filepath.Clean(filepath.FromSlash(root))
+			m := &Module{
+				Dir:      filepath.Join(dir, root),
+				Name:     c.Name,
+				Excludes: c.Build.Excludes,
+				Lint:     c.Lint,
+				Breaking: c.Breaking,
+			}
+			if c.Version == versionV1Beta1 {
+				// The paths in v1beta1 are relative to buf.yaml rather than to the root.
+				m.Excludes = relPaths(root, m.Excludes)
+				m.Lint = m.Lint.relTo(root)
+				m.Breaking = m.Breaking.relTo(root)
+			}
+			w.Modules = append(w.Modules, m)
+		}
+
+	default:
+		return fmt.Errorf("unsupported version %q of %s", c.Version, filepath.Join(dir, ConfigFilename))
+	}
+
+	w.Deps = append(w.Deps, c.Deps...)
+
+	lock, err := loadLock(filepath.Join(dir, LockFilename))
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", filepath.Join(dir, LockFilename), err)
+	}
+	for _, dep := range lock.Deps {
+		w.Commits[dep.Reference()] = dep.Commit
+	}
+
+	return nil
+}
+
+// Contains returns true if a given directory is the directory of this workspace or one of its modules.
+func (w *Workspace) Contains(dir string) bool {
+	if dir == w.Dir {
+		return true
+	}
+	for _, m := range w.Modules {
+		if dir == m.Dir {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportPaths returns the directories which imports are resolved against in this workspace.
+// The dependencies are resolved from a given local module cache directory.
+// The references of the dependencies which are not found in the cache are returned as missing.
+func (w *Workspace) ImportPaths(cacheDir string) (paths, missing []string) {
+	for _, m := range w.Modules {
+		paths = append(paths, m.Dir)
+	}
+	for _, dep := range w.Deps {
+		ref, commit := parseReference(dep)
+		if c, ok := w.Commits[ref]; ok {
+			commit = c
+		}
+		dir, ok := ResolveDep(cacheDir, ref, commit)
+		if !ok {
+			missing = append(missing, dep)
+			continue
+		}
+		paths = append(paths, dir)
+	}
+	return
+}
+
+// Projects converts the modules of this workspace to project configurations of the server.
+func (w *Workspace) Projects(cacheDir string) ([]config.Project, error) {
+	paths, missing := w.ImportPaths(cacheDir)
+
+	projects := make([]config.Project, 0, len(w.Modules))
+	for _, m := range w.Modules {
+		p := config.DefaultProjectConfig
+		p.Dir = m.Dir
+		p.IncludePaths = paths
+		p.Excludes = m.Excludes
+		p.Lint = m.Lint.Lint()
+		p.Breaking = m.Breaking.Breaking()
+		projects = append(projects, p)
+	}
+
+	if len(missing) > 0 {
+		return projects, fmt.Errorf("buf dependencies of %s not found in local module cache %s: %s",
+			w.Dir, cacheDir, strings.Join(missing, ", "))
+	}
+	return projects, nil
+}
+
+// relTo rebases the paths in checks relative to a given directory.
+func (c Checks) relTo(dir string) Checks {
+	rebased := Checks{
+		Use:    c.Use,
+		Except: c.Except,
+		Ignore: relPaths(dir, c.Ignore),
+	}
+	if c.IgnoreOnly != nil {
+		rebased.IgnoreOnly = make(map[string][]string, len(c.IgnoreOnly))
+		for id, paths := range c.IgnoreOnly {
+			rebased.IgnoreOnly[id] = relPaths(dir, paths)
+		}
+	}
+	return rebased
+}
+
+// relPaths converts slash-separated paths relative to the parent of dir into paths relative to dir.
+// Paths outside dir are dropped.
+func relPaths(dir string, paths []string) []string {
+	if dir == "." {
+		return paths
+	}
+	prefix := filepath.ToSlash(dir) + "/"
+	var rel []string
+	for _, path := range paths {
+		if strings.HasPrefix(path, prefix) {
+			rel = append(rel, strings.TrimPrefix(path, prefix))
+		}
+	}
+	return rel
+}
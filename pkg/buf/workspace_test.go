@@ -0,0 +1,176 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantModules []*Module
+		wantDeps    []string
+		wantErr     bool
+	}{
+		{
+			name: "v1 workspace",
+			files: map[string]string{
+				WorkFilename: "version: v1\ndirectories: [proto, vendor]\n",
+				"proto/" + ConfigFilename: `
+version: v1
+name: buf.build/acme/weather
+deps: [buf.build/googleapis/googleapis]
+build:
+  excludes: [gen]
+lint:
+  use: [BASIC]
+`,
+			},
+			wantModules: []*Module{
+				{
+					Dir:      "proto",
+					Name:     "buf.build/acme/weather",
+					Excludes: []string{"gen"},
+					Lint:     Checks{Use: []string{"BASIC"}},
+				},
+				{
+					Dir: "vendor",
+				},
+			},
+			wantDeps: []string{"buf.build/googleapis/googleapis"},
+		},
+		{
+			name: "v2 modules",
+			files: map[string]string{
+				ConfigFilename: `
+version: v2
+modules:
+  - path: proto
+    excludes: [proto/gen, other/gen]
+  - path: api
+    lint:
+      ignore: [api/legacy]
+lint:
+  except: [ENUM_VALUE_PREFIX]
+`,
+			},
+			wantModules: []*Module{
+				{
+					Dir:      "proto",
+					Excludes: []string{"gen"},
+					Lint:     Checks{Except: []string{"ENUM_VALUE_PREFIX"}},
+				},
+				{
+					Dir:  "api",
+					Lint: Checks{Ignore: []string{"legacy"}},
+				},
+			},
+		},
+		{
+			name: "unsupported version",
+			files: map[string]string{
+				ConfigFilename: "version: v3\n",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := ioutil.TempDir("", "buf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(root)
+			writeFiles(t, root, tt.files)
+
+			w, err := Load(root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, m := range tt.wantModules {
+				m.Dir = filepath.Join(root, m.Dir)
+			}
+			if !reflect.DeepEqual(w.Modules, tt.wantModules) {
+				t.Errorf("Load().Modules = %+v, want %+v", w.Modules, tt.wantModules)
+			}
+			if !reflect.DeepEqual(w.Deps, tt.wantDeps) {
+				t.Errorf("Load().Deps = %v, want %v", w.Deps, tt.wantDeps)
+			}
+		})
+	}
+}
+
+func TestWorkspace_ImportPaths(t *testing.T) {
+	root, err := ioutil.TempDir("", "buf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cacheDir := filepath.Join(root, "cache")
+	writeFiles(t, root, map[string]string{
+		ConfigFilename: "version: v1\ndeps: [buf.build/googleapis/googleapis, buf.build/acme/missing]\n",
+		LockFilename: `
+version: v1
+deps:
+  - remote: buf.build
+    owner: googleapis
+    repository: googleapis
+    commit: 62f35d8aed1149c291d606d958a7ce32
+`,
+		"cache/v1/module/data/buf.build/googleapis/googleapis/62f35d8aed1149c291d606d958a7ce32/google/api/http.proto": "",
+		"cache/v1/module/data/buf.build/googleapis/googleapis/75b4300737fb4efca0831636be94e517/google/api/http.proto": "",
+	})
+
+	w, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, missing := w.ImportPaths(cacheDir)
+
+	wantPaths := []string{
+		root,
+		filepath.Join(cacheDir, "v1", "module", "data", "buf.build", "googleapis", "googleapis", "62f35d8aed1149c291d606d958a7ce32"),
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("ImportPaths() paths = %v, want %v", paths, wantPaths)
+	}
+	if want := []string{"buf.build/acme/missing"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("ImportPaths() missing = %v, want %v", missing, want)
+	}
+}
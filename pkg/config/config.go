@@ -24,6 +24,17 @@ const envPrefix = "PROTOBUF_LSP"
 var (
 	DefaultLSPConfig = LSP{
 		TextDocumentSyncKind: protocol.Full,
+		Lint: LSPLint{
+			Enabled: true,
+		},
+		Index: LSPIndex{
+			Enabled:     true,
+			MaxFileSize: 1 << 20,
+		},
+		InlayHints: LSPInlayHints{
+			ResolvedTypes: true,
+			EnumAliases:   true,
+		},
 	}
 )
 
@@ -46,8 +57,94 @@ type Server struct {
 }
 
 // LSP represents a configuration for LSP.
+// It is populated from the settings sent by a client.
 type LSP struct {
-	TextDocumentSyncKind protocol.TextDocumentSyncKind
+	// TextDocumentSyncKind is only respected in initializationOptions
+	// since it is advertised as a server capability.
+	TextDocumentSyncKind protocol.TextDocumentSyncKind `json:"syncKind"`
+
+	// IncludePaths is a list of directories which imports are resolved against
+	// in addition to the ones declared in project configuration files.
+	// Relative paths are resolved against the workspace folder.
+	IncludePaths []string `json:"includePaths"`
+
+	Lint LSPLint `json:"lint"`
+
+	Index LSPIndex `json:"index"`
+
+	Breaking LSPBreaking `json:"breaking"`
+
+	OnSave LSPOnSave `json:"onSave"`
+
+	InlayHints LSPInlayHints `json:"inlayHints"`
+
+	// GoNavigation toggles adding the Go declarations generated by protoc-gen-go and protoc-gen-go-grpc
+	// to the results of definition and references.
+	GoNavigation bool `json:"goNavigation"`
+
+	// Format overrides the formatter options declared in project configuration files if set.
+	Format *Format `json:"format"`
+
+	// LogLevel changes the level of logging at runtime if set.
+	LogLevel string `json:"logLevel"`
+}
+
+// LSPLint represents a configuration for lint sent by a client.
+type LSPLint struct {
+	// Enabled toggles all lint diagnostics.
+	Enabled bool `json:"enabled"`
+
+	// Enable is a list of rule IDs to enable in addition to the ones declared in project configuration files.
+	Enable []string `json:"enable"`
+
+	// Disable is a list of rule IDs to disable in addition to the ones declared in project configuration files.
+	Disable []string `json:"disable"`
+}
+
+// LSPIndex represents a configuration for indexing sent by a client.
+type LSPIndex struct {
+	// Enabled toggles indexing all proto files in workspace folders in the background.
+	Enabled bool `json:"enabled"`
+
+	// Excludes is a list of glob patterns of files which are not indexed.
+	// Patterns are matched against slash-separated paths relative to the workspace folder.
+	Excludes []string `json:"excludes"`
+
+	// MaxFileSize is the size in bytes of the largest file to index.
+	MaxFileSize int64 `json:"maxFileSize"`
+}
+
+// LSPBreaking represents a configuration for breaking change detection sent by a client.
+type LSPBreaking struct {
+	// Enabled toggles breaking change diagnostics.
+	Enabled bool `json:"enabled"`
+
+	// Against overrides the git revision declared in project configuration files if set.
+	Against string `json:"against"`
+}
+
+// LSPOnSave represents a configuration for the source actions applied when a file is saved.
+type LSPOnSave struct {
+	// OrganizeImports toggles removing, adding, grouping and sorting imports on save.
+	OrganizeImports bool `json:"organizeImports"`
+
+	// SortFields toggles sorting fields in messages by number on save.
+	SortFields bool `json:"sortFields"`
+}
+
+// LSPInlayHints represents a configuration for inlay hints sent by a client.
+type LSPInlayHints struct {
+	// ResolvedTypes toggles the fully-qualified names of the types referred to with partial names.
+	ResolvedTypes bool `json:"resolvedTypes"`
+
+	// WireTypes toggles the wire types of scalar fields.
+	WireTypes bool `json:"wireTypes"`
+
+	// DefaultValues toggles the implicit default values of singular fields.
+	DefaultValues bool `json:"defaultValues"`
+
+	// EnumAliases toggles the names of the enum values which aliases share their numbers with.
+	EnumAliases bool `json:"enumAliases"`
 }
 
 // Log represents a configuration for zap.Logger.
@@ -0,0 +1,83 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+	}{
+		{
+			name:    "exact match",
+			pattern: "foo/bar.proto",
+			path:    "foo/bar.proto",
+			want:    true,
+		},
+		{
+			name:    "wildcard in segment",
+			pattern: "foo/*.proto",
+			path:    "foo/bar.proto",
+			want:    true,
+		},
+		{
+			name:    "wildcard does not cross directories",
+			pattern: "foo/*.proto",
+			path:    "foo/baz/bar.proto",
+			want:    false,
+		},
+		{
+			name:    "double star matches nested directories",
+			pattern: "**/gen/*.proto",
+			path:    "a/b/gen/bar.proto",
+			want:    true,
+		},
+		{
+			name:    "double star matches zero directories",
+			pattern: "**/bar.proto",
+			path:    "bar.proto",
+			want:    true,
+		},
+		{
+			name:    "parent directory matches",
+			pattern: "vendor",
+			path:    "vendor/google/api/http.proto",
+			want:    true,
+		},
+		{
+			name:    "no match",
+			pattern: "vendor",
+			path:    "api/vendor.proto",
+			want:    false,
+		},
+		{
+			name:    "empty pattern",
+			pattern: "",
+			path:    "foo.proto",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ProjectFilename is the name of a project configuration file.
+// It is looked up at the root of a workspace folder and its nested directories.
+const ProjectFilename = ".protobuf-language-server.yaml"
+
+var (
+	DefaultProjectConfig = Project{
+		Format: Format{
+			IndentSize: 2,
+		},
+		Generate: Generate{
+			Timeout: 10 * time.Second,
+		},
+	}
+)
+
+// Project represents a configuration for a project declared in a project configuration file.
+// A project configuration applies to the directory it is placed in and all of its subdirectories
+// unless a subdirectory has its own project configuration.
+type Project struct {
+	// Dir is the absolute path to the directory the configuration was loaded from.
+	Dir string `yaml:"-"`
+
+	// IncludePaths is a list of directories which imports are resolved against.
+	// Relative paths are resolved against Dir.
+	IncludePaths []string `yaml:"include_paths"`
+
+	// Excludes is a list of glob patterns for files which are ignored.
+	// Patterns are matched against slash-separated paths relative to Dir.
+	Excludes []string `yaml:"excludes"`
+
+	Lint     Lint     `yaml:"lint"`
+	Breaking Breaking `yaml:"breaking"`
+	Format   Format   `yaml:"format"`
+	Generate Generate `yaml:"generate"`
+}
+
+// Lint represents a configuration for lint rules.
+type Lint struct {
+	// Use is a list of rule IDs which replaces the default rule set.
+	// A nil Use means the default rule set, while a non-nil empty Use means no rules.
+	Use []string `yaml:"use"`
+
+	// Enable is a list of rule IDs to enable in addition to the default ones.
+	Enable []string `yaml:"enable"`
+
+	// Disable is a list of rule IDs to disable.
+	Disable []string `yaml:"disable"`
+
+	// Severity overrides the severity of rules keyed by rule ID.
+	// Valid values are "error", "warning", "info" and "hint".
+	Severity map[string]string `yaml:"severity"`
+
+	// Ignore is a list of glob patterns of files which all rules are ignored for.
+	Ignore []string `yaml:"ignore"`
+
+	// IgnoreOnly is a list of glob patterns of files which a rule is ignored for keyed by rule ID.
+	IgnoreOnly map[string][]string `yaml:"ignore_only"`
+}
+
+// Breaking represents a configuration for breaking change detection.
+type Breaking struct {
+	// Use is a list of rule IDs which replaces the default rule set.
+	// A nil Use means the default rule set, while a non-nil empty Use means no rules.
+	Use []string `yaml:"use"`
+
+	// Disable is a list of rule IDs to disable.
+	Disable []string `yaml:"disable"`
+
+	// Ignore is a list of glob patterns of files which are not checked.
+	Ignore []string `yaml:"ignore"`
+
+	// Against is the git revision which files are compared with, such as "HEAD" or "main".
+	// It is read from the local repository the files belong to.
+	Against string `yaml:"against"`
+}
+
+// Format represents a configuration for formatter.
+type Format struct {
+	// IndentSize is the number of spaces used for an indentation level.
+	IndentSize int `yaml:"indent_size" json:"indentSize"`
+
+	// UseTabs uses tabs instead of spaces for indentation.
+	UseTabs bool `yaml:"use_tabs" json:"useTabs"`
+
+	// AlignFields aligns field names, numbers and options in consecutive declarations.
+	AlignFields bool `yaml:"align_fields" json:"alignFields"`
+}
+
+// Generate represents a configuration for previews of generated code.
+type Generate struct {
+	// Plugins is a list of protoc plugins to run.
+	// An empty Plugins means protoc-gen-go and protoc-gen-go-grpc if they are installed.
+	Plugins []Plugin `yaml:"plugins"`
+
+	// Timeout is the time each plugin may run for, such as "30s".
+	// A plugin running longer is killed so that a hung plugin doesn't block the server.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Plugin represents a protoc plugin.
+type Plugin struct {
+	// Name is the name of the plugin, such as "go" for protoc-gen-go.
+	Name string `yaml:"name"`
+
+	// Path is the path to the executable of the plugin. Defaults to protoc-gen-<Name> in PATH.
+	Path string `yaml:"path"`
+
+	// Opt is the parameter passed to the plugin, such as "paths=source_relative".
+	Opt string `yaml:"opt"`
+}
+
+// LoadProject reads a project configuration file and returns Project.
+func LoadProject(filename string) (Project, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Project{}, err
+	}
+	p, err := ParseProject(data)
+	if err != nil {
+		return Project{}, err
+	}
+	p.Dir = filepath.Dir(filename)
+	return p, nil
+}
+
+// ParseProject parses a content of a project configuration file.
+// Missing values are filled with the values of DefaultProjectConfig.
+func ParseProject(data []byte) (Project, error) {
+	p := DefaultProjectConfig
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return Project{}, err
+	}
+	if p.Format.IndentSize <= 0 {
+		p.Format.IndentSize = DefaultProjectConfig.Format.IndentSize
+	}
+	if p.Generate.Timeout <= 0 {
+		p.Generate.Timeout = DefaultProjectConfig.Generate.Timeout
+	}
+	return p, nil
+}
+
+// AbsIncludePaths returns the include paths resolved against the directory of the configuration.
+func (p Project) AbsIncludePaths() []string {
+	paths := make([]string, 0, len(p.IncludePaths))
+	for _, path := range p.IncludePaths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.Dir, path)
+		}
+		paths = append(paths, filepath.Clean(path))
+	}
+	return paths
+}
+
+// Excluded returns true if a given file matches one of the exclude patterns.
+func (p Project) Excluded(filename string) bool {
+	return p.Match(p.Excludes, filename)
+}
+
+// Match returns true if a given file matches one of the patterns relative to Dir.
+func (p Project) Match(patterns []string, filename string) bool {
+	rel, err := filepath.Rel(p.Dir, filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
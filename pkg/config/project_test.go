@@ -0,0 +1,130 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Project
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			data: "",
+			want: DefaultProjectConfig,
+		},
+		{
+			name: "full",
+			data: `
+include_paths:
+  - third_party
+excludes:
+  - gen/**
+lint:
+  enable: [FIELD_LOWER_SNAKE_CASE]
+  disable: [PACKAGE_DIRECTORY_MATCH]
+  severity:
+    ENUM_ZERO_VALUE_SUFFIX: error
+format:
+  indent_size: 4
+  align_fields: true
+generate:
+  plugins:
+    - name: go
+      opt: paths=source_relative
+  timeout: 30s
+`,
+			want: Project{
+				IncludePaths: []string{"third_party"},
+				Excludes:     []string{"gen/**"},
+				Lint: Lint{
+					Enable:   []string{"FIELD_LOWER_SNAKE_CASE"},
+					Disable:  []string{"PACKAGE_DIRECTORY_MATCH"},
+					Severity: map[string]string{"ENUM_ZERO_VALUE_SUFFIX": "error"},
+				},
+				Format: Format{
+					IndentSize:  4,
+					AlignFields: true,
+				},
+				Generate: Generate{
+					Plugins: []Plugin{{Name: "go", Opt: "paths=source_relative"}},
+					Timeout: 30 * time.Second,
+				},
+			},
+		},
+		{
+			name: "zero timeout",
+			data: "generate: {timeout: 0s}",
+			want: DefaultProjectConfig,
+		},
+		{
+			name:    "unknown field",
+			data:    "unknown: true",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProject([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProject() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProject_AbsIncludePaths(t *testing.T) {
+	dir := filepath.FromSlash("/workspace/api")
+	p := Project{
+		Dir:          dir,
+		IncludePaths: []string{"third_party", filepath.FromSlash("/usr/include")},
+	}
+	want := []string{
+		filepath.Join(dir, "third_party"),
+		filepath.FromSlash("/usr/include"),
+	}
+	if got := p.AbsIncludePaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AbsIncludePaths() = %v, want %v", got, want)
+	}
+}
+
+func TestProject_Excluded(t *testing.T) {
+	dir := filepath.FromSlash("/workspace/api")
+	p := Project{
+		Dir:      dir,
+		Excludes: []string{"gen/**"},
+	}
+	if !p.Excluded(filepath.Join(dir, "gen", "foo.proto")) {
+		t.Error("Excluded() = false, want true")
+	}
+	if p.Excluded(filepath.Join(dir, "foo.proto")) {
+		t.Error("Excluded() = true, want false")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/go-language-server/protocol"
+)
+
+// Section is the name of the configuration section of the server in client settings.
+const Section = "protobuf"
+
+// ParseLSP overlays settings sent by a client on a given base configuration.
+// The settings may be nested in Section as most clients send all of their settings.
+// A nil settings returns the base configuration as it is.
+func ParseLSP(settings interface{}, base LSP) (LSP, error) {
+	if settings == nil {
+		return base, nil
+	}
+	if m, ok := settings.(map[string]interface{}); ok {
+		if section, ok := m[Section]; ok {
+			settings = section
+		}
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return LSP{}, err
+	}
+
+	cfg := base
+	// Copy the slices so that unmarshaling doesn't modify the ones of base.
+	cfg.IncludePaths = append([]string(nil), base.IncludePaths...)
+	cfg.Lint.Enable = append([]string(nil), base.Lint.Enable...)
+	cfg.Lint.Disable = append([]string(nil), base.Lint.Disable...)
+	cfg.Index.Excludes = append([]string(nil), base.Index.Excludes...)
+	if base.Format != nil {
+		format := *base.Format
+		cfg.Format = &format
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return LSP{}, err
+	}
+	// Incremental changes are not supported, so any sync kind but None falls back to Full.
+	if cfg.TextDocumentSyncKind != protocol.None {
+		cfg.TextDocumentSyncKind = protocol.Full
+	}
+	if cfg.Format != nil && cfg.Format.IndentSize <= 0 {
+		cfg.Format.IndentSize = DefaultProjectConfig.Format.IndentSize
+	}
+	return cfg, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+)
+
+func TestParseLSP(t *testing.T) {
+	base := LSP{
+		TextDocumentSyncKind: protocol.Full,
+		IncludePaths:         []string{"third_party"},
+		Lint: LSPLint{
+			Enabled: true,
+		},
+		InlayHints: LSPInlayHints{
+			ResolvedTypes: true,
+			EnumAliases:   true,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		settings interface{}
+		want     LSP
+		wantErr  bool
+	}{
+		{
+			name:     "nil",
+			settings: nil,
+			want:     base,
+		},
+		{
+			name: "section",
+			settings: map[string]interface{}{
+				"protobuf": map[string]interface{}{
+					"includePaths": []interface{}{"vendor"},
+					"lint": map[string]interface{}{
+						"enabled": false,
+						"disable": []interface{}{"PACKAGE_DIRECTORY_MATCH"},
+					},
+					"breaking": map[string]interface{}{
+						"enabled": true,
+						"against": "main",
+					},
+					"onSave": map[string]interface{}{
+						"organizeImports": true,
+					},
+					"inlayHints": map[string]interface{}{
+						"wireTypes":   true,
+						"enumAliases": false,
+					},
+					"goNavigation": true,
+					"logLevel":     "debug",
+				},
+			},
+			want: LSP{
+				TextDocumentSyncKind: protocol.Full,
+				IncludePaths:         []string{"vendor"},
+				Lint: LSPLint{
+					Enabled: false,
+					Disable: []string{"PACKAGE_DIRECTORY_MATCH"},
+				},
+				Breaking: LSPBreaking{
+					Enabled: true,
+					Against: "main",
+				},
+				OnSave: LSPOnSave{
+					OrganizeImports: true,
+				},
+				InlayHints: LSPInlayHints{
+					ResolvedTypes: true,
+					WireTypes:     true,
+				},
+				GoNavigation: true,
+				LogLevel:     "debug",
+			},
+		},
+		{
+			name: "without section",
+			settings: map[string]interface{}{
+				"format": map[string]interface{}{
+					"useTabs": true,
+				},
+			},
+			want: LSP{
+				TextDocumentSyncKind: protocol.Full,
+				IncludePaths:         []string{"third_party"},
+				Lint: LSPLint{
+					Enabled: true,
+				},
+				InlayHints: LSPInlayHints{
+					ResolvedTypes: true,
+					EnumAliases:   true,
+				},
+				Format: &Format{
+					IndentSize: DefaultProjectConfig.Format.IndentSize,
+					UseTabs:    true,
+				},
+			},
+		},
+		{
+			name: "incremental sync",
+			settings: map[string]interface{}{
+				"syncKind": float64(protocol.Incremental),
+			},
+			want: base,
+		},
+		{
+			name: "no sync",
+			settings: map[string]interface{}{
+				"syncKind": float64(protocol.None),
+			},
+			want: LSP{
+				TextDocumentSyncKind: protocol.None,
+				IncludePaths:         []string{"third_party"},
+				Lint: LSPLint{
+					Enabled: true,
+				},
+				InlayHints: LSPInlayHints{
+					ResolvedTypes: true,
+					EnumAliases:   true,
+				},
+			},
+		},
+		{
+			name: "invalid type",
+			settings: map[string]interface{}{
+				"includePaths": "vendor",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLSP(tt.settings, base)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLSP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLSP() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(base.IncludePaths, []string{"third_party"}) {
+		t.Errorf("ParseLSP() modified base: %v", base.IncludePaths)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change is a replacement of the lines A[A0:A1] with the lines B[B0:B1].
+// Either range may be empty for a pure insertion or deletion.
+type Change struct {
+	A0, A1 int
+	B0, B1 int
+}
+
+// SplitLines splits a text into lines keeping their line terminators.
+// The last line has no terminator if the text doesn't end with a newline.
+func SplitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines returns the changes which turn the lines a into the lines b in ascending order.
+// It finds a shortest edit script by the Myers' algorithm.
+func Lines(a, b []string) []Change {
+	n, m := len(a), len(b)
+	offset := n + m
+	if offset == 0 {
+		return nil
+	}
+
+	// v holds the furthest x of each diagonal k = x - y at the index offset+k.
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+	found := false
+	for d := 0; d <= offset && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Walk back the trace to collect whether each line is kept, deleted or inserted.
+	type op struct {
+		kind byte
+		x, y int
+	}
+	var ops []op
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: '=', x: x, y: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{kind: '+', x: x, y: y})
+		} else {
+			x--
+			ops = append(ops, op{kind: '-', x: x, y: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{kind: '=', x: x, y: y})
+	}
+
+	var changes []Change
+	var cur *Change
+	for i := len(ops) - 1; i >= 0; i-- {
+		o := ops[i]
+		if o.kind == '=' {
+			if cur != nil {
+				changes = append(changes, *cur)
+				cur = nil
+			}
+			continue
+		}
+		if cur == nil {
+			cur = &Change{A0: o.x, A1: o.x, B0: o.y, B1: o.y}
+		}
+		if o.kind == '-' {
+			cur.A1 = o.x + 1
+		} else {
+			cur.B1 = o.y + 1
+		}
+	}
+	if cur != nil {
+		changes = append(changes, *cur)
+	}
+	return changes
+}
+
+// contextLines is the number of unchanged lines around changes in a unified diff.
+const contextLines = 3
+
+// Unified returns the unified diff between two texts with given names.
+// It returns the empty string if the texts are the same.
+func Unified(fromName, toName, from, to string) string {
+	a, b := SplitLines(from), SplitLines(to)
+	changes := Lines(a, b)
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(changes); {
+		// Merge the changes whose contexts overlap into a hunk.
+		j := i + 1
+		for j < len(changes) && changes[j].A0-changes[j-1].A1 <= 2*contextLines {
+			j++
+		}
+		first, last := changes[i], changes[j-1]
+		a0 := max(first.A0-contextLines, 0)
+		a1 := min(last.A1+contextLines, len(a))
+		b0 := first.B0 - (first.A0 - a0)
+		b1 := last.B1 + (a1 - last.A1)
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(a0, a1), hunkRange(b0, b1))
+
+		pos := a0
+		for _, c := range changes[i:j] {
+			for ; pos < c.A0; pos++ {
+				writeLine(&sb, ' ', a[pos])
+			}
+			for _, line := range a[c.A0:c.A1] {
+				writeLine(&sb, '-', line)
+			}
+			for _, line := range b[c.B0:c.B1] {
+				writeLine(&sb, '+', line)
+			}
+			pos = c.A1
+		}
+		for ; pos < a1; pos++ {
+			writeLine(&sb, ' ', a[pos])
+		}
+		i = j
+	}
+	return sb.String()
+}
+
+func hunkRange(start, end int) string {
+	switch n := end - start; n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, n)
+	}
+}
+
+func writeLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []Change
+	}{
+		{
+			name: "same",
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+		},
+		{
+			name: "insert",
+			a:    []string{"a", "c"},
+			b:    []string{"a", "b", "c"},
+			want: []Change{{A0: 1, A1: 1, B0: 1, B1: 2}},
+		},
+		{
+			name: "delete",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"a", "c"},
+			want: []Change{{A0: 1, A1: 2, B0: 1, B1: 1}},
+		},
+		{
+			name: "replace",
+			a:    []string{"a", "b", "c", "d"},
+			b:    []string{"a", "x", "c", "y"},
+			want: []Change{{A0: 1, A1: 2, B0: 1, B1: 2}, {A0: 3, A1: 4, B0: 3, B1: 4}},
+		},
+		{
+			name: "from empty",
+			b:    []string{"a"},
+			want: []Change{{A0: 0, A1: 0, B0: 0, B1: 1}},
+		},
+		{
+			name: "to empty",
+			a:    []string{"a"},
+			want: []Change{{A0: 0, A1: 1, B0: 0, B1: 0}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLines_Apply(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(20))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := random(), random()
+		var got []string
+		pos := 0
+		for _, c := range Lines(a, b) {
+			got = append(got, a[pos:c.A0]...)
+			got = append(got, b[c.B0:c.B1]...)
+			pos = c.A1
+		}
+		got = append(got, a[pos:]...)
+		if strings.Join(got, "") != strings.Join(b, "") {
+			t.Fatalf("applying Lines(%v, %v) = %v", a, b, got)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj"
+	want := `--- a.proto
++++ b.proto
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -7,4 +7,4 @@
+ g
+ h
+ i
+-j
++j
+\ No newline at end of file
+`
+	if got := Unified("a.proto", "b.proto", from, to); got != want {
+		t.Errorf("Unified() = %q, want %q", got, want)
+	}
+	if got := Unified("a.proto", "b.proto", from, from); got != "" {
+		t.Errorf("Unified() = %q, want empty", got)
+	}
+}
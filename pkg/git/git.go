@@ -0,0 +1,67 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotExist is returned when a file doesn't exist at a revision.
+var ErrNotExist = errors.New("file does not exist at the revision")
+
+// ShowFile returns the content of a file at a given revision of the repository the file belongs to.
+// It only reads the local repository and never accesses the network.
+func ShowFile(ctx context.Context, filename, rev string) ([]byte, error) {
+	dir, base := filepath.Split(filename)
+	// "./" makes the path relative to the working directory rather than the root of the repository.
+	out, err := run(ctx, dir, "show", fmt.Sprintf("%s:./%s", rev, base))
+	if err != nil {
+		if strings.Contains(err.Error(), "exists on disk, but not in") || strings.Contains(err.Error(), "does not exist in") {
+			return nil, ErrNotExist
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResolveRevision returns the commit hash which a given revision points to in the repository of a directory.
+func ResolveRevision(ctx context.Context, dir, rev string) (string, error) {
+	out, err := run(ctx, dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve revision %q: %w", rev, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestShowFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	if err := os.MkdirAll(filepath.Join(dir, "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "foo", "foo.proto")
+	if err := ioutil.WriteFile(filename, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		if _, err := run(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filename, []byte("after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ShowFile(ctx, filename, "HEAD")
+	if err != nil {
+		t.Fatalf("ShowFile() error = %v", err)
+	}
+	if string(got) != "before" {
+		t.Errorf("ShowFile() = %q, want %q", got, "before")
+	}
+
+	if _, err := ShowFile(ctx, filepath.Join(dir, "foo", "bar.proto"), "HEAD"); err != ErrNotExist {
+		t.Errorf("ShowFile() error = %v, want %v", err, ErrNotExist)
+	}
+
+	if _, err := ResolveRevision(ctx, dir, "HEAD"); err != nil {
+		t.Errorf("ResolveRevision() error = %v", err)
+	}
+	if _, err := ResolveRevision(ctx, dir, "unknown"); err == nil {
+		t.Error("ResolveRevision() error = nil, want an error")
+	}
+}
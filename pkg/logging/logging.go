@@ -27,11 +27,22 @@ import (
 
 // NewLogger returns *zap.Logger initialized by provided log level and []zap.Option.
 func NewLogger(cfg config.Log, opts ...zap.Option) (*zap.Logger, error) {
+	logger, _, err := NewLoggerWithLevel(cfg, opts...)
+	return logger, err
+}
+
+// NewLoggerWithLevel is like NewLogger but also returns zap.AtomicLevel
+// which changes the level of the returned logger at runtime.
+func NewLoggerWithLevel(cfg config.Log, opts ...zap.Option) (*zap.Logger, zap.AtomicLevel, error) {
 	c, err := newConfig(cfg)
 	if err != nil {
-		return nil, err
+		return nil, zap.AtomicLevel{}, err
+	}
+	logger, err := c.Build(opts...)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, err
 	}
-	return c.Build(opts...)
+	return logger, c.Level, nil
 }
 
 func newConfig(cfg config.Log) (zap.Config, error) {
@@ -61,7 +72,7 @@ func newConfig(cfg config.Log) (zap.Config, error) {
 		ErrorOutputPaths: []string{"stderr"},
 	}
 
-	l, err := parseLogLevel(cfg.Level)
+	l, err := ParseLevel(cfg.Level)
 	if err != nil {
 		return zap.Config{}, err
 	}
@@ -79,7 +90,8 @@ func logTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	enc.AppendString(t.Format("2006-01-02T15:04:05"))
 }
 
-func parseLogLevel(levelStr string) (zapcore.Level, error) {
+// ParseLevel parses a case-insensitive name of log level.
+func ParseLevel(levelStr string) (zapcore.Level, error) {
 	switch strings.ToUpper(levelStr) {
 	case zapcore.DebugLevel.CapitalString():
 		return zapcore.DebugLevel, nil
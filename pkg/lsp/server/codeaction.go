@@ -0,0 +1,127 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-language-server/protocol"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// codeActionKinds are the kinds of the code actions which the server provides.
+var codeActionKinds = []protocol.CodeActionKind{
+	protocol.QuickFix,
+	protocol.RefactorExtract,
+	protocol.RefactorInline,
+	source.RefactorMove,
+	protocol.SourceOrganizeImports,
+	source.SourceSortFields,
+}
+
+func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionParams) (result []source.CodeAction, err error) {
+	logger := logging.FromContext(ctx)
+
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+	only := params.Context.Only
+
+	result = []source.CodeAction{}
+	if wantCodeActionKind(only, protocol.QuickFix) {
+		actions, err := source.QuickFixes(ctx, v, uri, params.Context.Diagnostics)
+		if err != nil {
+			logger.Debug("failed to compute quick fixes", zap.String("uri", string(uri)), zap.Error(err))
+		}
+		result = append(result, actions...)
+	}
+	if wantCodeActionKind(only, protocol.Refactor) {
+		actions, err := source.Refactorings(ctx, v, uri, params.Range, s.canCreateFiles())
+		if err != nil {
+			logger.Debug("failed to compute refactorings", zap.String("uri", string(uri)), zap.Error(err))
+		}
+		for _, a := range actions {
+			if wantCodeActionKind(only, a.Kind) {
+				result = append(result, a)
+			}
+		}
+	}
+	if wantCodeActionKind(only, protocol.Source) {
+		actions, err := source.SourceActions(ctx, v, uri)
+		if err != nil {
+			logger.Debug("failed to compute source actions", zap.String("uri", string(uri)), zap.Error(err))
+		}
+		for _, a := range actions {
+			if wantCodeActionKind(only, a.Kind) {
+				result = append(result, a)
+			}
+		}
+	}
+	return result, nil
+}
+
+// wantCodeActionKind returns true if the code actions of a kind are requested.
+// An empty only requests all kinds. A kind is requested by its parents such as "refactor" for "refactor.extract",
+// and requests its children in turn.
+func wantCodeActionKind(only []protocol.CodeActionKind, kind protocol.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, k := range only {
+		if kind == k || strings.HasPrefix(string(kind), string(k)+".") || strings.HasPrefix(string(k), string(kind)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// canCreateFiles returns true if the client can create files with workspace edits.
+func (s *Server) canCreateFiles() bool {
+	w := s.capabilities.Workspace
+	if w == nil || w.WorkspaceEdit == nil || !w.WorkspaceEdit.DocumentChanges {
+		return false
+	}
+	for _, op := range w.WorkspaceEdit.ResourceOperations {
+		if op == string(protocol.CreateResourceOperation) {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolCodeActions converts code actions to the ones of the protocol package.
+// The code actions with document changes are dropped since they may create files.
+func protocolCodeActions(actions []source.CodeAction) []protocol.CodeAction {
+	result := make([]protocol.CodeAction, 0, len(actions))
+	for _, a := range actions {
+		action := protocol.CodeAction{
+			Title:       a.Title,
+			Kind:        a.Kind,
+			Diagnostics: a.Diagnostics,
+			Command:     a.Command,
+		}
+		if a.Edit != nil {
+			if len(a.Edit.DocumentChanges) > 0 {
+				continue
+			}
+			action.Edit = &protocol.WorkspaceEdit{Changes: a.Edit.Changes}
+		}
+		result = append(result, action)
+	}
+	return result
+}
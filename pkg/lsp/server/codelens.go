@@ -0,0 +1,60 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-language-server/jsonrpc2"
+	"github.com/go-language-server/protocol"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+func (s *Server) codeLens(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	logger := logging.FromContext(ctx)
+
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+
+	lenses, err := source.CodeLenses(ctx, v, uri)
+	if err != nil {
+		logger.Debug("failed to compute code lenses", zap.String("uri", string(uri)), zap.Error(err))
+		return []protocol.CodeLens{}, nil
+	}
+	return lenses, nil
+}
+
+func (s *Server) codeLensResolve(ctx context.Context, params *protocol.CodeLens) (*protocol.CodeLens, error) {
+	// The data is decoded as a map since its type is unknown to the protocol package.
+	raw, err := json.Marshal(params.Data)
+	if err != nil {
+		return nil, err
+	}
+	var data source.CodeLensData
+	if err := json.Unmarshal(raw, &data); err != nil || data.URI == "" {
+		return nil, jsonrpc2.Errorf(jsonrpc2.InvalidParams, "invalid code lens data: %s", raw)
+	}
+
+	v := s.lookupView(ctx, data.URI)
+	lens, err := source.ResolveCodeLens(ctx, v, *params, data)
+	if err != nil {
+		return nil, err
+	}
+	return &lens, nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-language-server/jsonrpc2"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// Commands which the server executes in addition to the ones run by code lenses.
+const (
+	// commandReindex indexes the workspace folders again.
+	commandReindex = "protobuf.reindex"
+	// commandImportGraph returns the files which a file imports directly or indirectly.
+	commandImportGraph = "protobuf.importGraph"
+	// commandDumpFile returns the registry view of a file.
+	commandDumpFile = "protobuf.dumpFile"
+	// commandClearCaches forgets the files read from disk and git, and indexes the workspace folders again.
+	commandClearCaches = "protobuf.clearCaches"
+	// commandGeneratedCode returns the files which protoc plugins generate for a file.
+	commandGeneratedCode = "protobuf.generatedCode"
+	// commandProtoDeclaration returns the proto declarations which a Go identifier generated from them refers to.
+	commandProtoDeclaration = "protobuf.protoDeclaration"
+)
+
+// commandHandler executes a command with its arguments.
+type commandHandler struct {
+	// args returns a pointer to a new struct which the arguments are decoded into by position,
+	// or nil if the command takes no arguments.
+	args func() interface{}
+	run  func(ctx context.Context, args interface{}) (interface{}, error)
+}
+
+// commandRegistry maps the names of the commands to their handlers.
+type commandRegistry struct {
+	handlers map[string]commandHandler
+}
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{handlers: make(map[string]commandHandler)}
+}
+
+// register registers a command. run is called with the value returned by args after the arguments are decoded into it.
+// It panics if the command is already registered.
+func (r *commandRegistry) register(name string, args func() interface{}, run func(ctx context.Context, args interface{}) (interface{}, error)) {
+	if _, ok := r.handlers[name]; ok {
+		panic("command " + name + " is already registered")
+	}
+	r.handlers[name] = commandHandler{args: args, run: run}
+}
+
+// names returns the sorted names of the registered commands.
+func (r *commandRegistry) names() []string {
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// execute decodes the arguments of a command and runs it.
+func (r *commandRegistry) execute(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	h, ok := r.handlers[params.Command]
+	if !ok {
+		return nil, jsonrpc2.Errorf(jsonrpc2.InvalidParams, "unknown command %q", params.Command)
+	}
+
+	var args interface{}
+	if h.args != nil {
+		args = h.args()
+	}
+	if err := decodeArguments(params.Arguments, args); err != nil {
+		return nil, jsonrpc2.Errorf(jsonrpc2.InvalidParams, "invalid arguments of %s: %v", params.Command, err)
+	}
+	return h.run(ctx, args)
+}
+
+// decodeArguments decodes command arguments into the fields of a struct pointed to by v in order.
+// A nil v takes no arguments.
+func decodeArguments(arguments []interface{}, v interface{}) error {
+	if v == nil {
+		if len(arguments) > 0 {
+			return fmt.Errorf("want no arguments, got %d", len(arguments))
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	if len(arguments) != rv.NumField() {
+		return fmt.Errorf("want %d arguments, got %d", rv.NumField(), len(arguments))
+	}
+	for i, arg := range arguments {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, rv.Field(i).Addr().Interface()); err != nil {
+			return fmt.Errorf("argument %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// fileArgs are the arguments of the commands which take a file.
+type fileArgs struct {
+	URI uri.URI
+}
+
+// rpcArgs are the arguments of the commands which take an RPC.
+type rpcArgs struct {
+	URI uri.URI
+	// RPC is the fully-qualified name of the RPC.
+	RPC string
+}
+
+// positionArgs are the arguments of the commands which take a position in a file.
+type positionArgs struct {
+	URI      uri.URI
+	Position protocol.Position
+}
+
+// registerCommands registers the built-in commands of the server.
+func (s *Server) registerCommands(r *commandRegistry) {
+	newFileArgs := func() interface{} { return &fileArgs{} }
+	newRPCArgs := func() interface{} { return &rpcArgs{} }
+	newPositionArgs := func() interface{} { return &positionArgs{} }
+
+	r.register(source.CommandCopyGrpcurl, newRPCArgs, func(ctx context.Context, args interface{}) (interface{}, error) {
+		a := args.(*rpcArgs)
+		text, err := source.GrpcurlCommand(ctx, s.lookupView(ctx, a.URI), a.URI, a.RPC)
+		if err != nil {
+			return nil, err
+		}
+		return s.showText(ctx, text)
+	})
+	r.register(source.CommandCopyRequest, newRPCArgs, func(ctx context.Context, args interface{}) (interface{}, error) {
+		a := args.(*rpcArgs)
+		text, err := source.RequestJSON(ctx, s.lookupView(ctx, a.URI), a.URI, a.RPC)
+		if err != nil {
+			return nil, err
+		}
+		return s.showText(ctx, text)
+	})
+	r.register(commandReindex, nil, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		for _, v := range s.session.Views() {
+			s.index(v)
+		}
+		return nil, nil
+	})
+	r.register(commandImportGraph, newFileArgs, func(ctx context.Context, args interface{}) (interface{}, error) {
+		a := args.(*fileArgs)
+		return source.ImportGraph(s.lookupView(ctx, a.URI), a.URI), nil
+	})
+	r.register(commandDumpFile, newFileArgs, func(ctx context.Context, args interface{}) (interface{}, error) {
+		a := args.(*fileArgs)
+		return source.DumpFile(s.lookupView(ctx, a.URI), a.URI)
+	})
+	r.register(commandClearCaches, nil, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		for _, v := range s.session.Views() {
+			v.ClearCache()
+			s.index(v)
+		}
+		s.diagnoseOpenFiles(ctx)
+		return nil, nil
+	})
+	r.register(commandGeneratedCode, newFileArgs, func(ctx context.Context, args interface{}) (interface{}, error) {
+		a := args.(*fileArgs)
+		files, err := source.GeneratedCode(ctx, s.lookupView(ctx, a.URI), a.URI)
+		if err != nil {
+			return nil, err
+		}
+		s.showPreviews(ctx, files)
+		return files, nil
+	})
+	r.register(commandProtoDeclaration, newPositionArgs, func(ctx context.Context, args interface{}) (interface{}, error) {
+		a := args.(*positionArgs)
+		return source.ProtoDeclarations(ctx, s.lookupView(ctx, a.URI), a.URI, a.Position)
+	})
+}
+
+func (s *Server) executeCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	return s.commands.execute(ctx, params)
+}
+
+// showText shows a text as a message and returns it so that the client can copy it,
+// since the protocol has no way to write to the clipboard.
+func (s *Server) showText(ctx context.Context, text string) (interface{}, error) {
+	if s.Client == nil {
+		return text, nil
+	}
+	if err := s.Client.ShowMessage(ctx, &protocol.ShowMessageParams{
+		Type:    protocol.Info,
+		Message: text,
+	}); err != nil {
+		return nil, err
+	}
+	return text, nil
+}
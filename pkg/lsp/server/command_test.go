@@ -0,0 +1,261 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-language-server/jsonrpc2"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// testArgs are the arguments of the command registered by newTestRegistry.
+type testArgs struct {
+	URI   uri.URI
+	Count int
+}
+
+// newTestRegistry returns a registry with "test.args", which returns its arguments,
+// and "test.noArgs", which returns "ok".
+func newTestRegistry() *commandRegistry {
+	r := newCommandRegistry()
+	r.register("test.args", func() interface{} { return &testArgs{} }, func(ctx context.Context, args interface{}) (interface{}, error) {
+		return args, nil
+	})
+	r.register("test.noArgs", nil, func(ctx context.Context, args interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	return r
+}
+
+func TestCommandRegistry_Execute(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   *protocol.ExecuteCommandParams
+		want     interface{}
+		wantCode jsonrpc2.Code
+	}{
+		{
+			name: "arguments",
+			params: &protocol.ExecuteCommandParams{
+				Command:   "test.args",
+				Arguments: []interface{}{"file:///foo.proto", float64(2)},
+			},
+			want: &testArgs{URI: "file:///foo.proto", Count: 2},
+		},
+		{
+			name: "no arguments",
+			params: &protocol.ExecuteCommandParams{
+				Command: "test.noArgs",
+			},
+			want: "ok",
+		},
+		{
+			name: "too few arguments",
+			params: &protocol.ExecuteCommandParams{
+				Command:   "test.args",
+				Arguments: []interface{}{"file:///foo.proto"},
+			},
+			wantCode: jsonrpc2.InvalidParams,
+		},
+		{
+			name: "too many arguments",
+			params: &protocol.ExecuteCommandParams{
+				Command:   "test.args",
+				Arguments: []interface{}{"file:///foo.proto", float64(2), true},
+			},
+			wantCode: jsonrpc2.InvalidParams,
+		},
+		{
+			name: "wrong type",
+			params: &protocol.ExecuteCommandParams{
+				Command:   "test.args",
+				Arguments: []interface{}{"file:///foo.proto", "2"},
+			},
+			wantCode: jsonrpc2.InvalidParams,
+		},
+		{
+			name: "arguments for a command without arguments",
+			params: &protocol.ExecuteCommandParams{
+				Command:   "test.noArgs",
+				Arguments: []interface{}{"file:///foo.proto"},
+			},
+			wantCode: jsonrpc2.InvalidParams,
+		},
+		{
+			name: "unknown command",
+			params: &protocol.ExecuteCommandParams{
+				Command: "test.unknown",
+			},
+			wantCode: jsonrpc2.InvalidParams,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestRegistry().execute(context.Background(), tt.params)
+			if tt.wantCode != 0 {
+				var rpcErr *jsonrpc2.Error
+				if !errors.As(err, &rpcErr) || rpcErr.Code != tt.wantCode {
+					t.Fatalf("execute() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("execute() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("execute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments []interface{}
+		v         interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "position",
+			arguments: []interface{}{"file:///foo.proto", map[string]interface{}{"line": float64(1), "character": float64(2)}},
+			v:         &positionArgs{},
+			want:      &positionArgs{URI: "file:///foo.proto", Position: protocol.Position{Line: 1, Character: 2}},
+		},
+		{
+			name:      "rpc",
+			arguments: []interface{}{"file:///foo.proto", "foo.v1.FooService.GetFoo"},
+			v:         &rpcArgs{},
+			want:      &rpcArgs{URI: "file:///foo.proto", RPC: "foo.v1.FooService.GetFoo"},
+		},
+		{
+			name:      "nil without arguments",
+			arguments: nil,
+			v:         nil,
+			want:      nil,
+		},
+		{
+			name:      "nil with arguments",
+			arguments: []interface{}{"file:///foo.proto"},
+			v:         nil,
+			wantErr:   true,
+		},
+		{
+			name:      "count mismatch",
+			arguments: []interface{}{"file:///foo.proto"},
+			v:         &rpcArgs{},
+			wantErr:   true,
+		},
+		{
+			name:      "string for a struct",
+			arguments: []interface{}{"file:///foo.proto", "1:2"},
+			v:         &positionArgs{},
+			wantErr:   true,
+		},
+		{
+			name:      "number for a string",
+			arguments: []interface{}{float64(1)},
+			v:         &fileArgs{},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeArguments(tt.arguments, tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeArguments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(tt.v, tt.want) {
+				t.Errorf("decodeArguments() = %+v, want %+v", tt.v, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandRegistry_RegisterDuplicate(t *testing.T) {
+	r := newTestRegistry()
+	defer func() {
+		if recover() == nil {
+			t.Error("register() didn't panic for a duplicate command")
+		}
+	}()
+	r.register("test.noArgs", nil, func(ctx context.Context, args interface{}) (interface{}, error) {
+		return nil, nil
+	})
+}
+
+func TestExecuteCommandProvider(t *testing.T) {
+	s := &Server{
+		state:      stateCreated,
+		stateMu:    &sync.RWMutex{},
+		session:    source.NewSession(),
+		config:     config.DefaultLSPConfig,
+		initConfig: config.DefaultLSPConfig,
+		configMu:   &sync.RWMutex{},
+		logger:     zap.NewNop(),
+		commands:   newCommandRegistry(),
+	}
+	s.registerCommands(s.commands)
+
+	result, err := s.initialize(context.Background(), &protocol.InitializeParams{})
+	if err != nil {
+		t.Fatalf("initialize() error = %v", err)
+	}
+	got := result.Capabilities.ExecuteCommandProvider.Commands
+	want := []string{
+		source.CommandCopyGrpcurl,
+		source.CommandCopyRequest,
+		commandClearCaches,
+		commandDumpFile,
+		commandGeneratedCode,
+		commandImportGraph,
+		commandProtoDeclaration,
+		commandReindex,
+	}
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExecuteCommandProvider.Commands = %v, want %v", got, want)
+	}
+	if names := s.commands.names(); !reflect.DeepEqual(got, names) {
+		t.Errorf("ExecuteCommandProvider.Commands = %v, want names() %v", got, names)
+	}
+}
+
+func TestShowText_NoClient(t *testing.T) {
+	got, err := newTestServer().showText(context.Background(), "grpcurl")
+	if err != nil {
+		t.Fatalf("showText() error = %v", err)
+	}
+	if got != "grpcurl" {
+		t.Errorf("showText() = %v, want %v", got, "grpcurl")
+	}
+}
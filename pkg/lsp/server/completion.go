@@ -33,7 +33,16 @@ func (s *Server) completion(ctx context.Context, params *protocol.CompletionPara
 	uri := params.TextDocument.URI
 	filename := uri.Filename()
 
-	v := s.session.ViewOf(uri)
+	v := s.lookupView(ctx, uri)
+
+	if source.IsTextProto(uri) {
+		items, err := source.TextProtoCompletions(ctx, v, uri, params.Position)
+		if err != nil {
+			logger.Warn("failed to complete text format", zap.String("filename", filename), zap.Error(err))
+			return nil, nil
+		}
+		return &protocol.CompletionList{Items: items}, nil
+	}
 
 	f, err := v.GetFile(uri)
 	if err != nil {
@@ -46,6 +55,11 @@ func (s *Server) completion(ctx context.Context, params *protocol.CompletionPara
 		return
 	}
 
+	// Options are completed from the text since the file is usually incomplete while writing them.
+	if items, ok := source.OptionCompletions(ctx, v, uri, params.Position); ok {
+		return &protocol.CompletionList{Items: items}, nil
+	}
+
 	proto := protoFile.Proto()
 	var items []protocol.CompletionItem
 
@@ -24,16 +24,37 @@ import (
 	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
 )
 
+// definition returns the definition of the type at a position, and the Go declarations generated
+// for the proto declaration at the position if the client enables navigation to Go.
+func (s *Server) definition(ctx context.Context, params *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+	result, err := s.protoDefinition(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+	if !v.Options().GoNavigation {
+		return result, nil
+	}
+	locations, err := source.GoDefinitions(ctx, v, uri, params.Position)
+	if err != nil {
+		logging.FromContext(ctx).Debug("failed to find generated Go declarations", zap.String("uri", string(uri)), zap.Error(err))
+		return result, nil
+	}
+	return append(result, locations...), nil
+}
+
 // TODO: Match position with line and column.
 // Currently matches with only line.
-func (s *Server) definition(ctx context.Context, params *protocol.TextDocumentPositionParams) (result []protocol.Location, err error) {
+func (s *Server) protoDefinition(ctx context.Context, params *protocol.TextDocumentPositionParams) (result []protocol.Location, err error) {
 	logger := logging.FromContext(ctx)
 	logger = logger.With(zap.Any("params", params))
 
 	uri := params.TextDocument.URI
 	filename := uri.Filename()
 
-	v := s.session.ViewOf(uri)
+	v := s.lookupView(ctx, uri)
 
 	f, err := v.GetFile(uri)
 	if err != nil {
@@ -47,6 +68,9 @@ func (s *Server) definition(ctx context.Context, params *protocol.TextDocumentPo
 	}
 
 	proto := protoFile.Proto()
+	if proto == nil {
+		return
+	}
 
 	line := int(params.Position.Line) + 1
 	field, ok := proto.GetMessageFieldByLine(line)
@@ -55,23 +79,26 @@ func (s *Server) definition(ctx context.Context, params *protocol.TextDocumentPo
 		return
 	}
 
+	scope := ""
+	if pkgs := proto.Packages(); len(pkgs) > 0 {
+		scope = pkgs[0].ProtoPackage.Name
+	}
+
+	// The type is searched in the requested proto file first and then in the other indexed files.
 	typ := field.ProtoField.Type
-	// TODO: Search the requested proto file and imported proto files.
-	m, ok := proto.GetMessageByName(typ)
+	symbol, ok := source.ResolveType(v, uri, scope, typ)
 	if !ok {
-		logger.Warn("message not found", zap.String("name", typ))
+		logger.Warn("type not found", zap.String("name", typ))
 		return
 	}
 
-	line, column := m.Protobuf().Position.Line, m.Protobuf().Position.Column
-
 	result = []protocol.Location{
 		{
-			URI: uri,
+			URI: symbol.URI,
 			Range: protocol.Range{
 				Start: protocol.Position{
-					Line:      float64(line) - 1,
-					Character: float64(column) - 1,
+					Line:      float64(symbol.Line) - 1,
+					Character: float64(symbol.Column) - 1,
 				},
 			},
 		},
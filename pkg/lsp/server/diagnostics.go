@@ -0,0 +1,71 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// diagnose computes the diagnostics of a file and publishes them to the client.
+func (s *Server) diagnose(ctx context.Context, view source.View, uri uri.URI) {
+	logger := logging.FromContext(ctx)
+
+	diagnostics, err := source.Diagnostics(ctx, view, uri)
+	if err != nil {
+		logger.Warn("failed to compute diagnostics", zap.String("uri", string(uri)), zap.Error(err))
+		return
+	}
+	if view.Options().Breaking.Enabled {
+		breaking, err := source.BreakingDiagnostics(ctx, view, uri, "")
+		if err != nil {
+			// The file may be outside of any git repository, which is not worth warning.
+			logger.Debug("failed to detect breaking changes", zap.String("uri", string(uri)), zap.Error(err))
+		}
+		diagnostics = append(diagnostics, breaking...)
+	}
+	s.publishDiagnostics(ctx, uri, diagnostics)
+}
+
+// diagnoseOpenFiles re-computes the diagnostics of all files open in the editor.
+func (s *Server) diagnoseOpenFiles(ctx context.Context) {
+	for _, view := range s.session.Views() {
+		for _, uri := range view.OpenFiles() {
+			s.diagnose(ctx, view, uri)
+		}
+	}
+}
+
+func (s *Server) publishDiagnostics(ctx context.Context, uri uri.URI, diagnostics []protocol.Diagnostic) {
+	if s.Client == nil {
+		return
+	}
+	if diagnostics == nil {
+		// Send an empty array rather than null to clear the diagnostics.
+		diagnostics = []protocol.Diagnostic{}
+	}
+	if err := s.Client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	}); err != nil {
+		logging.FromContext(ctx).Error("failed to publish diagnostics", zap.Error(err))
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"github.com/go-language-server/protocol"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// formatting formats a whole file with the formatter options of the project configuration,
+// which take precedence over the ones in params so that the result is the same as the command line.
+func (s *Server) formatting(ctx context.Context, params *protocol.DocumentFormattingParams) (result []protocol.TextEdit, err error) {
+	logger := logging.FromContext(ctx)
+
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+
+	data, formatted, err := source.Format(ctx, v, uri)
+	if err != nil {
+		// Broken files are left as they are since the parse error is already reported as a diagnostic.
+		logger.Debug("failed to format", zap.String("uri", string(uri)), zap.Error(err))
+		return nil, nil
+	}
+	return source.TextEdits(data, formatted), nil
+}
@@ -20,7 +20,6 @@ package server
 
 import (
 	"context"
-	"errors"
 	"os"
 	"path/filepath"
 
@@ -48,46 +47,69 @@ func (s *Server) initialize(ctx context.Context, params *protocol.InitializePara
 	s.state = stateInitializing
 	s.stateMu.Unlock()
 
+	s.capabilities = params.Capabilities
+
+	cfg, err := config.ParseLSP(params.InitializationOptions, config.DefaultLSPConfig)
+	if err != nil {
+		logger.Warn("failed to parse initialization options", zap.Error(err))
+		cfg = config.DefaultLSPConfig
+		err = nil
+	}
+	s.configMu.Lock()
+	s.config = cfg
+	s.initConfig = cfg
+	s.configMu.Unlock()
+	s.setLogLevel(ctx, cfg.LogLevel)
+
 	folders := params.WorkspaceFolders
 	if len(folders) == 0 {
-		rootURI := params.RootURI
-		if rootURI == "" {
-			err = errors.New("single file mode not supported yet")
-			return
-		}
-		folders = []protocol.WorkspaceFolder{
-			{
-				URI:  rootURI.Filename(),
-				Name: filepath.Base(rootURI.Filename()),
-			},
+		// Without any root, the server runs in single-file mode where each file opened
+		// gets an ad-hoc view rooted at its directory.
+		if rootURI := params.RootURI; rootURI != "" {
+			folders = []protocol.WorkspaceFolder{
+				{
+					URI:  rootURI.Filename(),
+					Name: filepath.Base(rootURI.Filename()),
+				},
+			}
 		}
 	}
 
 	for _, folder := range folders {
-		s.addView(ctx, folder.Name, uri.File(folder.URI))
+		s.addView(ctx, folder.Name, uri.New(folder.URI))
 	}
 
-	cfg := config.DefaultLSPConfig
-
 	result = &protocol.InitializeResult{
 		Capabilities: protocol.ServerCapabilities{
 			TextDocumentSync: protocol.TextDocumentSyncOptions{
-				OpenClose: true,
-				Change:    float64(cfg.TextDocumentSyncKind),
+				OpenClose:         true,
+				Change:            float64(cfg.TextDocumentSyncKind),
+				WillSaveWaitUntil: true,
 			},
-			HoverProvider: false,
+			HoverProvider: true,
 			CompletionProvider: &protocol.CompletionOptions{
-				TriggerCharacters: []string{"."},
+				TriggerCharacters: []string{".", "("},
 			},
 			SignatureHelpProvider: &protocol.SignatureHelpOptions{
-				TriggerCharacters: nil,
+				TriggerCharacters: signatureHelpTriggerCharacters,
+			},
+			DefinitionProvider:        true,
+			TypeDefinitionProvider:    true,
+			ReferencesProvider:        true,
+			ImplementationProvider:    true,
+			DocumentHighlightProvider: true,
+			CodeActionProvider:        !s.dynamicCodeAction(),
+			CodeLensProvider: &protocol.CodeLensOptions{
+				ResolveProvider: true,
 			},
-			DefinitionProvider:              true,
 			WorkspaceSymbolProvider:         false,
-			DocumentFormattingProvider:      false,
+			DocumentFormattingProvider:      true,
 			DocumentRangeFormattingProvider: false,
 			RenameProvider:                  nil,
 			FoldingRangeProvider:            nil,
+			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+				Commands: s.commands.names(),
+			},
 			Workspace: &protocol.ServerCapabilitiesWorkspace{
 				WorkspaceFolders: &protocol.ServerCapabilitiesWorkspaceFolders{
 					Supported:           false,
@@ -104,9 +126,79 @@ func (s *Server) initialized(ctx context.Context, params *protocol.InitializedPa
 	s.stateMu.Lock()
 	s.state = stateInitialized
 	s.stateMu.Unlock()
+
+	s.registerCapabilities(ctx)
+	s.fetchConfiguration(ctx)
+
+	for _, view := range s.session.Views() {
+		s.index(view)
+	}
 	return
 }
 
+// registerCapabilities dynamically registers the capabilities which the client supports registering.
+func (s *Server) registerCapabilities(ctx context.Context) {
+	if s.Client == nil {
+		return
+	}
+
+	var registrations []protocol.Registration
+	if w := s.capabilities.Workspace; w != nil {
+		if w.DidChangeConfiguration != nil && w.DidChangeConfiguration.DynamicRegistration {
+			registrations = append(registrations, protocol.Registration{
+				ID:     protocol.MethodWorkspaceDidChangeConfiguration,
+				Method: protocol.MethodWorkspaceDidChangeConfiguration,
+			})
+		}
+		if w.DidChangeWatchedFiles != nil && w.DidChangeWatchedFiles.DynamicRegistration {
+			registrations = append(registrations, protocol.Registration{
+				ID:     protocol.MethodWorkspaceDidChangeWatchedFiles,
+				Method: protocol.MethodWorkspaceDidChangeWatchedFiles,
+				RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+					Watchers: fileWatchers(),
+				},
+			})
+		}
+	}
+	if s.dynamicCodeAction() {
+		// The kinds of code actions can be advertised only with the registration options.
+		registrations = append(registrations, protocol.Registration{
+			ID:     protocol.MethodTextDocumentCodeAction,
+			Method: protocol.MethodTextDocumentCodeAction,
+			RegisterOptions: protocol.CodeActionRegistrationOptions{
+				TextDocumentRegistrationOptions: protocol.TextDocumentRegistrationOptions{
+					DocumentSelector: protoDocumentSelector(),
+				},
+				CodeActionOptions: protocol.CodeActionOptions{
+					CodeActionKinds: codeActionKinds,
+				},
+			},
+		})
+	}
+	if len(registrations) == 0 {
+		return
+	}
+
+	if err := s.Client.RegisterCapability(ctx, &protocol.RegistrationParams{
+		Registrations: registrations,
+	}); err != nil {
+		logging.FromContext(ctx).Warn("failed to register capabilities", zap.Error(err))
+	}
+}
+
+// dynamicCodeAction returns true if the client supports registering code actions dynamically.
+func (s *Server) dynamicCodeAction() bool {
+	t := s.capabilities.TextDocument
+	return t != nil && t.CodeAction != nil && t.CodeAction.DynamicRegistration
+}
+
+// protoDocumentSelector returns the selector of the documents which the server cares about.
+func protoDocumentSelector() protocol.DocumentSelector {
+	return protocol.DocumentSelector{
+		{Pattern: "**/*.proto"},
+	}
+}
+
 func (s *Server) shutdown(ctx context.Context) (err error) {
 	s.stateMu.RLock()
 	state := s.state
@@ -115,6 +207,7 @@ func (s *Server) shutdown(ctx context.Context) (err error) {
 		err = jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server not initialized")
 		return
 	}
+	s.cancelBackground()
 	s.session.Shutdown(ctx)
 	s.stateMu.Lock()
 	s.state = stateShutdown
@@ -0,0 +1,175 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-language-server/jsonrpc2"
+	"github.com/go-language-server/protocol"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+)
+
+// handler returns the handler which handles the requests whose params or results can't be represented
+// with the types of the protocol package, and passes the other requests to next.
+func (s *Server) handler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, r *jsonrpc2.Request) {
+		var (
+			result interface{}
+			err    error
+		)
+		switch r.Method {
+		case protocol.MethodInitialize:
+			var params protocol.InitializeParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			var extra initializeParams
+			if err := decodeParams(r, &extra); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			s.window = extra.Capabilities.Window
+			var res *protocol.InitializeResult
+			if res, err = s.Initialize(ctx, &params); res != nil {
+				result = &initializeResult{Capabilities: s.serverCapabilities(res.Capabilities)}
+			}
+		case protocol.MethodTextDocumentCodeAction:
+			var params protocol.CodeActionParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			result, err = s.codeAction(ctx, &params)
+		case protocol.MethodTextDocumentSignatureHelp:
+			var params protocol.TextDocumentPositionParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			result, err = s.signatureHelp(ctx, &params)
+		case methodSemanticTokensFull:
+			var params semanticTokensParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			result, err = s.semanticTokensFull(ctx, &params)
+		case methodSemanticTokensFullDelta:
+			var params semanticTokensDeltaParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			result, err = s.semanticTokensFullDelta(ctx, &params)
+		case methodSemanticTokensRange:
+			var params semanticTokensRangeParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			result, err = s.semanticTokensRange(ctx, &params)
+		case methodInlayHint:
+			var params inlayHintParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			result, err = s.inlayHint(ctx, &params)
+		case methodPreviewContent:
+			var params previewContentParams
+			if err := decodeParams(r, &params); err != nil {
+				protocol.ReplyError(ctx, err, r, logging.FromContext(ctx))
+				return
+			}
+			result, err = s.previewContent(ctx, &params)
+		default:
+			next(ctx, r)
+			return
+		}
+
+		if err := r.Reply(ctx, result, err); err != nil {
+			logging.FromContext(ctx).Error(r.Method, zap.Error(err))
+		}
+	}
+}
+
+// initializeParams is protocol.InitializeParams with the client capabilities which the protocol package doesn't have.
+type initializeParams struct {
+	Capabilities struct {
+		Window *windowClientCapabilities `json:"window,omitempty"`
+	} `json:"capabilities"`
+}
+
+type windowClientCapabilities struct {
+	WorkDoneProgress bool `json:"workDoneProgress,omitempty"`
+	ShowDocument     *struct {
+		Support bool `json:"support"`
+	} `json:"showDocument,omitempty"`
+}
+
+// workDoneProgress returns true if the client supports work done progress created by the server.
+func (w *windowClientCapabilities) workDoneProgress() bool {
+	return w != nil && w.WorkDoneProgress
+}
+
+// showDocument returns true if the client supports window/showDocument.
+func (w *windowClientCapabilities) showDocument() bool {
+	return w != nil && w.ShowDocument != nil && w.ShowDocument.Support
+}
+
+// initializeResult is protocol.InitializeResult with the capabilities which the protocol package doesn't have.
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+}
+
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	// CodeActionProvider overrides the one of protocol.ServerCapabilities, which is a bool.
+	CodeActionProvider     *protocol.CodeActionOptions `json:"codeActionProvider,omitempty"`
+	SemanticTokensProvider *semanticTokensOptions      `json:"semanticTokensProvider,omitempty"`
+	InlayHintProvider      bool                        `json:"inlayHintProvider,omitempty"`
+	DeclarationProvider    bool                        `json:"declarationProvider,omitempty"`
+}
+
+// serverCapabilities adds the capabilities which the protocol package doesn't have to given ones.
+func (s *Server) serverCapabilities(capabilities protocol.ServerCapabilities) serverCapabilities {
+	result := serverCapabilities{
+		ServerCapabilities:     capabilities,
+		SemanticTokensProvider: semanticTokensProvider(),
+		InlayHintProvider:      true,
+		DeclarationProvider:    true,
+	}
+	// The kinds of code actions are advertised here unless they are registered dynamically,
+	// so that clients can filter code actions by kind either way.
+	if !s.dynamicCodeAction() {
+		result.CodeActionProvider = &protocol.CodeActionOptions{CodeActionKinds: codeActionKinds}
+	}
+	return result
+}
+
+func decodeParams(r *jsonrpc2.Request, v interface{}) error {
+	if r.Params == nil {
+		return jsonrpc2.Errorf(jsonrpc2.InvalidParams, "missing params")
+	}
+	if err := json.Unmarshal(*r.Params, v); err != nil {
+		return jsonrpc2.Errorf(jsonrpc2.ParseError, "failed to decode params: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+)
+
+func TestServerCapabilities_CodeActionProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities protocol.ClientCapabilities
+		want         interface{}
+	}{
+		{
+			name: "static",
+			want: map[string]interface{}{
+				"codeActionKinds": []interface{}{"quickfix", "refactor.extract", "refactor.inline", "refactor.move", "source.organizeImports", "source.sortFields"},
+			},
+		},
+		{
+			name: "dynamic registration",
+			capabilities: protocol.ClientCapabilities{
+				TextDocument: &protocol.TextDocumentClientCapabilities{
+					CodeAction: &protocol.TextDocumentClientCapabilitiesCodeAction{DynamicRegistration: true},
+				},
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			s.capabilities = tt.capabilities
+			data, err := json.Marshal(s.serverCapabilities(protocol.ServerCapabilities{CodeActionProvider: !s.dynamicCodeAction()}))
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got["codeActionProvider"], tt.want) {
+				t.Errorf("codeActionProvider = %v, want %v", got["codeActionProvider"], tt.want)
+			}
+		})
+	}
+}
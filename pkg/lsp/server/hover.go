@@ -0,0 +1,42 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"github.com/go-language-server/protocol"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// hover shows the declarations of the fields and the enum values in text format files.
+func (s *Server) hover(ctx context.Context, params *protocol.TextDocumentPositionParams) (*protocol.Hover, error) {
+	uri := params.TextDocument.URI
+	if !source.IsTextProto(uri) {
+		return nil, nil
+	}
+	v := s.lookupView(ctx, uri)
+	logger := logging.FromContext(ctx).With(zap.String("uri", string(uri)))
+
+	hover, err := source.TextProtoHover(ctx, v, uri, params.Position)
+	if err != nil {
+		logger.Debug("failed to hover", zap.Error(err))
+		return nil, nil
+	}
+	return hover, nil
+}
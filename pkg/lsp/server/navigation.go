@@ -0,0 +1,61 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"github.com/go-language-server/protocol"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+func (s *Server) typeDefinition(ctx context.Context, params *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+
+	locations, err := source.TypeDefinition(ctx, v, uri, params.Position)
+	if err != nil {
+		logging.FromContext(ctx).Debug("failed to find type definition", zap.String("uri", string(uri)), zap.Error(err))
+		return nil, nil
+	}
+	return locations, nil
+}
+
+func (s *Server) declaration(ctx context.Context, params *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+
+	locations, err := source.Declaration(ctx, v, uri, params.Position)
+	if err != nil {
+		logging.FromContext(ctx).Debug("failed to find declaration", zap.String("uri", string(uri)), zap.Error(err))
+		return nil, nil
+	}
+	return locations, nil
+}
+
+func (s *Server) implementation(ctx context.Context, params *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+
+	locations, err := source.Implementations(ctx, v, uri, params.Position)
+	if err != nil {
+		logging.FromContext(ctx).Debug("failed to find implementations", zap.String("uri", string(uri)), zap.Error(err))
+		return nil, nil
+	}
+	return locations, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-language-server/jsonrpc2"
+	"github.com/go-language-server/uri"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+const (
+	// methodPreviewContent returns the content of a generated file in source.PreviewScheme,
+	// which editor extensions call from their content providers of the scheme.
+	methodPreviewContent = "protobuf/previewContent"
+
+	// methodShowDocument is not provided by the protocol package yet.
+	// https://microsoft.github.io/language-server-protocol/specification#window_showDocument
+	methodShowDocument = "window/showDocument"
+)
+
+type previewContentParams struct {
+	URI uri.URI `json:"uri"`
+}
+
+type showDocumentParams struct {
+	URI       uri.URI `json:"uri"`
+	TakeFocus bool    `json:"takeFocus,omitempty"`
+}
+
+type showDocumentResult struct {
+	Success bool `json:"success"`
+}
+
+// previews holds the contents of the generated files last previewed by their URIs.
+type previews struct {
+	contents map[uri.URI]string
+	mu       sync.RWMutex
+}
+
+// showPreviews keeps generated files to serve their contents and opens them in the client
+// if it supports window/showDocument. The first file takes focus.
+func (s *Server) showPreviews(ctx context.Context, files []source.GeneratedFile) {
+	s.previews.mu.Lock()
+	if s.previews.contents == nil {
+		s.previews.contents = make(map[uri.URI]string)
+	}
+	for _, f := range files {
+		s.previews.contents[f.URI] = f.Content
+	}
+	s.previews.mu.Unlock()
+
+	if s.Conn == nil || !s.window.showDocument() {
+		return
+	}
+	for i, f := range files {
+		var result showDocumentResult
+		if err := s.Conn.Call(ctx, methodShowDocument, &showDocumentParams{URI: f.URI, TakeFocus: i == 0}, &result); err != nil || !result.Success {
+			logging.FromContext(ctx).Warn("failed to show generated file", zap.String("uri", string(f.URI)), zap.Error(err))
+		}
+	}
+}
+
+func (s *Server) previewContent(ctx context.Context, params *previewContentParams) (string, error) {
+	s.previews.mu.RLock()
+	defer s.previews.mu.RUnlock()
+
+	content, ok := s.previews.contents[params.URI]
+	if !ok {
+		return "", jsonrpc2.Errorf(jsonrpc2.InvalidParams, "unknown preview %s", params.URI)
+	}
+	return content, nil
+}
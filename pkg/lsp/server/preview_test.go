@@ -0,0 +1,97 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+func TestPreviewContent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer()
+	s.showPreviews(ctx, []source.GeneratedFile{
+		{Plugin: "go", Name: "foo/v1/foo.pb.go", URI: "protobuf-preview:///go/foo/v1/foo.pb.go", Content: "package foov1\n"},
+	})
+
+	tests := []struct {
+		name    string
+		uri     uri.URI
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "generated file",
+			uri:  "protobuf-preview:///go/foo/v1/foo.pb.go",
+			want: "package foov1\n",
+		},
+		{
+			name:    "unknown file",
+			uri:     "protobuf-preview:///go/bar/v1/bar.pb.go",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.previewContent(ctx, &previewContentParams{URI: tt.uri})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("previewContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("previewContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowClientCapabilities_ShowDocument(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   bool
+	}{
+		{
+			name:   "supported",
+			params: `{"capabilities":{"window":{"showDocument":{"support":true}}}}`,
+			want:   true,
+		},
+		{
+			name:   "unsupported",
+			params: `{"capabilities":{"window":{"showDocument":{"support":false}}}}`,
+		},
+		{
+			name:   "no window capabilities",
+			params: `{"capabilities":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var params initializeParams
+			if err := json.Unmarshal([]byte(tt.params), &params); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if got := params.Capabilities.Window.showDocument(); got != tt.want {
+				t.Errorf("showDocument() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+)
+
+// The methods and types of work done progress which are not provided by the protocol package yet.
+// https://microsoft.github.io/language-server-protocol/specification#workDoneProgress
+const (
+	methodWorkDoneProgressCreate = "window/workDoneProgress/create"
+	methodProgress               = "$/progress"
+)
+
+type workDoneProgressCreateParams struct {
+	Token string `json:"token"`
+}
+
+type progressParams struct {
+	Token string      `json:"token"`
+	Value interface{} `json:"value"`
+}
+
+type workDoneProgressBegin struct {
+	Kind        string  `json:"kind"`
+	Title       string  `json:"title"`
+	Cancellable bool    `json:"cancellable,omitempty"`
+	Message     string  `json:"message,omitempty"`
+	Percentage  float64 `json:"percentage"`
+}
+
+type workDoneProgressReport struct {
+	Kind       string  `json:"kind"`
+	Message    string  `json:"message,omitempty"`
+	Percentage float64 `json:"percentage"`
+}
+
+type workDoneProgressEnd struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message,omitempty"`
+}
+
+var progressIndex int64
+
+// progress reports the progress of a long running operation to the client.
+// It does nothing if the client doesn't support work done progress.
+type progress struct {
+	s       *Server
+	token   string
+	enabled bool
+
+	// percentage is the last reported percentage.
+	percentage int
+}
+
+// startProgress creates a work done progress and begins it with a given title.
+func (s *Server) startProgress(ctx context.Context, title string) *progress {
+	p := &progress{
+		s:     s,
+		token: fmt.Sprintf("protobuf-%d", atomic.AddInt64(&progressIndex, 1)),
+	}
+	if s.Conn == nil || !s.window.workDoneProgress() {
+		return p
+	}
+
+	if err := s.Conn.Call(ctx, methodWorkDoneProgressCreate, &workDoneProgressCreateParams{Token: p.token}, nil); err != nil {
+		logging.FromContext(ctx).Warn("failed to create work done progress", zap.Error(err))
+		return p
+	}
+	p.enabled = true
+
+	p.notify(ctx, &workDoneProgressBegin{
+		Kind:  "begin",
+		Title: title,
+	})
+	return p
+}
+
+// report reports the progress in percentage. Reports which don't change the percentage are omitted.
+func (p *progress) report(ctx context.Context, message string, percentage int) {
+	if percentage <= p.percentage {
+		return
+	}
+	p.percentage = percentage
+	p.notify(ctx, &workDoneProgressReport{
+		Kind:       "report",
+		Message:    message,
+		Percentage: float64(percentage),
+	})
+}
+
+// end ends the progress.
+func (p *progress) end(ctx context.Context, message string) {
+	p.notify(ctx, &workDoneProgressEnd{
+		Kind:    "end",
+		Message: message,
+	})
+	p.enabled = false
+}
+
+func (p *progress) notify(ctx context.Context, value interface{}) {
+	if !p.enabled {
+		return
+	}
+	if err := p.s.Conn.Notify(ctx, methodProgress, &progressParams{Token: p.token, Value: value}); err != nil {
+		logging.FromContext(ctx).Warn("failed to notify progress", zap.Error(err))
+	}
+}
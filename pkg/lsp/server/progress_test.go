@@ -0,0 +1,54 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWindowClientCapabilities_WorkDoneProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   bool
+	}{
+		{
+			name:   "supported",
+			params: `{"capabilities":{"window":{"workDoneProgress":true}}}`,
+			want:   true,
+		},
+		{
+			name:   "unsupported",
+			params: `{"capabilities":{"window":{"workDoneProgress":false}}}`,
+		},
+		{
+			name:   "no window capabilities",
+			params: `{"capabilities":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var params initializeParams
+			if err := json.Unmarshal([]byte(tt.params), &params); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if got := params.Capabilities.Window.workDoneProgress(); got != tt.want {
+				t.Errorf("workDoneProgress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"github.com/go-language-server/protocol"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+func (s *Server) references(ctx context.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+	logger := logging.FromContext(ctx).With(zap.String("uri", string(uri)))
+
+	locations, err := source.ReferenceLocations(ctx, v, uri, params.Position, params.Context.IncludeDeclaration)
+	if err != nil {
+		logger.Debug("failed to find references", zap.Error(err))
+		return nil, nil
+	}
+	if !v.Options().GoNavigation {
+		return locations, nil
+	}
+	goLocations, err := source.GoDefinitions(ctx, v, uri, params.Position)
+	if err != nil {
+		logger.Debug("failed to find generated Go declarations", zap.Error(err))
+		return locations, nil
+	}
+	return append(locations, goLocations...), nil
+}
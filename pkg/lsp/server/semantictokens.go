@@ -0,0 +1,129 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+// Methods of semantic tokens, which the protocol package doesn't have.
+const (
+	methodSemanticTokensFull      = "textDocument/semanticTokens/full"
+	methodSemanticTokensFullDelta = "textDocument/semanticTokens/full/delta"
+	methodSemanticTokensRange     = "textDocument/semanticTokens/range"
+)
+
+// semanticTokensOptions is the server capability of semantic tokens.
+type semanticTokensOptions struct {
+	Legend semanticTokensLegend     `json:"legend"`
+	Range  bool                     `json:"range"`
+	Full   semanticTokensFullOption `json:"full"`
+}
+
+type semanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type semanticTokensFullOption struct {
+	Delta bool `json:"delta"`
+}
+
+type semanticTokensParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+}
+
+type semanticTokensDeltaParams struct {
+	TextDocument     protocol.TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                          `json:"previousResultId"`
+}
+
+type semanticTokensRangeParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+type semanticTokens struct {
+	ResultID string   `json:"resultId,omitempty"`
+	Data     []uint32 `json:"data"`
+}
+
+type semanticTokensDelta struct {
+	ResultID string                      `json:"resultId,omitempty"`
+	Edits    []source.SemanticTokensEdit `json:"edits"`
+}
+
+func semanticTokensProvider() *semanticTokensOptions {
+	return &semanticTokensOptions{
+		Legend: semanticTokensLegend{
+			TokenTypes:     source.SemanticTokenTypes,
+			TokenModifiers: source.SemanticTokenModifiers,
+		},
+		Range: true,
+		Full:  semanticTokensFullOption{Delta: true},
+	}
+}
+
+func (s *Server) semanticTokensFull(ctx context.Context, params *semanticTokensParams) (*semanticTokens, error) {
+	uri := params.TextDocument.URI
+	data := s.encodeSemanticTokens(ctx, uri, nil)
+
+	s.semanticTokensMu.Lock()
+	defer s.semanticTokensMu.Unlock()
+	s.semanticTokensSeq++
+	result := &semanticTokens{ResultID: strconv.Itoa(s.semanticTokensSeq), Data: data}
+	s.semanticTokens[uri] = result
+	return result, nil
+}
+
+// semanticTokensFullDelta returns the edits from the tokens returned previously,
+// or all tokens if the previous ones are not known.
+func (s *Server) semanticTokensFullDelta(ctx context.Context, params *semanticTokensDeltaParams) (interface{}, error) {
+	uri := params.TextDocument.URI
+	s.semanticTokensMu.Lock()
+	prev, ok := s.semanticTokens[uri]
+	s.semanticTokensMu.Unlock()
+
+	result, err := s.semanticTokensFull(ctx, &semanticTokensParams{TextDocument: params.TextDocument})
+	if err != nil || !ok || prev.ResultID != params.PreviousResultID {
+		return result, err
+	}
+	return &semanticTokensDelta{
+		ResultID: result.ResultID,
+		Edits:    source.DiffSemanticTokens(prev.Data, result.Data),
+	}, nil
+}
+
+func (s *Server) semanticTokensRange(ctx context.Context, params *semanticTokensRangeParams) (*semanticTokens, error) {
+	return &semanticTokens{Data: s.encodeSemanticTokens(ctx, params.TextDocument.URI, &params.Range)}, nil
+}
+
+// encodeSemanticTokens returns the encoded semantic tokens of a file, which are empty if the file can't be read.
+func (s *Server) encodeSemanticTokens(ctx context.Context, uri uri.URI, rng *protocol.Range) []uint32 {
+	v := s.lookupView(ctx, uri)
+	tokens, err := source.SemanticTokens(ctx, v, uri)
+	if err != nil {
+		logging.FromContext(ctx).Debug("failed to compute semantic tokens", zap.String("uri", string(uri)), zap.Error(err))
+	}
+	return source.EncodeSemanticTokens(tokens, rng)
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/go-language-server/jsonrpc2"
 	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
 	"go.uber.org/zap"
 
 	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
@@ -51,9 +52,34 @@ type Server struct {
 
 	session source.Session
 
-	config config.LSP
+	// backgroundCtx is the context for the work which outlives requests, such as indexing.
+	// It is canceled on shutdown.
+	backgroundCtx    context.Context
+	cancelBackground context.CancelFunc
 
-	logger *zap.Logger
+	// config is the configuration sent by the client.
+	// initConfig is the one sent as initializationOptions which config is derived from.
+	config     config.LSP
+	initConfig config.LSP
+	configMu   *sync.RWMutex
+
+	capabilities protocol.ClientCapabilities
+	// window is the window capabilities of the client which protocol.ClientCapabilities doesn't have.
+	window *windowClientCapabilities
+
+	// commands is the registry of the commands executed with workspace/executeCommand.
+	commands *commandRegistry
+
+	// semanticTokens is the last semantic tokens of each file which deltas are computed from.
+	semanticTokens    map[uri.URI]*semanticTokens
+	semanticTokensSeq int
+	semanticTokensMu  *sync.Mutex
+
+	// previews is the generated files which are previewed.
+	previews previews
+
+	logger   *zap.Logger
+	logLevel *zap.AtomicLevel
 }
 
 var _ protocol.ServerInterface = (*Server)(nil)
@@ -66,16 +92,31 @@ func WithLogger(logger *zap.Logger) Option {
 	}
 }
 
+// WithLogLevel makes the level of logging changeable via the client configuration.
+func WithLogLevel(level zap.AtomicLevel) Option {
+	return func(s *Server) {
+		s.logLevel = &level
+	}
+}
+
 func NewServer(ctx context.Context, session source.Session, stream jsonrpc2.Stream, opts ...Option) (context.Context, *Server) {
 	s := &Server{
-		state:   stateCreated,
-		stateMu: &sync.RWMutex{},
-		session: session,
-		logger:  zap.NewNop(),
+		state:      stateCreated,
+		stateMu:    &sync.RWMutex{},
+		session:    session,
+		config:     config.DefaultLSPConfig,
+		initConfig: config.DefaultLSPConfig,
+		configMu:   &sync.RWMutex{},
+		logger:     zap.NewNop(),
+		commands:   newCommandRegistry(),
+
+		semanticTokens:   make(map[uri.URI]*semanticTokens),
+		semanticTokensMu: &sync.Mutex{},
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
+	s.registerCommands(s.commands)
 
 	jsonrpcOpts := []jsonrpc2.Options{
 		jsonrpc2.WithCanceler(protocol.Canceller),
@@ -83,9 +124,11 @@ func NewServer(ctx context.Context, session source.Session, stream jsonrpc2.Stre
 		jsonrpc2.WithLogger(s.logger.Named("jsonrpc2")),
 	}
 	s.Conn, s.Client = protocol.NewServer(ctx, s, stream, zap.NewNop(), jsonrpcOpts...)
+	s.Conn.Handler = s.handler(s.Conn.Handler)
 
 	logger := s.logger.Named("server")
-	logging.WithContext(ctx, logger)
+	ctx = logging.WithContext(ctx, logger)
+	s.backgroundCtx, s.cancelBackground = context.WithCancel(ctx)
 
 	return ctx, s
 }
@@ -140,19 +183,26 @@ func (s *Server) Exit(ctx context.Context) (err error) {
 	return s.exit(ctx)
 }
 
+// CodeAction is called only if the request bypasses the handler of the server,
+// so the code actions which create files are dropped.
 func (s *Server) CodeAction(ctx context.Context, params *protocol.CodeActionParams) (result []protocol.CodeAction, err error) {
-	err = notImplemented("CodeAction")
-	return
+	actions, err := s.codeAction(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return protocolCodeActions(actions), nil
 }
 
+// CodeLens implements textDocument/codeLens method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_codeLens
 func (s *Server) CodeLens(ctx context.Context, params *protocol.CodeLensParams) (result []protocol.CodeLens, err error) {
-	err = notImplemented("CodeLens")
-	return
+	return s.codeLens(ctx, params)
 }
 
+// CodeLensResolve implements codeLens/resolve method.
+// https://microsoft.github.io/language-server-protocol/specification#codeLens_resolve
 func (s *Server) CodeLensResolve(ctx context.Context, params *protocol.CodeLens) (result *protocol.CodeLens, err error) {
-	err = notImplemented("CodeLensResolve")
-	return
+	return s.codeLensResolve(ctx, params)
 }
 
 func (s *Server) ColorPresentation(ctx context.Context, params *protocol.ColorPresentationParams) (result []protocol.ColorPresentation, err error) {
@@ -171,9 +221,10 @@ func (s *Server) CompletionResolve(ctx context.Context, params *protocol.Complet
 	return
 }
 
+// Declaration implements textDocument/declaration method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_declaration
 func (s *Server) Declaration(ctx context.Context, params *protocol.TextDocumentPositionParams) (result []protocol.Location, err error) {
-	err = notImplemented("Declaration")
-	return
+	return s.declaration(ctx, params)
 }
 
 // Definition implements textDocument/definition method.
@@ -189,12 +240,12 @@ func (s *Server) DidChange(ctx context.Context, params *protocol.DidChangeTextDo
 }
 
 func (s *Server) DidChangeConfiguration(ctx context.Context, params *protocol.DidChangeConfigurationParams) (err error) {
-	err = notImplemented("DidChangeConfiguration")
+	err = s.didChangeConfiguration(ctx, params)
 	return
 }
 
 func (s *Server) DidChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) (err error) {
-	err = notImplemented("DidChangeWatchedFiles")
+	err = s.didChangeWatchedFiles(ctx, params)
 	return
 }
 
@@ -227,9 +278,10 @@ func (s *Server) DocumentColor(ctx context.Context, params *protocol.DocumentCol
 	return
 }
 
+// DocumentHighlight implements textDocument/documentHighlight method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_documentHighlight
 func (s *Server) DocumentHighlight(ctx context.Context, params *protocol.TextDocumentPositionParams) (result []protocol.DocumentHighlight, err error) {
-	err = notImplemented("DocumentHighlight")
-	return
+	return s.documentHighlight(ctx, params)
 }
 
 func (s *Server) DocumentLink(ctx context.Context, params *protocol.DocumentLinkParams) (result []protocol.DocumentLink, err error) {
@@ -247,9 +299,10 @@ func (s *Server) DocumentSymbol(ctx context.Context, params *protocol.DocumentSy
 	return
 }
 
+// ExecuteCommand implements workspace/executeCommand method.
+// https://microsoft.github.io/language-server-protocol/specification#workspace_executeCommand
 func (s *Server) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (result interface{}, err error) {
-	err = notImplemented("ExecuteCommand")
-	return
+	return s.executeCommand(ctx, params)
 }
 
 func (s *Server) FoldingRanges(ctx context.Context, params *protocol.FoldingRangeParams) (result []protocol.FoldingRange, err error) {
@@ -257,19 +310,22 @@ func (s *Server) FoldingRanges(ctx context.Context, params *protocol.FoldingRang
 	return
 }
 
+// Formatting implements textDocument/formatting method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_formatting
 func (s *Server) Formatting(ctx context.Context, params *protocol.DocumentFormattingParams) (result []protocol.TextEdit, err error) {
-	err = notImplemented("Formatting")
-	return
+	return s.formatting(ctx, params)
 }
 
+// Hover implements textDocument/hover method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_hover
 func (s *Server) Hover(ctx context.Context, params *protocol.TextDocumentPositionParams) (result *protocol.Hover, err error) {
-	err = notImplemented("Hover")
-	return
+	return s.hover(ctx, params)
 }
 
+// Implementation implements textDocument/implementation method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_implementation
 func (s *Server) Implementation(ctx context.Context, params *protocol.TextDocumentPositionParams) (result []protocol.Location, err error) {
-	err = notImplemented("Implementation")
-	return
+	return s.implementation(ctx, params)
 }
 
 func (s *Server) OnTypeFormatting(ctx context.Context, params *protocol.DocumentOnTypeFormattingParams) (result []protocol.TextEdit, err error) {
@@ -287,9 +343,10 @@ func (s *Server) RangeFormatting(ctx context.Context, params *protocol.DocumentR
 	return
 }
 
+// References implements textDocument/references method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_references
 func (s *Server) References(ctx context.Context, params *protocol.ReferenceParams) (result []protocol.Location, err error) {
-	err = notImplemented("References")
-	return
+	return s.references(ctx, params)
 }
 
 func (s *Server) Rename(ctx context.Context, params *protocol.RenameParams) (result *protocol.WorkspaceEdit, err error) {
@@ -297,9 +354,10 @@ func (s *Server) Rename(ctx context.Context, params *protocol.RenameParams) (res
 	return
 }
 
+// SignatureHelp is called only if the request bypasses the handler of the server,
+// so no signatures are returned since protocol.SignatureHelp can't represent their labels.
 func (s *Server) SignatureHelp(ctx context.Context, params *protocol.TextDocumentPositionParams) (result *protocol.SignatureHelp, err error) {
-	err = notImplemented("SignatureHelp")
-	return
+	return nil, nil
 }
 
 func (s *Server) Symbols(ctx context.Context, params *protocol.WorkspaceSymbolParams) (result []protocol.SymbolInformation, err error) {
@@ -307,9 +365,10 @@ func (s *Server) Symbols(ctx context.Context, params *protocol.WorkspaceSymbolPa
 	return
 }
 
+// TypeDefinition implements textDocument/typeDefinition method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_typeDefinition
 func (s *Server) TypeDefinition(ctx context.Context, params *protocol.TextDocumentPositionParams) (result []protocol.Location, err error) {
-	err = notImplemented("TypeDefinition")
-	return
+	return s.typeDefinition(ctx, params)
 }
 
 func (s *Server) WillSave(ctx context.Context, params *protocol.WillSaveTextDocumentParams) (err error) {
@@ -317,9 +376,10 @@ func (s *Server) WillSave(ctx context.Context, params *protocol.WillSaveTextDocu
 	return
 }
 
+// WillSaveWaitUntil implements textDocument/willSaveWaitUntil method.
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_willSaveWaitUntil
 func (s *Server) WillSaveWaitUntil(ctx context.Context, params *protocol.WillSaveTextDocumentParams) (result []protocol.TextEdit, err error) {
-	err = notImplemented("WillSaveWaitUntil")
-	return
+	return s.willSaveWaitUntil(ctx, params)
 }
 
 func notImplemented(method string) error {
@@ -27,6 +27,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
 )
 
 func (s *Server) didOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
@@ -36,8 +37,9 @@ func (s *Server) didOpen(ctx context.Context, params *protocol.DidOpenTextDocume
 	uri := params.TextDocument.URI
 	text := []byte(params.TextDocument.Text)
 
-	v := s.session.ViewOf(uri)
+	v := s.viewOf(ctx, uri)
 	v.DidOpen(uri, text)
+	s.diagnose(ctx, v, uri)
 
 	return nil
 }
@@ -51,7 +53,11 @@ func (s *Server) didChange(ctx context.Context, params *protocol.DidChangeTextDo
 	// TODO: Support incremental change. Currently support only full change.
 	text := params.ContentChanges[0].Text
 
-	switch s.config.TextDocumentSyncKind {
+	s.configMu.RLock()
+	syncKind := s.config.TextDocumentSyncKind
+	s.configMu.RUnlock()
+
+	switch syncKind {
 	case protocol.None:
 		return nil
 	case protocol.Full:
@@ -59,8 +65,9 @@ func (s *Server) didChange(ctx context.Context, params *protocol.DidChangeTextDo
 		return fmt.Errorf("incremental change is not supported yet")
 	}
 
-	v := s.session.ViewOf(uri)
+	v := s.lookupView(ctx, uri)
 	v.SetContent(ctx, uri, []byte(text))
+	s.diagnose(ctx, v, uri)
 
 	return nil
 }
@@ -68,18 +75,70 @@ func (s *Server) didChange(ctx context.Context, params *protocol.DidChangeTextDo
 func (s *Server) didClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
 	uri := params.TextDocument.URI
 
-	v := s.session.ViewOf(uri)
+	v := s.lookupView(ctx, uri)
 	v.DidClose(uri)
-	v.SetContent(ctx, uri, nil)
+
+	s.semanticTokensMu.Lock()
+	delete(s.semanticTokens, uri)
+	s.semanticTokensMu.Unlock()
+	// The file is kept in the view with the content on disk since it may be imported by other files.
+	dependents, err := v.DidChangeOnDisk(ctx, uri, false)
+	if err != nil {
+		v.SetContent(ctx, uri, nil)
+	}
+	s.publishDiagnostics(ctx, uri, nil)
+	for _, dependent := range dependents {
+		s.diagnose(ctx, v, dependent)
+	}
+
+	// An ad-hoc view is dropped once all of its files are closed.
+	// A transient view for a file which isn't opened isn't in the session in the first place.
+	if v.AdHoc() && len(v.OpenFiles()) == 0 && s.session.ViewOf(uri) == v {
+		if err := v.Shutdown(ctx); err != nil {
+			logging.FromContext(ctx).Warn("failed to shutdown view", zap.String("view", v.Name()), zap.Error(err))
+		}
+	}
 
 	return nil
 }
 
-func (s *Server) didSave(_ context.Context, params *protocol.DidSaveTextDocumentParams) error {
+func (s *Server) didSave(ctx context.Context, params *protocol.DidSaveTextDocumentParams) error {
 	uri := params.TextDocument.URI
 
-	v := s.session.ViewOf(uri)
+	v := s.lookupView(ctx, uri)
 	v.DidSave(uri)
 
+	if source.IsProjectConfig(uri) {
+		s.reloadConfig(ctx, v)
+		s.diagnoseOpenFiles(ctx)
+		return nil
+	}
+	s.diagnose(ctx, v, uri)
+
 	return nil
 }
+
+func (s *Server) willSaveWaitUntil(ctx context.Context, params *protocol.WillSaveTextDocumentParams) ([]protocol.TextEdit, error) {
+	logger := logging.FromContext(ctx)
+
+	uri := params.TextDocument.URI
+	v := s.lookupView(ctx, uri)
+	opts := v.Options().OnSave
+
+	edits := []protocol.TextEdit{}
+	if opts.OrganizeImports {
+		e, err := source.OrganizeImports(ctx, v, uri)
+		if err != nil {
+			logger.Debug("failed to organize imports", zap.String("uri", string(uri)), zap.Error(err))
+		}
+		edits = append(edits, e...)
+	}
+	if opts.SortFields {
+		e, err := source.SortFields(ctx, v, uri)
+		if err != nil {
+			logger.Debug("failed to sort fields", zap.String("uri", string(uri)), zap.Error(err))
+		}
+		edits = append(edits, e...)
+	}
+	return edits, nil
+}
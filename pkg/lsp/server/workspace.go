@@ -20,10 +20,16 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 
 	"github.com/go-language-server/protocol"
 	"github.com/go-language-server/uri"
+	"go.uber.org/zap"
 
+	"github.com/micnncim/protocol-buffers-language-server/pkg/buf"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/logging"
 	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
 )
 
@@ -39,12 +45,263 @@ func (s *Server) changeWorkspace(ctx context.Context, event protocol.WorkspaceFo
 	}
 
 	for _, folder := range event.Added {
-		s.addView(ctx, folder.Name, uri.File(folder.URI))
+		view := s.addView(ctx, folder.Name, uri.New(folder.URI))
+		s.index(view)
 	}
 	return nil
 }
 
-func (s *Server) addView(ctx context.Context, name string, uri uri.URI) {
+func (s *Server) addView(ctx context.Context, name string, uri uri.URI) source.View {
 	view := source.NewView(s.session, name, uri)
+	s.setupView(ctx, view)
+	return view
+}
+
+// viewOf returns the view which a given URI belongs to.
+// If no workspace folder contains the URI, as in single-file mode, an ad-hoc view rooted at
+// the directory of the file is added, which is dropped once all of its files are closed.
+// It is only for opened files; the other requests use lookupView not to leave ad-hoc views behind.
+func (s *Server) viewOf(ctx context.Context, fileURI uri.URI) source.View {
+	view, added := s.session.ViewOrAdd(ctx, fileURI, func() source.View {
+		return s.newAdHocView(fileURI)
+	})
+	if added {
+		s.reloadConfig(ctx, view)
+	}
+	return view
+}
+
+// lookupView returns the view which a given URI belongs to.
+// If no view contains the URI, it returns a transient ad-hoc view which isn't added to the session.
+func (s *Server) lookupView(ctx context.Context, fileURI uri.URI) source.View {
+	if view := s.session.ViewOf(fileURI); view != nil {
+		return view
+	}
+	view := s.newAdHocView(fileURI)
+	if err := view.ReloadConfig(ctx); err != nil {
+		logging.FromContext(ctx).Debug("failed to load project configuration", zap.String("view", view.Name()), zap.Error(err))
+	}
+	return view
+}
+
+func (s *Server) newAdHocView(fileURI uri.URI) source.View {
+	view := source.NewAdHocView(s.session, uri.File(filepath.Dir(fileURI.Filename())))
+	s.configMu.RLock()
+	view.SetOptions(s.config)
+	s.configMu.RUnlock()
+	return view
+}
+
+func (s *Server) setupView(ctx context.Context, view source.View) {
+	s.configMu.RLock()
+	view.SetOptions(s.config)
+	s.configMu.RUnlock()
 	s.session.AddView(ctx, view)
+	s.reloadConfig(ctx, view)
+}
+
+// index indexes the files of a view in the background and reports the progress to the client.
+func (s *Server) index(view source.View) {
+	if view.AdHoc() || !view.Options().Index.Enabled {
+		return
+	}
+
+	ctx := s.backgroundCtx
+	go func() {
+		logger := logging.FromContext(ctx).With(zap.String("view", view.Name()))
+
+		p := s.startProgress(ctx, fmt.Sprintf("Indexing %s", view.Name()))
+		err := view.Index(ctx, func(indexed, total int) {
+			p.report(ctx, fmt.Sprintf("%d/%d files", indexed, total), indexed*100/total)
+		})
+		p.end(ctx, "Indexing done")
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("failed to index files", zap.Error(err))
+			}
+			return
+		}
+
+		// Indexed files may resolve imports of the open files.
+		s.diagnoseOpenFiles(ctx)
+	}()
+}
+
+// reloadConfig reloads the project configurations of a given view
+// and shows an error to the user if any of them is invalid.
+func (s *Server) reloadConfig(ctx context.Context, view source.View) {
+	err := view.ReloadConfig(ctx)
+	if err == nil {
+		return
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Warn("failed to load project configuration", zap.String("view", view.Name()), zap.Error(err))
+
+	if s.Client == nil {
+		return
+	}
+	if err := s.Client.ShowMessage(ctx, &protocol.ShowMessageParams{
+		Type:    protocol.Warning,
+		Message: err.Error(),
+	}); err != nil {
+		logger.Error("failed to show message", zap.Error(err))
+	}
+}
+
+func (s *Server) didChangeConfiguration(ctx context.Context, params *protocol.DidChangeConfigurationParams) error {
+	logger := logging.FromContext(ctx)
+
+	// Clients which support workspace/configuration may send null settings,
+	// in which case the settings are pulled by fetchConfiguration.
+	if params.Settings != nil {
+		s.configMu.RLock()
+		base := s.initConfig
+		s.configMu.RUnlock()
+
+		cfg, err := config.ParseLSP(params.Settings, base)
+		if err != nil {
+			logger.Warn("failed to parse settings", zap.Error(err))
+			return err
+		}
+		// The sync kind can't be changed once it is advertised in the result of initialize.
+		cfg.TextDocumentSyncKind = base.TextDocumentSyncKind
+		s.configMu.Lock()
+		s.config = cfg
+		s.configMu.Unlock()
+	}
+
+	s.fetchConfiguration(ctx)
+	s.diagnoseOpenFiles(ctx)
+	return nil
+}
+
+// fetchConfiguration applies the configuration of the client to all views.
+// If the client supports workspace/configuration, the configuration is pulled per workspace folder.
+func (s *Server) fetchConfiguration(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	s.configMu.RLock()
+	cfg := s.config
+	s.configMu.RUnlock()
+	s.setLogLevel(ctx, cfg.LogLevel)
+
+	views := s.session.Views()
+	for _, view := range views {
+		view.SetOptions(cfg)
+	}
+
+	if w := s.capabilities.Workspace; w == nil || !w.Configuration || s.Client == nil || len(views) == 0 {
+		return
+	}
+
+	items := make([]protocol.ConfigurationItem, 0, len(views))
+	for _, view := range views {
+		items = append(items, protocol.ConfigurationItem{
+			ScopeURI: view.Folder(),
+			Section:  config.Section,
+		})
+	}
+	results, err := s.Client.WorkspaceConfiguration(ctx, &protocol.ConfigurationParams{Items: items})
+	if err != nil {
+		logger.Warn("failed to fetch configuration", zap.Error(err))
+		return
+	}
+
+	for i, result := range results {
+		if i >= len(views) {
+			break
+		}
+		options, err := config.ParseLSP(result, cfg)
+		if err != nil {
+			logger.Warn("failed to parse configuration", zap.String("view", views[i].Name()), zap.Error(err))
+			continue
+		}
+		options.TextDocumentSyncKind = cfg.TextDocumentSyncKind
+		views[i].SetOptions(options)
+		// The log level is global, so the first folder wins.
+		if i == 0 {
+			s.setLogLevel(ctx, options.LogLevel)
+		}
+	}
+}
+
+func (s *Server) setLogLevel(ctx context.Context, level string) {
+	if s.logLevel == nil || level == "" {
+		return
+	}
+	l, err := logging.ParseLevel(level)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to parse log level", zap.Error(err))
+		return
+	}
+	s.logLevel.SetLevel(l)
+}
+
+// fileWatchers returns the watchers for the files which the server cares about on disk.
+func fileWatchers() []protocol.FileSystemWatcher {
+	patterns := []string{
+		"**/*.proto",
+		"**/*.go",
+		"**/" + config.ProjectFilename,
+		"**/" + buf.ConfigFilename,
+		"**/" + buf.WorkFilename,
+		"**/" + buf.LockFilename,
+	}
+	watchers := make([]protocol.FileSystemWatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		// Kind is omitted since it defaults to all of create, change and delete.
+		watchers = append(watchers, protocol.FileSystemWatcher{GlobPattern: pattern})
+	}
+	return watchers
+}
+
+func (s *Server) didChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) error {
+	logger := logging.FromContext(ctx)
+
+	var (
+		configViews = make(map[source.View]struct{})
+		affected    = make(map[uri.URI]source.View)
+	)
+	for _, change := range params.Changes {
+		uri := change.URI
+		view := s.session.ViewOf(uri)
+		if view == nil {
+			continue
+		}
+
+		if source.IsProjectConfig(uri) {
+			configViews[view] = struct{}{}
+			continue
+		}
+		if filepath.Ext(uri.Filename()) == ".go" {
+			view.DidChangeGoFile(uri)
+			continue
+		}
+		if filepath.Ext(uri.Filename()) != ".proto" {
+			continue
+		}
+
+		dependents, err := view.DidChangeOnDisk(ctx, uri, change.Type == protocol.Deleted)
+		if err != nil {
+			logger.Warn("failed to reload file", zap.String("uri", string(uri)), zap.Error(err))
+		}
+		for _, dependent := range dependents {
+			affected[dependent] = view
+		}
+	}
+
+	if len(configViews) > 0 {
+		for view := range configViews {
+			s.reloadConfig(ctx, view)
+		}
+		// Project configurations may change the include paths or the lint rules of any file.
+		s.diagnoseOpenFiles(ctx)
+		return nil
+	}
+
+	for uri, view := range affected {
+		s.diagnose(ctx, view, uri)
+	}
+	return nil
 }
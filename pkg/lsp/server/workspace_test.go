@@ -13,3 +13,107 @@
 // limitations under the License.
 
 package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+	"go.uber.org/zap"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/lsp/source"
+)
+
+func newTestServer() *Server {
+	s := &Server{
+		state:      stateCreated,
+		stateMu:    &sync.RWMutex{},
+		session:    source.NewSession(),
+		config:     config.DefaultLSPConfig,
+		initConfig: config.DefaultLSPConfig,
+		configMu:   &sync.RWMutex{},
+		logger:     zap.NewNop(),
+		commands:   newCommandRegistry(),
+
+		semanticTokens:   make(map[uri.URI]*semanticTokens),
+		semanticTokensMu: &sync.Mutex{},
+	}
+	s.registerCommands(s.commands)
+	return s
+}
+
+func TestDidChangeConfiguration_SyncKind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	s := newTestServer()
+	if _, err := s.initialize(ctx, &protocol.InitializeParams{}); err != nil {
+		t.Fatalf("initialize() error = %v", err)
+	}
+
+	fileURI := uri.File(filepath.Join(dir, "foo.proto"))
+	if err := s.didOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: fileURI, Text: `syntax = "proto3";`},
+	}); err != nil {
+		t.Fatalf("didOpen() error = %v", err)
+	}
+	if err := s.didChangeConfiguration(ctx, &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{
+			"protobuf": map[string]interface{}{"syncKind": float64(protocol.None)},
+		},
+	}); err != nil {
+		t.Fatalf("didChangeConfiguration() error = %v", err)
+	}
+
+	want := `syntax = "proto3"; message Foo {}`
+	if err := s.didChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: fileURI}},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: want}},
+	}); err != nil {
+		t.Fatalf("didChange() error = %v", err)
+	}
+
+	f, err := s.lookupView(ctx, fileURI).GetFile(fileURI)
+	if err != nil {
+		t.Fatalf("GetFile() error = %v", err)
+	}
+	got, _, err := f.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("content after didChange = %q, want %q", got, want)
+	}
+}
+
+func TestLookupView(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer()
+	fileURI := uri.File("/tmp/foo.proto")
+
+	view := s.lookupView(ctx, fileURI)
+	if !view.AdHoc() {
+		t.Errorf("lookupView() = %v, want an ad-hoc view", view.Name())
+	}
+	if got := len(s.session.Views()); got != 0 {
+		t.Errorf("len(Views()) = %d after lookupView(), want 0", got)
+	}
+
+	opened := s.viewOf(ctx, fileURI)
+	if got := s.lookupView(ctx, fileURI); got != opened {
+		t.Errorf("lookupView() = %v, want the view of the opened file", got.Name())
+	}
+	if got := len(s.session.Views()); got != 1 {
+		t.Errorf("len(Views()) = %d after viewOf(), want 1", got)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/git"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/breaking"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/registry"
+)
+
+// revisionProto is a file parsed at a commit.
+type revisionProto struct {
+	commit string
+	proto  registry.Proto
+}
+
+// revisionKey is a git revision resolved in a directory.
+type revisionKey struct {
+	dir string
+	rev string
+}
+
+// revisionCache caches the commits which git revisions resolve to and the previous versions of files at them.
+type revisionCache struct {
+	// commits is resolved again once a file is saved or changed on disk, when the revisions may have moved,
+	// so that git is not run for every change in the editor.
+	commits map[revisionKey]string
+	// protos is keyed by filename. Only the one at the latest commit compared with is kept for each file.
+	protos map[string]revisionProto
+	mu     sync.Mutex
+}
+
+func newRevisionCache() *revisionCache {
+	return &revisionCache{
+		commits: make(map[revisionKey]string),
+		protos:  make(map[string]revisionProto),
+	}
+}
+
+// forgetCommits forgets the commits which the revisions resolve to.
+func (c *revisionCache) forgetCommits() {
+	c.mu.Lock()
+	c.commits = make(map[revisionKey]string)
+	c.mu.Unlock()
+}
+
+// clear forgets everything cached.
+func (c *revisionCache) clear() {
+	c.mu.Lock()
+	c.commits = make(map[revisionKey]string)
+	c.protos = make(map[string]revisionProto)
+	c.mu.Unlock()
+}
+
+// resolve returns the commit which a revision resolves to in a directory.
+func (c *revisionCache) resolve(ctx context.Context, dir, rev string) (string, error) {
+	key := revisionKey{dir: dir, rev: rev}
+	c.mu.Lock()
+	commit, ok := c.commits[key]
+	c.mu.Unlock()
+	if ok {
+		return commit, nil
+	}
+
+	commit, err := git.ResolveRevision(ctx, dir, rev)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.commits[key] = commit
+	c.mu.Unlock()
+	return commit, nil
+}
+
+// revisionsOf returns the revision cache of a view.
+func revisionsOf(v View) *revisionCache {
+	if cached, ok := v.(*view); ok {
+		return cached.revisions
+	}
+	return newRevisionCache()
+}
+
+// BreakingDiagnostics returns the diagnostics of the breaking changes of the file for a given URI
+// since a given git revision. An empty against means the revision configured for the file.
+// No diagnostics are returned for a file which can't be parsed or didn't exist at the revision.
+func BreakingDiagnostics(ctx context.Context, view View, uri uri.URI, against string) ([]protocol.Diagnostic, error) {
+	problems, lines, err := breakingProblems(ctx, view, uri, against)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := make([]protocol.Diagnostic, 0, len(problems))
+	for _, p := range problems {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    nameRange(lines, p.Line, p.Column, p.Name),
+			Severity: protocol.SeverityWarning,
+			Code:     p.RuleID,
+			Source:   DiagnosticSource,
+			Message:  p.Message,
+		})
+	}
+	return diagnostics, nil
+}
+
+// breakingProblems returns the breaking changes of the file for a given URI since a given git revision
+// with the content lines of the file.
+func breakingProblems(ctx context.Context, view View, uri uri.URI, against string) ([]breaking.Problem, []string, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.ParseError() != nil || pf.Proto() == nil {
+		return nil, nil, nil
+	}
+
+	filename := uri.Filename()
+	cfg := view.Config(uri)
+	if against == "" {
+		against = cfg.Breaking.Against
+	}
+	if against == "" {
+		against = breaking.DefaultAgainst
+	}
+
+	prev, err := revisionsOf(view).previousProto(ctx, filename, against)
+	if err != nil {
+		return nil, nil, err
+	}
+	if prev == nil {
+		return nil, nil, nil
+	}
+
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	return breaking.Check(prev, pf.Proto(), filename, cfg), lines, nil
+}
+
+// previousProto returns a file parsed at a git revision.
+// It returns nil if the file didn't exist or can't be parsed at the revision.
+func (c *revisionCache) previousProto(ctx context.Context, filename, rev string) (registry.Proto, error) {
+	commit, err := c.resolve(ctx, filepath.Dir(filename), rev)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached, ok := c.protos[filename]
+	c.mu.Unlock()
+	if ok && cached.commit == commit {
+		return cached.proto, nil
+	}
+
+	data, err := git.ShowFile(ctx, filename, commit)
+	if err != nil && err != git.ErrNotExist {
+		return nil, err
+	}
+	var proto registry.Proto
+	if err == nil {
+		// The previous version may not be valid, which has nothing to compare.
+		if parsed, err := parseProto(data); err == nil {
+			proto = parsed
+		}
+	}
+
+	c.mu.Lock()
+	c.protos[filename] = revisionProto{commit: commit, proto: proto}
+	c.mu.Unlock()
+	return proto, nil
+}
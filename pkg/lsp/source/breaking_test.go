@@ -0,0 +1,132 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestBreakingDiagnostics(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "foo.proto")
+	prev := `syntax = "proto3";
+message Foo {
+  string bar = 1;
+}
+`
+	if err := ioutil.WriteFile(filename, []byte(prev), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File(dir))
+	fileURI := uri.File(filename)
+	view.DidOpen(fileURI, []byte(`syntax = "proto3";
+message Foo {
+  int64 bar = 1;
+}
+`))
+
+	got, err := BreakingDiagnostics(ctx, view, fileURI, "")
+	if err != nil {
+		t.Fatalf("BreakingDiagnostics() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Code != "FIELD_SAME_TYPE" || got[0].Range.Start.Line != 2 {
+		t.Errorf("BreakingDiagnostics() = %+v, want a FIELD_SAME_TYPE diagnostic at line 2", got)
+	}
+
+	newURI := uri.File(filepath.Join(dir, "bar.proto"))
+	view.DidOpen(newURI, []byte(prev))
+	got, err = BreakingDiagnostics(ctx, view, newURI, "")
+	if err != nil {
+		t.Fatalf("BreakingDiagnostics() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("BreakingDiagnostics() = %+v, want no diagnostics for a new file", got)
+	}
+
+	view.SetContent(ctx, fileURI, []byte(`syntax = "proto3";
+message Foo {
+}
+`))
+	got, err = BreakingDiagnostics(ctx, view, fileURI, "")
+	if err != nil {
+		t.Fatalf("BreakingDiagnostics() error = %v", err)
+	}
+	actions, err := QuickFixes(ctx, view, fileURI, got)
+	if err != nil {
+		t.Fatalf("QuickFixes() error = %v", err)
+	}
+	want := []protocol.TextEdit{
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 2},
+				End:   protocol.Position{Line: 2},
+			},
+			NewText: "  reserved 1;\n  reserved \"bar\";\n",
+		},
+	}
+	if len(actions) != 1 || !reflect.DeepEqual(actions[0].Edit.Changes[fileURI], want) {
+		t.Errorf("QuickFixes() = %+v, want an action with %+v", actions, want)
+	}
+
+	// The commit of HEAD is reused until a file is saved.
+	if err := ioutil.WriteFile(filename, []byte(`syntax = "proto3";
+message Foo {
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-a", "-m", "delete")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v: %s", err, out)
+	}
+	if got, err = BreakingDiagnostics(ctx, view, fileURI, ""); err != nil || len(got) != 1 {
+		t.Errorf("BreakingDiagnostics() = %+v, %v, want the diagnostics against the previous commit", got, err)
+	}
+	view.DidSave(fileURI)
+	if got, err = BreakingDiagnostics(ctx, view, fileURI, ""); err != nil || len(got) != 0 {
+		t.Errorf("BreakingDiagnostics() = %+v, %v, want no diagnostics against the new commit", got, err)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// Commands run by the code lenses of RPCs. The arguments are the URI of a file
+// and the fully-qualified name of an RPC declared in it.
+const (
+	// CommandCopyGrpcurl returns a grpcurl command line which calls the RPC.
+	CommandCopyGrpcurl = "protobuf.copyGrpcurl"
+	// CommandCopyRequest returns a JSON request of the RPC.
+	CommandCopyRequest = "protobuf.copyRequest"
+)
+
+// CodeLensData is the data of a code lens which is resolved lazily to count the references to a message or an enum.
+type CodeLensData struct {
+	URI uri.URI `json:"uri"`
+	// Symbol is the fully-qualified name of the message or the enum.
+	Symbol string `json:"symbol"`
+}
+
+// CodeLenses returns the code lenses of the file for a given URI.
+// The ones of messages and enums are resolved by ResolveCodeLens since counting references needs all files.
+func CodeLenses(ctx context.Context, view View, uri uri.URI) ([]protocol.CodeLens, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	proto := pf.Proto().Protobuf()
+
+	lenses := []protocol.CodeLens{}
+	var walk func(scope string, elements []protobuf.Visitee)
+	walk = func(scope string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					continue
+				}
+				name := qualify(scope, v.Name)
+				lenses = append(lenses, protocol.CodeLens{
+					Range: nameRange(lines, v.Position.Line, v.Position.Column, v.Name),
+					Data:  CodeLensData{URI: uri, Symbol: name},
+				})
+				walk(name, v.Elements)
+			case *protobuf.Enum:
+				lenses = append(lenses, protocol.CodeLens{
+					Range: nameRange(lines, v.Position.Line, v.Position.Column, v.Name),
+					Data:  CodeLensData{URI: uri, Symbol: qualify(scope, v.Name)},
+				})
+			case *protobuf.Service:
+				name := qualify(scope, v.Name)
+				var rpcs []*protobuf.RPC
+				for _, el := range v.Elements {
+					if rpc, ok := el.(*protobuf.RPC); ok {
+						rpcs = append(rpcs, rpc)
+					}
+				}
+				lenses = append(lenses, protocol.CodeLens{
+					Range:   nameRange(lines, v.Position.Line, v.Position.Column, v.Name),
+					Command: &protocol.Command{Title: plural(len(rpcs), "RPC")},
+				})
+				for _, rpc := range rpcs {
+					rng := nameRange(lines, rpc.Position.Line, rpc.Position.Column, rpc.Name)
+					args := []interface{}{string(uri), qualify(name, rpc.Name)}
+					lenses = append(lenses,
+						protocol.CodeLens{
+							Range:   rng,
+							Command: &protocol.Command{Title: "Copy grpcurl command", Command: CommandCopyGrpcurl, Arguments: args},
+						},
+						protocol.CodeLens{
+							Range:   rng,
+							Command: &protocol.Command{Title: "Copy request JSON", Command: CommandCopyRequest, Arguments: args},
+						},
+					)
+				}
+			}
+		}
+	}
+	walk(packageName(proto), proto.Elements)
+	return lenses, nil
+}
+
+// ResolveCodeLens resolves a code lens returned by CodeLenses with its data,
+// setting the number of the references to the message or the enum as the title.
+func ResolveCodeLens(ctx context.Context, view View, lens protocol.CodeLens, data CodeLensData) (protocol.CodeLens, error) {
+	for _, sym := range view.LookupSymbol(data.Symbol) {
+		if sym.URI != data.URI || (sym.Kind != SymbolKindMessage && sym.Kind != SymbolKindEnum) {
+			continue
+		}
+		lens.Command = &protocol.Command{Title: plural(len(References(ctx, view, sym)), "reference")}
+		return lens, nil
+	}
+	return lens, fmt.Errorf("%q is not declared in %s", data.Symbol, data.URI)
+}
+
+// plural returns a count followed by a noun which is pluralized unless the count is one.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestCodeLenses(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	fileURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fileURI, []byte(`syntax = "proto3";
+package foo;
+message Foo {
+  Bar bar = 1;
+}
+enum Bar {
+  BAR_UNSPECIFIED = 0;
+}
+service FooService {
+  rpc GetFoo(Foo) returns (Foo);
+}
+`))
+	view.DidOpen(uri.File("/workspace/baz.proto"), []byte(`syntax = "proto3";
+package foo;
+import "foo.proto";
+message Baz {
+  Foo foo = 1;
+  Bar bar = 2;
+}
+`))
+
+	lenses, err := CodeLenses(ctx, view, fileURI)
+	if err != nil {
+		t.Fatalf("CodeLenses() error = %v", err)
+	}
+	var titles []string
+	for _, l := range lenses {
+		if l.Command == nil {
+			resolved, err := ResolveCodeLens(ctx, view, l, l.Data.(CodeLensData))
+			if err != nil {
+				t.Fatalf("ResolveCodeLens() error = %v", err)
+			}
+			l = resolved
+		}
+		titles = append(titles, l.Command.Title)
+	}
+	want := []string{"3 references", "2 references", "1 RPC", "Copy grpcurl command", "Copy request JSON"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("CodeLenses() titles = %v, want %v", titles, want)
+	}
+	if want := (protocol.Range{
+		Start: protocol.Position{Line: 9, Character: 6},
+		End:   protocol.Position{Line: 9, Character: 12},
+	}); lenses[3].Range != want {
+		t.Errorf("CodeLenses() range = %v, want %v", lenses[3].Range, want)
+	}
+	if want := []interface{}{string(fileURI), "foo.FooService.GetFoo"}; !reflect.DeepEqual(lenses[3].Command.Arguments, want) {
+		t.Errorf("CodeLenses() arguments = %v, want %v", lenses[3].Command.Arguments, want)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/uri"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/compiler"
+)
+
+// Compile compiles the files for given URIs and the files imported by them into a FileDescriptorSet.
+// Each file is named with its import path relative to the include paths of the project or the folder of the view.
+// Imports which can't be resolved are left to the compiler, which knows the well-known files bundled with protoc.
+func Compile(ctx context.Context, view View, uris []uri.URI, opts compiler.Options) (*descriptorpb.FileDescriptorSet, error) {
+	var (
+		files []*compiler.File
+		roots []string
+		seen  = make(map[string]bool)
+	)
+	var load func(u uri.URI, path string) error
+	load = func(u uri.URI, path string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+
+		proto, content, err := readCompiledProto(ctx, view, u)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, &compiler.File{Path: path, Proto: proto, Content: content})
+		for _, el := range proto.Elements {
+			imp, ok := el.(*protobuf.Import)
+			if !ok {
+				continue
+			}
+			target, ok := ResolveImport(view, u, imp.Filename)
+			if !ok {
+				continue
+			}
+			if err := load(target, imp.Filename); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, u := range uris {
+		path, ok := ImportPath(view, u, u)
+		if !ok {
+			path = filepath.Base(u.Filename())
+		}
+		if err := load(u, path); err != nil {
+			return nil, err
+		}
+		roots = append(roots, path)
+	}
+	return compiler.Compile(files, roots, opts)
+}
+
+// readCompiledProto returns the parsed file and the content of a file to compile.
+func readCompiledProto(ctx context.Context, view View, uri uri.URI) (*protobuf.Proto, []byte, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok {
+		return nil, nil, fmt.Errorf("not a proto file")
+	}
+	if err := pf.ParseError(); err != nil {
+		return nil, nil, err
+	}
+	if pf.Proto() == nil {
+		return nil, nil, fmt.Errorf("failed to parse")
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pf.Proto().Protobuf(), data, nil
+}
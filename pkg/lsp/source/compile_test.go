@@ -0,0 +1,67 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/compiler"
+)
+
+func TestCompile(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	fooURI := uri.File("/workspace/foo/foo.proto")
+	view.DidOpen(fooURI, []byte(`syntax = "proto3";
+package foo;
+import "bar/bar.proto";
+import "google/protobuf/empty.proto";
+message Foo {
+  bar.Bar bar = 1;
+  google.protobuf.Empty empty = 2;
+}
+`))
+	view.DidOpen(uri.File("/workspace/bar/bar.proto"), []byte(`syntax = "proto3";
+package bar;
+message Bar {}
+`))
+
+	set, err := Compile(ctx, view, []uri.URI{fooURI}, compiler.Options{IncludeImports: true})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	var names []string
+	for _, f := range set.File {
+		names = append(names, f.GetName())
+	}
+	want := []string{"bar/bar.proto", "google/protobuf/empty.proto", "foo/foo.proto"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Compile() = %v, want %v", names, want)
+	}
+	if got, want := set.File[2].MessageType[0].Field[0].GetTypeName(), ".bar.Bar"; got != want {
+		t.Errorf("Compile() type name = %q, want %q", got, want)
+	}
+
+	brokenURI := uri.File("/workspace/broken.proto")
+	view.DidOpen(brokenURI, []byte("message Foo {\n"))
+	if _, err := Compile(ctx, view, []uri.URI{brokenURI}, compiler.Options{}); err == nil {
+		t.Error("Compile() error = nil, want a parse error")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/lint"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/parser"
+)
+
+// DiagnosticSource is the source of diagnostics reported by this server.
+const DiagnosticSource = "protobuf"
+
+// Diagnostics returns the diagnostics of the file for a given URI.
+func Diagnostics(ctx context.Context, view View, uri uri.URI) ([]protocol.Diagnostic, error) {
+	if IsTextProto(uri) {
+		return TextProtoDiagnostics(ctx, view, uri)
+	}
+
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok {
+		return nil, nil
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	if err := pf.ParseError(); err != nil {
+		diagnostics = append(diagnostics, parseErrorDiagnostic(err))
+		return diagnostics, nil
+	}
+
+	problems, err := semanticProblems(ctx, view, pf)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics = append(diagnostics, semanticDiagnostics(problems)...)
+
+	if view.Options().Lint.Enabled {
+		data, _, err := pf.Read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		problems := lint.Lint(pf.Proto(), uri.Filename(), view.Config(uri))
+		diagnostics = append(diagnostics, LintDiagnostics(problems, data)...)
+	}
+	return diagnostics, nil
+}
+
+// LintDiagnostics converts lint problems in a file with given content to diagnostics.
+// The rule ID of each problem is set as the code of the diagnostic.
+func LintDiagnostics(problems []lint.Problem, data []byte) []protocol.Diagnostic {
+	lines := strings.Split(string(data), "\n")
+
+	diagnostics := make([]protocol.Diagnostic, 0, len(problems))
+	for _, p := range problems {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    nameRange(lines, p.Line, p.Column, p.Name),
+			Severity: diagnosticSeverity(p.Severity),
+			Code:     p.RuleID,
+			Source:   DiagnosticSource,
+			Message:  p.Message,
+		})
+	}
+	return diagnostics
+}
+
+// nameRange returns the range of a name which appears at or after a given 1-based position.
+// If the name is not found, the range covers the character at the position.
+func nameRange(lines []string, line, column int, name string) protocol.Range {
+	l, c := line-1, column-1
+	if l < 0 {
+		l = 0
+	}
+	if c < 0 {
+		c = 0
+	}
+	start, end := c, c+1
+	if l < len(lines) && c <= len(lines[l]) && name != "" {
+		if i := strings.Index(lines[l][c:], name); i >= 0 {
+			start, end = c+i, c+i+len(name)
+		}
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: float64(l), Character: float64(start)},
+		End:   protocol.Position{Line: float64(l), Character: float64(end)},
+	}
+}
+
+func diagnosticSeverity(s lint.Severity) protocol.DiagnosticSeverity {
+	switch s {
+	case lint.SeverityError:
+		return protocol.SeverityError
+	case lint.SeverityInfo:
+		return protocol.SeverityInformation
+	case lint.SeverityHint:
+		return protocol.SeverityHint
+	default:
+		return protocol.SeverityWarning
+	}
+}
+
+func parseErrorDiagnostic(err error) protocol.Diagnostic {
+	var rng protocol.Range
+	var perr *parser.Error
+	if errors.As(err, &perr) {
+		line, column := perr.Line-1, perr.Column-1
+		if line < 0 {
+			line = 0
+		}
+		if column < 0 {
+			column = 0
+		}
+		rng = protocol.Range{
+			Start: protocol.Position{Line: float64(line), Character: float64(column)},
+			End:   protocol.Position{Line: float64(line), Character: float64(column + 1)},
+		}
+		err = errors.New(perr.Message)
+	}
+	return protocol.Diagnostic{
+		Range:    rng,
+		Severity: protocol.SeverityError,
+		Source:   DiagnosticSource,
+		Message:  err.Error(),
+	}
+}
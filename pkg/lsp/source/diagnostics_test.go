@@ -0,0 +1,100 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []protocol.Diagnostic
+	}{
+		{
+			name: "valid",
+			text: `syntax = "proto3";
+message Foo {}
+`,
+			want: []protocol.Diagnostic{},
+		},
+		{
+			name: "syntax error",
+			text: `syntax = "proto3";
+message Foo {
+  string bar = ;
+}
+`,
+			want: []protocol.Diagnostic{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 2, Character: 15},
+						End:   protocol.Position{Line: 2, Character: 16},
+					},
+					Severity: protocol.SeverityError,
+					Source:   DiagnosticSource,
+				},
+			},
+		},
+		{
+			name: "lint",
+			text: `syntax = "proto3";
+message foo_bar {}
+`,
+			want: []protocol.Diagnostic{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 1, Character: 8},
+						End:   protocol.Position{Line: 1, Character: 15},
+					},
+					Severity: protocol.SeverityWarning,
+					Code:     "MESSAGE_PASCAL_CASE",
+					Source:   DiagnosticSource,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			folder := uri.File("/workspace")
+			view := NewView(NewSession(), "workspace", folder)
+			fileURI := uri.File("/workspace/foo.proto")
+			view.DidOpen(fileURI, []byte(tt.text))
+
+			got, err := Diagnostics(ctx, view, fileURI)
+			if err != nil {
+				t.Fatalf("Diagnostics() error = %v", err)
+			}
+			// Messages come from the parser, so only check they are set.
+			for i := range got {
+				if got[i].Message == "" {
+					t.Errorf("Diagnostics()[%d].Message is empty", i)
+				}
+				got[i].Message = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diagnostics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"sort"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/registry"
+)
+
+// FileDump is the registry view of a proto file, which is encoded as JSON for debugging.
+// Names are fully-qualified and lines are 1-based.
+type FileDump struct {
+	URI        uri.URI       `json:"uri"`
+	ParseError string        `json:"parseError,omitempty"`
+	Package    string        `json:"package,omitempty"`
+	Messages   []MessageDump `json:"messages,omitempty"`
+	Enums      []EnumDump    `json:"enums,omitempty"`
+	Services   []ServiceDump `json:"services,omitempty"`
+}
+
+// MessageDump is a message in a FileDump.
+type MessageDump struct {
+	Name     string        `json:"name"`
+	Line     int           `json:"line"`
+	Fields   []FieldDump   `json:"fields,omitempty"`
+	Oneofs   []OneofDump   `json:"oneofs,omitempty"`
+	Messages []MessageDump `json:"messages,omitempty"`
+	Enums    []EnumDump    `json:"enums,omitempty"`
+}
+
+// FieldDump is a field in a MessageDump or a OneofDump.
+type FieldDump struct {
+	Name string `json:"name"`
+	// Type is the type as written, which is like "map<string, Foo>" for a map field.
+	Type   string `json:"type"`
+	Number int    `json:"number"`
+	// Label is "repeated", "optional", "required" or empty.
+	Label string `json:"label,omitempty"`
+	Line  int    `json:"line"`
+}
+
+// OneofDump is a oneof in a MessageDump.
+type OneofDump struct {
+	Name   string      `json:"name"`
+	Line   int         `json:"line"`
+	Fields []FieldDump `json:"fields,omitempty"`
+}
+
+// EnumDump is an enum in a FileDump or a MessageDump.
+type EnumDump struct {
+	Name   string          `json:"name"`
+	Line   int             `json:"line"`
+	Values []EnumValueDump `json:"values,omitempty"`
+}
+
+// EnumValueDump is a value in an EnumDump.
+type EnumValueDump struct {
+	Name   string `json:"name"`
+	Number int    `json:"number"`
+	Line   int    `json:"line"`
+}
+
+// ServiceDump is a service in a FileDump.
+type ServiceDump struct {
+	Name string    `json:"name"`
+	Line int       `json:"line"`
+	RPCs []RPCDump `json:"rpcs,omitempty"`
+}
+
+// RPCDump is an RPC in a ServiceDump.
+type RPCDump struct {
+	Name            string `json:"name"`
+	RequestType     string `json:"requestType"`
+	ResponseType    string `json:"responseType"`
+	StreamsRequest  bool   `json:"streamsRequest,omitempty"`
+	StreamsResponse bool   `json:"streamsResponse,omitempty"`
+	Line            int    `json:"line"`
+}
+
+// DumpFile returns the registry view of the file for a given URI.
+func DumpFile(view View, uri uri.URI) (*FileDump, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a proto file", uri)
+	}
+
+	dump := &FileDump{URI: uri}
+	if err := pf.ParseError(); err != nil {
+		dump.ParseError = err.Error()
+	}
+	proto := pf.Proto()
+	if proto == nil {
+		return dump, nil
+	}
+
+	dump.Package = packageName(proto.Protobuf())
+	for _, m := range proto.Messages() {
+		if m.Protobuf().IsExtend {
+			continue
+		}
+		dump.Messages = append(dump.Messages, dumpMessage(dump.Package, m))
+	}
+	for _, e := range proto.Enums() {
+		dump.Enums = append(dump.Enums, dumpEnum(dump.Package, e.Protobuf()))
+	}
+	for _, s := range proto.Services() {
+		service := s.Protobuf()
+		sd := ServiceDump{Name: qualify(dump.Package, service.Name), Line: service.Position.Line}
+		for _, rpc := range s.RPCs() {
+			r := rpc.ProtoRPC
+			sd.RPCs = append(sd.RPCs, RPCDump{
+				Name:            qualify(sd.Name, r.Name),
+				RequestType:     r.RequestType,
+				ResponseType:    r.ReturnsType,
+				StreamsRequest:  r.StreamsRequest,
+				StreamsResponse: r.StreamsReturns,
+				Line:            r.Position.Line,
+			})
+		}
+		dump.Services = append(dump.Services, sd)
+	}
+	return dump, nil
+}
+
+func dumpMessage(scope string, m registry.Message) MessageDump {
+	message := m.Protobuf()
+	md := MessageDump{Name: qualify(scope, message.Name), Line: message.Position.Line}
+	for _, f := range m.Fields() {
+		md.Fields = append(md.Fields, dumpField(f.ProtoField.Field, f.ProtoField.Type, fieldLabel(f.ProtoField)))
+	}
+	for _, f := range m.MapFields() {
+		typ := fmt.Sprintf("map<%s, %s>", f.ProtoMapField.KeyType, f.ProtoMapField.Type)
+		md.Fields = append(md.Fields, dumpField(f.ProtoMapField.Field, typ, ""))
+	}
+	sort.SliceStable(md.Fields, func(i, j int) bool { return md.Fields[i].Line < md.Fields[j].Line })
+	for _, o := range m.Oneofs() {
+		oneof := o.Protobuf()
+		od := OneofDump{Name: oneof.Name, Line: oneof.Position.Line}
+		for _, el := range oneof.Elements {
+			if f, ok := el.(*protobuf.OneOfField); ok {
+				od.Fields = append(od.Fields, dumpField(f.Field, f.Type, ""))
+			}
+		}
+		md.Oneofs = append(md.Oneofs, od)
+	}
+	for _, nested := range m.NestedMessages() {
+		md.Messages = append(md.Messages, dumpMessage(md.Name, nested))
+	}
+	for _, e := range m.NestedEnums() {
+		md.Enums = append(md.Enums, dumpEnum(md.Name, e.Protobuf()))
+	}
+	return md
+}
+
+func dumpField(f *protobuf.Field, typ, label string) FieldDump {
+	return FieldDump{Name: f.Name, Type: typ, Number: f.Sequence, Label: label, Line: f.Position.Line}
+}
+
+func dumpEnum(scope string, e *protobuf.Enum) EnumDump {
+	ed := EnumDump{Name: qualify(scope, e.Name), Line: e.Position.Line}
+	for _, v := range enumValues(e) {
+		ed.Values = append(ed.Values, EnumValueDump{Name: v.Name, Number: v.Integer, Line: v.Position.Line})
+	}
+	return ed
+}
+
+func fieldLabel(f *protobuf.NormalField) string {
+	switch {
+	case f.Repeated:
+		return "repeated"
+	case f.Optional:
+		return "optional"
+	case f.Required:
+		return "required"
+	}
+	return ""
+}
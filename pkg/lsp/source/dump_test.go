@@ -0,0 +1,82 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/uri"
+)
+
+func TestDumpFile(t *testing.T) {
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	fileURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fileURI, []byte(`syntax = "proto3";
+package foo;
+message Foo {
+  map<string, Bar> bars = 2;
+  repeated string names = 1;
+  oneof id {
+    int64 number = 3;
+  }
+  message Bar {}
+}
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+}
+service FooService {
+  rpc Watch(Foo) returns (stream Foo);
+}
+`))
+
+	got, err := DumpFile(view, fileURI)
+	if err != nil {
+		t.Fatalf("DumpFile() error = %v", err)
+	}
+	want := &FileDump{
+		URI:     fileURI,
+		Package: "foo",
+		Messages: []MessageDump{
+			{
+				Name: "foo.Foo",
+				Line: 3,
+				Fields: []FieldDump{
+					{Name: "bars", Type: "map<string, Bar>", Number: 2, Line: 4},
+					{Name: "names", Type: "string", Number: 1, Label: "repeated", Line: 5},
+				},
+				Oneofs: []OneofDump{
+					{Name: "id", Line: 6, Fields: []FieldDump{{Name: "number", Type: "int64", Number: 3, Line: 7}}},
+				},
+				Messages: []MessageDump{{Name: "foo.Foo.Bar", Line: 9}},
+			},
+		},
+		Enums: []EnumDump{
+			{Name: "foo.Kind", Line: 11, Values: []EnumValueDump{{Name: "KIND_UNSPECIFIED", Line: 12}}},
+		},
+		Services: []ServiceDump{
+			{
+				Name: "foo.FooService",
+				Line: 14,
+				RPCs: []RPCDump{
+					{Name: "foo.FooService.Watch", RequestType: "Foo", ResponseType: "Foo", StreamsResponse: true, Line: 15},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DumpFile() = %+v, want %+v", got, want)
+	}
+}
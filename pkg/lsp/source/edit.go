@@ -0,0 +1,70 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"sort"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// WorkspaceEdit is a protocol.WorkspaceEdit whose document changes may include file creations,
+// which the protocol package can't represent yet.
+type WorkspaceEdit struct {
+	// Changes holds the edits of existing files.
+	Changes map[uri.URI][]protocol.TextEdit `json:"changes,omitempty"`
+
+	// DocumentChanges holds protocol.TextDocumentEdit and protocol.CreateFile values in the order to apply.
+	// Clients ignore Changes if it is set.
+	DocumentChanges []interface{} `json:"documentChanges,omitempty"`
+}
+
+// CodeAction is a protocol.CodeAction with a WorkspaceEdit.
+type CodeAction struct {
+	Title       string                  `json:"title"`
+	Kind        protocol.CodeActionKind `json:"kind,omitempty"`
+	Diagnostics []protocol.Diagnostic   `json:"diagnostics,omitempty"`
+	Edit        *WorkspaceEdit          `json:"edit,omitempty"`
+	Command     *protocol.Command       `json:"command,omitempty"`
+}
+
+// documentChanges converts the edits of files to document changes in the order of the URIs,
+// following the creations of given files.
+func documentChanges(creates []uri.URI, edits map[uri.URI][]protocol.TextEdit) []interface{} {
+	changes := make([]interface{}, 0, len(creates)+len(edits))
+	for _, u := range creates {
+		changes = append(changes, protocol.CreateFile{
+			Kind: protocol.CreateResourceOperation,
+			URI:  u,
+		})
+	}
+
+	uris := make([]uri.URI, 0, len(edits))
+	for u := range edits {
+		uris = append(uris, u)
+	}
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	for _, u := range uris {
+		changes = append(changes, protocol.TextDocumentEdit{
+			// A null version means the content the server knows, which is the one on disk for closed files.
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: u},
+			},
+			Edits: edits[u],
+		})
+	}
+	return changes
+}
@@ -42,6 +42,9 @@ type ProtoFile interface {
 	File
 	Proto() registry.Proto
 	SetProto(proto registry.Proto)
+
+	// ParseError returns the error which occurred while parsing the file, if any.
+	ParseError() error
 }
 
 // FileSystem is the interface to something that provides file contents.
@@ -67,7 +70,9 @@ var _ File = (*file)(nil)
 
 type protoFile struct {
 	File
-	proto registry.Proto
+	proto    registry.Proto
+	parseErr error
+	symbols  []Symbol
 }
 
 var _ ProtoFile = (*protoFile)(nil)
@@ -103,3 +108,16 @@ func (p *protoFile) Proto() registry.Proto {
 func (p *protoFile) SetProto(proto registry.Proto) {
 	p.proto = proto
 }
+
+func (p *protoFile) ParseError() error {
+	return p.parseErr
+}
+
+// parse parses data and updates the proto, the parse error and the symbols of the file.
+func (p *protoFile) parse(data []byte) {
+	p.proto, p.parseErr = parseProto(data)
+	p.symbols = nil
+	if p.proto != nil {
+		p.symbols = fileSymbols(p.URI(), p.proto.Protobuf())
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/diff"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/format"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/textformat"
+)
+
+// Format returns the content of the file for a given URI and the formatted one
+// with the formatter options configured for the file.
+// It returns an error for a file which can't be parsed so that broken content isn't garbled.
+func Format(ctx context.Context, view View, uri uri.URI) (data, formatted []byte, err error) {
+	if IsTextProto(uri) {
+		data, err = readTextProto(ctx, view, uri)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := textformat.Parse(data); err != nil {
+			return nil, nil, err
+		}
+		formatted, err = textformat.Format(data, view.Config(uri).Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		return data, formatted, nil
+	}
+
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a proto file", uri.Filename())
+	}
+	if err := pf.ParseError(); err != nil {
+		return nil, nil, err
+	}
+
+	data, _, err = pf.Read(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	formatted, err = format.Format(data, view.Config(uri).Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, formatted, nil
+}
+
+// TextEdits returns the edits which turn the content before into the one after.
+// Only the changed lines are replaced so that the cursor and marks of editors are kept elsewhere.
+func TextEdits(before, after []byte) []protocol.TextEdit {
+	a, b := diff.SplitLines(string(before)), diff.SplitLines(string(after))
+
+	// The end of the content is at the end of the last line if it has no line terminator.
+	end := protocol.Position{Line: float64(len(a))}
+	if n := len(a); n > 0 && !strings.HasSuffix(a[n-1], "\n") {
+		end = protocol.Position{Line: float64(n - 1), Character: float64(utf16Len(a[n-1]))}
+	}
+	position := func(line int) protocol.Position {
+		if line >= len(a) {
+			return end
+		}
+		return protocol.Position{Line: float64(line)}
+	}
+
+	var edits []protocol.TextEdit
+	for _, c := range diff.Lines(a, b) {
+		edits = append(edits, protocol.TextEdit{
+			Range: protocol.Range{
+				Start: position(c.A0),
+				End:   position(c.A1),
+			},
+			NewText: strings.Join(b[c.B0:c.B1], ""),
+		})
+	}
+	return edits
+}
+
+// utf16Len returns the length of a string in UTF-16 code units, which positions of LSP are counted in.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+			continue
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestFormat(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	fileURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fileURI, []byte("syntax = \"proto3\";\nmessage Foo {\nstring bar=1;\n}\n"))
+	data, formatted, err := Format(ctx, view, fileURI)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	want := []protocol.TextEdit{
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 2},
+				End:   protocol.Position{Line: 3},
+			},
+			NewText: "  string bar = 1;\n",
+		},
+	}
+	if got := TextEdits(data, formatted); !reflect.DeepEqual(got, want) {
+		t.Errorf("TextEdits() = %v, want %v", got, want)
+	}
+
+	brokenURI := uri.File("/workspace/broken.proto")
+	view.DidOpen(brokenURI, []byte("message Foo {\n"))
+	if _, _, err := Format(ctx, view, brokenURI); err == nil {
+		t.Error("Format() error = nil, want a parse error")
+	}
+}
+
+func TestTextEdits(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []protocol.TextEdit
+	}{
+		{
+			name:   "same",
+			before: "a\n",
+			after:  "a\n",
+		},
+		{
+			name:   "missing newline at end",
+			before: "a\nb😀",
+			after:  "a\nb😀\n",
+			want: []protocol.TextEdit{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 1},
+						End:   protocol.Position{Line: 1, Character: 3},
+					},
+					NewText: "b😀\n",
+				},
+			},
+		},
+		{
+			name:   "delete",
+			before: "a\n\n\nb\n",
+			after:  "a\n\nb\n",
+			want: []protocol.TextEdit{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 2},
+						End:   protocol.Position{Line: 3},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TextEdits([]byte(tt.before), []byte(tt.after)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TextEdits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path"
+	"path/filepath"
+
+	"github.com/go-language-server/uri"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/plugin"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/compiler"
+)
+
+// PreviewScheme is the URI scheme of generated files, which editors open as read-only documents.
+const PreviewScheme = "protobuf-preview"
+
+// defaultPlugins are the plugins run if they are installed and no plugins are configured.
+var defaultPlugins = []string{"go", "go-grpc"}
+
+// GeneratedFile is a file which a protoc plugin generates for a proto file.
+type GeneratedFile struct {
+	// Plugin is the name of the plugin, such as "go" for protoc-gen-go.
+	Plugin string `json:"plugin"`
+	// Name is the path to the file relative to the output directory of the plugin.
+	Name string `json:"name"`
+	// URI is the URI of the preview of the file, such as "protobuf-preview:///go/foo/v1/foo.pb.go".
+	URI     uri.URI `json:"uri"`
+	Content string  `json:"content"`
+}
+
+// GeneratedCode runs the protoc plugins configured for the file for a given URI and returns the files
+// they generate for it, without running protoc or writing any files.
+// The plugins receive the file and its imports compiled from the view, including unsaved changes.
+// Each plugin is killed if it runs longer than the configured timeout.
+func GeneratedCode(ctx context.Context, view View, fileURI uri.URI) ([]GeneratedFile, error) {
+	cfg := view.Config(fileURI)
+	plugins := cfg.Generate.Plugins
+	if len(plugins) == 0 {
+		for _, name := range defaultPlugins {
+			if _, err := exec.LookPath("protoc-gen-" + name); err == nil {
+				plugins = append(plugins, config.Plugin{Name: name})
+			}
+		}
+	}
+	if len(plugins) == 0 {
+		return nil, errors.New("no protoc plugins are configured or installed")
+	}
+
+	set, err := Compile(ctx, view, []uri.URI{fileURI}, compiler.Options{IncludeImports: true, IncludeSourceInfo: true})
+	if err != nil {
+		return nil, err
+	}
+	// The file follows all of its imports.
+	target := set.File[len(set.File)-1].GetName()
+
+	var generated []GeneratedFile
+	for _, p := range plugins {
+		req := &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{target},
+			ProtoFile:      set.File,
+		}
+		if p.Opt != "" {
+			req.Parameter = proto.String(p.Opt)
+		}
+		runCtx, cancel := context.WithTimeout(ctx, cfg.Generate.Timeout)
+		files, err := plugin.Run(runCtx, pluginPath(cfg, p), req)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			generated = append(generated, GeneratedFile{
+				Plugin:  p.Name,
+				Name:    f.Name,
+				URI:     previewURI(p.Name, f.Name),
+				Content: f.Content,
+			})
+		}
+	}
+	return generated, nil
+}
+
+// pluginPath returns the path to the executable of a plugin.
+// Relative paths with directories are resolved against the directory of the project configuration.
+func pluginPath(cfg config.Project, p config.Plugin) string {
+	if p.Path == "" {
+		return "protoc-gen-" + p.Name
+	}
+	if !filepath.IsAbs(p.Path) && filepath.Base(p.Path) != p.Path && cfg.Dir != "" {
+		return filepath.Join(cfg.Dir, p.Path)
+	}
+	return p.Path
+}
+
+// previewURI returns the URI of the preview of a file generated by a plugin.
+func previewURI(plugin, name string) uri.URI {
+	return uri.URI(PreviewScheme + "://" + path.Join("/", plugin, name))
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+// fakePlugin is a plugin which generates foo.txt whose content is "hello" regardless of the request.
+const fakePlugin = `#!/bin/sh
+cat > /dev/null
+printf '\172\020\012\007foo.txt\172\005hello'
+`
+
+func TestGeneratedCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake plugin is a shell script")
+	}
+	ctx := context.Background()
+	root, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		config.ProjectFilename: "generate: {plugins: [{name: fake, path: bin/protoc-gen-fake}]}",
+		"foo.proto":            "syntax = \"proto3\";\nmessage Foo {}\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "bin", "protoc-gen-fake"), []byte(fakePlugin), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	view := NewView(NewSession(), "workspace", uri.File(root))
+	if err := view.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GeneratedCode(ctx, view, uri.File(filepath.Join(root, "foo.proto")))
+	if err != nil {
+		t.Fatalf("GeneratedCode() error = %v", err)
+	}
+	want := []GeneratedFile{
+		{
+			Plugin:  "fake",
+			Name:    "foo.txt",
+			URI:     "protobuf-preview:///fake/foo.txt",
+			Content: "hello",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GeneratedCode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPluginPath(t *testing.T) {
+	dir := filepath.FromSlash("/workspace")
+	tests := []struct {
+		name   string
+		plugin config.Plugin
+		want   string
+	}{
+		{
+			name:   "name",
+			plugin: config.Plugin{Name: "go"},
+			want:   "protoc-gen-go",
+		},
+		{
+			name:   "command in PATH",
+			plugin: config.Plugin{Name: "go", Path: "protoc-gen-go-v2"},
+			want:   "protoc-gen-go-v2",
+		},
+		{
+			name:   "relative path",
+			plugin: config.Plugin{Name: "go", Path: filepath.Join("bin", "protoc-gen-go")},
+			want:   filepath.Join(dir, "bin", "protoc-gen-go"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pluginPath(config.Project{Dir: dir}, tt.plugin); got != tt.want {
+				t.Errorf("pluginPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
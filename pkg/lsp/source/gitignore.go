@@ -0,0 +1,130 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+// gitignoreFilename is the name of a file which declares files ignored by git.
+const gitignoreFilename = ".gitignore"
+
+// gitignore represents patterns declared in a .gitignore file.
+type gitignore struct {
+	dir      string
+	patterns []gitignorePattern
+}
+
+type gitignorePattern struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// loadGitignore reads a .gitignore file in a given directory.
+// It returns nil if the directory has no .gitignore file.
+func loadGitignore(dir string) (*gitignore, error) {
+	f, err := os.Open(filepath.Join(dir, gitignoreFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g := &gitignore{dir: dir}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p gitignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// A pattern without a slash matches at any level below the directory.
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		p.pattern = strings.TrimPrefix(line, "/")
+		if p.pattern == "" {
+			continue
+		}
+		g.patterns = append(g.patterns, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// match returns whether a given path matches one of the patterns and, if so,
+// whether it is ignored. The last matching pattern wins.
+func (g *gitignore) match(path string, isDir bool) (matched, ignored bool) {
+	rel, err := filepath.Rel(g.dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, p := range g.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if config.MatchGlob(p.pattern, rel) {
+			matched, ignored = true, !p.negate
+		}
+	}
+	return
+}
+
+// gitignores holds .gitignore files keyed by their directories.
+type gitignores map[string]*gitignore
+
+// ignored returns true if a given path is ignored by the .gitignore files in its ancestor directories
+// up to a given root. Files in deeper directories take precedence.
+func (gs gitignores) ignored(root, path string, isDir bool) bool {
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == root || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	for i := range dirs {
+		g, ok := gs[dirs[i]]
+		if !ok || g == nil {
+			continue
+		}
+		if matched, ignored := g.match(path, isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
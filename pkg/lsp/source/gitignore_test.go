@@ -0,0 +1,82 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitignores_Ignored(t *testing.T) {
+	root, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		".gitignore":     "# comment\n*.pb.go\n/build\ntmp/\n!keep.pb.go\n",
+		"sub/.gitignore": "local.proto\n/anchored.proto\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gs := make(gitignores)
+	for _, dir := range []string{root, filepath.Join(root, "sub")} {
+		g, err := loadGitignore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gs[dir] = g
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "a.proto", want: false},
+		{path: "a.pb.go", want: true},
+		{path: "sub/a.pb.go", want: true},
+		{path: "keep.pb.go", want: false},
+		{path: "build", isDir: true, want: true},
+		{path: "sub/build", isDir: true, want: false},
+		{path: "tmp", isDir: true, want: true},
+		{path: "tmp", isDir: false, want: false},
+		{path: "sub/local.proto", want: true},
+		{path: "sub/nested/local.proto", want: true},
+		{path: "local.proto", want: false},
+		{path: "sub/anchored.proto", want: true},
+		{path: "sub/nested/anchored.proto", want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(root, filepath.FromSlash(tt.path))
+			if got := gs.ignored(root, path, tt.isDir); got != tt.want {
+				t.Errorf("ignored(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,602 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// GoDefinitions returns the locations of the Go declarations which protoc-gen-go and protoc-gen-go-grpc
+// generate for the proto declaration at a given position, in the generated files in the folder of a view.
+// The generated files are found by the source comment which the plugins write, and if the go_package option
+// declares an import path in a Go module in the folder, only the directory of the package is searched.
+func GoDefinitions(ctx context.Context, view View, uri uri.URI, pos protocol.Position) ([]protocol.Location, error) {
+	proto, lines, err := readProto(ctx, view, uri)
+	if err != nil || proto == nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, d := range goDecls(proto) {
+		if contains(d.location(uri, lines).Range, pos) {
+			names = append(names, d.name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	source, ok := ImportPath(view, uri, uri)
+	if !ok {
+		return nil, nil
+	}
+
+	ws, err := parseGoWorkspace(ctx, view.Folder().Filename())
+	if err != nil {
+		return nil, err
+	}
+	importPath, pkg := goPackageOption(proto)
+	var locations []protocol.Location
+	for _, f := range ws.files(source, importPath, pkg) {
+		for _, name := range names {
+			if ident, ok := f.decls[name]; ok {
+				locations = append(locations, goLocation(f.fset, ident))
+			}
+		}
+	}
+	sortLocations(locations)
+	return locations, nil
+}
+
+// ProtoDeclarations returns the locations of the proto declarations which the Go identifier at a given position
+// in a Go file refers to, if the identifier is declared in a file generated by protoc-gen-go or protoc-gen-go-grpc
+// in the folder of a view. The identifier may be a declaration in a generated file, a qualified identifier,
+// a key of a composite literal or an identifier in the package of a generated file.
+// A field or a method selected from a value is matched by name in the generated packages which the file imports,
+// so it may refer to multiple declarations. The Go file is read from disk.
+func ProtoDeclarations(ctx context.Context, view View, goURI uri.URI, pos protocol.Position) ([]protocol.Location, error) {
+	filename := goURI.Filename()
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, data, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	path := goPathAt(fset, file, pos)
+	if path == nil {
+		return nil, nil
+	}
+
+	ws, err := parseGoWorkspace(ctx, view.Folder().Filename())
+	if err != nil {
+		return nil, err
+	}
+	var targets []goTarget
+	if f, ok := newGeneratedFile(fset, file); ok {
+		ident := path[len(path)-1]
+		for name, id := range f.decls {
+			if id == ident {
+				targets = append(targets, goTarget{file: f, name: name})
+			}
+		}
+	} else {
+		targets = ws.targets(file, filepath.Dir(filename), path)
+	}
+
+	var locations []protocol.Location
+	seen := make(map[protocol.Location]bool)
+	for _, t := range targets {
+		protoURI, ok := ResolveImport(view, goURI, t.file.source)
+		if !ok {
+			continue
+		}
+		proto, lines, err := readProto(ctx, view, protoURI)
+		if err != nil || proto == nil {
+			continue
+		}
+		for _, d := range goDecls(proto) {
+			if d.name != t.name {
+				continue
+			}
+			if loc := d.location(protoURI, lines); !seen[loc] {
+				seen[loc] = true
+				locations = append(locations, loc)
+			}
+		}
+	}
+	sortLocations(locations)
+	return locations, nil
+}
+
+// goDecl is a Go declaration which protoc-gen-go or protoc-gen-go-grpc generates for a proto declaration.
+type goDecl struct {
+	// name is the name of a package-level declaration, or "Type.Member" for a field or a method.
+	name string
+	// word is the name of the proto declaration, which is searched at or after the 1-based line and column
+	// and after skip, such as the type of a field, if it is not empty.
+	word   string
+	skip   string
+	line   int
+	column int
+}
+
+// location returns the location of the name of the proto declaration in a file with given content lines.
+func (d goDecl) location(uri uri.URI, lines []string) protocol.Location {
+	line, column := d.line, d.column
+	if l, c, ok := findWord(lines, line, column, d.skip); ok {
+		line, column = l, c+len(d.skip)
+	}
+	if l, c, ok := findWord(lines, line, column, d.word); ok {
+		return protocol.Location{
+			URI: uri,
+			Range: protocol.Range{
+				Start: protocol.Position{Line: float64(l - 1), Character: float64(c - 1)},
+				End:   protocol.Position{Line: float64(l - 1), Character: float64(c - 1 + len(d.word))},
+			},
+		}
+	}
+	return protocol.Location{URI: uri, Range: nameRange(lines, line, column, d.word)}
+}
+
+// goDecls returns the Go declarations generated for the messages, the fields, the oneofs, the enums,
+// the enum values, the services and the RPCs in a proto file following the naming rules of the plugins.
+func goDecls(proto *protobuf.Proto) []goDecl {
+	var decls []goDecl
+	add := func(word, skip string, pos scanner.Position, names ...string) {
+		for _, name := range names {
+			decls = append(decls, goDecl{name: name, word: word, skip: skip, line: pos.Line, column: pos.Column})
+		}
+	}
+
+	// parent is the Go name of the message which the elements are declared in.
+	var walk func(scope, parent string, elements []protobuf.Visitee)
+	walk = func(scope, parent string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					continue
+				}
+				name := qualify(scope, v.Name)
+				typ := GoCamelCase(name)
+				add(v.Name, "", v.Position, typ)
+				walk(name, typ, v.Elements)
+			case *protobuf.NormalField:
+				f := GoCamelCase(v.Name)
+				add(v.Name, v.Type, v.Position, parent+"."+f, parent+".Get"+f)
+			case *protobuf.MapField:
+				f := GoCamelCase(v.Name)
+				add(v.Name, v.Type, v.Position, parent+"."+f, parent+".Get"+f)
+			case *protobuf.Oneof:
+				f := GoCamelCase(v.Name)
+				add(v.Name, "", v.Position, parent+"."+f, parent+".Get"+f)
+				walk(scope, parent, v.Elements)
+			case *protobuf.OneOfField:
+				// A member of a oneof is a field of a wrapper type.
+				f := GoCamelCase(v.Name)
+				add(v.Name, v.Type, v.Position, parent+"_"+f, parent+"_"+f+"."+f, parent+".Get"+f)
+			case *protobuf.Enum:
+				typ := GoCamelCase(qualify(scope, v.Name))
+				add(v.Name, "", v.Position, typ)
+				// The values of a nested enum are prefixed with the name of the message instead of the enum.
+				prefix := typ
+				if parent != "" {
+					prefix = parent
+				}
+				for _, e := range v.Elements {
+					if f, ok := e.(*protobuf.EnumField); ok {
+						add(f.Name, "", f.Position, prefix+"_"+f.Name)
+					}
+				}
+			case *protobuf.Service:
+				s := GoCamelCase(v.Name)
+				add(v.Name, "", v.Position, s+"Server", s+"Client", "Unimplemented"+s+"Server", "Register"+s+"Server", "New"+s+"Client")
+				for _, e := range v.Elements {
+					if r, ok := e.(*protobuf.RPC); ok {
+						m := GoCamelCase(r.Name)
+						add(r.Name, "", r.Position, s+"Server."+m, s+"Client."+m, "Unimplemented"+s+"Server."+m)
+					}
+				}
+			}
+		}
+	}
+	walk("", "", proto.Elements)
+
+	return decls
+}
+
+// goPackageOption returns the import path and the name of the Go package declared with the go_package option
+// of a proto file. The name defaults to the last element of the import path.
+func goPackageOption(proto *protobuf.Proto) (string, string) {
+	for _, el := range proto.Elements {
+		o, ok := el.(*protobuf.Option)
+		if !ok || o.Name != "go_package" {
+			continue
+		}
+		v := o.Constant.Source
+		if i := strings.Index(v, ";"); i >= 0 {
+			return v[:i], v[i+1:]
+		}
+		return v, goPackageName(v[strings.LastIndex(v, "/")+1:])
+	}
+	return "", ""
+}
+
+// goPackageName returns a valid Go package name for the last element of an import path.
+func goPackageName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// generatedFile is a Go file generated by protoc-gen-go or protoc-gen-go-grpc.
+type generatedFile struct {
+	fset *token.FileSet
+	// source is the import path of the proto file which the file is generated from.
+	source string
+	// pkg is the name of the Go package.
+	pkg string
+	// decls maps the names of the declarations in the form of goDecl.name to their identifiers.
+	decls map[string]*ast.Ident
+}
+
+// newGeneratedFile returns the declarations in a Go file if it has the source comment of a generated file.
+func newGeneratedFile(fset *token.FileSet, file *ast.File) (*generatedFile, bool) {
+	source := ""
+	for _, g := range file.Comments {
+		if g.Pos() > file.Package {
+			break
+		}
+		for _, c := range g.List {
+			if s := strings.TrimPrefix(c.Text, "// source: "); s != c.Text {
+				source = strings.TrimSpace(s)
+			}
+		}
+	}
+	if source == "" {
+		return nil, false
+	}
+
+	f := &generatedFile{fset: fset, source: source, pkg: file.Name.Name, decls: make(map[string]*ast.Ident)}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					f.decls[s.Name.Name] = s.Name
+					var fields *ast.FieldList
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						fields = t.Fields
+					case *ast.InterfaceType:
+						fields = t.Methods
+					}
+					if fields == nil {
+						continue
+					}
+					for _, field := range fields.List {
+						for _, name := range field.Names {
+							f.decls[s.Name.Name+"."+name.Name] = name
+						}
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						f.decls[name.Name] = name
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				f.decls[d.Name.Name] = d.Name
+			} else if recv := embeddedName(d.Recv.List[0].Type); recv != "" {
+				f.decls[recv+"."+d.Name.Name] = d.Name
+			}
+		}
+	}
+	return f, true
+}
+
+// goWorkspace is the generated Go files and the Go modules in a folder.
+type goWorkspace struct {
+	// modules maps the module paths to their directories.
+	modules map[string]string
+	// generated is the generated files keyed by the directories.
+	generated map[string][]*generatedFile
+}
+
+// parseGoWorkspace parses the generated Go files and the go.mod files in a directory and its subdirectories.
+// Files which can't be parsed are skipped.
+func parseGoWorkspace(ctx context.Context, root string) (*goWorkspace, error) {
+	ws := &goWorkspace{
+		modules:   make(map[string]string),
+		generated: make(map[string][]*generatedFile),
+	}
+	ignores := make(gitignores)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if _, ok := skipDirs[info.Name()]; ok && path != root {
+				return filepath.SkipDir
+			}
+			if path != root && (info.Name() == "vendor" || ignores.ignored(root, path, true)) {
+				return filepath.SkipDir
+			}
+			if g, err := loadGitignore(path); err == nil {
+				ignores[path] = g
+			}
+			return nil
+		}
+		if info.Name() == "go.mod" {
+			if mod, ok := modulePath(path); ok {
+				ws.modules[mod] = filepath.Dir(path)
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".pb.go") || ignores.ignored(root, path, false) {
+			return nil
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil
+		}
+		if f, ok := newGeneratedFile(fset, file); ok {
+			dir := filepath.Dir(path)
+			ws.generated[dir] = append(ws.generated[dir], f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// modulePath returns the module path declared in a go.mod file.
+func modulePath(filename string) (string, bool) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if path, err := strconv.Unquote(fields[1]); err == nil {
+			return path, true
+		}
+		return fields[1], true
+	}
+	return "", false
+}
+
+// dir returns the directory of a Go package with a given import path in the module which contains it.
+// The innermost module takes precedence if modules are nested.
+func (ws *goWorkspace) dir(importPath string) (string, bool) {
+	dir, found := "", ""
+	for mod, d := range ws.modules {
+		if len(mod) <= len(found) {
+			continue
+		}
+		switch {
+		case importPath == mod:
+			dir, found = d, mod
+		case strings.HasPrefix(importPath, mod+"/"):
+			dir, found = filepath.Join(d, filepath.FromSlash(importPath[len(mod)+1:])), mod
+		}
+	}
+	return dir, found != ""
+}
+
+// files returns the files generated from the proto file with a given import path into the Go package
+// with a given import path and a name. The package is ignored if the import path is empty,
+// and only its directory is searched if it is in a module of the workspace.
+func (ws *goWorkspace) files(source, importPath, pkg string) []*generatedFile {
+	var files []*generatedFile
+	match := func(f *generatedFile) {
+		if f.source == source && (pkg == "" || f.pkg == pkg) {
+			files = append(files, f)
+		}
+	}
+	if dir, ok := ws.dir(importPath); ok && importPath != "" {
+		for _, f := range ws.generated[dir] {
+			match(f)
+		}
+		return files
+	}
+	for _, fs := range ws.generated {
+		for _, f := range fs {
+			match(f)
+		}
+	}
+	return files
+}
+
+// goTarget is a declaration in a generated file.
+type goTarget struct {
+	file *generatedFile
+	name string
+}
+
+// targets returns the declarations in the generated files which the identifier at the end of a path
+// in a Go file in a given directory may refer to.
+func (ws *goWorkspace) targets(file *ast.File, dir string, path []ast.Node) []goTarget {
+	// imports maps the names of the imported packages containing generated files to their directories.
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		d, ok := ws.dir(p)
+		if !ok || len(ws.generated[d]) == 0 {
+			continue
+		}
+		name := ws.generated[d][0].pkg
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = d
+	}
+
+	lookup := func(dir, name string) []goTarget {
+		var targets []goTarget
+		for _, f := range ws.generated[dir] {
+			if _, ok := f.decls[name]; ok {
+				targets = append(targets, goTarget{file: f, name: name})
+			}
+		}
+		return targets
+	}
+	// typeDir returns the directory and the name of a named type expression.
+	typeDir := func(expr ast.Expr) (string, string) {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return dir, e.Name
+		case *ast.SelectorExpr:
+			if x, ok := e.X.(*ast.Ident); ok {
+				return imports[x.Name], e.Sel.Name
+			}
+		}
+		return "", ""
+	}
+
+	ident := path[len(path)-1].(*ast.Ident)
+	if len(path) < 2 {
+		return lookup(dir, ident.Name)
+	}
+	switch parent := path[len(path)-2].(type) {
+	case *ast.SelectorExpr:
+		if parent.Sel != ident {
+			break
+		}
+		if x, ok := parent.X.(*ast.Ident); ok {
+			if d, ok := imports[x.Name]; ok {
+				return lookup(d, ident.Name)
+			}
+		}
+		// The type of the operand is unknown, so the members of all types are candidates.
+		var targets []goTarget
+		dirs := []string{dir}
+		for _, d := range imports {
+			dirs = append(dirs, d)
+		}
+		for _, d := range dirs {
+			for _, f := range ws.generated[d] {
+				for name := range f.decls {
+					if strings.HasSuffix(name, "."+ident.Name) {
+						targets = append(targets, goTarget{file: f, name: name})
+					}
+				}
+			}
+		}
+		return targets
+	case *ast.KeyValueExpr:
+		if parent.Key != ident || len(path) < 3 {
+			break
+		}
+		if lit, ok := path[len(path)-3].(*ast.CompositeLit); ok {
+			if d, typ := typeDir(lit.Type); d != "" {
+				return lookup(d, typ+"."+ident.Name)
+			}
+		}
+		return nil
+	}
+	return lookup(dir, ident.Name)
+}
+
+// goPathAt returns the nodes enclosing the identifier at a given position in a Go file from the root,
+// or nil if there is no identifier at the position.
+func goPathAt(fset *token.FileSet, file *ast.File, pos protocol.Position) []ast.Node {
+	tf := fset.File(file.Pos())
+	line := int(pos.Line) + 1
+	if tf == nil || line < 1 || line > tf.LineCount() {
+		return nil
+	}
+	offset := tf.LineStart(line) + token.Pos(pos.Character)
+
+	var path, found []ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			path = path[:len(path)-1]
+			return false
+		}
+		if found != nil || offset < n.Pos() || offset > n.End() {
+			return false
+		}
+		path = append(path, n)
+		if _, ok := n.(*ast.Ident); ok {
+			found = append([]ast.Node(nil), path...)
+		}
+		return true
+	})
+	return found
+}
+
+// goLocation returns the location of an identifier in a Go file.
+func goLocation(fset *token.FileSet, ident *ast.Ident) protocol.Location {
+	pos := fset.Position(ident.Pos())
+	return protocol.Location{
+		URI: uri.File(pos.Filename),
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(pos.Line - 1), Character: float64(pos.Column - 1)},
+			End:   protocol.Position{Line: float64(pos.Line - 1), Character: float64(pos.Column - 1 + len(ident.Name))},
+		},
+	}
+}
+
+func sortLocations(locations []protocol.Location) {
+	sort.Slice(locations, func(i, j int) bool {
+		a, b := locations[i], locations[j]
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Range.Start.Character < b.Range.Start.Character
+	})
+}
@@ -0,0 +1,318 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+const goNavigationProto = `syntax = "proto3";
+package foo.v1;
+option go_package = "example.com/gen/foo/v1;foov1";
+message Foo {
+  string name = 1;
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+  }
+  Kind kind = 2;
+  oneof value {
+    int64 number = 3;
+  }
+}
+service FooService {
+  rpc GetFoo(Foo) returns (Foo);
+}
+`
+
+// writeGoNavigationWorkspace writes a Go module with the code generated for goNavigationProto
+// and returns the directory.
+func writeGoNavigationWorkspace(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"go.mod":           "module example.com\n\ngo 1.13\n",
+		"foo/v1/foo.proto": goNavigationProto,
+		"gen/foo/v1/foo.pb.go": `// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: foo/v1/foo.proto
+
+package foov1
+
+type Foo_Kind int32
+
+const (
+	Foo_KIND_UNSPECIFIED Foo_Kind = 0
+)
+
+type Foo struct {
+	Name  string
+	Kind  Foo_Kind
+	Value isFoo_Value
+}
+
+func (x *Foo) GetName() string { return x.Name }
+
+type isFoo_Value interface{ isFoo_Value() }
+
+type Foo_Number struct {
+	Number int64
+}
+`,
+		"gen/foo/v1/foo_grpc.pb.go": `// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// source: foo/v1/foo.proto
+
+package foov1
+
+type FooServiceClient interface {
+	GetFoo()
+}
+
+type FooServiceServer interface {
+	GetFoo()
+}
+`,
+		"other/foo.pb.go": `// source: foo/v1/foo.proto
+
+package other
+
+type Foo struct{}
+`,
+		"server/server.go": `package server
+
+import foov1 "example.com/gen/foo/v1"
+
+func f(x *foov1.Foo) string {
+	_ = foov1.Foo{Name: "foo"}
+	_ = foov1.Foo_KIND_UNSPECIFIED
+	return x.GetName()
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGoDefinitions(t *testing.T) {
+	dir := writeGoNavigationWorkspace(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File(dir))
+	fileURI := uri.File(filepath.Join(dir, "foo/v1/foo.proto"))
+	view.DidOpen(fileURI, []byte(goNavigationProto))
+
+	location := func(name string, line, start, end int) protocol.Location {
+		return protocol.Location{
+			URI: uri.File(filepath.Join(dir, name)),
+			Range: protocol.Range{
+				Start: protocol.Position{Line: float64(line), Character: float64(start)},
+				End:   protocol.Position{Line: float64(line), Character: float64(end)},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want []protocol.Location
+	}{
+		{
+			name: "message",
+			pos:  protocol.Position{Line: 3, Character: 9},
+			want: []protocol.Location{
+				location("gen/foo/v1/foo.pb.go", 11, 5, 8),
+			},
+		},
+		{
+			name: "field",
+			pos:  protocol.Position{Line: 4, Character: 10},
+			want: []protocol.Location{
+				location("gen/foo/v1/foo.pb.go", 12, 1, 5),
+				location("gen/foo/v1/foo.pb.go", 17, 14, 21),
+			},
+		},
+		{
+			name: "nested enum value",
+			pos:  protocol.Position{Line: 6, Character: 5},
+			want: []protocol.Location{
+				location("gen/foo/v1/foo.pb.go", 8, 1, 21),
+			},
+		},
+		{
+			name: "oneof field",
+			pos:  protocol.Position{Line: 10, Character: 11},
+			want: []protocol.Location{
+				location("gen/foo/v1/foo.pb.go", 21, 5, 15),
+				location("gen/foo/v1/foo.pb.go", 22, 1, 7),
+			},
+		},
+		{
+			name: "rpc",
+			pos:  protocol.Position{Line: 14, Character: 7},
+			want: []protocol.Location{
+				location("gen/foo/v1/foo_grpc.pb.go", 6, 1, 7),
+				location("gen/foo/v1/foo_grpc.pb.go", 10, 1, 7),
+			},
+		},
+		{
+			name: "no declaration",
+			pos:  protocol.Position{Line: 0, Character: 2},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GoDefinitions(ctx, view, fileURI, tt.pos)
+			if err != nil {
+				t.Fatalf("GoDefinitions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GoDefinitions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtoDeclarations(t *testing.T) {
+	dir := writeGoNavigationWorkspace(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File(dir))
+	protoURI := uri.File(filepath.Join(dir, "foo/v1/foo.proto"))
+
+	location := func(line, start, end int) []protocol.Location {
+		return []protocol.Location{
+			{
+				URI: protoURI,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: float64(line), Character: float64(start)},
+					End:   protocol.Position{Line: float64(line), Character: float64(end)},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		file string
+		pos  protocol.Position
+		want []protocol.Location
+	}{
+		{
+			name: "qualified type",
+			file: "server/server.go",
+			pos:  protocol.Position{Line: 4, Character: 17},
+			want: location(3, 8, 11),
+		},
+		{
+			name: "composite literal key",
+			file: "server/server.go",
+			pos:  protocol.Position{Line: 5, Character: 16},
+			want: location(4, 9, 13),
+		},
+		{
+			name: "qualified enum value",
+			file: "server/server.go",
+			pos:  protocol.Position{Line: 6, Character: 12},
+			want: location(6, 4, 20),
+		},
+		{
+			name: "method selector",
+			file: "server/server.go",
+			pos:  protocol.Position{Line: 7, Character: 11},
+			want: location(4, 9, 13),
+		},
+		{
+			name: "package name",
+			file: "server/server.go",
+			pos:  protocol.Position{Line: 4, Character: 12},
+		},
+		{
+			name: "generated declaration",
+			file: "gen/foo/v1/foo_grpc.pb.go",
+			pos:  protocol.Position{Line: 10, Character: 2},
+			want: location(14, 6, 12),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProtoDeclarations(ctx, view, uri.File(filepath.Join(dir, tt.file)), tt.pos)
+			if err != nil {
+				t.Fatalf("ProtoDeclarations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProtoDeclarations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoPackageOption(t *testing.T) {
+	tests := []struct {
+		name           string
+		option         string
+		wantImportPath string
+		wantName       string
+	}{
+		{
+			name:           "with name",
+			option:         `option go_package = "example.com/foo/v1;foov1";`,
+			wantImportPath: "example.com/foo/v1",
+			wantName:       "foov1",
+		},
+		{
+			name:           "without name",
+			option:         `option go_package = "example.com/foo-bar";`,
+			wantImportPath: "example.com/foo-bar",
+			wantName:       "foo_bar",
+		},
+		{
+			name: "none",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			proto, err := protobuf.NewParser(strings.NewReader(`syntax = "proto3";` + "\n" + tt.option)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			importPath, name := goPackageOption(proto)
+			if importPath != tt.wantImportPath || name != tt.wantName {
+				t.Errorf("goPackageOption() = %q, %q, want %q, %q", importPath, name, tt.wantImportPath, tt.wantName)
+			}
+		})
+	}
+}
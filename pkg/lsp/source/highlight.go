@@ -0,0 +1,255 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// occurrence is a declaration of or a reference to a symbol in a file.
+type occurrence struct {
+	// name is the fully-qualified name of the symbol.
+	name string
+	rng  protocol.Range
+	kind protocol.DocumentHighlightKind
+}
+
+// DocumentHighlights returns the occurrences in a file of the symbol at a given position.
+// Declarations are highlighted as Write and references as Read.
+// References are the types of fields including map values, the request and response types of RPCs,
+// the targets of extensions and the enum values used as option values.
+func DocumentHighlights(ctx context.Context, view View, uri uri.URI, pos protocol.Position) ([]protocol.DocumentHighlight, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proto := pf.Proto().Protobuf()
+
+	occurrences := fileOccurrences(view, uri, proto, strings.Split(string(data), "\n"))
+	target := ""
+	for _, o := range occurrences {
+		if contains(o.rng, pos) {
+			target = o.name
+			break
+		}
+	}
+	if target == "" {
+		return nil, nil
+	}
+
+	var highlights []protocol.DocumentHighlight
+	for _, o := range occurrences {
+		if o.name == target {
+			highlights = append(highlights, protocol.DocumentHighlight{Range: o.rng, Kind: o.kind})
+		}
+	}
+	sort.SliceStable(highlights, func(i, j int) bool {
+		a, b := highlights[i].Range.Start, highlights[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return highlights, nil
+}
+
+// fileOccurrences returns the occurrences of the symbols in a proto file with given content lines.
+func fileOccurrences(view View, uri uri.URI, proto *protobuf.Proto, lines []string) []occurrence {
+	var occurrences []occurrence
+	add := func(name string, line, column int, word string, kind protocol.DocumentHighlightKind) (int, int) {
+		l, c, ok := findWord(lines, line, column, word)
+		if !ok {
+			return line, column
+		}
+		occurrences = append(occurrences, occurrence{
+			name: name,
+			rng: protocol.Range{
+				Start: protocol.Position{Line: float64(l - 1), Character: float64(c - 1)},
+				End:   protocol.Position{Line: float64(l - 1), Character: float64(c - 1 + len(word))},
+			},
+			kind: kind,
+		})
+		return l, c + len(word)
+	}
+
+	// Enum values are declared in the scope enclosing their enum.
+	values := make(map[string]string)
+	walkValues(packageName(proto), proto.Elements, func(scope string, v *protobuf.EnumField) {
+		if _, ok := values[v.Name]; !ok {
+			values[v.Name] = qualify(scope, v.Name)
+		}
+	})
+
+	var literal func(l *protobuf.Literal)
+	literal = func(l *protobuf.Literal) {
+		if l == nil {
+			return
+		}
+		if !l.IsString && isIdentifier(l.Source) {
+			name := l.Source
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			if full, ok := values[name]; ok {
+				add(full, l.Position.Line, l.Position.Column, l.Source, protocol.Read)
+			}
+		}
+		for _, e := range l.Array {
+			literal(e)
+		}
+		for _, e := range l.OrderedMap {
+			literal(e.Literal)
+		}
+	}
+	options := func(options []*protobuf.Option) {
+		for _, o := range options {
+			literal(&o.Constant)
+		}
+	}
+	field := func(scope string, f *protobuf.Field) {
+		line, column := f.Position.Line, f.Position.Column
+		if l, c, ok := findWord(lines, line, column, f.Type); ok {
+			line, column = l, c+len(f.Type)
+		}
+		add(qualify(scope, f.Name), line, column, f.Name, protocol.Write)
+		options(f.Options)
+	}
+
+	var walk func(scope string, elements []protobuf.Visitee)
+	walk = func(scope string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Option:
+				literal(&v.Constant)
+			case *protobuf.Message:
+				if v.IsExtend {
+					walk(scope, v.Elements)
+					continue
+				}
+				name := qualify(scope, v.Name)
+				add(name, v.Position.Line, v.Position.Column, v.Name, protocol.Write)
+				walk(name, v.Elements)
+			case *protobuf.Enum:
+				add(qualify(scope, v.Name), v.Position.Line, v.Position.Column, v.Name, protocol.Write)
+				for _, el := range v.Elements {
+					switch e := el.(type) {
+					case *protobuf.Option:
+						literal(&e.Constant)
+					case *protobuf.EnumField:
+						add(qualify(scope, e.Name), e.Position.Line, e.Position.Column, e.Name, protocol.Write)
+						walk(scope, e.Elements)
+					}
+				}
+			case *protobuf.Oneof:
+				add(qualify(scope, v.Name), v.Position.Line, v.Position.Column, v.Name, protocol.Write)
+				walk(scope, v.Elements)
+			case *protobuf.NormalField:
+				field(scope, v.Field)
+			case *protobuf.MapField:
+				field(scope, v.Field)
+			case *protobuf.OneOfField:
+				field(scope, v.Field)
+			case *protobuf.Service:
+				name := qualify(scope, v.Name)
+				add(name, v.Position.Line, v.Position.Column, v.Name, protocol.Write)
+				walk(name, v.Elements)
+			case *protobuf.RPC:
+				add(qualify(scope, v.Name), v.Position.Line, v.Position.Column, v.Name, protocol.Write)
+				walk(scope, v.Elements)
+			}
+		}
+	}
+	walk(packageName(proto), proto.Elements)
+
+	for _, ref := range typeRefs(proto, lines) {
+		if !ref.found {
+			continue
+		}
+		sym, ok := ResolveType(view, uri, ref.scope, ref.typ)
+		if !ok {
+			continue
+		}
+		// Each component of a reference to a nested type such as "Foo.Bar" refers to its own symbol.
+		for name := sym.Name; name != ""; name = parentName(name) {
+			if !isType(view, name) {
+				break
+			}
+			rng, ok := componentRange(ref, name, sym.Name)
+			if !ok {
+				break
+			}
+			occurrences = append(occurrences, occurrence{name: name, rng: rng, kind: protocol.Read})
+		}
+	}
+	return occurrences
+}
+
+// walkValues calls a function with the enum values declared in elements including the nested ones
+// and the fully-qualified names of the scopes they are declared in.
+func walkValues(scope string, elements []protobuf.Visitee, fn func(scope string, v *protobuf.EnumField)) {
+	for _, el := range elements {
+		switch v := el.(type) {
+		case *protobuf.Enum:
+			for _, value := range enumValues(v) {
+				fn(scope, value)
+			}
+		case *protobuf.Message:
+			if !v.IsExtend {
+				walkValues(qualify(scope, v.Name), v.Elements, fn)
+			}
+		}
+	}
+}
+
+// isType returns true if a fully-qualified name is a message or an enum known to a view.
+func isType(view View, name string) bool {
+	for _, s := range view.LookupSymbol(name) {
+		if s.Kind == SymbolKindMessage || s.Kind == SymbolKindEnum {
+			return true
+		}
+	}
+	return false
+}
+
+func parentName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// isIdentifier returns true if the source of a literal is an identifier, which is not a keyword.
+func isIdentifier(s string) bool {
+	switch s {
+	case "", "true", "false", "inf", "nan":
+		return false
+	}
+	c := s[0]
+	return c == '_' || c == '.' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
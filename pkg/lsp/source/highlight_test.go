@@ -0,0 +1,112 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestDocumentHighlights(t *testing.T) {
+	const text = `syntax = "proto3";
+package foo.v1;
+message Foo {
+  message Bar {}
+  Bar bar = 1;
+  map<string, Foo.Bar> bars = 2;
+  Kind kind = 3 [(kind_option) = KIND_A];
+}
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_A = 1;
+}
+extend Foo {
+  Kind kind_option = 100;
+}
+service FooService {
+  rpc GetFoo(Foo) returns (Foo.Bar);
+}
+`
+	highlight := func(line, start, end int, kind protocol.DocumentHighlightKind) protocol.DocumentHighlight {
+		return protocol.DocumentHighlight{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: float64(line), Character: float64(start)},
+				End:   protocol.Position{Line: float64(line), Character: float64(end)},
+			},
+			Kind: kind,
+		}
+	}
+
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want []protocol.DocumentHighlight
+	}{
+		{
+			name: "message declaration",
+			pos:  protocol.Position{Line: 2, Character: 9},
+			want: []protocol.DocumentHighlight{
+				highlight(2, 8, 11, protocol.Write),
+				highlight(5, 14, 17, protocol.Read),
+				highlight(12, 7, 10, protocol.Read),
+				highlight(16, 13, 16, protocol.Read),
+				highlight(16, 27, 30, protocol.Read),
+			},
+		},
+		{
+			name: "nested message reference",
+			pos:  protocol.Position{Line: 16, Character: 32},
+			want: []protocol.DocumentHighlight{
+				highlight(3, 10, 13, protocol.Write),
+				highlight(4, 2, 5, protocol.Read),
+				highlight(5, 18, 21, protocol.Read),
+				highlight(16, 31, 34, protocol.Read),
+			},
+		},
+		{
+			name: "enum value in option",
+			pos:  protocol.Position{Line: 6, Character: 34},
+			want: []protocol.DocumentHighlight{
+				highlight(6, 33, 39, protocol.Read),
+				highlight(10, 2, 8, protocol.Write),
+			},
+		},
+		{
+			name: "nothing",
+			pos:  protocol.Position{Line: 0, Character: 0},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+			fileURI := uri.File("/workspace/foo.proto")
+			view.DidOpen(fileURI, []byte(text))
+
+			got, err := DocumentHighlights(ctx, view, fileURI, tt.pos)
+			if err != nil {
+				t.Fatalf("DocumentHighlights() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DocumentHighlights() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// Implementations returns the locations of the Go types in the folder of a view which implement
+// the server interface generated for the service at a given position, or the methods of them
+// for the RPC at the position. A type implements the interface if it declares the methods of all RPCs
+// with the signatures of the server interface, or embeds the generated Unimplemented type.
+// Generated files are not scanned, and the Go files parsed are cached in the view.
+func Implementations(ctx context.Context, view View, uri uri.URI, pos protocol.Position) ([]protocol.Location, error) {
+	proto, lines, err := readProto(ctx, view, uri)
+	if err != nil || proto == nil {
+		return nil, err
+	}
+
+	var service, rpc string
+	for _, s := range fileSymbols(uri, proto) {
+		if s.Kind != SymbolKindService && s.Kind != SymbolKindRPC {
+			continue
+		}
+		name := s.Name[strings.LastIndex(s.Name, ".")+1:]
+		if !contains(nameRange(lines, s.Line, s.Column, name), pos) {
+			continue
+		}
+		if s.Kind == SymbolKindService {
+			service = s.Name
+		} else {
+			service, rpc = parentName(s.Name), name
+		}
+		break
+	}
+	if service == "" {
+		return nil, nil
+	}
+
+	pkg := packageName(proto)
+	var rpcs []goRPC
+	for _, el := range proto.Elements {
+		if sv, ok := el.(*protobuf.Service); ok && qualify(pkg, sv.Name) == service {
+			for _, e := range sv.Elements {
+				if r, ok := e.(*protobuf.RPC); ok {
+					rpcs = append(rpcs, newGoRPC(pkg, sv.Name, r))
+				}
+			}
+		}
+	}
+	iface := GoCamelCase(service[strings.LastIndex(service, ".")+1:]) + "Server"
+
+	pkgs, err := cachedGoPackages(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	var locations []protocol.Location
+	for _, p := range pkgs {
+		for _, typ := range p.implementations(iface, rpcs) {
+			ident := typ.name
+			if rpc != "" {
+				ident = nil
+				for _, r := range rpcs {
+					if m := typ.methods[r.name]; r.name == GoCamelCase(rpc) && m != nil && m.implements(r) {
+						ident = m.name
+					}
+				}
+				if ident == nil {
+					continue
+				}
+			}
+			locations = append(locations, p.location(ident))
+		}
+	}
+	sortLocations(locations)
+	return locations, nil
+}
+
+// goRPC is the signature of the method of a generated server interface for an RPC.
+type goRPC struct {
+	name string
+	// request is the name of the Go type of the request message.
+	request string
+	// stream is the name of the stream type generated for a streaming RPC, such as "FooService_ListFoosServer".
+	stream         string
+	streamsRequest bool
+	streamsReturns bool
+}
+
+func newGoRPC(pkg, service string, rpc *protobuf.RPC) goRPC {
+	name := GoCamelCase(rpc.Name)
+	return goRPC{
+		name:           name,
+		request:        goMessageName(pkg, rpc.RequestType),
+		stream:         GoCamelCase(service) + "_" + name + "Server",
+		streamsRequest: rpc.StreamsRequest,
+		streamsReturns: rpc.StreamsReturns,
+	}
+}
+
+// goMessageName returns the name of the Go type generated for a message referred to by a type name.
+// The components of the name starting with lowercase letters are regarded as a package.
+func goMessageName(pkg, typ string) string {
+	typ = strings.TrimPrefix(typ, ".")
+	if pkg != "" {
+		typ = strings.TrimPrefix(typ, pkg+".")
+	}
+	for i := strings.Index(typ, "."); i >= 0 && isLower(typ[0]); i = strings.Index(typ, ".") {
+		typ = typ[i+1:]
+	}
+	return GoCamelCase(typ)
+}
+
+// isStream returns true if a type is the stream of an RPC, either the generated one or
+// the generic one of grpc-go such as grpc.ServerStreamingServer[T].
+func (r goRPC) isStream(typ string) bool {
+	switch typ {
+	case r.stream:
+		return true
+	case "ServerStreamingServer":
+		return !r.streamsRequest && r.streamsReturns
+	case "ClientStreamingServer":
+		return r.streamsRequest && !r.streamsReturns
+	case "BidiStreamingServer":
+		return r.streamsRequest && r.streamsReturns
+	}
+	return false
+}
+
+// goPackageKey identifies a Go package. The files of a directory may belong to two packages
+// such as "foo" and "foo_test".
+type goPackageKey struct {
+	dir  string
+	name string
+}
+
+// goPackage is the declarations in the Go files of a package.
+type goPackage struct {
+	fset  *token.FileSet
+	types map[string]*goType
+}
+
+// goType is a named type declared in Go.
+type goType struct {
+	name *ast.Ident
+	// embedded is the names of the embedded fields without the qualifiers if the type is a struct.
+	embedded []string
+	methods  map[string]*goMethod
+}
+
+// goMethod is a method declared in Go.
+type goMethod struct {
+	name *ast.Ident
+	// params and results are the names of the types of the parameters and the results
+	// as embeddedName returns them.
+	params  []string
+	results []string
+}
+
+// implementations returns the types in a package which implement an interface for given RPCs.
+func (p *goPackage) implementations(iface string, rpcs []goRPC) []*goType {
+	unimplemented := "Unimplemented" + iface
+	var types []*goType
+	for name, typ := range p.types {
+		if typ.name == nil || name == iface || name == unimplemented {
+			continue
+		}
+		if containsString(typ.embedded, unimplemented) || typ.implements(rpcs) {
+			types = append(types, typ)
+		}
+	}
+	return types
+}
+
+func (t *goType) implements(rpcs []goRPC) bool {
+	if len(rpcs) == 0 {
+		return false
+	}
+	for _, r := range rpcs {
+		if m, ok := t.methods[r.name]; !ok || !m.implements(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// implements returns true if a method has the signature of the server method for an RPC, which tells
+// a server from a client or a mock of it with the same method names.
+func (m *goMethod) implements(r goRPC) bool {
+	if len(m.results) == 0 || m.results[len(m.results)-1] != "error" {
+		return false
+	}
+	switch {
+	case !r.streamsRequest && !r.streamsReturns:
+		return len(m.params) == 2 && m.params[0] == "Context" && m.params[1] == r.request && len(m.results) == 2
+	case !r.streamsRequest:
+		return len(m.params) == 2 && m.params[0] == r.request && r.isStream(m.params[1]) && len(m.results) == 1
+	default:
+		return len(m.params) == 1 && r.isStream(m.params[0]) && len(m.results) == 1
+	}
+}
+
+func (p *goPackage) location(ident *ast.Ident) protocol.Location {
+	return goLocation(p.fset, ident)
+}
+
+// cachedGoPackages returns the Go packages in the folder of a view.
+// They are parsed once and kept until a Go file is changed on disk.
+func cachedGoPackages(ctx context.Context, v View) (map[goPackageKey]*goPackage, error) {
+	cached, ok := v.(*view)
+	if !ok {
+		return parseGoPackages(ctx, v.Folder().Filename())
+	}
+	cached.goPackageMu.Lock()
+	defer cached.goPackageMu.Unlock()
+
+	if cached.goPackages == nil {
+		pkgs, err := parseGoPackages(ctx, cached.folder.Filename())
+		if err != nil {
+			return nil, err
+		}
+		cached.goPackages = pkgs
+	}
+	return cached.goPackages, nil
+}
+
+// parseGoPackages parses the Go files in a directory and its subdirectories except the generated ones,
+// and returns the declarations keyed by the packages. Files which can't be parsed are skipped.
+func parseGoPackages(ctx context.Context, root string) (map[goPackageKey]*goPackage, error) {
+	ignores := make(gitignores)
+	pkgs := make(map[goPackageKey]*goPackage)
+	fset := token.NewFileSet()
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if _, ok := skipDirs[info.Name()]; ok && path != root {
+				return filepath.SkipDir
+			}
+			if path != root && (info.Name() == "vendor" || ignores.ignored(root, path, true)) {
+				return filepath.SkipDir
+			}
+			if g, err := loadGitignore(path); err == nil {
+				ignores[path] = g
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" || strings.HasSuffix(path, ".pb.go") || ignores.ignored(root, path, false) {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+		key := goPackageKey{dir: filepath.Dir(path), name: file.Name.Name}
+		pkg, ok := pkgs[key]
+		if !ok {
+			pkg = &goPackage{fset: fset, types: make(map[string]*goType)}
+			pkgs[key] = pkg
+		}
+		pkg.add(file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// add adds the type and method declarations in a file.
+func (p *goPackage) add(file *ast.File) {
+	typ := func(name string) *goType {
+		t, ok := p.types[name]
+		if !ok {
+			t = &goType{methods: make(map[string]*goMethod)}
+			p.types[name] = t
+		}
+		return t
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				s, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				t := typ(s.Name.Name)
+				t.name = s.Name
+				if st, ok := s.Type.(*ast.StructType); ok {
+					for _, f := range st.Fields.List {
+						if len(f.Names) == 0 {
+							t.embedded = append(t.embedded, embeddedName(f.Type))
+						}
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				continue
+			}
+			// The bodies aren't needed to be kept in the cache.
+			d.Body = nil
+			if name := embeddedName(d.Recv.List[0].Type); name != "" {
+				typ(name).methods[d.Name.Name] = &goMethod{
+					name:    d.Name,
+					params:  fieldTypes(d.Type.Params),
+					results: fieldTypes(d.Type.Results),
+				}
+			}
+		}
+	}
+}
+
+// fieldTypes returns the names of the types of the fields in a list as embeddedName returns them,
+// repeated for the fields declared together like "a, b int".
+func fieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var types []string
+	for _, f := range fields.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			types = append(types, embeddedName(f.Type))
+		}
+	}
+	return types
+}
+
+// embeddedName returns the name of a type expression without a pointer, a qualifier and type arguments.
+func embeddedName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel.Name
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// GoCamelCase returns the name of a Go identifier generated for a protobuf name by protoc-gen-go.
+func GoCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip over '.' in ".{{lowercase}}".
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			// Convert initial '_' to ensure we start with a capital letter.
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip over '_' in "_{{lowercase}}".
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			// Assume we have a letter now - if not, it's a bogus identifier.
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isLower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestImplementations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"foo/v1/foo.pb.go": `package foov1
+
+import "context"
+
+type FooServiceServer interface {
+	GetFoo(context.Context, *Foo) (*Foo, error)
+	ListFoos(*Foo, FooService_ListFoosServer) error
+}
+
+type UnimplementedFooServiceServer struct{}
+
+func (UnimplementedFooServiceServer) GetFoo(context.Context, *Foo) (*Foo, error) { return nil, nil }
+func (UnimplementedFooServiceServer) ListFoos(*Foo, FooService_ListFoosServer) error { return nil }
+`,
+		"server/server.go": `package server
+
+import foov1 "example.com/foo/v1"
+
+type server struct {
+	foov1.UnimplementedFooServiceServer
+}
+
+func (s *server) GetFoo(ctx context.Context, req *foov1.Foo) (*foov1.Foo, error) { return nil, nil }
+`,
+		"server/full.go": `package server
+
+type full struct{}
+
+func (full) GetFoo(ctx context.Context, req *foov1.Foo) (*foov1.Foo, error) { return nil, nil }
+func (*full) ListFoos(req *foov1.Foo, stream foov1.FooService_ListFoosServer) error { return nil }
+
+type partial struct{}
+
+func (partial) GetFoo(context.Context, *foov1.Foo) (*foov1.Foo, error) { return nil, nil }
+
+type client struct{}
+
+func (client) GetFoo(ctx context.Context, in *foov1.Foo, opts ...grpc.CallOption) (*foov1.Foo, error) { return nil, nil }
+func (client) ListFoos(ctx context.Context, in *foov1.Foo, opts ...grpc.CallOption) (foov1.FooService_ListFoosClient, error) { return nil, nil }
+`,
+		"server/full_test.go": `package server_test
+
+type full struct{}
+
+func (full) GetFoo(ctx context.Context, req *foov1.Foo) (*foov1.Foo, error) { return nil, nil }
+func (full) ListFoos(req *foov1.Foo, stream grpc.ServerStreamingServer[foov1.Foo]) error { return nil }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File(dir))
+	fileURI := uri.File(filepath.Join(dir, "foo.proto"))
+	view.DidOpen(fileURI, []byte(`syntax = "proto3";
+package foo.v1;
+service FooService {
+  rpc GetFoo(Foo) returns (Foo);
+  rpc ListFoos(Foo) returns (stream Foo);
+}
+message Foo {}
+`))
+
+	location := func(name string, line, start, end int) protocol.Location {
+		return protocol.Location{
+			URI: uri.File(filepath.Join(dir, name)),
+			Range: protocol.Range{
+				Start: protocol.Position{Line: float64(line), Character: float64(start)},
+				End:   protocol.Position{Line: float64(line), Character: float64(end)},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want []protocol.Location
+	}{
+		{
+			name: "service",
+			pos:  protocol.Position{Line: 2, Character: 10},
+			want: []protocol.Location{
+				location("server/full.go", 2, 5, 9),
+				location("server/full_test.go", 2, 5, 9),
+				location("server/server.go", 4, 5, 11),
+			},
+		},
+		{
+			name: "rpc",
+			pos:  protocol.Position{Line: 3, Character: 8},
+			want: []protocol.Location{
+				location("server/full.go", 4, 12, 18),
+				location("server/full_test.go", 4, 12, 18),
+				location("server/server.go", 8, 17, 23),
+			},
+		},
+		{
+			name: "rpc implemented only by embedding",
+			pos:  protocol.Position{Line: 4, Character: 8},
+			want: []protocol.Location{
+				location("server/full.go", 5, 13, 21),
+				location("server/full_test.go", 5, 12, 20),
+			},
+		},
+		{
+			name: "message",
+			pos:  protocol.Position{Line: 6, Character: 9},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Implementations(ctx, view, fileURI, tt.pos)
+			if err != nil {
+				t.Fatalf("Implementations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Implementations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		path := filepath.Join(dir, "server/other.go")
+		if err := ioutil.WriteFile(path, []byte(`package server
+
+type other struct{}
+
+func (other) GetFoo(context.Context, *foov1.Foo) (*foov1.Foo, error) { return nil, nil }
+func (other) ListFoos(*foov1.Foo, foov1.FooService_ListFoosServer) error { return nil }
+`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		pos := protocol.Position{Line: 4, Character: 8}
+		got, err := Implementations(ctx, view, fileURI, pos)
+		if err != nil {
+			t.Fatalf("Implementations() error = %v", err)
+		}
+		if len(got) != 2 {
+			t.Errorf("Implementations() = %+v, want the cached result", got)
+		}
+
+		view.DidChangeGoFile(uri.File(path))
+		got, err = Implementations(ctx, view, fileURI, pos)
+		if err != nil {
+			t.Fatalf("Implementations() error = %v", err)
+		}
+		if want := location("server/other.go", 5, 13, 21); len(got) != 3 || !reflect.DeepEqual(got[2], want) {
+			t.Errorf("Implementations() = %+v, want %+v included", got, want)
+		}
+	})
+}
+
+func TestGoCamelCase(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "GetFoo", want: "GetFoo"},
+		{in: "get_foo", want: "GetFoo"},
+		{in: "foo_service", want: "FooService"},
+		{in: "_foo", want: "XFoo"},
+		{in: "foo2bar", want: "Foo2Bar"},
+		{in: "Foo.bar_baz", want: "FooBarBaz"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			if got := GoCamelCase(tt.in); got != tt.want {
+				t.Errorf("GoCamelCase() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
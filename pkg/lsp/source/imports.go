@@ -0,0 +1,156 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/uri"
+)
+
+// Imports returns the import paths declared in a given proto file.
+// It returns nil if the file has not been parsed successfully.
+func Imports(pf ProtoFile) []string {
+	proto := pf.Proto()
+	if proto == nil || proto.Protobuf() == nil {
+		return nil
+	}
+
+	var paths []string
+	for _, el := range proto.Protobuf().Elements {
+		if i, ok := el.(*protobuf.Import); ok {
+			paths = append(paths, i.Filename)
+		}
+	}
+	return paths
+}
+
+// ResolveImport returns the URI of a file imported with a given import path from a given file.
+// The import path is resolved against the include paths of the project, the folder of the view
+// and the directory of the importing file in this order.
+func ResolveImport(view View, from uri.URI, path string) (uri.URI, bool) {
+	for _, u := range importCandidates(view, from, path) {
+		if view.IsOpen(u) {
+			return u, true
+		}
+		if info, err := os.Stat(u.Filename()); err == nil && !info.IsDir() {
+			return u, true
+		}
+	}
+	return "", false
+}
+
+// importCandidates returns the URIs which an import path may refer to in the order of precedence.
+func importCandidates(view View, from uri.URI, path string) []uri.URI {
+	dirs := importDirs(view, from)
+	uris := make([]uri.URI, 0, len(dirs))
+	for _, dir := range dirs {
+		uris = append(uris, uri.File(filepath.Join(dir, filepath.FromSlash(path))))
+	}
+	return uris
+}
+
+// importDirs returns the directories which import paths in a given file are resolved against
+// in the order of precedence.
+func importDirs(view View, from uri.URI) []string {
+	dirs := view.Config(from).AbsIncludePaths()
+	return append(dirs, view.Folder().Filename(), filepath.Dir(from.Filename()))
+}
+
+// ImportPath returns the import path with which a given file imports another one.
+// It returns false if the file can't be imported with any path relative to the include paths,
+// the folder of the view or the directory of the importing file.
+// The imported file doesn't need to exist yet.
+func ImportPath(view View, from, target uri.URI) (string, bool) {
+	dirs := importDirs(view, from)
+	for i, dir := range dirs {
+		rel, err := filepath.Rel(dir, target.Filename())
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		path := filepath.ToSlash(rel)
+		if !shadowed(view, dirs[:i], path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// shadowed returns true if an import path refers to an existing file in any of given directories.
+func shadowed(view View, dirs []string, path string) bool {
+	for _, dir := range dirs {
+		u := uri.File(filepath.Join(dir, filepath.FromSlash(path)))
+		if view.IsOpen(u) {
+			return true
+		}
+		if info, err := os.Stat(u.Filename()); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportNode is a file in an import graph with the imports declared in it.
+type ImportNode struct {
+	URI     uri.URI      `json:"uri"`
+	Imports []ImportEdge `json:"imports"`
+}
+
+// ImportEdge is an import declared in a file.
+type ImportEdge struct {
+	Path string `json:"path"`
+	// Kind is "public", "weak" or empty.
+	Kind string `json:"kind,omitempty"`
+	// URI is the URI of the imported file, which is empty if the import can't be resolved.
+	URI uri.URI `json:"uri,omitempty"`
+}
+
+// ImportGraph returns the files which a given file imports directly or indirectly in breadth-first order,
+// starting with the file itself. Each file appears once even if it is imported by multiple files.
+func ImportGraph(view View, from uri.URI) []ImportNode {
+	var nodes []ImportNode
+	visited := map[uri.URI]bool{from: true}
+	queue := []uri.URI{from}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+
+		node := ImportNode{URI: u, Imports: []ImportEdge{}}
+		if f, err := view.GetFile(u); err == nil {
+			if pf, ok := f.(ProtoFile); ok && pf.Proto() != nil {
+				for _, el := range pf.Proto().Protobuf().Elements {
+					i, ok := el.(*protobuf.Import)
+					if !ok {
+						continue
+					}
+					edge := ImportEdge{Path: i.Filename, Kind: i.Kind}
+					if imported, ok := ResolveImport(view, u, i.Filename); ok {
+						edge.URI = imported
+						if !visited[imported] {
+							visited[imported] = true
+							queue = append(queue, imported)
+						}
+					}
+					node.Imports = append(node.Imports, edge)
+				}
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/uri"
+)
+
+func TestImportGraph(t *testing.T) {
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	aURI := uri.File("/workspace/a.proto")
+	bURI := uri.File("/workspace/b.proto")
+	cURI := uri.File("/workspace/c.proto")
+	view.DidOpen(aURI, []byte("syntax = \"proto3\";\nimport \"b.proto\";\nimport public \"c.proto\";\n"))
+	view.DidOpen(bURI, []byte("syntax = \"proto3\";\nimport \"c.proto\";\nimport \"missing.proto\";\n"))
+	view.DidOpen(cURI, []byte("syntax = \"proto3\";\nimport \"a.proto\";\n"))
+
+	got := ImportGraph(view, aURI)
+	want := []ImportNode{
+		{
+			URI: aURI,
+			Imports: []ImportEdge{
+				{Path: "b.proto", URI: bURI},
+				{Path: "c.proto", Kind: "public", URI: cURI},
+			},
+		},
+		{
+			URI: bURI,
+			Imports: []ImportEdge{
+				{Path: "c.proto", URI: cURI},
+				{Path: "missing.proto"},
+			},
+		},
+		{
+			URI: cURI,
+			Imports: []ImportEdge{
+				{Path: "a.proto", URI: aURI},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportGraph() = %+v, want %+v", got, want)
+	}
+}
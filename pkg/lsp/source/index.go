@@ -0,0 +1,184 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+// Index walks the folder of the view and parses all proto files in it in parallel.
+// Files which are open in the editor, ignored by .gitignore, excluded by the configurations
+// or larger than the size limit are skipped.
+// progress is called each time a file is indexed with the number of indexed files and the total.
+func (v *view) Index(ctx context.Context, progress func(indexed, total int)) error {
+	if v.adHoc {
+		return nil
+	}
+
+	uris, err := v.indexTargets(ctx)
+	if err != nil {
+		return err
+	}
+
+	workers := runtime.NumCPU()
+	if workers > len(uris) {
+		workers = len(uris)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		queue   = make(chan uri.URI)
+		indexed = make(chan struct{})
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uri := range queue {
+				if !v.IsOpen(uri) {
+					// Files which fail to be read are just skipped; they are reported on open.
+					if err := v.loadFile(uri); err != nil {
+						v.forgetFile(uri)
+					}
+				}
+				indexed <- struct{}{}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(queue)
+		for _, uri := range uris {
+			select {
+			case queue <- uri:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(indexed)
+	}()
+
+	n := 0
+	for range indexed {
+		n++
+		if progress != nil {
+			progress(n, len(uris))
+		}
+	}
+	return ctx.Err()
+}
+
+// indexTargets returns the URIs of the proto files to index in the folder of the view.
+func (v *view) indexTargets(ctx context.Context) ([]uri.URI, error) {
+	root := v.folder.Filename()
+	options := v.Options().Index
+	folder := config.Project{Dir: root}
+	ignores := make(gitignores)
+
+	var uris []uri.URI
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			// Skip unreadable directories rather than aborting the whole walk.
+			return nil
+		}
+
+		if info.IsDir() {
+			if _, ok := skipDirs[info.Name()]; ok && path != root {
+				return filepath.SkipDir
+			}
+			if path != root && (ignores.ignored(root, path, true) || folder.Match(options.Excludes, path)) {
+				return filepath.SkipDir
+			}
+			g, err := loadGitignore(path)
+			if err != nil {
+				return nil
+			}
+			ignores[path] = g
+			return nil
+		}
+
+		if filepath.Ext(path) != ".proto" {
+			return nil
+		}
+		if options.MaxFileSize > 0 && info.Size() > options.MaxFileSize {
+			return nil
+		}
+		if ignores.ignored(root, path, false) || folder.Match(options.Excludes, path) {
+			return nil
+		}
+		uri := uri.File(path)
+		if v.Config(uri).Excluded(path) {
+			return nil
+		}
+		uris = append(uris, uri)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uris, nil
+}
+
+// LookupSymbol returns the symbols declared with a given fully-qualified name
+// in the files known to the view.
+func (v *view) LookupSymbol(name string) []Symbol {
+	v.fileMu.RLock()
+	defer v.fileMu.RUnlock()
+
+	var symbols []Symbol
+	for uri, f := range v.filesByURI {
+		pf, ok := f.(*protoFile)
+		// A file may be mapped to multiple URIs, so only the canonical one is used.
+		if !ok || pf.URI() != uri {
+			continue
+		}
+		for _, s := range pf.symbols {
+			if s.Name == name {
+				symbols = append(symbols, s)
+			}
+		}
+	}
+	return symbols
+}
+
+func (v *view) KnownFiles() []uri.URI {
+	v.fileMu.RLock()
+	defer v.fileMu.RUnlock()
+
+	var uris []uri.URI
+	for uri, f := range v.filesByURI {
+		// A file may be mapped to multiple URIs, so only the canonical one is used.
+		if f.URI() == uri {
+			uris = append(uris, uri)
+		}
+	}
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	return uris
+}
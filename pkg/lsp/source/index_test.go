@@ -0,0 +1,108 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+func TestView_Index(t *testing.T) {
+	root, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"a.proto":                   "syntax = \"proto3\";\npackage foo;\nmessage A { message B {} }\n",
+		"sub/c.proto":               "syntax = \"proto3\";\npackage foo.sub;\nenum C { C_UNSPECIFIED = 0; }\n",
+		"gen/d.proto":               "syntax = \"proto3\";\nmessage D {}\n",
+		"node_modules/e.proto":      "syntax = \"proto3\";\nmessage E {}\n",
+		"vendor/f.proto":            "syntax = \"proto3\";\nmessage F {}\n",
+		"large.proto":               "syntax = \"proto3\";\nmessage Large {}\n" + strings.Repeat("// padding\n", 100),
+		".gitignore":                "gen/\n",
+		config.ProjectFilename:      "excludes: [vendor]",
+		"sub/not_a_proto_file.yaml": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	view := NewView(NewSession(), "root", uri.File(root))
+	if err := view.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	options := config.DefaultLSPConfig
+	options.Index.MaxFileSize = 512
+	view.SetOptions(options)
+
+	var indexed, total int
+	if err := view.Index(ctx, func(i, t int) {
+		indexed, total = i, t
+	}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if indexed != 2 || total != 2 {
+		t.Errorf("Index() progress = %d/%d, want 2/2", indexed, total)
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "foo.A", want: []string{"a.proto"}},
+		{name: "foo.A.B", want: []string{"a.proto"}},
+		{name: "foo.sub.C", want: []string{"sub/c.proto"}},
+		{name: "D"},
+		{name: "E"},
+		{name: "F"},
+		{name: "Large"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range view.LookupSymbol(tt.name) {
+				rel, err := filepath.Rel(root, s.URI.Filename())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupSymbol(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
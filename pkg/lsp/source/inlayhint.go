@@ -0,0 +1,241 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// Kinds of inlay hints.
+const (
+	InlayHintKindType      = 1
+	InlayHintKindParameter = 2
+)
+
+// InlayHint is a label shown inline in a document, which is not a part of the content.
+type InlayHint struct {
+	Position    protocol.Position `json:"position"`
+	Label       string            `json:"label"`
+	Kind        int               `json:"kind,omitempty"`
+	PaddingLeft bool              `json:"paddingLeft,omitempty"`
+}
+
+// wireTypes maps scalar types to their wire types.
+var wireTypes = map[string]string{
+	"double":   "fixed64",
+	"float":    "fixed32",
+	"int32":    "varint",
+	"int64":    "varint",
+	"uint32":   "varint",
+	"uint64":   "varint",
+	"sint32":   "varint",
+	"sint64":   "varint",
+	"fixed32":  "fixed32",
+	"fixed64":  "fixed64",
+	"sfixed32": "fixed32",
+	"sfixed64": "fixed64",
+	"bool":     "varint",
+	"string":   "length-delimited",
+	"bytes":    "length-delimited",
+}
+
+// InlayHints returns the inlay hints in a given range of a file, which are enabled in the options of a view.
+func InlayHints(ctx context.Context, view View, uri uri.URI, rng protocol.Range) ([]InlayHint, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proto := pf.Proto().Protobuf()
+	lines := strings.Split(string(data), "\n")
+	options := view.Options().InlayHints
+
+	var hints []InlayHint
+	add := func(line, character int, label string, kind int) {
+		pos := protocol.Position{Line: float64(line), Character: float64(character)}
+		if !contains(rng, pos) {
+			return
+		}
+		hints = append(hints, InlayHint{Position: pos, Label: label, Kind: kind, PaddingLeft: true})
+	}
+
+	if options.ResolvedTypes {
+		for _, ref := range typeRefs(proto, lines) {
+			if !ref.found {
+				continue
+			}
+			sym, ok := ResolveType(view, uri, ref.scope, ref.typ)
+			if !ok || sym.Name == strings.TrimPrefix(ref.typ, ".") {
+				continue
+			}
+			add(int(ref.rng.End.Line), int(ref.rng.End.Character), "("+sym.Name+")", InlayHintKindType)
+		}
+	}
+
+	if options.WireTypes || options.DefaultValues {
+		proto2 := syntax(proto) != "proto3"
+		walkMessages(packageName(proto), proto.Elements, func(scope string, m *protobuf.Message) {
+			for _, el := range m.Elements {
+				v, ok := el.(*protobuf.NormalField)
+				if !ok {
+					continue
+				}
+				line, character, ok := numberEnd(lines, v.Position.Line, v.Position.Column, v.Name, v.Sequence)
+				if !ok {
+					continue
+				}
+				if wire, ok := wireTypes[v.Type]; ok && options.WireTypes {
+					add(line, character, wire, InlayHintKindType)
+				}
+				if v.Repeated || !options.DefaultValues || (proto2 && hasOption(v.Options, "default")) {
+					continue
+				}
+				if value, ok := defaultValue(view, uri, scope, v.Type); ok {
+					add(line, character, "default: "+value, InlayHintKindParameter)
+				}
+			}
+		})
+	}
+
+	if options.EnumAliases {
+		walkEnums(proto.Elements, func(e *protobuf.Enum) {
+			canonical := make(map[int]string)
+			for _, v := range enumValues(e) {
+				name, ok := canonical[v.Integer]
+				if !ok {
+					canonical[v.Integer] = v.Name
+					continue
+				}
+				if line, character, ok := numberEnd(lines, v.Position.Line, v.Position.Column, v.Name, v.Integer); ok {
+					add(line, character, "alias of "+name, InlayHintKindParameter)
+				}
+			}
+		})
+	}
+
+	sort.SliceStable(hints, func(i, j int) bool {
+		a, b := hints[i].Position, hints[j].Position
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return hints, nil
+}
+
+// numberEnd returns the 0-based position of the end of the number of a field or an enum value
+// declared at a given 1-based position.
+func numberEnd(lines []string, line, column int, name string, number int) (int, int, bool) {
+	if l, c, ok := findWord(lines, line, column, name); ok {
+		line, column = l, c+len(name)
+	}
+	n := strconv.Itoa(number)
+	l, c, ok := findWord(lines, line, column, n)
+	if !ok {
+		return 0, 0, false
+	}
+	return l - 1, c - 1 + len(n), true
+}
+
+// defaultValue returns the implicit default value of a singular field of a given type.
+// Messages have no default values.
+func defaultValue(view View, from uri.URI, scope, typ string) (string, bool) {
+	switch typ {
+	case "double", "float", "int32", "int64", "uint32", "uint64", "sint32", "sint64",
+		"fixed32", "fixed64", "sfixed32", "sfixed64":
+		return "0", true
+	case "bool":
+		return "false", true
+	case "string", "bytes":
+		return `""`, true
+	}
+
+	sym, ok := ResolveType(view, from, scope, typ)
+	if !ok || sym.Kind != SymbolKindEnum {
+		return "", false
+	}
+	f, err := view.GetFile(sym.URI)
+	if err != nil {
+		return "", false
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return "", false
+	}
+	proto := pf.Proto().Protobuf()
+	e, ok := findEnum(proto, packageName(proto), sym.Name)
+	if !ok {
+		return "", false
+	}
+	values := enumValues(e)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0].Name, true
+}
+
+// walkMessages calls a function with the messages declared in elements including the nested ones
+// and the fully-qualified names of them.
+func walkMessages(scope string, elements []protobuf.Visitee, fn func(scope string, m *protobuf.Message)) {
+	for _, el := range elements {
+		if m, ok := el.(*protobuf.Message); ok && !m.IsExtend {
+			name := qualify(scope, m.Name)
+			fn(name, m)
+			walkMessages(name, m.Elements, fn)
+		}
+	}
+}
+
+// syntax returns the syntax declared in a proto file, which is empty if it's not declared.
+func syntax(proto *protobuf.Proto) string {
+	for _, el := range proto.Elements {
+		if s, ok := el.(*protobuf.Syntax); ok {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+func hasOption(options []*protobuf.Option, name string) bool {
+	for _, o := range options {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether a range contains a position including its end.
+func contains(rng protocol.Range, pos protocol.Position) bool {
+	start, end := rng.Start, rng.End
+	if pos.Line < start.Line || (pos.Line == start.Line && pos.Character < start.Character) {
+		return false
+	}
+	return pos.Line < end.Line || (pos.Line == end.Line && pos.Character <= end.Character)
+}
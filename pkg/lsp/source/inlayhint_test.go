@@ -0,0 +1,147 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+func TestInlayHints(t *testing.T) {
+	const text = `syntax = "proto3";
+package foo.v1;
+message Foo {
+  message Bar {}
+  Bar bar = 1;
+  int64 id = 2;
+  Kind kind = 3;
+  repeated string tags = 4;
+}
+enum Kind {
+  option allow_alias = true;
+  KIND_UNSPECIFIED = 0;
+  KIND_DEFAULT = 0;
+}
+`
+	hint := func(line, character int, label string, kind int) InlayHint {
+		return InlayHint{
+			Position:    protocol.Position{Line: float64(line), Character: float64(character)},
+			Label:       label,
+			Kind:        kind,
+			PaddingLeft: true,
+		}
+	}
+	all := protocol.Range{End: protocol.Position{Line: 20}}
+
+	tests := []struct {
+		name    string
+		options config.LSPInlayHints
+		rng     protocol.Range
+		want    []InlayHint
+	}{
+		{
+			name:    "defaults",
+			options: config.DefaultLSPConfig.InlayHints,
+			rng:     all,
+			want: []InlayHint{
+				hint(4, 5, "(foo.v1.Foo.Bar)", InlayHintKindType),
+				hint(6, 6, "(foo.v1.Kind)", InlayHintKindType),
+				hint(12, 18, "alias of KIND_UNSPECIFIED", InlayHintKindParameter),
+			},
+		},
+		{
+			name: "wire types and default values",
+			options: config.LSPInlayHints{
+				WireTypes:     true,
+				DefaultValues: true,
+			},
+			rng: all,
+			want: []InlayHint{
+				hint(5, 14, "varint", InlayHintKindType),
+				hint(5, 14, "default: 0", InlayHintKindParameter),
+				hint(6, 15, "default: KIND_UNSPECIFIED", InlayHintKindParameter),
+				hint(7, 26, "length-delimited", InlayHintKindType),
+			},
+		},
+		{
+			name:    "range",
+			options: config.DefaultLSPConfig.InlayHints,
+			rng: protocol.Range{
+				Start: protocol.Position{Line: 5},
+				End:   protocol.Position{Line: 10},
+			},
+			want: []InlayHint{
+				hint(6, 6, "(foo.v1.Kind)", InlayHintKindType),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+			options := config.DefaultLSPConfig
+			options.InlayHints = tt.options
+			view.SetOptions(options)
+			fileURI := uri.File("/workspace/foo.proto")
+			view.DidOpen(fileURI, []byte(text))
+
+			got, err := InlayHints(ctx, view, fileURI, tt.rng)
+			if err != nil {
+				t.Fatalf("InlayHints() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InlayHints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlayHints_Proto2Default(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	options := config.DefaultLSPConfig
+	options.InlayHints = config.LSPInlayHints{DefaultValues: true}
+	view.SetOptions(options)
+	fileURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fileURI, []byte(`syntax = "proto2";
+message Foo {
+  optional int32 a = 1 [default = 5];
+  optional bool b = 2;
+}
+`))
+
+	got, err := InlayHints(ctx, view, fileURI, protocol.Range{End: protocol.Position{Line: 10}})
+	if err != nil {
+		t.Fatalf("InlayHints() error = %v", err)
+	}
+	want := []InlayHint{
+		{
+			Position:    protocol.Position{Line: 3, Character: 21},
+			Label:       "default: false",
+			Kind:        InlayHintKindParameter,
+			PaddingLeft: true,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InlayHints() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// TypeDefinition returns the location of the message or the enum which is the type of the field at a given position.
+// The type of a map field is its value type.
+func TypeDefinition(ctx context.Context, view View, uri uri.URI, pos protocol.Position) ([]protocol.Location, error) {
+	proto, lines, err := readProto(ctx, view, uri)
+	if err != nil || proto == nil {
+		return nil, err
+	}
+
+	var (
+		found bool
+		scope string
+		typ   string
+	)
+	field := func(s string, f *protobuf.Field) {
+		line, column := f.Position.Line, f.Position.Column
+		if l, c, ok := findWord(lines, line, column, f.Type); ok {
+			line, column = l, c+len(f.Type)
+		}
+		if !found && contains(nameRange(lines, line, column, f.Name), pos) {
+			found, scope, typ = true, s, f.Type
+		}
+	}
+	var walk func(scope string, elements []protobuf.Visitee)
+	walk = func(scope string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					walk(scope, v.Elements)
+					continue
+				}
+				walk(qualify(scope, v.Name), v.Elements)
+			case *protobuf.Oneof:
+				walk(scope, v.Elements)
+			case *protobuf.NormalField:
+				field(scope, v.Field)
+			case *protobuf.MapField:
+				field(scope, v.Field)
+			case *protobuf.OneOfField:
+				field(scope, v.Field)
+			}
+		}
+	}
+	walk(packageName(proto), proto.Elements)
+	if !found || isScalar(typ) {
+		return nil, nil
+	}
+
+	sym, ok := ResolveType(view, uri, scope, typ)
+	if !ok {
+		return nil, nil
+	}
+	return []protocol.Location{symbolLocation(ctx, view, sym)}, nil
+}
+
+// Declaration returns the location of the declaration which the component of a type reference
+// at a given position refers to. A component of the package such as "foo" in "foo.v1.Bar"
+// refers to the package statement of the file declaring the type,
+// and the one of a nested type such as "Bar" in "Bar.Baz" refers to the declaration of it.
+func Declaration(ctx context.Context, view View, uri uri.URI, pos protocol.Position) ([]protocol.Location, error) {
+	proto, lines, err := readProto(ctx, view, uri)
+	if err != nil || proto == nil {
+		return nil, err
+	}
+
+	for _, ref := range typeRefs(proto, lines) {
+		if !ref.found || !contains(ref.rng, pos) {
+			continue
+		}
+		sym, ok := ResolveType(view, uri, ref.scope, ref.typ)
+		if !ok {
+			return nil, nil
+		}
+		for name := sym.Name; name != ""; name = parentName(name) {
+			rng, ok := componentRange(ref, name, sym.Name)
+			if !ok || !contains(rng, pos) {
+				continue
+			}
+			if !isType(view, name) {
+				return packageLocation(ctx, view, sym.URI)
+			}
+			for _, s := range view.LookupSymbol(name) {
+				if s.URI == sym.URI {
+					return []protocol.Location{symbolLocation(ctx, view, s)}, nil
+				}
+			}
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// packageLocation returns the location of the package statement of a file.
+func packageLocation(ctx context.Context, view View, uri uri.URI) ([]protocol.Location, error) {
+	proto, _, err := readProto(ctx, view, uri)
+	if err != nil || proto == nil {
+		return nil, err
+	}
+	for _, s := range fileSymbols(uri, proto) {
+		if s.Kind == SymbolKindPackage {
+			return []protocol.Location{symbolLocation(ctx, view, s)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// symbolLocation returns the location of the name of a symbol.
+// It is the position of the declaration if the file can't be read.
+func symbolLocation(ctx context.Context, view View, sym Symbol) protocol.Location {
+	name := sym.Name
+	if sym.Kind != SymbolKindPackage {
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	loc := protocol.Location{
+		URI: sym.URI,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(sym.Line - 1), Character: float64(sym.Column - 1)},
+			End:   protocol.Position{Line: float64(sym.Line - 1), Character: float64(sym.Column - 1)},
+		},
+	}
+	_, lines, err := readProto(ctx, view, sym.URI)
+	if err != nil {
+		return loc
+	}
+	if l, c, ok := findWord(lines, sym.Line, sym.Column, name); ok {
+		loc.Range = protocol.Range{
+			Start: protocol.Position{Line: float64(l - 1), Character: float64(c - 1)},
+			End:   protocol.Position{Line: float64(l - 1), Character: float64(c - 1 + len(name))},
+		}
+	}
+	return loc
+}
+
+// readProto returns the parsed proto file and the content lines of a file known to a view.
+// The proto file is nil if the file is not a proto file or can't be parsed.
+func readProto(ctx context.Context, view View, uri uri.URI) (*protobuf.Proto, []string, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return nil, nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pf.Proto().Protobuf(), strings.Split(string(data), "\n"), nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestTypeDefinition(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	barURI := uri.File("/workspace/bar.proto")
+	view.DidOpen(barURI, []byte(`syntax = "proto3";
+package bar.v1;
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+}
+`))
+	fooURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fooURI, []byte(`syntax = "proto3";
+package foo.v1;
+import "bar.proto";
+message Foo {
+  message Baz {}
+  bar.v1.Kind kind = 1;
+  map<string, Baz> bazs = 2;
+  string name = 3;
+}
+`))
+
+	location := func(u uri.URI, line, start, end int) []protocol.Location {
+		return []protocol.Location{
+			{
+				URI: u,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: float64(line), Character: float64(start)},
+					End:   protocol.Position{Line: float64(line), Character: float64(end)},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want []protocol.Location
+	}{
+		{
+			name: "enum in another file",
+			pos:  protocol.Position{Line: 5, Character: 15},
+			want: location(barURI, 2, 5, 9),
+		},
+		{
+			name: "map value",
+			pos:  protocol.Position{Line: 6, Character: 20},
+			want: location(fooURI, 4, 10, 13),
+		},
+		{
+			name: "scalar",
+			pos:  protocol.Position{Line: 7, Character: 10},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TypeDefinition(ctx, view, fooURI, tt.pos)
+			if err != nil {
+				t.Fatalf("TypeDefinition() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TypeDefinition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclaration(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	barURI := uri.File("/workspace/bar.proto")
+	view.DidOpen(barURI, []byte(`syntax = "proto3";
+package bar.v1;
+message Bar {
+  message Baz {}
+}
+`))
+	fooURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fooURI, []byte(`syntax = "proto3";
+package foo.v1;
+import "bar.proto";
+message Foo {
+  bar.v1.Bar.Baz baz = 1;
+}
+`))
+
+	location := func(line, start, end int) []protocol.Location {
+		return []protocol.Location{
+			{
+				URI: barURI,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: float64(line), Character: float64(start)},
+					End:   protocol.Position{Line: float64(line), Character: float64(end)},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want []protocol.Location
+	}{
+		{
+			name: "package",
+			pos:  protocol.Position{Line: 4, Character: 3},
+			want: location(1, 8, 14),
+		},
+		{
+			name: "outer message",
+			pos:  protocol.Position{Line: 4, Character: 10},
+			want: location(2, 8, 11),
+		},
+		{
+			name: "nested message",
+			pos:  protocol.Position{Line: 4, Character: 14},
+			want: location(3, 10, 13),
+		},
+		{
+			name: "field name",
+			pos:  protocol.Position{Line: 4, Character: 18},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Declaration(ctx, view, fooURI, tt.pos)
+			if err != nil {
+				t.Fatalf("Declaration() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Declaration() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
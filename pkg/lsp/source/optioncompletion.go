@@ -0,0 +1,165 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+var (
+	// optionNamePattern matches the name of an option statement which is being written.
+	optionNamePattern = regexp.MustCompile(`\boption\s+(\(?)([\w.]*)$`)
+	// fieldOptionNamePattern matches the name of an option in brackets which is being written.
+	fieldOptionNamePattern = regexp.MustCompile(`[\[,]\s*(\(?)([\w.]*)$`)
+	// optionPathPattern matches the path of the fields of a custom option which is being written, such as "(foo).bar.".
+	optionPathPattern = regexp.MustCompile(`(\([\w.]+\))((?:\.\w+)*)\.(\w*)$`)
+	// blockPattern matches the start of a block before its brace.
+	blockPattern = regexp.MustCompile(`\b(message|enum|service|oneof|extend)\s+[\w.]+\s*$|\brpc\b[^;{}]*$`)
+	// partialWordPattern matches the word being written at the end of a text.
+	partialWordPattern = regexp.MustCompile(`\w*$`)
+)
+
+// OptionCompletions returns the completion items of option names, the fields of custom options
+// and the fields in their aggregate values at a given position.
+// ok is false if the position is not in an option, in which case the other completions apply.
+func OptionCompletions(ctx context.Context, view View, uri uri.URI, pos protocol.Position) (items []protocol.CompletionItem, ok bool) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, false
+	}
+	data, _, err := f.Read(ctx)
+	if err != nil {
+		return nil, false
+	}
+	prefix := stripComments(textBefore(string(data), pos))
+	scope := packageOf(prefix)
+
+	if m := optionPathPattern.FindStringSubmatch(prefix); m != nil {
+		var path []string
+		if m[2] != "" {
+			path = strings.Split(m[2][1:], ".")
+		}
+		t, ok := optionType(view, scope, m[1], path)
+		if !ok || !t.isMessage {
+			return nil, true
+		}
+		return fieldItems(t), true
+	}
+
+	if option, path, content, ok := aggregateAt(prefix); ok {
+		before := strings.TrimSpace(content[:len(content)-len(partialWordPattern.FindString(content))])
+		if strings.HasSuffix(before, ":") {
+			return nil, true
+		}
+		t, ok := optionType(view, scope, option, path)
+		if !ok || !t.isMessage {
+			return nil, true
+		}
+		return fieldItems(t), true
+	}
+
+	target := ""
+	var m []string
+	if m = optionNamePattern.FindStringSubmatch(prefix); m != nil {
+		target = blockTarget(prefix)
+	} else if m = fieldOptionNamePattern.FindStringSubmatch(prefix); m != nil && inBrackets(prefix) {
+		target = fieldOptions
+		if blockTarget(prefix) == enumOptions {
+			target = enumValueOptions
+		}
+	} else {
+		return nil, false
+	}
+
+	paren := m[1] != ""
+	if !paren {
+		for _, name := range builtinOptionNames(target, usesEditions(prefix)) {
+			items = append(items, protocol.CompletionItem{
+				Label:  name,
+				Kind:   float64(protocol.PropertyCompletion),
+				Detail: builtinOptions[target][name].typ,
+			})
+		}
+	}
+	var custom []protocol.CompletionItem
+	for name, ext := range collectExtensions(view) {
+		if ext.extendee != target {
+			continue
+		}
+		item := protocol.CompletionItem{
+			Label:  name,
+			Kind:   float64(protocol.PropertyCompletion),
+			Detail: ext.field.Type,
+		}
+		if !paren {
+			item.Label = "(" + name + ")"
+		}
+		custom = append(custom, item)
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Label < custom[j].Label })
+	return append(items, custom...), true
+}
+
+// fieldItems returns the completion items of the fields of a message type.
+func fieldItems(t valueType) []protocol.CompletionItem {
+	items := make([]protocol.CompletionItem, 0, len(t.fields))
+	for _, f := range t.fields {
+		items = append(items, protocol.CompletionItem{
+			Label:  f.Name,
+			Kind:   float64(protocol.FieldCompletion),
+			Detail: f.Type,
+		})
+	}
+	return items
+}
+
+// blockTarget returns the message which the options of the innermost block not closed at the end of a text extend.
+func blockTarget(text string) string {
+	braces := unclosedBraces(text)
+	if len(braces) == 0 {
+		return fileOptions
+	}
+	m := blockPattern.FindStringSubmatch(text[:braces[len(braces)-1]])
+	if m == nil {
+		return fileOptions
+	}
+	switch m[1] {
+	case "message":
+		return messageOptions
+	case "enum":
+		return enumOptions
+	case "service":
+		return serviceOptions
+	case "oneof":
+		return oneofOptions
+	case "extend":
+		return fieldOptions
+	default:
+		return methodOptions
+	}
+}
+
+// inBrackets returns true if the last bracket in the current statement of a text is not closed.
+func inBrackets(text string) bool {
+	i := strings.LastIndexAny(text, ";{}")
+	statement := text[i+1:]
+	return strings.LastIndex(statement, "[") > strings.LastIndex(statement, "]")
+}
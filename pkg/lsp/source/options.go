@@ -0,0 +1,536 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Names of the messages which options of each kind of declaration extend.
+const (
+	fileOptions      = "google.protobuf.FileOptions"
+	messageOptions   = "google.protobuf.MessageOptions"
+	fieldOptions     = "google.protobuf.FieldOptions"
+	oneofOptions     = "google.protobuf.OneofOptions"
+	enumOptions      = "google.protobuf.EnumOptions"
+	enumValueOptions = "google.protobuf.EnumValueOptions"
+	serviceOptions   = "google.protobuf.ServiceOptions"
+	methodOptions    = "google.protobuf.MethodOptions"
+)
+
+// optionTargets maps the messages which options extend to the declarations they apply to.
+var optionTargets = map[string]string{
+	fileOptions:      "files",
+	messageOptions:   "messages",
+	fieldOptions:     "fields",
+	oneofOptions:     "oneofs",
+	enumOptions:      "enums",
+	enumValueOptions: "enum values",
+	serviceOptions:   "services",
+	methodOptions:    "RPCs",
+}
+
+// builtinOption is an option declared in descriptor.proto, or a pseudo option like json_name.
+type builtinOption struct {
+	// typ is a scalar type or the fully-qualified name of an enum.
+	typ string
+	// values are the values of the enum if typ is an enum.
+	values []string
+}
+
+// builtinOptions maps the messages which options extend to the options built in protocol buffers.
+// They are read from descriptor.proto so that new options are known as soon as the dependency is updated.
+// The fields of an option of a message type are flattened like "features.field_presence".
+// The default option of fields has the type of the field, so it's not included.
+var builtinOptions = func() map[string]map[string]builtinOption {
+	options := make(map[string]map[string]builtinOption)
+	for _, m := range []proto.Message{
+		&descriptorpb.FileOptions{},
+		&descriptorpb.MessageOptions{},
+		&descriptorpb.FieldOptions{},
+		&descriptorpb.OneofOptions{},
+		&descriptorpb.EnumOptions{},
+		&descriptorpb.EnumValueOptions{},
+		&descriptorpb.ServiceOptions{},
+		&descriptorpb.MethodOptions{},
+	} {
+		md := m.ProtoReflect().Descriptor()
+		target := make(map[string]builtinOption)
+		addBuiltinOptions(target, "", md)
+		options[string(md.FullName())] = target
+	}
+	options[fieldOptions]["json_name"] = builtinOption{typ: "string"}
+	return options
+}()
+
+// addBuiltinOptions adds the fields of a message in descriptor.proto to options with a prefix.
+// uninterpreted_option is what protoc parses options into, and repeated messages can't be set
+// by a name, so they are skipped.
+func addBuiltinOptions(options map[string]builtinOption, prefix string, md protoreflect.MessageDescriptor) {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := prefix + string(fd.Name())
+		switch {
+		case fd.Name() == "uninterpreted_option":
+		case fd.Message() != nil:
+			if !fd.IsList() && fd.Message().FullName() != md.FullName() {
+				addBuiltinOptions(options, name+".", fd.Message())
+			}
+		case fd.Enum() != nil:
+			option := builtinOption{typ: string(fd.Enum().FullName())}
+			values := fd.Enum().Values()
+			for j := 0; j < values.Len(); j++ {
+				option.values = append(option.values, string(values.Get(j).Name()))
+			}
+			options[name] = option
+		default:
+			options[name] = builtinOption{typ: fd.Kind().String()}
+		}
+	}
+}
+
+// featureOptionPrefix is the prefix of the built-in options which set the features of editions.
+const featureOptionPrefix = "features."
+
+// editionPattern matches an edition statement, which a file declares instead of a syntax statement to use editions.
+var editionPattern = regexp.MustCompile(`(?m)^\s*edition\s*=`)
+
+// usesEditions returns true if a text of a proto file declares an edition.
+// Features can't be set in the files of proto2 or proto3.
+func usesEditions(text string) bool {
+	return editionPattern.MatchString(stripComments(text))
+}
+
+// extension is a field declared in an extend block.
+type extension struct {
+	uri uri.URI
+	// scope is the fully-qualified name of the scope the field is declared in.
+	scope string
+	// extendee is the fully-qualified name of the extended message.
+	extendee string
+	field    *protobuf.Field
+}
+
+// collectExtensions returns the extension fields declared in the files known to a view
+// keyed by their fully-qualified names.
+func collectExtensions(view View) map[string]extension {
+	extensions := make(map[string]extension)
+	for _, u := range view.KnownFiles() {
+		proto, _, err := readProto(context.Background(), view, u)
+		if err != nil || proto == nil {
+			continue
+		}
+		var walk func(scope string, elements []protobuf.Visitee)
+		walk = func(scope string, elements []protobuf.Visitee) {
+			for _, el := range elements {
+				m, ok := el.(*protobuf.Message)
+				if !ok {
+					continue
+				}
+				if !m.IsExtend {
+					walk(qualify(scope, m.Name), m.Elements)
+					continue
+				}
+				// descriptor.proto is often unknown since protoc bundles it, so the extendee is the name as written then.
+				extendee := strings.TrimPrefix(m.Name, ".")
+				if sym, ok := ResolveType(view, u, scope, m.Name); ok {
+					extendee = sym.Name
+				}
+				for _, f := range messageFields(m) {
+					full := qualify(scope, f.Name)
+					if _, ok := extensions[full]; !ok {
+						extensions[full] = extension{uri: u, scope: scope, extendee: extendee, field: f}
+					}
+				}
+			}
+		}
+		walk(packageName(proto), proto.Elements)
+	}
+	return extensions
+}
+
+// lookupExtension returns the extension field which a name refers to from a given scope.
+func lookupExtension(extensions map[string]extension, scope, name string) (extension, bool) {
+	for _, candidate := range typeCandidates(scope, name) {
+		if ext, ok := extensions[candidate]; ok {
+			return ext, true
+		}
+	}
+	return extension{}, false
+}
+
+// valueType is the type of an option value or a field in it.
+type valueType struct {
+	// name is a scalar type or the fully-qualified name of an enum or a message.
+	name string
+	// values are the values of an enum.
+	values []string
+	// fields are the fields of a message, which is declared in uri.
+	fields    []*protobuf.Field
+	isMessage bool
+	uri       uri.URI
+}
+
+func (t valueType) isEnum() bool {
+	return t.values != nil
+}
+
+func (t valueType) field(name string) (*protobuf.Field, bool) {
+	for _, f := range t.fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// resolveValueType returns the type which a type reference in a given file and scope refers to.
+func resolveValueType(view View, from uri.URI, scope, typ string) (valueType, bool) {
+	if isScalar(typ) {
+		return valueType{name: typ}, true
+	}
+	sym, ok := ResolveType(view, from, scope, typ)
+	if !ok {
+		return valueType{}, false
+	}
+	proto, _, err := readProto(context.Background(), view, sym.URI)
+	if err != nil || proto == nil {
+		return valueType{}, false
+	}
+	if sym.Kind == SymbolKindEnum {
+		e, ok := findEnum(proto, packageName(proto), sym.Name)
+		if !ok {
+			return valueType{}, false
+		}
+		t := valueType{name: sym.Name, values: []string{}}
+		for _, v := range enumValues(e) {
+			t.values = append(t.values, v.Name)
+		}
+		return t, true
+	}
+	m, ok := findMessage(proto, packageName(proto), sym.Name)
+	if !ok {
+		return valueType{}, false
+	}
+	return valueType{name: sym.Name, fields: messageFields(m), isMessage: true, uri: sym.URI}, true
+}
+
+// fieldType returns the type of a field of a message type.
+func (t valueType) fieldType(view View, f *protobuf.Field) (valueType, bool) {
+	return resolveValueType(view, t.uri, t.name, f.Type)
+}
+
+// optionChecker reports the unknown options and the invalid option values in a file.
+type optionChecker struct {
+	view       View
+	from       uri.URI
+	lines      []string
+	extensions map[string]extension
+	// editions is true if the file declares an edition, in which case the feature options are available.
+	editions bool
+	// complete is false if some imports can't be resolved, in which case unknown custom options are not reported.
+	complete bool
+	problems []semanticProblem
+}
+
+// optionProblems returns the problems of the options in a file.
+func optionProblems(view View, from uri.URI, proto *protobuf.Proto, lines []string, complete bool) []semanticProblem {
+	c := &optionChecker{
+		view:       view,
+		from:       from,
+		lines:      lines,
+		extensions: collectExtensions(view),
+		editions:   usesEditions(strings.Join(lines, "\n")),
+		complete:   complete,
+	}
+	c.walk(packageName(proto), fileOptions, proto.Elements)
+	return c.problems
+}
+
+// walk checks the options in elements declared in a given scope.
+// target is the message which the options among the elements extend.
+func (c *optionChecker) walk(scope, target string, elements []protobuf.Visitee) {
+	for _, el := range elements {
+		switch v := el.(type) {
+		case *protobuf.Option:
+			c.check(scope, target, v, nil)
+		case *protobuf.Message:
+			if v.IsExtend {
+				c.walk(scope, fieldOptions, v.Elements)
+				continue
+			}
+			c.walk(qualify(scope, v.Name), messageOptions, v.Elements)
+		case *protobuf.Oneof:
+			c.walk(scope, oneofOptions, v.Elements)
+		case *protobuf.NormalField:
+			c.fieldOptions(scope, v.Field)
+		case *protobuf.MapField:
+			c.fieldOptions(scope, v.Field)
+		case *protobuf.OneOfField:
+			c.fieldOptions(scope, v.Field)
+		case *protobuf.Enum:
+			c.walk(scope, enumOptions, v.Elements)
+		case *protobuf.EnumField:
+			c.walk(scope, enumValueOptions, v.Elements)
+		case *protobuf.Service:
+			c.walk(scope, serviceOptions, v.Elements)
+		case *protobuf.RPC:
+			c.walk(scope, methodOptions, v.Elements)
+		}
+	}
+}
+
+func (c *optionChecker) fieldOptions(scope string, f *protobuf.Field) {
+	for _, o := range f.Options {
+		c.check(scope, fieldOptions, o, f)
+	}
+}
+
+// check checks the name and the value of an option which extends target.
+// field is the field which the option is declared for if any.
+func (c *optionChecker) check(scope, target string, o *protobuf.Option, field *protobuf.Field) {
+	if !strings.HasPrefix(o.Name, "(") {
+		nameRange := c.wordRange(o.Position.Line, o.Position.Column, o.Name)
+		if o.Name == "default" && field != nil {
+			if t, ok := resolveValueType(c.view, c.from, scope, field.Type); ok {
+				c.checkValue(&o.Constant, t, nameRange)
+			}
+			return
+		}
+		option, ok := builtinOptions[target][o.Name]
+		if ok && !c.editions && strings.HasPrefix(o.Name, featureOptionPrefix) {
+			c.report(CodeUnknownOption, nameRange, "Option %q is available only in files using editions.", o.Name)
+			return
+		}
+		if !ok {
+			c.report(CodeUnknownOption, nameRange, "Option %q is not defined for %s.", o.Name, optionTargets[target])
+			return
+		}
+		t := valueType{name: option.typ, values: option.values}
+		c.checkValue(&o.Constant, t, nameRange)
+		return
+	}
+
+	end := strings.Index(o.Name, ")")
+	if end < 0 {
+		return
+	}
+	name := o.Name[1:end]
+	nameRange := c.wordRange(o.Position.Line, o.Position.Column, name)
+	ext, ok := lookupExtension(c.extensions, scope, name)
+	if !ok {
+		if c.complete {
+			c.report(CodeUnknownOption, nameRange, "Option %q is not defined.", name)
+		}
+		return
+	}
+	if ext.extendee != target {
+		c.report(CodeUnknownOption, nameRange, "Option %q extends %s, so it can't be used for %s.", name, ext.extendee, optionTargets[target])
+		return
+	}
+
+	t, ok := resolveValueType(c.view, ext.uri, ext.scope, ext.field.Type)
+	if !ok {
+		return
+	}
+	offset := end + 1
+	for _, component := range strings.Split(o.Name[end+1:], ".")[1:] {
+		offset++
+		f, ok := t.field(component)
+		if !ok {
+			// The components follow the name in parentheses, which is assumed to be written without spaces.
+			rng := nameRange
+			rng.Start.Character += float64(offset - 1)
+			rng.End.Character = rng.Start.Character + float64(len(component))
+			c.report(CodeUnknownOption, rng, "%q has no field %q.", t.name, component)
+			return
+		}
+		offset += len(component)
+		if t, ok = t.fieldType(c.view, f); !ok {
+			return
+		}
+	}
+	c.checkValue(&o.Constant, t, nameRange)
+}
+
+// checkValue checks if a literal is a valid value of a type.
+// rng is the range reported if the literal has no position.
+func (c *optionChecker) checkValue(l *protobuf.Literal, t valueType, rng protocol.Range) {
+	if len(l.Array) > 0 {
+		for _, e := range l.Array {
+			c.checkValue(e, t, rng)
+		}
+		return
+	}
+	if l.Position.Line > 0 {
+		width := len(l.Source)
+		if l.IsString {
+			width += 2
+		}
+		rng = protocol.Range{
+			Start: protocol.Position{Line: float64(l.Position.Line - 1), Character: float64(l.Position.Column - 1)},
+			End:   protocol.Position{Line: float64(l.Position.Line - 1), Character: float64(l.Position.Column - 1 + width)},
+		}
+	}
+
+	aggregate := !l.IsString && l.Source == ""
+	if t.isMessage != aggregate {
+		c.reportValue(l, t, rng)
+		return
+	}
+	if t.isMessage {
+		for _, n := range l.OrderedMap {
+			// Extensions in aggregate values like "[foo.bar]" are not checked.
+			if strings.HasPrefix(n.Name, "[") {
+				continue
+			}
+			f, ok := t.field(n.Name)
+			if !ok {
+				c.report(CodeUnknownOption, c.literalRange(n.Literal, rng, n.Name), "%q has no field %q.", t.name, n.Name)
+				continue
+			}
+			if ft, ok := t.fieldType(c.view, f); ok {
+				c.checkValue(n.Literal, ft, rng)
+			}
+		}
+		return
+	}
+	if !validLiteral(l, t) {
+		c.reportValue(l, t, rng)
+	}
+}
+
+// validLiteral returns true if a literal which is not an aggregate value is a valid value of a type.
+func validLiteral(l *protobuf.Literal, t valueType) bool {
+	if t.isEnum() {
+		if l.IsString {
+			return false
+		}
+		for _, v := range t.values {
+			if l.Source == v {
+				return true
+			}
+		}
+		_, err := strconv.ParseInt(l.Source, 0, 32)
+		return err == nil
+	}
+
+	switch t.name {
+	case "string", "bytes":
+		return l.IsString
+	case "bool":
+		return !l.IsString && (l.Source == "true" || l.Source == "false")
+	}
+	if l.IsString {
+		return false
+	}
+	switch t.name {
+	case "double", "float":
+		switch strings.TrimPrefix(l.Source, "-") {
+		case "inf", "nan":
+			return true
+		}
+		_, err := strconv.ParseFloat(l.Source, 64)
+		return err == nil
+	case "int32", "sint32", "sfixed32":
+		_, err := strconv.ParseInt(l.Source, 0, 32)
+		return err == nil
+	case "int64", "sint64", "sfixed64":
+		_, err := strconv.ParseInt(l.Source, 0, 64)
+		return err == nil
+	case "uint32", "fixed32":
+		_, err := strconv.ParseUint(l.Source, 0, 32)
+		return err == nil
+	case "uint64", "fixed64":
+		_, err := strconv.ParseUint(l.Source, 0, 64)
+		return err == nil
+	}
+	return true
+}
+
+func (c *optionChecker) reportValue(l *protobuf.Literal, t valueType, rng protocol.Range) {
+	value := l.Source
+	switch {
+	case l.IsString:
+		value = strconv.Quote(l.Source)
+	case value == "":
+		value = "A message"
+	}
+	c.report(CodeInvalidOptionValue, rng, "%s is not a valid value of type %s.", value, t.name)
+}
+
+func (c *optionChecker) report(code string, rng protocol.Range, format string, args ...interface{}) {
+	c.problems = append(c.problems, semanticProblem{
+		code:     code,
+		severity: protocol.SeverityError,
+		message:  fmt.Sprintf(format, args...),
+		rng:      rng,
+	})
+}
+
+// wordRange returns the range of a word found at or after a given 1-based position,
+// or the range of the character at the position if it's not found.
+func (c *optionChecker) wordRange(line, column int, word string) protocol.Range {
+	if l, col, ok := findWord(c.lines, line, column, word); ok {
+		return protocol.Range{
+			Start: protocol.Position{Line: float64(l - 1), Character: float64(col - 1)},
+			End:   protocol.Position{Line: float64(l - 1), Character: float64(col - 1 + len(word))},
+		}
+	}
+	return nameRange(c.lines, line, column, "")
+}
+
+// literalRange returns the range of the name of a field in an aggregate value before a literal.
+// The name is searched from the start of the line of the literal since the position of the name is unknown.
+func (c *optionChecker) literalRange(l *protobuf.Literal, fallback protocol.Range, name string) protocol.Range {
+	line := l.Position.Line
+	if line == 0 {
+		line = int(fallback.Start.Line) + 1
+	}
+	if l, col, ok := findWord(c.lines, line, 1, name); ok {
+		return protocol.Range{
+			Start: protocol.Position{Line: float64(l - 1), Character: float64(col - 1)},
+			End:   protocol.Position{Line: float64(l - 1), Character: float64(col - 1 + len(name))},
+		}
+	}
+	return fallback
+}
+
+// builtinOptionNames returns the names of the options built in protocol buffers which extend target.
+// The feature options are included only if editions is true.
+func builtinOptionNames(target string, editions bool) []string {
+	names := make([]string, 0, len(builtinOptions[target]))
+	for name := range builtinOptions[target] {
+		if !editions && strings.HasPrefix(name, featureOptionPrefix) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+const validateProto = `syntax = "proto3";
+package validate;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.FieldOptions {
+  FieldRules rules = 1071;
+}
+extend google.protobuf.MessageOptions {
+  bool disabled = 1071;
+}
+message FieldRules {
+  StringRules string = 14;
+  Level level = 1;
+}
+message StringRules {
+  uint64 min_len = 2;
+  string pattern = 6;
+}
+enum Level {
+  LEVEL_UNSPECIFIED = 0;
+  LEVEL_STRICT = 1;
+}
+`
+
+func TestOptionProblems(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	view.DidOpen(uri.File("/workspace/validate/validate.proto"), []byte(validateProto))
+	mainURI := uri.File("/workspace/main.proto")
+	view.DidOpen(mainURI, []byte(`syntax = "proto3";
+package foo;
+import "validate/validate.proto";
+option go_package = 1;
+option optimize_for = SPEED;
+option unknown = true;
+message Foo {
+  option (validate.disabled) = true;
+  string a = 1 [(validate.rules).string.min_len = 1, json_name = "aa"];
+  string b = 2 [(validate.rules).string.min_len = -1];
+  string c = 3 [(validate.rules).string.max_len = 1];
+  string d = 4 [(validate.rules) = {level: LEVEL_LOOSE, string: {pattern: "x"}}];
+  string e = 5 [(validate.disabled) = true];
+  string f = 6 [(missing) = true, packed = true];
+  string g = 7 [retention = RETENTION_SOURCE, targets = TARGET_TYPE_FIELD, debug_redact = true];
+  string h = 8 [unverified_lazy = false, features.field_presence = EXPLICIT, retention = RETENTION];
+}
+`))
+
+	f, err := view.GetFile(mainURI)
+	if err != nil {
+		t.Fatalf("GetFile() error = %v", err)
+	}
+	problems, err := semanticProblems(ctx, view, f.(ProtoFile))
+	if err != nil {
+		t.Fatalf("semanticProblems() error = %v", err)
+	}
+
+	rng := func(line, start, end float64) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: line, Character: start},
+			End:   protocol.Position{Line: line, Character: end},
+		}
+	}
+	want := []semanticProblem{
+		{code: CodeInvalidOptionValue, severity: protocol.SeverityError, rng: rng(3, 20, 21)},
+		{code: CodeUnknownOption, severity: protocol.SeverityError, rng: rng(5, 7, 14)},
+		{code: CodeInvalidOptionValue, severity: protocol.SeverityError, rng: rng(9, 50, 52)},
+		{code: CodeUnknownOption, severity: protocol.SeverityError, rng: rng(10, 40, 47)},
+		{code: CodeInvalidOptionValue, severity: protocol.SeverityError, rng: rng(11, 43, 54)},
+		{code: CodeUnknownOption, severity: protocol.SeverityError, rng: rng(12, 17, 34)},
+		{code: CodeUnknownOption, severity: protocol.SeverityError, rng: rng(13, 17, 24)},
+		{code: CodeUnknownOption, severity: protocol.SeverityError, rng: rng(15, 41, 64)},
+		{code: CodeInvalidOptionValue, severity: protocol.SeverityError, rng: rng(15, 89, 98)},
+	}
+	for i := range problems {
+		if problems[i].message == "" {
+			t.Errorf("semanticProblems()[%d].message is empty", i)
+		}
+		problems[i].message = ""
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("semanticProblems() = %+v, want %+v", problems, want)
+	}
+}
+
+func TestOptionCompletions(t *testing.T) {
+	labels := func(items []protocol.CompletionItem) []string {
+		var labels []string
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+
+	tests := []struct {
+		name string
+		// text is the content of the file after the header, where the cursor is at "|".
+		text string
+		// edition is declared in the header instead of proto3 if not empty.
+		edition string
+		want    []string
+		wantOK  bool
+	}{
+		{
+			name:   "file option",
+			text:   "option java_|",
+			want:   builtinOptionNames(fileOptions, false),
+			wantOK: true,
+		},
+		{
+			name: "message option",
+			text: "message Foo {\n  option |",
+			want: []string{
+				"deprecated", "deprecated_legacy_json_field_conflicts",
+				"map_entry", "message_set_wire_format", "no_standard_descriptor_accessor", "(validate.disabled)",
+			},
+			wantOK: true,
+		},
+		{
+			name:   "custom field option",
+			text:   "message Foo {\n  string a = 1 [(|",
+			want:   []string{"validate.rules"},
+			wantOK: true,
+		},
+		{
+			name: "enum value option",
+			text: "enum Foo {\n  FOO = 0 [|",
+			want: []string{
+				"debug_redact", "deprecated",
+			},
+			wantOK: true,
+		},
+		{
+			name:    "enum value option with edition",
+			text:    "enum Foo {\n  FOO = 0 [|",
+			edition: "2023",
+			want: []string{
+				"debug_redact", "deprecated",
+				"features.enum_type", "features.field_presence", "features.json_format",
+				"features.message_encoding", "features.repeated_field_encoding", "features.utf8_validation",
+			},
+			wantOK: true,
+		},
+		{
+			name:   "option path",
+			text:   "message Foo {\n  string a = 1 [(validate.rules).string.|",
+			want:   []string{"min_len", "pattern"},
+			wantOK: true,
+		},
+		{
+			name:   "aggregate field",
+			text:   "message Foo {\n  string a = 1 [(validate.rules) = {level: LEVEL_STRICT, |",
+			want:   []string{"string", "level"},
+			wantOK: true,
+		},
+		{
+			name:   "aggregate value",
+			text:   "message Foo {\n  string a = 1 [(validate.rules) = {level: |",
+			wantOK: true,
+		},
+		{
+			name: "field type",
+			text: "message Foo {\n  |",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+			view.DidOpen(uri.File("/workspace/validate/validate.proto"), []byte(validateProto))
+
+			header := "syntax = \"proto3\";\n"
+			if tt.edition != "" {
+				header = "edition = \"" + tt.edition + "\";\n"
+			}
+			text := header + "package foo;\nimport \"validate/validate.proto\";\n" + tt.text
+			i := strings.Index(text, "|")
+			lines := strings.Split(text[:i], "\n")
+			pos := protocol.Position{Line: float64(len(lines) - 1), Character: float64(len(lines[len(lines)-1]))}
+			fileURI := uri.File("/workspace/foo.proto")
+			view.DidOpen(fileURI, []byte(text[:i]+text[i+1:]))
+
+			got, ok := OptionCompletions(ctx, view, fileURI, pos)
+			if ok != tt.wantOK {
+				t.Fatalf("OptionCompletions() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(labels(got), tt.want) {
+				t.Errorf("OptionCompletions() = %v, want %v", labels(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// SourceSortFields is the kind of the source action which sorts fields in messages by number.
+const SourceSortFields protocol.CodeActionKind = "source.sortFields"
+
+// Groups of imports in the order they are placed in a file.
+const (
+	importGroupWellKnown = iota
+	importGroupThirdParty
+	importGroupLocal
+)
+
+// SourceActions returns the source actions for the file for a given URI which change it.
+func SourceActions(ctx context.Context, view View, fileURI uri.URI) ([]CodeAction, error) {
+	actions := []CodeAction{}
+	edits, err := OrganizeImports(ctx, view, fileURI)
+	if err != nil {
+		return nil, err
+	}
+	if len(edits) > 0 {
+		actions = append(actions, CodeAction{
+			Title: "Organize imports",
+			Kind:  protocol.SourceOrganizeImports,
+			Edit:  &WorkspaceEdit{Changes: map[uri.URI][]protocol.TextEdit{fileURI: edits}},
+		})
+	}
+	if edits, err = SortFields(ctx, view, fileURI); err != nil {
+		return nil, err
+	}
+	if len(edits) > 0 {
+		actions = append(actions, CodeAction{
+			Title: "Sort fields by number",
+			Kind:  SourceSortFields,
+			Edit:  &WorkspaceEdit{Changes: map[uri.URI][]protocol.TextEdit{fileURI: edits}},
+		})
+	}
+	return actions, nil
+}
+
+// importUnit is an import statement together with its leading comment.
+type importUnit struct {
+	path  string
+	group int
+	// text is the lines of the statement and the comment without the trailing newline.
+	text string
+}
+
+// OrganizeImports returns the edits which remove unused imports from the file for a given URI,
+// add the missing ones, and sort them in groups of the well-known types, third-party files and local files.
+// It returns no edits if the imports are interleaved with other statements or share lines with them.
+func OrganizeImports(ctx context.Context, view View, uri uri.URI) ([]protocol.TextEdit, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.ParseError() != nil || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	proto := pf.Proto().Protobuf()
+
+	problems, err := semanticProblems(ctx, view, pf)
+	if err != nil {
+		return nil, err
+	}
+	unused := make(map[string]bool)
+	var missing []string
+	for _, p := range problems {
+		switch {
+		case p.code == CodeUnusedImport:
+			unused[p.importPath] = true
+		case p.code == CodeMissingImport && p.importPath != "":
+			missing = append(missing, p.importPath)
+		}
+	}
+
+	// first and last are the 1-based lines of the first and the last import statements including comments.
+	var first, last int
+	var units []importUnit
+	covered := make(map[int]bool)
+	seen := make(map[string]bool)
+	for _, el := range proto.Elements {
+		i, ok := el.(*protobuf.Import)
+		if !ok {
+			continue
+		}
+		start, ok := importStart(lines, i)
+		if !ok {
+			return nil, nil
+		}
+		if first == 0 {
+			first = start
+		}
+		last = i.Position.Line
+		for l := start; l <= last; l++ {
+			covered[l] = true
+		}
+		if unused[i.Filename] || seen[i.Filename] {
+			continue
+		}
+		seen[i.Filename] = true
+		units = append(units, importUnit{
+			path:  i.Filename,
+			group: importGroup(view, uri, i.Filename),
+			text:  strings.Join(lines[start-1:last], "\n"),
+		})
+	}
+	for l := first; l > 0 && l <= last; l++ {
+		if !covered[l] && strings.TrimSpace(lines[l-1]) != "" {
+			return nil, nil
+		}
+	}
+	for _, path := range missing {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		units = append(units, importUnit{
+			path:  path,
+			group: importGroup(view, uri, path),
+			text:  fmt.Sprintf("import %q;", path),
+		})
+	}
+
+	sort.SliceStable(units, func(i, j int) bool {
+		if units[i].group != units[j].group {
+			return units[i].group < units[j].group
+		}
+		return units[i].path < units[j].path
+	})
+	var b strings.Builder
+	for i, u := range units {
+		if i > 0 && u.group != units[i-1].group {
+			b.WriteString("\n")
+		}
+		b.WriteString(u.text)
+		b.WriteString("\n")
+	}
+	text := b.String()
+
+	if first == 0 {
+		if text == "" {
+			return nil, nil
+		}
+		return []protocol.TextEdit{insertImports(proto, text)}, nil
+	}
+	end := last
+	if text == "" && end < len(lines) && strings.TrimSpace(lines[end]) == "" && end+1 < len(lines) {
+		// Remove the blank line separating the imports from the following statements as well.
+		end++
+	}
+	if old := strings.Join(lines[first-1:end], "\n") + "\n"; old == text {
+		return nil, nil
+	}
+	return []protocol.TextEdit{
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: float64(first - 1)},
+				End:   protocol.Position{Line: float64(end)},
+			},
+			NewText: text,
+		},
+	}, nil
+}
+
+// importStart returns the 1-based line where an import statement starts including its leading comment.
+// It returns false if the statement shares a line with other statements.
+func importStart(lines []string, i *protobuf.Import) (int, bool) {
+	line := i.Position.Line
+	if line < 1 || line > len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[line-1]), "import") {
+		return 0, false
+	}
+	code := lines[line-1]
+	if idx := strings.Index(code, "//"); idx >= 0 {
+		code = code[:idx]
+	}
+	if strings.Count(code, ";") != 1 || !strings.HasSuffix(strings.TrimSpace(code), ";") {
+		return 0, false
+	}
+	if i.Comment != nil && i.Comment.Position.Line < line {
+		return i.Comment.Position.Line, true
+	}
+	return line, true
+}
+
+// importGroup returns the group of an import path in a given file.
+// Imports of files outside the folder of the view, or in include paths which don't contain the importing file,
+// are third-party, as well as the ones which can't be resolved.
+func importGroup(view View, from uri.URI, path string) int {
+	if strings.HasPrefix(path, wellKnownPrefix) {
+		return importGroupWellKnown
+	}
+	u, ok := ResolveImport(view, from, path)
+	if !ok {
+		return importGroupThirdParty
+	}
+	for _, dir := range view.Config(from).AbsIncludePaths() {
+		if inDir(dir, u.Filename()) && !inDir(dir, from.Filename()) {
+			return importGroupThirdParty
+		}
+	}
+	if !inDir(view.Folder().Filename(), u.Filename()) {
+		return importGroupThirdParty
+	}
+	return importGroupLocal
+}
+
+// inDir returns true if a file is in a given directory or its subdirectories.
+func inDir(dir, filename string) bool {
+	rel, err := filepath.Rel(dir, filename)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// insertImports returns the edit which inserts import statements into a file after the last import,
+// or the package or syntax statement if there are no imports.
+func insertImports(proto *protobuf.Proto, text string) protocol.TextEdit {
+	var last, pkg, syntax int
+	for _, el := range proto.Elements {
+		switch v := el.(type) {
+		case *protobuf.Import:
+			last = v.Position.Line
+		case *protobuf.Package:
+			pkg = v.Position.Line
+		case *protobuf.Syntax:
+			syntax = v.Position.Line
+		}
+	}
+
+	line := last
+	if line == 0 {
+		line = maxInt(pkg, syntax)
+		if line > 0 {
+			text = "\n" + text
+		}
+	}
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(line)},
+			End:   protocol.Position{Line: float64(line)},
+		},
+		NewText: text,
+	}
+}
+
+// fieldUnit is a field declared on a single line together with its leading comment.
+type fieldUnit struct {
+	number int
+	// start and end are the 1-based lines of the comment and the field.
+	start, end int
+}
+
+// SortFields returns the edits which sort fields in the messages of the file for a given URI by number.
+// Only consecutive fields declared on their own lines are sorted together, so blank lines,
+// nested declarations and fields spanning multiple lines separate fields which are sorted independently.
+func SortFields(ctx context.Context, view View, uri uri.URI) ([]protocol.TextEdit, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.ParseError() != nil || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	edits := []protocol.TextEdit{}
+	var walk func(elements []protobuf.Visitee)
+	walk = func(elements []protobuf.Visitee) {
+		var run []fieldUnit
+		flush := func() {
+			if e, ok := sortFieldsEdit(lines, run); ok {
+				edits = append(edits, e)
+			}
+			run = nil
+		}
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Message:
+				flush()
+				walk(v.Elements)
+				continue
+			case *protobuf.Oneof:
+				flush()
+				walk(v.Elements)
+				continue
+			}
+			field, ok := elementField(el)
+			if !ok {
+				flush()
+				continue
+			}
+			u, ok := newFieldUnit(lines, field)
+			if !ok {
+				flush()
+				continue
+			}
+			if len(run) > 0 && run[len(run)-1].end+1 != u.start {
+				flush()
+			}
+			run = append(run, u)
+		}
+		flush()
+	}
+	walk(pf.Proto().Protobuf().Elements)
+	return edits, nil
+}
+
+// elementField returns the field of an element of a message or a oneof.
+func elementField(el protobuf.Visitee) (*protobuf.Field, bool) {
+	switch v := el.(type) {
+	case *protobuf.NormalField:
+		return v.Field, true
+	case *protobuf.MapField:
+		return v.Field, true
+	case *protobuf.OneOfField:
+		return v.Field, true
+	}
+	return nil, false
+}
+
+// newFieldUnit returns the unit of a field. It returns false if the field doesn't occupy its lines alone.
+func newFieldUnit(lines []string, f *protobuf.Field) (fieldUnit, bool) {
+	line := f.Position.Line
+	if line < 1 || line > len(lines) || strings.TrimSpace(lines[line-1][:f.Position.Column-1]) != "" {
+		return fieldUnit{}, false
+	}
+	code := lines[line-1]
+	if idx := strings.Index(code, "//"); idx >= 0 {
+		code = code[:idx]
+	}
+	if strings.Count(code, ";") != 1 || !strings.HasSuffix(strings.TrimSpace(code), ";") {
+		return fieldUnit{}, false
+	}
+	u := fieldUnit{number: f.Sequence, start: line, end: line}
+	if c := f.Comment; c != nil && c.Position.Line < line {
+		if strings.TrimSpace(lines[c.Position.Line-1][:c.Position.Column-1]) != "" {
+			return fieldUnit{}, false
+		}
+		u.start = c.Position.Line
+	}
+	return u, true
+}
+
+// sortFieldsEdit returns the edit which sorts a run of consecutive fields by number.
+// It returns false if they are already sorted.
+func sortFieldsEdit(lines []string, run []fieldUnit) (protocol.TextEdit, bool) {
+	if sort.SliceIsSorted(run, func(i, j int) bool { return run[i].number < run[j].number }) {
+		return protocol.TextEdit{}, false
+	}
+	sorted := make([]fieldUnit, len(run))
+	copy(sorted, run)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].number < sorted[j].number })
+
+	var b strings.Builder
+	for _, u := range sorted {
+		b.WriteString(strings.Join(lines[u.start-1:u.end], "\n"))
+		b.WriteString("\n")
+	}
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(run[0].start - 1)},
+			End:   protocol.Position{Line: float64(run[len(run)-1].end)},
+		},
+		NewText: b.String(),
+	}, true
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestOrganizeImports(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	view.DidOpen(uri.File("/workspace/a/bar.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage Bar {}\n"))
+	view.DidOpen(uri.File("/workspace/b/baz.proto"), []byte("syntax = \"proto3\";\npackage b;\nmessage Baz {}\n"))
+	view.DidOpen(uri.File("/workspace/c/qux.proto"), []byte("syntax = \"proto3\";\npackage c;\nmessage Qux {}\n"))
+
+	tests := []struct {
+		name string
+		text string
+		want []protocol.TextEdit
+	}{
+		{
+			name: "organize",
+			text: `syntax = "proto3";
+
+package foo;
+
+import "c/qux.proto";
+// Bar is used.
+import "a/bar.proto";
+import "google/protobuf/timestamp.proto";
+import "third_party/x.proto";
+
+message Foo {
+  a.Bar bar = 1;
+  b.Baz baz = 2;
+  google.protobuf.Timestamp time = 3;
+}
+`,
+			want: []protocol.TextEdit{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 4},
+						End:   protocol.Position{Line: 9},
+					},
+					NewText: `import "google/protobuf/timestamp.proto";
+
+import "third_party/x.proto";
+
+// Bar is used.
+import "a/bar.proto";
+import "b/baz.proto";
+`,
+				},
+			},
+		},
+		{
+			name: "no imports",
+			text: "syntax = \"proto3\";\npackage foo;\nmessage Foo {\n  b.Baz baz = 1;\n}\n",
+			want: []protocol.TextEdit{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 2},
+						End:   protocol.Position{Line: 2},
+					},
+					NewText: "\nimport \"b/baz.proto\";\n",
+				},
+			},
+		},
+		{
+			name: "remove all",
+			text: "syntax = \"proto3\";\n\nimport \"c/qux.proto\";\n\nmessage Foo {}\n",
+			want: []protocol.TextEdit{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 2},
+						End:   protocol.Position{Line: 4},
+					},
+				},
+			},
+		},
+		{
+			name: "organized",
+			text: "syntax = \"proto3\";\nimport \"a/bar.proto\";\nimport \"b/baz.proto\";\nmessage Foo {\n  a.Bar bar = 1;\n  b.Baz baz = 2;\n}\n",
+		},
+		{
+			name: "interleaved",
+			text: "syntax = \"proto3\";\nimport \"c/qux.proto\";\noption go_package = \"foo\";\nimport \"a/bar.proto\";\nmessage Foo {\n  a.Bar bar = 1;\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fileURI := uri.File("/workspace/foo.proto")
+			view.DidOpen(fileURI, []byte(tt.text))
+			got, err := OrganizeImports(ctx, view, fileURI)
+			if err != nil {
+				t.Fatalf("OrganizeImports() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OrganizeImports() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortFields(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	fileURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fileURI, []byte(`syntax = "proto3";
+message Foo {
+  // c is the third.
+  string c = 3;
+  string a = 1; // a is the first.
+
+  string e = 5;
+  oneof d {
+    string g = 7;
+    string f = 6;
+  }
+  message Bar {
+    int32 y = 2;
+    int32 x = 1;
+  }
+  string b = 2 [
+    deprecated = true
+  ];
+}
+`))
+	got, err := SortFields(ctx, view, fileURI)
+	if err != nil {
+		t.Fatalf("SortFields() error = %v", err)
+	}
+	want := []protocol.TextEdit{
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 2},
+				End:   protocol.Position{Line: 5},
+			},
+			NewText: "  string a = 1; // a is the first.\n  // c is the third.\n  string c = 3;\n",
+		},
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 8},
+				End:   protocol.Position{Line: 10},
+			},
+			NewText: "    string f = 6;\n    string g = 7;\n",
+		},
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 12},
+				End:   protocol.Position{Line: 14},
+			},
+			NewText: "    int32 x = 1;\n    int32 y = 2;\n",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortFields() = %+v, want %+v", got, want)
+	}
+}
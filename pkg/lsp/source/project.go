@@ -0,0 +1,189 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/buf"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+// skipDirs is the set of directory names which are never walked into.
+var skipDirs = map[string]struct{}{
+	".git":         {},
+	".hg":          {},
+	".svn":         {},
+	"node_modules": {},
+}
+
+// IsProjectConfig returns true if a given URI is a file which configures a project,
+// that is a project configuration file or a buf configuration file.
+func IsProjectConfig(uri uri.URI) bool {
+	switch filepath.Base(uri.Filename()) {
+	case config.ProjectFilename, buf.ConfigFilename, buf.WorkFilename, buf.LockFilename:
+		return true
+	default:
+		return false
+	}
+}
+
+// loadProjects walks a given root directory and loads all project configuration files
+// and buf configuration files in it. The returned map is keyed by the directory which
+// the configuration applies to. A project configuration file takes precedence over
+// a buf module in the same directory.
+// Files which fail to load are skipped and the first error is returned along with the others.
+func loadProjects(root string) (map[string]config.Project, error) {
+	projects := make(map[string]config.Project)
+	var (
+		workDirs []string
+		bufDirs  []string
+		firstErr error
+	)
+	setErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Skip unreadable directories rather than aborting the whole walk.
+			return nil
+		}
+		if info.IsDir() {
+			if _, ok := skipDirs[info.Name()]; ok {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch info.Name() {
+		case config.ProjectFilename:
+			p, err := config.LoadProject(path)
+			if err != nil {
+				setErr(fmt.Errorf("failed to load %s: %w", path, err))
+				return nil
+			}
+			projects[p.Dir] = p
+		case buf.WorkFilename:
+			workDirs = append(workDirs, filepath.Dir(path))
+		case buf.ConfigFilename:
+			bufDirs = append(bufDirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// buf.yaml which belongs to a workspace is loaded as a part of the workspace.
+	var workspaces []*buf.Workspace
+	for _, dir := range append(workDirs, bufDirs...) {
+		contained := false
+		for _, w := range workspaces {
+			if w.Contains(dir) {
+				contained = true
+				break
+			}
+		}
+		if contained {
+			continue
+		}
+		w, err := buf.Load(dir)
+		if err != nil {
+			setErr(err)
+			continue
+		}
+		workspaces = append(workspaces, w)
+	}
+
+	cacheDir := buf.CacheDir()
+	for _, w := range workspaces {
+		ps, err := w.Projects(cacheDir)
+		if err != nil {
+			setErr(err)
+		}
+		for _, p := range ps {
+			if _, ok := projects[p.Dir]; ok {
+				continue
+			}
+			projects[p.Dir] = p
+		}
+	}
+
+	return projects, firstErr
+}
+
+// nearestProject returns the project configuration whose directory is
+// the nearest ancestor of a given filename.
+func nearestProject(projects map[string]config.Project, filename string) (config.Project, bool) {
+	var (
+		nearest config.Project
+		found   bool
+	)
+	for dir, p := range projects {
+		if filename != dir && !strings.HasPrefix(filename, dir+string(filepath.Separator)) {
+			continue
+		}
+		if found && len(nearest.Dir) > len(dir) {
+			continue
+		}
+		nearest, found = p, true
+	}
+	return nearest, found
+}
+
+// loadNearestProjects looks up the nearest directory which has a project configuration file
+// or a buf configuration file in a given directory and its ancestors, and loads the configurations in it.
+// It returns an empty map if no configuration file is found.
+func loadNearestProjects(dir string) (map[string]config.Project, error) {
+	projects := make(map[string]config.Project)
+	for ; ; dir = filepath.Dir(dir) {
+		if filename := filepath.Join(dir, config.ProjectFilename); exists(filename) {
+			p, err := config.LoadProject(filename)
+			if err != nil {
+				return projects, fmt.Errorf("failed to load %s: %w", filename, err)
+			}
+			projects[p.Dir] = p
+			return projects, nil
+		}
+
+		if exists(filepath.Join(dir, buf.WorkFilename)) || exists(filepath.Join(dir, buf.ConfigFilename)) {
+			w, err := buf.Load(dir)
+			if err != nil {
+				return projects, err
+			}
+			ps, err := w.Projects(buf.CacheDir())
+			for _, p := range ps {
+				projects[p.Dir] = p
+			}
+			return projects, err
+		}
+
+		if dir == filepath.Dir(dir) {
+			return projects, nil
+		}
+	}
+}
+
+func exists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
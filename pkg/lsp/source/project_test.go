@@ -0,0 +1,150 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+func TestLoadProjects(t *testing.T) {
+	root, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		config.ProjectFilename:                                       "include_paths: [proto]",
+		filepath.Join("sub", config.ProjectFilename):                 "format: {indent_size: 4}",
+		filepath.Join("node_modules", "pkg", config.ProjectFilename): "include_paths: [ignored]",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projects, err := loadProjects(root)
+	if err != nil {
+		t.Fatalf("loadProjects() error = %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("len(loadProjects()) = %d, want 2", len(projects))
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		wantDir  string
+	}{
+		{
+			name:     "root",
+			filename: filepath.Join(root, "a.proto"),
+			wantDir:  root,
+		},
+		{
+			name:     "nested",
+			filename: filepath.Join(root, "sub", "b", "c.proto"),
+			wantDir:  filepath.Join(root, "sub"),
+		},
+		{
+			name:     "sibling with common prefix",
+			filename: filepath.Join(root, "subway", "d.proto"),
+			wantDir:  root,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := nearestProject(projects, tt.filename)
+			if !ok {
+				t.Fatal("nearestProject() not found")
+			}
+			if p.Dir != tt.wantDir {
+				t.Errorf("nearestProject().Dir = %s, want %s", p.Dir, tt.wantDir)
+			}
+		})
+	}
+}
+
+func TestLoadNearestProjects(t *testing.T) {
+	root, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		filepath.Join("a", config.ProjectFilename):             "include_paths: [proto]",
+		filepath.Join("a", "b", "sub", config.ProjectFilename): "format: {indent_size: 4}",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantDir string
+	}{
+		{
+			name:    "same directory",
+			dir:     filepath.Join(root, "a"),
+			wantDir: filepath.Join(root, "a"),
+		},
+		{
+			name:    "ancestor",
+			dir:     filepath.Join(root, "a", "b", "c"),
+			wantDir: filepath.Join(root, "a"),
+		},
+		{
+			name: "not found",
+			dir:  root,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			projects, err := loadNearestProjects(tt.dir)
+			if err != nil {
+				t.Fatalf("loadNearestProjects() error = %v", err)
+			}
+			if tt.wantDir == "" {
+				if len(projects) != 0 {
+					t.Errorf("loadNearestProjects() = %v, want empty", projects)
+				}
+				return
+			}
+			if _, ok := projects[tt.wantDir]; !ok || len(projects) != 1 {
+				t.Errorf("loadNearestProjects() = %v, want %s", projects, tt.wantDir)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/breaking"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/lint"
+)
+
+// fieldNoDeleteRule is the ID of the breaking rule whose problems are fixed by reserving the deleted fields.
+const fieldNoDeleteRule = "FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED"
+
+// quickFixer computes the quick fixes for the diagnostics of a file.
+// The problems are computed again and matched with the diagnostics since diagnostics
+// can't carry the data to fix them.
+type quickFixer struct {
+	view  View
+	uri   uri.URI
+	pf    ProtoFile
+	proto *protobuf.Proto
+	lines []string
+
+	semantic []semanticProblem
+	lint     []lint.Problem
+	breaking []breaking.Problem
+}
+
+// QuickFixes returns the code actions which fix given diagnostics of the file for a given URI.
+// The diagnostics which are not reported by this server or can't be fixed are ignored.
+func QuickFixes(ctx context.Context, view View, uri uri.URI, diagnostics []protocol.Diagnostic) ([]CodeAction, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.ParseError() != nil || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &quickFixer{
+		view:  view,
+		uri:   uri,
+		pf:    pf,
+		proto: pf.Proto().Protobuf(),
+		lines: strings.Split(string(data), "\n"),
+	}
+	var (
+		semanticDone bool
+		lintDone     bool
+		breakingDone bool
+	)
+
+	actions := []CodeAction{}
+	for _, d := range diagnostics {
+		if d.Source != DiagnosticSource {
+			continue
+		}
+		code, _ := d.Code.(string)
+
+		var action *CodeAction
+		switch code {
+		case CodeMissingImport, CodeUnusedImport, CodeDuplicateFieldNumber:
+			if !semanticDone {
+				if q.semantic, err = semanticProblems(ctx, view, pf); err != nil {
+					return nil, err
+				}
+				semanticDone = true
+			}
+			action = q.fixSemantic(d, code)
+		case fieldNoDeleteRule:
+			if !breakingDone {
+				if q.breaking, _, err = breakingProblems(ctx, view, uri, view.Options().Breaking.Against); err != nil {
+					return nil, err
+				}
+				breakingDone = true
+			}
+			action = q.fixDeletedField(d)
+		default:
+			if _, ok := lint.LookupRule(code); !ok {
+				continue
+			}
+			if !lintDone {
+				q.lint = lint.Lint(pf.Proto(), uri.Filename(), view.Config(uri))
+				lintDone = true
+			}
+			action = q.fixLint(ctx, d, code)
+		}
+		if action != nil {
+			action.Kind = protocol.QuickFix
+			action.Diagnostics = []protocol.Diagnostic{d}
+			actions = append(actions, *action)
+		}
+	}
+	return actions, nil
+}
+
+func (q *quickFixer) fixSemantic(d protocol.Diagnostic, code string) *CodeAction {
+	var problem *semanticProblem
+	for i := range q.semantic {
+		if p := &q.semantic[i]; p.code == code && p.rng == d.Range {
+			problem = p
+			break
+		}
+	}
+	if problem == nil {
+		return nil
+	}
+
+	switch code {
+	case CodeMissingImport:
+		if problem.importPath == "" {
+			return nil
+		}
+		return q.action(fmt.Sprintf("Add import %q", problem.importPath), importEdit(q.proto, problem.importPath))
+	case CodeUnusedImport:
+		return q.action(fmt.Sprintf("Remove unused import %q", problem.importPath), importRemoval(q.lines, problem.rng))
+	case CodeDuplicateFieldNumber:
+		if problem.number == 0 {
+			return nil
+		}
+		return q.action(fmt.Sprintf("Change field number to %d", problem.number), protocol.TextEdit{
+			Range:   problem.rng,
+			NewText: fmt.Sprint(problem.number),
+		})
+	}
+	return nil
+}
+
+// importRemoval returns the edit which removes the import statement whose path is at a given range.
+// The line is removed as well if nothing else is on it.
+func importRemoval(lines []string, path protocol.Range) protocol.TextEdit {
+	l := int(path.Start.Line)
+	wholeLine := protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(l)},
+			End:   protocol.Position{Line: float64(l + 1)},
+		},
+	}
+	if l >= len(lines) || int(path.End.Character) > len(lines[l]) {
+		return wholeLine
+	}
+	line := lines[l]
+	start := strings.LastIndex(line[:int(path.Start.Character)], "import")
+	end := strings.Index(line[int(path.End.Character):], ";")
+	if start < 0 || end < 0 {
+		// The statement spans multiple lines.
+		return wholeLine
+	}
+	end += int(path.End.Character) + 1
+	if strings.TrimSpace(line[:start]+line[end:]) == "" {
+		return wholeLine
+	}
+
+	// Remove the spaces separating the statement from the rest of the line.
+	if strings.TrimSpace(line[end:]) == "" {
+		for start > 0 && (line[start-1] == ' ' || line[start-1] == '\t') {
+			start--
+		}
+		end = len(line)
+	} else {
+		for end < len(line) && (line[end] == ' ' || line[end] == '\t') {
+			end++
+		}
+	}
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(l), Character: float64(start)},
+			End:   protocol.Position{Line: float64(l), Character: float64(end)},
+		},
+	}
+}
+
+// importEdit returns the edit which inserts an import into a file after the last import,
+// or the package or syntax statement if there are no imports.
+func importEdit(proto *protobuf.Proto, path string) protocol.TextEdit {
+	return insertImports(proto, fmt.Sprintf("import %q;\n", path))
+}
+
+func (q *quickFixer) fixLint(ctx context.Context, d protocol.Diagnostic, code string) *CodeAction {
+	var problem *lint.Problem
+	for i := range q.lint {
+		p := &q.lint[i]
+		if p.RuleID == code && p.Message == d.Message && nameRange(q.lines, p.Line, p.Column, p.Name) == d.Range {
+			problem = p
+			break
+		}
+	}
+	if problem == nil || problem.Suggestion == "" {
+		return nil
+	}
+
+	switch code {
+	case "ENUM_FIRST_VALUE_ZERO":
+		return q.addZeroValue(problem)
+	case "PACKAGE_DIRECTORY_MATCH", "RPC_REQUEST_STANDARD_NAME", "RPC_RESPONSE_STANDARD_NAME":
+		return nil
+	}
+
+	edits := map[uri.URI][]protocol.TextEdit{
+		q.uri: {{Range: d.Range, NewText: problem.Suggestion}},
+	}
+	if code == "MESSAGE_PASCAL_CASE" || code == "ENUM_PASCAL_CASE" {
+		for _, s := range fileSymbols(q.uri, q.proto) {
+			if s.Line != problem.Line || s.Column != problem.Column || (s.Kind != SymbolKindMessage && s.Kind != SymbolKindEnum) {
+				continue
+			}
+			for _, ref := range References(ctx, q.view, s) {
+				edits[ref.URI] = append(edits[ref.URI], protocol.TextEdit{Range: ref.Range, NewText: problem.Suggestion})
+			}
+			break
+		}
+	}
+	return &CodeAction{
+		Title: fmt.Sprintf("Rename %q to %q", problem.Name, problem.Suggestion),
+		Edit:  &WorkspaceEdit{Changes: edits},
+	}
+}
+
+// addZeroValue returns the action which inserts the zero value before the first value of an enum.
+// No action is returned if the enum already has a zero value, which should be moved instead.
+func (q *quickFixer) addZeroValue(problem *lint.Problem) *CodeAction {
+	var values []*protobuf.EnumField
+	walkEnums(q.proto.Elements, func(e *protobuf.Enum) {
+		vs := enumValues(e)
+		if len(vs) > 0 && vs[0].Position.Line == problem.Line && vs[0].Position.Column == problem.Column {
+			values = vs
+		}
+	})
+	if len(values) == 0 {
+		return nil
+	}
+	for _, v := range values {
+		if v.Integer == 0 || v.Name == problem.Suggestion {
+			return nil
+		}
+	}
+
+	line := problem.Line - 1
+	return q.action(fmt.Sprintf("Add zero value %q", problem.Suggestion), protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(line)},
+			End:   protocol.Position{Line: float64(line)},
+		},
+		NewText: indentation(q.lines[line]) + problem.Suggestion + " = 0;\n",
+	})
+}
+
+// fixDeletedField returns the action which reserves the number and the name of a deleted field
+// at the beginning of the message it was deleted from.
+func (q *quickFixer) fixDeletedField(d protocol.Diagnostic) *CodeAction {
+	var problem *breaking.Problem
+	for i := range q.breaking {
+		p := &q.breaking[i]
+		if p.RuleID == fieldNoDeleteRule && p.Field != nil && p.Message == d.Message && nameRange(q.lines, p.Line, p.Column, p.Name) == d.Range {
+			problem = p
+			break
+		}
+	}
+	if problem == nil {
+		return nil
+	}
+
+	line, ok := openBraceLine(q.lines, problem.Line-1)
+	if !ok {
+		return nil
+	}
+	indent := indentation(q.lines[problem.Line-1]) + indentUnit(q.view.Config(q.uri).Format)
+	return q.action(fmt.Sprintf("Reserve number %d and name %q", problem.Field.Number, problem.Field.Name), protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(line + 1)},
+			End:   protocol.Position{Line: float64(line + 1)},
+		},
+		NewText: fmt.Sprintf("%sreserved %d;\n%sreserved %q;\n", indent, problem.Field.Number, indent, problem.Field.Name),
+	})
+}
+
+// action returns a code action which applies given edits to the file.
+func (q *quickFixer) action(title string, edits ...protocol.TextEdit) *CodeAction {
+	return &CodeAction{
+		Title: title,
+		Edit: &WorkspaceEdit{
+			Changes: map[uri.URI][]protocol.TextEdit{q.uri: edits},
+		},
+	}
+}
+
+// openBraceLine returns the 0-based line of the first opening brace at or after a given 0-based line.
+func openBraceLine(lines []string, line int) (int, bool) {
+	for l := line; l >= 0 && l < len(lines); l++ {
+		if strings.Contains(lines[l], "{") {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// indentation returns the leading whitespace of a line.
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// indentUnit returns the string which indents a level with given formatter options.
+func indentUnit(opts config.Format) string {
+	if opts.UseTabs {
+		return "\t"
+	}
+	return strings.Repeat(" ", opts.IndentSize)
+}
+
+func walkEnums(elements []protobuf.Visitee, fn func(*protobuf.Enum)) {
+	for _, el := range elements {
+		switch v := el.(type) {
+		case *protobuf.Enum:
+			fn(v)
+		case *protobuf.Message:
+			walkEnums(v.Elements, fn)
+		}
+	}
+}
+
+func enumValues(e *protobuf.Enum) (values []*protobuf.EnumField) {
+	for _, el := range e.Elements {
+		if v, ok := el.(*protobuf.EnumField); ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestQuickFixes(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	mainURI := uri.File("/workspace/main.proto")
+	otherURI := uri.File("/workspace/other.proto")
+	typesURI := uri.File("/workspace/types.proto")
+	unusedURI := uri.File("/workspace/unused.proto")
+	view.DidOpen(typesURI, []byte(`syntax = "proto3";
+message Type {}
+`))
+	view.DidOpen(unusedURI, []byte(`syntax = "proto3";
+message Unused {}
+`))
+	view.DidOpen(otherURI, []byte(`syntax = "proto3";
+import "main.proto";
+message Other {
+  search_request req = 1;
+}
+`))
+	view.DidOpen(mainURI, []byte(`syntax = "proto3";
+import "other.proto"; // other
+message search_request {
+  Type type = 1;
+  int32 page = 1;
+}
+enum Status {
+  STATUS_OK = 1;
+}
+import "unused.proto";
+`))
+
+	diagnostics, err := Diagnostics(ctx, view, mainURI)
+	if err != nil {
+		t.Fatalf("Diagnostics() error = %v", err)
+	}
+	actions, err := QuickFixes(ctx, view, mainURI, diagnostics)
+	if err != nil {
+		t.Fatalf("QuickFixes() error = %v", err)
+	}
+
+	at := func(line, start, end float64) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: line, Character: start},
+			End:   protocol.Position{Line: line, Character: end},
+		}
+	}
+	lines := func(start, end float64) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: start},
+			End:   protocol.Position{Line: end},
+		}
+	}
+	want := map[string]map[uri.URI][]protocol.TextEdit{
+		`Add import "types.proto"`: {
+			mainURI: {{Range: lines(10, 10), NewText: "import \"types.proto\";\n"}},
+		},
+		`Remove unused import "other.proto"`: {
+			mainURI: {{Range: at(1, 0, 22)}},
+		},
+		`Remove unused import "unused.proto"`: {
+			mainURI: {{Range: lines(9, 10)}},
+		},
+		`Change field number to 2`: {
+			mainURI: {{Range: at(4, 15, 16), NewText: "2"}},
+		},
+		`Rename "search_request" to "SearchRequest"`: {
+			mainURI:  {{Range: at(2, 8, 22), NewText: "SearchRequest"}},
+			otherURI: {{Range: at(3, 2, 16), NewText: "SearchRequest"}},
+		},
+		`Add zero value "STATUS_UNSPECIFIED"`: {
+			mainURI: {{Range: lines(7, 7), NewText: "  STATUS_UNSPECIFIED = 0;\n"}},
+		},
+	}
+
+	got := make(map[string]map[uri.URI][]protocol.TextEdit)
+	for _, a := range actions {
+		if a.Kind != protocol.QuickFix || len(a.Diagnostics) != 1 || a.Edit == nil {
+			t.Errorf("QuickFixes() returned an invalid action %+v", a)
+			continue
+		}
+		got[a.Title] = a.Edit.Changes
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QuickFixes() = %+v, want %+v", got, want)
+	}
+}
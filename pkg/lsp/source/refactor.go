@@ -0,0 +1,700 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// RefactorMove is the kind of the code actions which move declarations to other files.
+// The protocol package doesn't declare it yet.
+const RefactorMove protocol.CodeActionKind = "refactor.move"
+
+// declaration is a message or an enum declared in a file.
+type declaration struct {
+	sym     Symbol
+	name    string
+	message *protobuf.Message
+	// parents are the messages which the declaration is nested in from the outermost.
+	parents []*protobuf.Message
+	block   block
+}
+
+// block is the lines of a declaration including its leading comment.
+type block struct {
+	// start and end are 0-based lines, which are inclusive.
+	start, end int
+	indent     string
+}
+
+func (b block) contains(line int) bool {
+	return b.start <= line && line <= b.end
+}
+
+// refactorer computes the refactorings of a declaration in a file.
+type refactorer struct {
+	ctx         context.Context
+	view        View
+	uri         uri.URI
+	proto       *protobuf.Proto
+	lines       []string
+	pkg         string
+	createFiles bool
+}
+
+// Refactorings returns the refactoring code actions for the message or the enum declared at the start line
+// of a given range in the file for a given URI. createFiles tells if the client can create files with
+// workspace edits, without which declarations can be moved only to existing files.
+func Refactorings(ctx context.Context, view View, uri uri.URI, rng protocol.Range, createFiles bool) ([]CodeAction, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.ParseError() != nil || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proto := pf.Proto().Protobuf()
+	r := &refactorer{
+		ctx:         ctx,
+		view:        view,
+		uri:         uri,
+		proto:       proto,
+		lines:       strings.Split(string(data), "\n"),
+		pkg:         packageName(proto),
+		createFiles: createFiles,
+	}
+	d, ok := r.declarationAt(int(rng.Start.Line) + 1)
+	if !ok {
+		return nil, nil
+	}
+
+	actions := []CodeAction{}
+	if a := r.extract(d); a != nil {
+		actions = append(actions, *a)
+	}
+	actions = append(actions, r.moves(d)...)
+	if a := r.inline(d); a != nil {
+		actions = append(actions, *a)
+	}
+	return actions, nil
+}
+
+// declarationAt returns the message or the enum declared at a given 1-based line.
+// Declarations which don't span whole lines are not returned since they can't be moved by lines.
+func (r *refactorer) declarationAt(line int) (*declaration, bool) {
+	var found *declaration
+	var walk func(scope string, parents []*protobuf.Message, elements []protobuf.Visitee)
+	walk = func(scope string, parents []*protobuf.Message, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			if found != nil {
+				return
+			}
+			switch v := el.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					continue
+				}
+				name := qualify(scope, v.Name)
+				if v.Position.Line == line {
+					found = r.newDeclaration(Symbol{Name: name, Kind: SymbolKindMessage, URI: r.uri, Line: v.Position.Line, Column: v.Position.Column}, v.Comment, parents)
+					if found != nil {
+						found.message = v
+					}
+					return
+				}
+				walk(name, append(parents[:len(parents):len(parents)], v), v.Elements)
+			case *protobuf.Enum:
+				if v.Position.Line == line {
+					found = r.newDeclaration(Symbol{Name: qualify(scope, v.Name), Kind: SymbolKindEnum, URI: r.uri, Line: v.Position.Line, Column: v.Position.Column}, v.Comment, parents)
+					return
+				}
+			}
+		}
+	}
+	walk(r.pkg, nil, r.proto.Elements)
+	return found, found != nil
+}
+
+func (r *refactorer) newDeclaration(sym Symbol, comment *protobuf.Comment, parents []*protobuf.Message) *declaration {
+	b, ok := findBlock(r.lines, sym.Line, sym.Column, comment)
+	if !ok {
+		return nil
+	}
+	name := sym.Name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return &declaration{sym: sym, name: name, parents: parents, block: b}
+}
+
+// extract returns the action which moves a nested declaration to the top level.
+// The declaration is renamed after its parents if the name is already used at the top level.
+func (r *refactorer) extract(d *declaration) *CodeAction {
+	if len(d.parents) == 0 {
+		return nil
+	}
+	outer := d.parents[0]
+	outerBlock, ok := findBlock(r.lines, outer.Position.Line, outer.Position.Column, outer.Comment)
+	if !ok {
+		return nil
+	}
+
+	name := d.name
+	if len(r.view.LookupSymbol(qualify(r.pkg, name))) > 0 {
+		name = ""
+		for _, p := range d.parents {
+			name += p.Name
+		}
+		name += d.name
+		if len(r.view.LookupSymbol(qualify(r.pkg, name))) > 0 {
+			return nil
+		}
+	}
+	newName := qualify(r.pkg, name)
+	parentName := strings.TrimSuffix(d.sym.Name, "."+d.name)
+
+	edits := make(map[uri.URI][]protocol.TextEdit)
+	for _, ref := range typeReferences(r.ctx, r.view, d.sym) {
+		suffix := strings.TrimPrefix(ref.sym.Name, d.sym.Name)
+		edits[ref.uri] = append(edits[ref.uri], protocol.TextEdit{
+			Range:   ref.rng,
+			NewText: r.rewriteType(ref.typeRef, d.sym.Name, name+suffix, newName+suffix),
+		})
+	}
+	// The references in the declaration to the types nested in its parents don't resolve
+	// from the top level, so they are qualified with the names of the parents.
+	for _, ref := range typeRefs(r.proto, r.lines) {
+		if !ref.found || !d.block.contains(int(ref.rng.Start.Line)) || strings.HasPrefix(ref.typ, ".") {
+			continue
+		}
+		sym, ok := ResolveType(r.view, r.uri, ref.scope, ref.typ)
+		if !ok || sym.URI != r.uri || !strings.HasPrefix(sym.Name, parentName+".") || isWithin(sym.Name, d.sym.Name) {
+			continue
+		}
+		edits[r.uri] = append(edits[r.uri], protocol.TextEdit{
+			Range:   ref.rng,
+			NewText: strings.TrimPrefix(sym.Name, r.pkg+"."),
+		})
+	}
+
+	var moved []protocol.TextEdit
+	moved, edits[r.uri] = splitEdits(edits[r.uri], d.block)
+	text := reindent(applyEdits(r.lines, d.block, moved), d.block.indent, outerBlock.indent)
+	edits[r.uri] = append(edits[r.uri], removeBlock(r.lines, d.block), insertAfter(r.lines, outerBlock.end, text))
+
+	return &CodeAction{
+		Title: fmt.Sprintf("Extract %q to the top level", d.name),
+		Kind:  protocol.RefactorExtract,
+		Edit:  &WorkspaceEdit{Changes: edits},
+	}
+}
+
+// rewriteType returns the type name to replace a reference to an extracted declaration with,
+// which refers to the new top-level type by its name relative to the package or its fully-qualified name.
+// oldName is the fully-qualified name of the declaration before the extraction.
+func (r *refactorer) rewriteType(ref typeRef, oldName, name, fullName string) string {
+	if strings.HasPrefix(ref.typ, ".") {
+		return "." + fullName
+	}
+	if r.pkg != "" && ref.scope != r.pkg && !strings.HasPrefix(ref.scope, r.pkg+".") {
+		return fullName
+	}
+	// The name relative to the package may be shadowed by a nested type.
+	for _, candidate := range typeCandidates(ref.scope, name) {
+		if candidate == fullName {
+			return name
+		}
+		if isWithin(candidate, oldName) {
+			// The declaration doesn't exist there after the extraction.
+			continue
+		}
+		if len(r.view.LookupSymbol(candidate)) > 0 {
+			break
+		}
+	}
+	return "." + fullName
+}
+
+// moves returns the actions which move a top-level declaration to the other files of the same package
+// in the same directory, or to a new file if the client can create files.
+// The files which refer to the declaration import the destination.
+func (r *refactorer) moves(d *declaration) []CodeAction {
+	if len(d.parents) != 0 {
+		return nil
+	}
+
+	refs := typeReferences(r.ctx, r.view, d.sym)
+	sourceUses := false
+	for _, ref := range refs {
+		if ref.uri == r.uri && !d.block.contains(int(ref.rng.Start.Line)) {
+			sourceUses = true
+		}
+	}
+
+	// deps are the files which declare the types referred to by the declaration.
+	deps := make(map[uri.URI]struct{})
+	for _, ref := range typeRefs(r.proto, r.lines) {
+		if !d.block.contains(int(ref.rng.Start.Line)) {
+			continue
+		}
+		sym, ok := ResolveType(r.view, r.uri, ref.scope, ref.typ)
+		if !ok || isWithin(sym.Name, d.sym.Name) && sym.URI == r.uri {
+			continue
+		}
+		deps[sym.URI] = struct{}{}
+	}
+	if _, ok := deps[r.uri]; ok && sourceUses {
+		// The source and the destination would import each other.
+		return nil
+	}
+
+	var actions []CodeAction
+	dir := filepath.Dir(r.uri.Filename())
+	for _, u := range r.view.KnownFiles() {
+		if u == r.uri || filepath.Dir(u.Filename()) != dir {
+			continue
+		}
+		f, err := r.view.GetFile(u)
+		if err != nil {
+			continue
+		}
+		pf, ok := f.(ProtoFile)
+		if !ok || pf.ParseError() != nil || pf.Proto() == nil || packageName(pf.Proto().Protobuf()) != r.pkg {
+			continue
+		}
+		if a := r.move(d, refs, deps, sourceUses, u, pf); a != nil {
+			actions = append(actions, *a)
+		}
+	}
+
+	if r.createFiles {
+		u := uri.File(filepath.Join(dir, fileName(d.name)))
+		if _, err := os.Stat(u.Filename()); os.IsNotExist(err) && !r.view.IsOpen(u) {
+			if a := r.move(d, refs, deps, sourceUses, u, nil); a != nil {
+				actions = append(actions, *a)
+			}
+		}
+	}
+	return actions
+}
+
+// move returns the action which moves a declaration to a given file, which is created if pf is nil.
+func (r *refactorer) move(d *declaration, refs []resolvedRef, deps map[uri.URI]struct{}, sourceUses bool, target uri.URI, pf ProtoFile) *CodeAction {
+	imported := make(map[uri.URI]bool)
+	var (
+		targetProto *protobuf.Proto
+		targetLines []string
+	)
+	if pf != nil {
+		targetProto = pf.Proto().Protobuf()
+		for _, u := range resolvedImports(r.view, target, targetProto) {
+			imported[u] = true
+		}
+		data, _, err := pf.Read(r.ctx)
+		if err != nil {
+			return nil
+		}
+		targetLines = strings.Split(string(data), "\n")
+	}
+	if sourceUses && imported[r.uri] {
+		return nil
+	}
+
+	var paths []string
+	for u := range deps {
+		if u == target || imported[u] {
+			continue
+		}
+		path, ok := ImportPath(r.view, target, u)
+		if !ok {
+			return nil
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	edits := map[uri.URI][]protocol.TextEdit{
+		r.uri: {removeBlock(r.lines, d.block)},
+	}
+	importers := make(map[uri.URI]bool)
+	for _, ref := range refs {
+		if ref.uri == target || importers[ref.uri] || ref.uri == r.uri && d.block.contains(int(ref.rng.Start.Line)) {
+			continue
+		}
+		importers[ref.uri] = true
+
+		f, err := r.view.GetFile(ref.uri)
+		if err != nil {
+			return nil
+		}
+		proto := f.(ProtoFile).Proto().Protobuf()
+		if containsURI(resolvedImports(r.view, ref.uri, proto), target) {
+			continue
+		}
+		path, ok := ImportPath(r.view, ref.uri, target)
+		if !ok {
+			return nil
+		}
+		edits[ref.uri] = append(edits[ref.uri], importEdit(proto, path))
+	}
+
+	text := reindent(applyEdits(r.lines, d.block, nil), d.block.indent, "")
+	base := filepath.Base(target.Filename())
+	if pf == nil {
+		edits[target] = []protocol.TextEdit{{NewText: r.newFile(paths, text)}}
+		return &CodeAction{
+			Title: fmt.Sprintf("Move %q to a new file %q", d.name, base),
+			Kind:  RefactorMove,
+			Edit:  &WorkspaceEdit{DocumentChanges: documentChanges([]uri.URI{target}, edits)},
+		}
+	}
+
+	for _, path := range paths {
+		edits[target] = append(edits[target], importEdit(targetProto, path))
+	}
+	edits[target] = append(edits[target], insertAfter(targetLines, lastLine(targetLines), text))
+	return &CodeAction{
+		Title: fmt.Sprintf("Move %q to %q", d.name, base),
+		Kind:  RefactorMove,
+		Edit:  &WorkspaceEdit{Changes: edits},
+	}
+}
+
+// newFile returns the content of a new file with the same syntax, package and file options as the source
+// which imports given paths and declares a given text.
+func (r *refactorer) newFile(imports []string, text string) string {
+	var b strings.Builder
+	var options []string
+	for _, el := range r.proto.Elements {
+		switch v := el.(type) {
+		case *protobuf.Syntax:
+			fmt.Fprintf(&b, "syntax = %q;\n\n", v.Value)
+		case *protobuf.Option:
+			// Only the options in single lines are copied.
+			if line := strings.TrimSpace(r.lines[v.Position.Line-1]); strings.HasPrefix(line, "option") && strings.HasSuffix(line, ";") {
+				options = append(options, line)
+			}
+		}
+	}
+	if r.pkg != "" {
+		fmt.Fprintf(&b, "package %s;\n\n", r.pkg)
+	}
+	for _, path := range imports {
+		fmt.Fprintf(&b, "import %q;\n", path)
+	}
+	if len(imports) > 0 {
+		b.WriteString("\n")
+	}
+	for _, o := range options {
+		b.WriteString(o + "\n")
+	}
+	if len(options) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// inline returns the action which moves a top-level declaration referred to only once
+// into the message referring to it.
+func (r *refactorer) inline(d *declaration) *CodeAction {
+	if len(d.parents) != 0 {
+		return nil
+	}
+	refs := typeReferences(r.ctx, r.view, d.sym)
+	if len(refs) != 1 {
+		return nil
+	}
+	ref := refs[0]
+	if ref.uri != r.uri || ref.sym.Name != d.sym.Name || d.block.contains(int(ref.rng.Start.Line)) {
+		return nil
+	}
+	user, ok := findMessage(r.proto, r.pkg, ref.scope)
+	if !ok {
+		return nil
+	}
+	line, ok := openBraceLine(r.lines, user.Position.Line-1)
+	if !ok || !strings.HasSuffix(strings.TrimSpace(r.lines[line]), "{") {
+		return nil
+	}
+
+	indent := indentation(r.lines[user.Position.Line-1]) + indentUnit(r.view.Config(r.uri).Format)
+	text := reindent(applyEdits(r.lines, d.block, nil), d.block.indent, indent)
+	if line+1 < len(r.lines) && strings.TrimSpace(r.lines[line+1]) != "}" {
+		text += "\n"
+	}
+	edits := []protocol.TextEdit{
+		removeBlock(r.lines, d.block),
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: float64(line + 1)},
+				End:   protocol.Position{Line: float64(line + 1)},
+			},
+			NewText: text,
+		},
+	}
+	if ref.typ != d.name {
+		edits = append(edits, protocol.TextEdit{Range: ref.rng, NewText: d.name})
+	}
+	return &CodeAction{
+		Title: fmt.Sprintf("Inline %q into %q", d.name, user.Name),
+		Kind:  protocol.RefactorInline,
+		Edit: &WorkspaceEdit{
+			Changes: map[uri.URI][]protocol.TextEdit{r.uri: edits},
+		},
+	}
+}
+
+// findMessage returns the message with a given fully-qualified name declared in a file.
+func findMessage(proto *protobuf.Proto, pkg, name string) (*protobuf.Message, bool) {
+	var found *protobuf.Message
+	var walk func(scope string, elements []protobuf.Visitee)
+	walk = func(scope string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			if m, ok := el.(*protobuf.Message); ok && !m.IsExtend && found == nil {
+				full := qualify(scope, m.Name)
+				if full == name {
+					found = m
+					return
+				}
+				walk(full, m.Elements)
+			}
+		}
+	}
+	walk(pkg, proto.Elements)
+	return found, found != nil
+}
+
+// resolvedImports returns the URIs of the files which a file imports.
+func resolvedImports(view View, from uri.URI, proto *protobuf.Proto) []uri.URI {
+	var uris []uri.URI
+	for _, el := range proto.Elements {
+		if i, ok := el.(*protobuf.Import); ok {
+			if u, ok := ResolveImport(view, from, i.Filename); ok {
+				uris = append(uris, u)
+			}
+		}
+	}
+	return uris
+}
+
+func containsURI(uris []uri.URI, u uri.URI) bool {
+	for _, v := range uris {
+		if v == u {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithin returns true if a fully-qualified name is the same as or nested in another one.
+func isWithin(name, parent string) bool {
+	return name == parent || strings.HasPrefix(name, parent+".")
+}
+
+// findBlock returns the block of a declaration at a given 1-based position with its leading comment.
+// It returns false unless the declaration starts and ends its lines.
+func findBlock(lines []string, line, column int, comment *protobuf.Comment) (block, bool) {
+	l, c := line-1, column-1
+	if l < 0 || l >= len(lines) || c < 0 || c > len(lines[l]) || strings.TrimSpace(lines[l][:c]) != "" {
+		return block{}, false
+	}
+
+	start := l
+	if comment != nil && comment.Position.Line < line {
+		cl, cc := comment.Position.Line-1, comment.Position.Column-1
+		if cl >= 0 && cc >= 0 && cc <= len(lines[cl]) && strings.TrimSpace(lines[cl][:cc]) == "" {
+			start = cl
+		}
+	}
+
+	endLine, endColumn, ok := matchBrace(lines, l, c)
+	if !ok {
+		return block{}, false
+	}
+	if rest := strings.TrimSpace(lines[endLine][endColumn+1:]); rest != "" && !strings.HasPrefix(rest, "//") {
+		return block{}, false
+	}
+	return block{start: start, end: endLine, indent: indentation(lines[l])}, true
+}
+
+// matchBrace returns the 0-based position of the brace which closes the first opening brace
+// at or after a given 0-based position. Braces in strings and comments are skipped.
+func matchBrace(lines []string, line, column int) (int, int, bool) {
+	depth := 0
+	inComment := false
+	for l := line; l < len(lines); l++ {
+		s := lines[l]
+		c := 0
+		if l == line {
+			c = column
+		}
+		for ; c < len(s); c++ {
+			if inComment {
+				if strings.HasPrefix(s[c:], "*/") {
+					inComment = false
+					c++
+				}
+				continue
+			}
+			switch ch := s[c]; {
+			case strings.HasPrefix(s[c:], "//"):
+				c = len(s)
+			case strings.HasPrefix(s[c:], "/*"):
+				inComment = true
+				c++
+			case ch == '"' || ch == '\'':
+				for c++; c < len(s) && s[c] != ch; c++ {
+					if s[c] == '\\' {
+						c++
+					}
+				}
+			case ch == '{':
+				depth++
+			case ch == '}':
+				depth--
+				if depth == 0 {
+					return l, c, true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// splitEdits splits edits into the ones in a block and the others.
+func splitEdits(edits []protocol.TextEdit, b block) (in, out []protocol.TextEdit) {
+	for _, e := range edits {
+		if b.contains(int(e.Range.Start.Line)) {
+			in = append(in, e)
+		} else {
+			out = append(out, e)
+		}
+	}
+	return in, out
+}
+
+// applyEdits returns the text of a block with given edits in single lines applied.
+func applyEdits(lines []string, b block, edits []protocol.TextEdit) string {
+	text := append([]string(nil), lines[b.start:b.end+1]...)
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].Range.Start.Line != edits[j].Range.Start.Line {
+			return edits[i].Range.Start.Line > edits[j].Range.Start.Line
+		}
+		return edits[i].Range.Start.Character > edits[j].Range.Start.Character
+	})
+	for _, e := range edits {
+		l := int(e.Range.Start.Line) - b.start
+		s := text[l]
+		text[l] = s[:int(e.Range.Start.Character)] + e.NewText + s[int(e.Range.End.Character):]
+	}
+	return strings.Join(text, "\n") + "\n"
+}
+
+// reindent replaces the indentation of the lines in a text from one to another.
+func reindent(text, from, to string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			b.WriteString(strings.TrimLeft(line, " \t"))
+			continue
+		}
+		if strings.HasPrefix(line, from) {
+			line = line[len(from):]
+		} else {
+			line = strings.TrimLeft(line, " \t")
+		}
+		b.WriteString(to + line)
+	}
+	return b.String()
+}
+
+// removeBlock returns the edit which removes the lines of a block.
+// A preceding blank line is also removed if the block is followed by another blank line or a closing brace.
+func removeBlock(lines []string, b block) protocol.TextEdit {
+	start := b.start
+	if start > 0 && strings.TrimSpace(lines[start-1]) == "" {
+		if next := b.end + 1; next >= len(lines) || strings.TrimSpace(lines[next]) == "" || strings.TrimSpace(lines[next]) == "}" {
+			start--
+		}
+	}
+	end := protocol.Position{Line: float64(b.end + 1)}
+	if b.end+1 >= len(lines) {
+		end = protocol.Position{Line: float64(b.end), Character: float64(len(lines[b.end]))}
+	}
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(start)},
+			End:   end,
+		},
+	}
+}
+
+// insertAfter returns the edit which inserts a text after a 0-based line separated by a blank line.
+func insertAfter(lines []string, line int, text string) protocol.TextEdit {
+	pos := protocol.Position{Line: float64(line + 1)}
+	text = "\n" + text
+	if line+1 >= len(lines) {
+		// The line is the last one without a newline.
+		pos = protocol.Position{Line: float64(line), Character: float64(len(lines[line]))}
+		text = "\n" + text
+	}
+	return protocol.TextEdit{
+		Range:   protocol.Range{Start: pos, End: pos},
+		NewText: text,
+	}
+}
+
+// lastLine returns the last 0-based line which is not blank.
+func lastLine(lines []string) int {
+	for l := len(lines) - 1; l > 0; l-- {
+		if strings.TrimSpace(lines[l]) != "" {
+			return l
+		}
+	}
+	return 0
+}
+
+// fileName returns the name of the file for a declaration, e.g. "foo_bar.proto" for "FooBar".
+func fileName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String() + ".proto"
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestRefactorings(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		file        string
+		line        float64
+		createFiles bool
+		title       string
+		want        map[string]string
+	}{
+		{
+			name: "extract",
+			files: map[string]string{
+				"foo.proto": `syntax = "proto3";
+package foo;
+
+message Outer {
+  // Inner is nested.
+  message Inner {
+    Sibling sibling = 1;
+  }
+  message Sibling {}
+  Inner inner = 1;
+}
+`,
+				"bar.proto": `syntax = "proto3";
+package bar;
+import "foo.proto";
+message Bar {
+  foo.Outer.Inner inner = 1;
+}
+`,
+			},
+			file:  "foo.proto",
+			line:  5,
+			title: `Extract "Inner" to the top level`,
+			want: map[string]string{
+				"foo.proto": `syntax = "proto3";
+package foo;
+
+message Outer {
+  message Sibling {}
+  Inner inner = 1;
+}
+
+// Inner is nested.
+message Inner {
+  Outer.Sibling sibling = 1;
+}
+`,
+				"bar.proto": `syntax = "proto3";
+package bar;
+import "foo.proto";
+message Bar {
+  foo.Inner inner = 1;
+}
+`,
+			},
+		},
+		{
+			name: "move to an existing file",
+			files: map[string]string{
+				"a.proto": `syntax = "proto3";
+package foo;
+
+message Foo {
+  Bar bar = 1;
+}
+
+message Bar {}
+`,
+				"b.proto": `syntax = "proto3";
+package foo;
+
+message Baz {}
+`,
+				"user.proto": `syntax = "proto3";
+package foo;
+import "a.proto";
+message User {
+  Foo foo = 1;
+  Bar bar = 2;
+}
+`,
+			},
+			file:  "a.proto",
+			line:  3,
+			title: `Move "Foo" to "b.proto"`,
+			want: map[string]string{
+				"a.proto": `syntax = "proto3";
+package foo;
+
+message Bar {}
+`,
+				"b.proto": `syntax = "proto3";
+package foo;
+
+import "a.proto";
+
+message Baz {}
+
+message Foo {
+  Bar bar = 1;
+}
+`,
+				"user.proto": `syntax = "proto3";
+package foo;
+import "a.proto";
+import "b.proto";
+message User {
+  Foo foo = 1;
+  Bar bar = 2;
+}
+`,
+			},
+		},
+		{
+			name: "move to a new file",
+			files: map[string]string{
+				"a.proto": `syntax = "proto3";
+package foo;
+
+option go_package = "example.com/foo";
+
+message Foo {}
+
+message FooBar {
+  Foo foo = 1;
+}
+`,
+			},
+			file:        "a.proto",
+			line:        7,
+			createFiles: true,
+			title:       `Move "FooBar" to a new file "foo_bar.proto"`,
+			want: map[string]string{
+				"a.proto": `syntax = "proto3";
+package foo;
+
+option go_package = "example.com/foo";
+
+message Foo {}
+`,
+				"foo_bar.proto": `syntax = "proto3";
+
+package foo;
+
+import "a.proto";
+
+option go_package = "example.com/foo";
+
+message FooBar {
+  Foo foo = 1;
+}
+`,
+			},
+		},
+		{
+			name: "inline",
+			files: map[string]string{
+				"a.proto": `syntax = "proto3";
+package foo;
+
+message Foo {
+  string name = 1;
+}
+
+message Bar {
+  int32 id = 1;
+  foo.Foo foo = 2;
+}
+`,
+			},
+			file:  "a.proto",
+			line:  3,
+			title: `Inline "Foo" into "Bar"`,
+			want: map[string]string{
+				"a.proto": `syntax = "proto3";
+package foo;
+
+message Bar {
+  message Foo {
+    string name = 1;
+  }
+
+  int32 id = 1;
+  Foo foo = 2;
+}
+`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+			for name, text := range tt.files {
+				view.DidOpen(uri.File("/workspace/"+name), []byte(text))
+			}
+			source := uri.File("/workspace/" + tt.file)
+
+			rng := protocol.Range{Start: protocol.Position{Line: tt.line}, End: protocol.Position{Line: tt.line}}
+			actions, err := Refactorings(ctx, view, source, rng, tt.createFiles)
+			if err != nil {
+				t.Fatalf("Refactorings() error = %v", err)
+			}
+			var action *CodeAction
+			var titles []string
+			for i := range actions {
+				titles = append(titles, actions[i].Title)
+				if actions[i].Title == tt.title {
+					action = &actions[i]
+				}
+			}
+			if action == nil {
+				t.Fatalf("Refactorings() = %v, want %q", titles, tt.title)
+			}
+
+			got := make(map[string]string)
+			for name, text := range tt.files {
+				got[name] = text
+			}
+			apply := func(u uri.URI, edits []protocol.TextEdit) {
+				name := strings.TrimPrefix(u.Filename(), "/workspace/")
+				got[name] = applyTextEdits(got[name], edits)
+			}
+			for u, edits := range action.Edit.Changes {
+				apply(u, edits)
+			}
+			for _, c := range action.Edit.DocumentChanges {
+				switch c := c.(type) {
+				case protocol.CreateFile:
+					got[strings.TrimPrefix(c.URI.Filename(), "/workspace/")] = ""
+				case protocol.TextDocumentEdit:
+					apply(c.TextDocument.URI, c.Edits)
+				}
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := map[string]string{
+		"Foo":        "foo.proto",
+		"FooBar":     "foo_bar.proto",
+		"HTTPServer": "http_server.proto",
+		"V2Request":  "v2_request.proto",
+	}
+	for name, want := range tests {
+		if got := fileName(name); got != want {
+			t.Errorf("fileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// applyTextEdits applies edits to a text. Edits at the same position are applied in the order.
+func applyTextEdits(text string, edits []protocol.TextEdit) string {
+	lines := strings.SplitAfter(text, "\n")
+	offset := func(p protocol.Position) int {
+		n := 0
+		for l := 0; l < int(p.Line) && l < len(lines); l++ {
+			n += len(lines[l])
+		}
+		return n + int(p.Character)
+	}
+	sorted := append([]protocol.TextEdit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return offset(sorted[i].Range.Start) < offset(sorted[j].Range.Start)
+	})
+	for i := len(sorted) - 1; i >= 0; i-- {
+		e := sorted[i]
+		text = text[:offset(e.Range.Start)] + e.NewText + text[offset(e.Range.End):]
+	}
+	return text
+}
@@ -0,0 +1,293 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/types"
+)
+
+// typeRef is a reference to a message or an enum in a proto file.
+type typeRef struct {
+	// typ is the type name as written.
+	typ string
+	// scope is the fully-qualified name of the message the reference appears in, or the package.
+	scope string
+	// rng is the range of the type name.
+	rng protocol.Range
+	// found is false if the type name can't be found in the content,
+	// in which case rng covers the character at the position of the element.
+	found bool
+}
+
+// typeRefs returns the references to messages and enums in a proto file with given content lines.
+// Scalar types are not included.
+func typeRefs(proto *protobuf.Proto, lines []string) []typeRef {
+	if proto == nil {
+		return nil
+	}
+
+	pkg := packageName(proto)
+
+	var refs []typeRef
+	add := func(scope, typ string, line, column int) (int, int) {
+		if typ == "" || isScalar(typ) {
+			return line, column
+		}
+		ref := typeRef{typ: typ, scope: scope}
+		if l, c, ok := findWord(lines, line, column, typ); ok {
+			ref.rng = protocol.Range{
+				Start: protocol.Position{Line: float64(l - 1), Character: float64(c - 1)},
+				End:   protocol.Position{Line: float64(l - 1), Character: float64(c - 1 + len(typ))},
+			}
+			ref.found = true
+			line, column = l, c+len(typ)
+		} else {
+			ref.rng = nameRange(lines, line, column, "")
+		}
+		refs = append(refs, ref)
+		return line, column
+	}
+
+	var walk func(scope string, elements []protobuf.Visitee)
+	walk = func(scope string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					// The fields of an extension are declared in the enclosing scope.
+					add(scope, v.Name, v.Position.Line, v.Position.Column)
+					walk(scope, v.Elements)
+					continue
+				}
+				walk(qualify(scope, v.Name), v.Elements)
+			case *protobuf.Oneof:
+				walk(scope, v.Elements)
+			case *protobuf.NormalField:
+				add(scope, v.Type, v.Position.Line, v.Position.Column)
+			case *protobuf.OneOfField:
+				add(scope, v.Type, v.Position.Line, v.Position.Column)
+			case *protobuf.MapField:
+				add(scope, v.Type, v.Position.Line, v.Position.Column)
+			case *protobuf.Service:
+				walk(scope, v.Elements)
+			case *protobuf.RPC:
+				// The request type is searched after the name of the RPC and the response type
+				// after the returns keyword since a type may have the same name as the RPC.
+				line, column := v.Position.Line, v.Position.Column
+				if l, c, ok := findWord(lines, line, column, v.Name); ok {
+					line, column = l, c+len(v.Name)
+				}
+				line, column = add(scope, v.RequestType, line, column)
+				if l, c, ok := findWord(lines, line, column, "returns"); ok {
+					line, column = l, c+len("returns")
+				}
+				add(scope, v.ReturnsType, line, column)
+			}
+		}
+	}
+	walk(pkg, proto.Elements)
+
+	return refs
+}
+
+// packageName returns the package declared in a proto file.
+func packageName(proto *protobuf.Proto) string {
+	for _, el := range proto.Elements {
+		if p, ok := el.(*protobuf.Package); ok {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// findWord returns the 1-based position of the first occurrence of a word at or after
+// a given 1-based position. A word doesn't match a part of a longer identifier or a qualified name.
+// Only a few lines are searched since a declaration rarely spans more.
+func findWord(lines []string, line, column int, word string) (int, int, bool) {
+	const maxLines = 10
+
+	if word == "" {
+		return 0, 0, false
+	}
+	c := column - 1
+	if c < 0 {
+		c = 0
+	}
+	for l := line - 1; l >= 0 && l < len(lines) && l < line-1+maxLines; l++ {
+		s := lines[l]
+		for c <= len(s) {
+			i := strings.Index(s[c:], word)
+			if i < 0 {
+				break
+			}
+			start, end := c+i, c+i+len(word)
+			if (start == 0 || !isWordChar(s[start-1])) && (end == len(s) || !isWordChar(s[end])) {
+				return l + 1, start + 1, true
+			}
+			c = start + 1
+		}
+		c = 0
+	}
+	return 0, 0, false
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '.' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isScalar(typ string) bool {
+	for _, t := range types.BuildInProtoTypes {
+		if string(t) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Reference is a location of a name which refers to a message or an enum.
+type Reference struct {
+	URI   uri.URI
+	Range protocol.Range
+}
+
+// References returns the references to a message or an enum in the files known to a view.
+// A reference to a type nested in the target such as "Foo.Bar" for "Foo" refers to it
+// with its component, so the range covers only "Foo".
+func References(ctx context.Context, view View, target Symbol) []Reference {
+	var refs []Reference
+	for _, r := range typeReferences(ctx, view, target) {
+		if rng, ok := componentRange(r.typeRef, target.Name, r.sym.Name); ok {
+			refs = append(refs, Reference{URI: r.uri, Range: rng})
+		}
+	}
+	return refs
+}
+
+// ReferenceLocations returns the locations of the references to the message or the enum which is declared
+// or referred to at a given position, including the location of the declaration if includeDeclaration is true.
+func ReferenceLocations(ctx context.Context, view View, uri uri.URI, pos protocol.Position, includeDeclaration bool) ([]protocol.Location, error) {
+	proto, lines, err := readProto(ctx, view, uri)
+	if err != nil || proto == nil {
+		return nil, err
+	}
+
+	var (
+		target Symbol
+		found  bool
+	)
+	for _, s := range fileSymbols(uri, proto) {
+		if s.Kind != SymbolKindMessage && s.Kind != SymbolKindEnum {
+			continue
+		}
+		name := s.Name[strings.LastIndex(s.Name, ".")+1:]
+		if l, c, ok := findWord(lines, s.Line, s.Column, name); ok && contains(nameRange(lines, l, c, name), pos) {
+			target, found = s, true
+			break
+		}
+	}
+	if !found {
+		for _, ref := range typeRefs(proto, lines) {
+			if ref.found && contains(ref.rng, pos) {
+				target, found = ResolveType(view, uri, ref.scope, ref.typ)
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	var locations []protocol.Location
+	if includeDeclaration {
+		locations = append(locations, symbolLocation(ctx, view, target))
+	}
+	for _, r := range References(ctx, view, target) {
+		locations = append(locations, protocol.Location{URI: r.URI, Range: r.Range})
+	}
+	return locations, nil
+}
+
+// resolvedRef is a type reference with the symbol it resolves to.
+type resolvedRef struct {
+	typeRef
+	uri uri.URI
+	sym Symbol
+}
+
+// typeReferences returns the type references which resolve to a message or an enum,
+// or the types nested in it, in the files known to a view.
+func typeReferences(ctx context.Context, view View, target Symbol) []resolvedRef {
+	var refs []resolvedRef
+	for _, u := range view.KnownFiles() {
+		f, err := view.GetFile(u)
+		if err != nil {
+			continue
+		}
+		pf, ok := f.(ProtoFile)
+		if !ok || pf.Proto() == nil {
+			continue
+		}
+		data, _, err := pf.Read(ctx)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(data), "\n")
+
+		for _, ref := range typeRefs(pf.Proto().Protobuf(), lines) {
+			if !ref.found {
+				continue
+			}
+			sym, ok := ResolveType(view, u, ref.scope, ref.typ)
+			if !ok || sym.URI != target.URI {
+				continue
+			}
+			if sym.Name != target.Name && !strings.HasPrefix(sym.Name, target.Name+".") {
+				continue
+			}
+			refs = append(refs, resolvedRef{typeRef: ref, uri: u, sym: sym})
+		}
+	}
+	return refs
+}
+
+// componentRange returns the range of the component of a type reference which refers to
+// a symbol named target, given the name of the symbol which the whole reference resolves to.
+// For example, it is the range of "Foo" in "pkg.Foo.Bar" for both "pkg.Foo" and "pkg.Foo.Bar".
+func componentRange(ref typeRef, target, resolved string) (protocol.Range, bool) {
+	typ := strings.TrimPrefix(ref.typ, ".")
+	written := strings.Split(typ, ".")
+	depth := strings.Count(resolved, ".") - strings.Count(target, ".")
+	i := len(written) - 1 - depth
+	if i < 0 {
+		return protocol.Range{}, false
+	}
+
+	offset := len(ref.typ) - len(typ)
+	for _, c := range written[:i] {
+		offset += len(c) + 1
+	}
+	start := ref.rng.Start
+	start.Character += float64(offset)
+	end := start
+	end.Character += float64(len(written[i]))
+	return protocol.Range{Start: start, End: end}, true
+}
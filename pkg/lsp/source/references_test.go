@@ -0,0 +1,139 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestReferences(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	typesURI := uri.File("/workspace/types.proto")
+	fooURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(typesURI, []byte(`syntax = "proto3";
+package pkg;
+message Outer {
+  message Inner {}
+}
+`))
+	view.DidOpen(fooURI, []byte(`syntax = "proto3";
+package pkg;
+import "types.proto";
+message Foo {
+  Outer outer = 1;
+  repeated Outer.Inner inner = 2;
+  map<string, .pkg.Outer.Inner> inners = 3;
+}
+service FooService {
+  rpc Outer(Outer) returns (Foo);
+}
+`))
+
+	at := func(line, start, end float64) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: line, Character: start},
+			End:   protocol.Position{Line: line, Character: end},
+		}
+	}
+	want := []Reference{
+		{URI: fooURI, Range: at(4, 2, 7)},
+		{URI: fooURI, Range: at(5, 11, 16)},
+		{URI: fooURI, Range: at(6, 19, 24)},
+		{URI: fooURI, Range: at(9, 12, 17)},
+	}
+	got := References(ctx, view, Symbol{Name: "pkg.Outer", Kind: SymbolKindMessage, URI: typesURI})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("References() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReferenceLocations(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	typesURI := uri.File("/workspace/types.proto")
+	fooURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(typesURI, []byte(`syntax = "proto3";
+package pkg;
+message Outer {}
+`))
+	view.DidOpen(fooURI, []byte(`syntax = "proto3";
+package pkg;
+import "types.proto";
+message Foo {
+  Outer outer = 1;
+}
+`))
+
+	location := func(u uri.URI, line, start, end float64) protocol.Location {
+		return protocol.Location{
+			URI: u,
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line, Character: start},
+				End:   protocol.Position{Line: line, Character: end},
+			},
+		}
+	}
+	tests := []struct {
+		name               string
+		uri                uri.URI
+		pos                protocol.Position
+		includeDeclaration bool
+		want               []protocol.Location
+	}{
+		{
+			name:               "declaration",
+			uri:                typesURI,
+			pos:                protocol.Position{Line: 2, Character: 9},
+			includeDeclaration: true,
+			want: []protocol.Location{
+				location(typesURI, 2, 8, 13),
+				location(fooURI, 4, 2, 7),
+			},
+		},
+		{
+			name: "reference",
+			uri:  fooURI,
+			pos:  protocol.Position{Line: 4, Character: 3},
+			want: []protocol.Location{
+				location(fooURI, 4, 2, 7),
+			},
+		},
+		{
+			name: "field name",
+			uri:  fooURI,
+			pos:  protocol.Position{Line: 4, Character: 9},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReferenceLocations(ctx, view, tt.uri, tt.pos, tt.includeDeclaration)
+			if err != nil {
+				t.Fatalf("ReferenceLocations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReferenceLocations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
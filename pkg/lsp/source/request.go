@@ -0,0 +1,300 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/uri"
+)
+
+// grpcurlAddress is the address of the server in grpcurl command lines, which is meant to be replaced.
+const grpcurlAddress = "localhost:50051"
+
+// shellSafeRegexp matches the strings which don't need quoting in shell command lines.
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_./:=@+-]+$`)
+
+// wrapperTypes maps the names of the well-known wrapper types to the scalar types they wrap.
+var wrapperTypes = map[string]string{
+	"google.protobuf.DoubleValue": "double",
+	"google.protobuf.FloatValue":  "float",
+	"google.protobuf.Int64Value":  "int64",
+	"google.protobuf.UInt64Value": "uint64",
+	"google.protobuf.Int32Value":  "int32",
+	"google.protobuf.UInt32Value": "uint32",
+	"google.protobuf.BoolValue":   "bool",
+	"google.protobuf.StringValue": "string",
+	"google.protobuf.BytesValue":  "bytes",
+}
+
+// RequestJSON returns a request of an RPC declared in the file for a given URI as JSON,
+// in which the fields have their default values. rpc is the fully-qualified name of the RPC.
+// Only the first field of each oneof is set.
+func RequestJSON(ctx context.Context, view View, uri uri.URI, rpc string) (string, error) {
+	v, err := requestValue(ctx, view, uri, rpc)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// GrpcurlCommand returns a grpcurl command line which calls an RPC declared in the file for a given URI
+// with the request returned by RequestJSON. rpc is the fully-qualified name of the RPC.
+// The import path is relative to the folder of the view if the file is in it.
+func GrpcurlCommand(ctx context.Context, view View, uri uri.URI, rpc string) (string, error) {
+	v, err := requestValue(ctx, view, uri, rpc)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	filename := uri.Filename()
+	dir, path := filepath.Dir(filename), filepath.Base(filename)
+	if p, ok := ImportPath(view, uri, uri); ok {
+		dir, path = strings.TrimSuffix(strings.TrimSuffix(filename, filepath.FromSlash(p)), string(filepath.Separator)), p
+	}
+	if rel, err := filepath.Rel(view.Folder().Filename(), dir); err == nil && !strings.HasPrefix(rel, "..") {
+		dir = rel
+	}
+
+	method := rpc
+	if i := strings.LastIndex(rpc, "."); i >= 0 {
+		method = rpc[:i] + "/" + rpc[i+1:]
+	}
+	return fmt.Sprintf("grpcurl -plaintext -import-path %s -proto %s -d %s %s %s",
+		shellQuote(filepath.ToSlash(dir)), shellQuote(path), shellQuote(string(data)), grpcurlAddress, method), nil
+}
+
+// requestValue returns the value of the request of an RPC to encode as JSON.
+func requestValue(ctx context.Context, view View, uri uri.URI, rpc string) (interface{}, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return nil, fmt.Errorf("%s can't be parsed", uri)
+	}
+	proto := pf.Proto().Protobuf()
+	pkg := packageName(proto)
+
+	var found *protobuf.RPC
+	for _, el := range proto.Elements {
+		if s, ok := el.(*protobuf.Service); ok {
+			for _, el := range s.Elements {
+				if r, ok := el.(*protobuf.RPC); ok && qualify(qualify(pkg, s.Name), r.Name) == rpc {
+					found = r
+				}
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("RPC %q is not declared in %s", rpc, uri)
+	}
+
+	b := &requestBuilder{ctx: ctx, view: view, visiting: make(map[string]bool)}
+	return b.value(uri, pkg, found.RequestType), nil
+}
+
+// requestBuilder builds the values of messages with the default values of the fields.
+type requestBuilder struct {
+	ctx  context.Context
+	view View
+	// visiting is the set of the messages being built, which are empty when they are nested recursively.
+	visiting map[string]bool
+}
+
+// value returns the default value of a type referred to in a given file and scope.
+func (b *requestBuilder) value(from uri.URI, scope, typ string) interface{} {
+	switch typ {
+	case "double", "float", "int32", "uint32", "sint32", "fixed32", "sfixed32":
+		return 0
+	case "int64", "uint64", "sint64", "fixed64", "sfixed64":
+		// 64-bit integers are strings in JSON.
+		return "0"
+	case "bool":
+		return false
+	case "string", "bytes":
+		return ""
+	}
+
+	name := strings.TrimPrefix(typ, ".")
+	sym, resolved := ResolveType(b.view, from, scope, typ)
+	if resolved {
+		name = sym.Name
+	}
+	switch name {
+	case "google.protobuf.Timestamp":
+		return "1970-01-01T00:00:00Z"
+	case "google.protobuf.Duration":
+		return "0s"
+	case "google.protobuf.Struct":
+		return jsonObject{}
+	case "google.protobuf.Value":
+		return nil
+	}
+	if scalar, ok := wrapperTypes[name]; ok {
+		return b.value(from, scope, scalar)
+	}
+	if !resolved {
+		return nil
+	}
+
+	f, err := b.view.GetFile(sym.URI)
+	if err != nil {
+		return nil
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return nil
+	}
+	proto := pf.Proto().Protobuf()
+	if sym.Kind == SymbolKindEnum {
+		if e, ok := findEnum(proto, packageName(proto), sym.Name); ok {
+			if values := enumValues(e); len(values) > 0 {
+				return values[0].Name
+			}
+		}
+		return 0
+	}
+	m, ok := findMessage(proto, packageName(proto), sym.Name)
+	if !ok || b.visiting[sym.Name] {
+		return jsonObject{}
+	}
+	b.visiting[sym.Name] = true
+	defer delete(b.visiting, sym.Name)
+
+	obj := jsonObject{}
+	for _, el := range m.Elements {
+		switch v := el.(type) {
+		case *protobuf.NormalField:
+			value := b.value(sym.URI, sym.Name, v.Type)
+			if v.Repeated {
+				value = []interface{}{value}
+			}
+			obj = append(obj, jsonMember{name: jsonName(v.Field), value: value})
+		case *protobuf.MapField:
+			obj = append(obj, jsonMember{name: jsonName(v.Field), value: jsonObject{}})
+		case *protobuf.Oneof:
+			for _, el := range v.Elements {
+				if field, ok := el.(*protobuf.OneOfField); ok {
+					obj = append(obj, jsonMember{name: jsonName(field.Field), value: b.value(sym.URI, sym.Name, field.Type)})
+					break
+				}
+			}
+		}
+	}
+	return obj
+}
+
+// findEnum returns the enum with a given fully-qualified name declared in a file.
+func findEnum(proto *protobuf.Proto, pkg, name string) (*protobuf.Enum, bool) {
+	var found *protobuf.Enum
+	var walk func(scope string, elements []protobuf.Visitee)
+	walk = func(scope string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Enum:
+				if qualify(scope, v.Name) == name && found == nil {
+					found = v
+				}
+			case *protobuf.Message:
+				if !v.IsExtend {
+					walk(qualify(scope, v.Name), v.Elements)
+				}
+			}
+		}
+	}
+	walk(pkg, proto.Elements)
+	return found, found != nil
+}
+
+// jsonName returns the name of a field in JSON, which is the json_name option or the lowerCamelCase name.
+func jsonName(f *protobuf.Field) string {
+	for _, o := range f.Options {
+		if o.Name == "json_name" {
+			return o.Constant.Source
+		}
+	}
+	var b strings.Builder
+	upper := false
+	for _, r := range f.Name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && 'a' <= r && r <= 'z':
+			b.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(r)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+// jsonObject is a JSON object which keeps the order of its members.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	name  string
+	value interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(m.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// shellQuote quotes a string for POSIX shells if needed.
+func shellQuote(s string) string {
+	if shellSafeRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
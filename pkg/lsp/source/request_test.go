@@ -0,0 +1,109 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-language-server/uri"
+)
+
+func TestRequestJSON(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	fileURI := uri.File("/workspace/foo/v1/foo.proto")
+	view.DidOpen(fileURI, []byte(`syntax = "proto3";
+package foo.v1;
+import "google/protobuf/timestamp.proto";
+message GetFooRequest {
+  string foo_id = 1;
+  int64 version = 2 [json_name = "ver"];
+  repeated Kind kinds = 3;
+  Filter filter = 4;
+  map<string, string> labels = 5;
+  oneof page {
+    int32 page_size = 6;
+    string page_token = 7;
+  }
+  google.protobuf.Timestamp time = 8;
+}
+message Filter {
+  Filter not = 1;
+  bool deleted = 2;
+}
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+}
+service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooRequest);
+}
+`))
+
+	got, err := RequestJSON(ctx, view, fileURI, "foo.v1.FooService.GetFoo")
+	if err != nil {
+		t.Fatalf("RequestJSON() error = %v", err)
+	}
+	want := `{
+  "fooId": "",
+  "ver": "0",
+  "kinds": [
+    "KIND_UNSPECIFIED"
+  ],
+  "filter": {
+    "not": {},
+    "deleted": false
+  },
+  "labels": {},
+  "pageSize": 0,
+  "time": "1970-01-01T00:00:00Z"
+}`
+	if got != want {
+		t.Errorf("RequestJSON() = %s, want %s", got, want)
+	}
+
+	got, err = GrpcurlCommand(ctx, view, fileURI, "foo.v1.FooService.GetFoo")
+	if err != nil {
+		t.Fatalf("GrpcurlCommand() error = %v", err)
+	}
+	want = `grpcurl -plaintext -import-path . -proto foo/v1/foo.proto -d '{"fooId":"","ver":"0","kinds":["KIND_UNSPECIFIED"],"filter":{"not":{},"deleted":false},"labels":{},"pageSize":0,"time":"1970-01-01T00:00:00Z"}' localhost:50051 foo.v1.FooService/GetFoo`
+	if got != want {
+		t.Errorf("GrpcurlCommand() = %s, want %s", got, want)
+	}
+
+	if _, err := RequestJSON(ctx, view, fileURI, "foo.v1.FooService.ListFoos"); err == nil {
+		t.Error("RequestJSON() error = nil, want an error for an unknown RPC")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "foo/bar.proto", want: "foo/bar.proto"},
+		{s: "my dir", want: "'my dir'"},
+		{s: `{"a":"it's"}`, want: `'{"a":"it'\''s"}'`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.s, func(t *testing.T) {
+			if got := shellQuote(tt.s); got != tt.want {
+				t.Errorf("shellQuote() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// Codes of the diagnostics reported by analyzing a file together with the files it imports.
+const (
+	CodeUnresolvedType       = "UNRESOLVED_TYPE"
+	CodeMissingImport        = "MISSING_IMPORT"
+	CodeUnusedImport         = "UNUSED_IMPORT"
+	CodeDuplicateFieldNumber = "DUPLICATE_FIELD_NUMBER"
+	CodeUnknownOption        = "UNKNOWN_OPTION"
+	CodeInvalidOptionValue   = "INVALID_OPTION_VALUE"
+)
+
+// wellKnownPrefix is the prefix of the import paths of the well-known types,
+// which are often not found in the include paths since protoc bundles them.
+const wellKnownPrefix = "google/protobuf/"
+
+// customOptionRegexp matches the start of a custom option, e.g. "option (foo)" or "[(foo)".
+var customOptionRegexp = regexp.MustCompile(`\boption\s*\(|[\[,]\s*\(`)
+
+// semanticProblem is a problem found by analyzing a file together with the files it imports.
+type semanticProblem struct {
+	code     string
+	severity protocol.DiagnosticSeverity
+	message  string
+	rng      protocol.Range
+
+	// importPath is the path to import for a missing import, or the path of an unused import.
+	importPath string
+	// number is a free field number of the message for a duplicate field number, or 0 if there is none.
+	number int
+}
+
+// fileImport is an import declared in a proto file.
+type fileImport struct {
+	*protobuf.Import
+	uri      uri.URI
+	resolved bool
+	// used is true if a reference in the importing file resolves to a symbol declared
+	// in the imported file or the ones it imports publicly.
+	used bool
+}
+
+// semanticProblems analyzes the references and the declarations in a given file
+// which can't be checked without the files it imports.
+func semanticProblems(ctx context.Context, view View, pf ProtoFile) ([]semanticProblem, error) {
+	if pf.ParseError() != nil || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	proto := pf.Proto().Protobuf()
+	from := pf.URI()
+
+	// complete is false if some declarations visible from the file are unknown,
+	// in which case a type which is not found may be declared in them.
+	complete := true
+
+	// providers maps each file visible from the file to the imports which make it visible.
+	providers := map[uri.URI][]*fileImport{from: nil}
+	var imports []*fileImport
+	for _, el := range proto.Elements {
+		i, ok := el.(*protobuf.Import)
+		if !ok {
+			continue
+		}
+		imp := &fileImport{Import: i}
+		imports = append(imports, imp)
+		imp.uri, imp.resolved = ResolveImport(view, from, i.Filename)
+		if !imp.resolved {
+			if !strings.HasPrefix(i.Filename, wellKnownPrefix) {
+				complete = false
+			}
+			continue
+		}
+		for _, u := range publicClosure(view, imp.uri) {
+			providers[u] = append(providers[u], imp)
+		}
+	}
+	for u := range providers {
+		f, err := view.GetFile(u)
+		if err != nil {
+			complete = false
+			continue
+		}
+		if pf, ok := f.(ProtoFile); !ok || pf.ParseError() != nil {
+			complete = false
+		}
+	}
+
+	var problems []semanticProblem
+	for _, ref := range typeRefs(proto, lines) {
+		sym, visible, ok := resolveVisible(view, from, ref.scope, ref.typ, providers)
+		switch {
+		case ok && visible:
+			for _, imp := range providers[sym.URI] {
+				imp.used = true
+			}
+		case ok:
+			path, _ := ImportPath(view, from, sym.URI)
+			problems = append(problems, semanticProblem{
+				code:       CodeMissingImport,
+				severity:   protocol.SeverityError,
+				message:    fmt.Sprintf("%q is declared in %q, which is not imported.", ref.typ, relPath(view, sym.URI)),
+				rng:        ref.rng,
+				importPath: path,
+			})
+		case complete && !strings.HasPrefix(strings.TrimPrefix(ref.typ, "."), "google.protobuf."):
+			problems = append(problems, semanticProblem{
+				code:     CodeUnresolvedType,
+				severity: protocol.SeverityError,
+				message:  fmt.Sprintf("%q is not defined.", ref.typ),
+				rng:      ref.rng,
+			})
+		}
+	}
+
+	usesOptions := customOptionRegexp.Match(data)
+	for _, imp := range imports {
+		if !imp.resolved || imp.used || imp.Kind == "public" {
+			continue
+		}
+		// An import may provide the extensions for custom options, which are not type references.
+		if usesOptions && declaresExtension(view, imp.uri) {
+			continue
+		}
+		problems = append(problems, semanticProblem{
+			code:       CodeUnusedImport,
+			severity:   protocol.SeverityWarning,
+			message:    fmt.Sprintf("Import %q is not used.", imp.Filename),
+			rng:        nameRange(lines, imp.Position.Line, imp.Position.Column, imp.Filename),
+			importPath: imp.Filename,
+		})
+	}
+
+	problems = append(problems, duplicateFieldNumbers(proto, lines)...)
+	problems = append(problems, optionProblems(view, from, proto, lines, complete)...)
+	return problems, nil
+}
+
+// semanticDiagnostics converts semantic problems to diagnostics.
+func semanticDiagnostics(problems []semanticProblem) []protocol.Diagnostic {
+	diagnostics := make([]protocol.Diagnostic, 0, len(problems))
+	for _, p := range problems {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    p.rng,
+			Severity: p.severity,
+			Code:     p.code,
+			Source:   DiagnosticSource,
+			Message:  p.message,
+		})
+	}
+	return diagnostics
+}
+
+// resolveVisible resolves a type reference like ResolveType, but prefers the symbols declared
+// in the files visible from the referring file. visible is false if the type resolves only to
+// symbols in the other files.
+func resolveVisible(view View, from uri.URI, scope, typ string, files map[uri.URI][]*fileImport) (sym Symbol, visible, ok bool) {
+	for _, name := range typeCandidates(scope, typ) {
+		var symbols []Symbol
+		for _, s := range view.LookupSymbol(name) {
+			if s.Kind == SymbolKindMessage || s.Kind == SymbolKindEnum {
+				symbols = append(symbols, s)
+			}
+		}
+		if len(symbols) == 0 {
+			continue
+		}
+		for _, s := range symbols {
+			if s.URI == from {
+				return s, true, true
+			}
+		}
+		for _, s := range symbols {
+			if _, ok := files[s.URI]; ok {
+				return s, true, true
+			}
+		}
+		return symbols[0], false, true
+	}
+	return Symbol{}, false, false
+}
+
+// publicClosure returns a file and the files it imports publicly, directly or indirectly.
+// The files are loaded into the view if they are not known yet.
+func publicClosure(view View, target uri.URI) []uri.URI {
+	uris := []uri.URI{target}
+	visited := map[uri.URI]struct{}{target: {}}
+	for i := 0; i < len(uris); i++ {
+		f, err := view.GetFile(uris[i])
+		if err != nil {
+			continue
+		}
+		pf, ok := f.(ProtoFile)
+		if !ok || pf.Proto() == nil {
+			continue
+		}
+		for _, el := range pf.Proto().Protobuf().Elements {
+			imp, ok := el.(*protobuf.Import)
+			if !ok || imp.Kind != "public" {
+				continue
+			}
+			u, ok := ResolveImport(view, uris[i], imp.Filename)
+			if !ok {
+				continue
+			}
+			if _, ok := visited[u]; ok {
+				continue
+			}
+			visited[u] = struct{}{}
+			uris = append(uris, u)
+		}
+	}
+	return uris
+}
+
+// declaresExtension returns true if a file or the ones it imports publicly extend any message.
+func declaresExtension(view View, target uri.URI) bool {
+	var walk func(elements []protobuf.Visitee) bool
+	walk = func(elements []protobuf.Visitee) bool {
+		for _, el := range elements {
+			if m, ok := el.(*protobuf.Message); ok && (m.IsExtend || walk(m.Elements)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, u := range publicClosure(view, target) {
+		f, err := view.GetFile(u)
+		if err != nil {
+			continue
+		}
+		if pf, ok := f.(ProtoFile); ok && pf.Proto() != nil && walk(pf.Proto().Protobuf().Elements) {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateFieldNumbers reports the fields which reuse the numbers of the preceding fields in the same message.
+func duplicateFieldNumbers(proto *protobuf.Proto, lines []string) []semanticProblem {
+	var problems []semanticProblem
+	var walk func(elements []protobuf.Visitee)
+	walk = func(elements []protobuf.Visitee) {
+		for _, el := range elements {
+			m, ok := el.(*protobuf.Message)
+			if !ok || m.IsExtend {
+				continue
+			}
+			seen := make(map[int]string)
+			numbers := newFieldNumbers(m)
+			for _, f := range messageFields(m) {
+				name, ok := seen[f.Sequence]
+				if !ok {
+					seen[f.Sequence] = f.Name
+					continue
+				}
+				problems = append(problems, semanticProblem{
+					code:     CodeDuplicateFieldNumber,
+					severity: protocol.SeverityError,
+					message:  fmt.Sprintf("Field number %d of %q is already used by %q.", f.Sequence, f.Name, name),
+					rng:      fieldNumberRange(lines, f),
+					number:   numbers.next(),
+				})
+			}
+			walk(m.Elements)
+		}
+	}
+	walk(proto.Elements)
+	return problems
+}
+
+// messageFields returns the fields of a message including the ones in its oneofs in declaration order.
+func messageFields(m *protobuf.Message) []*protobuf.Field {
+	var fields []*protobuf.Field
+	var walk func(elements []protobuf.Visitee)
+	walk = func(elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.NormalField:
+				fields = append(fields, v.Field)
+			case *protobuf.MapField:
+				fields = append(fields, v.Field)
+			case *protobuf.OneOfField:
+				fields = append(fields, v.Field)
+			case *protobuf.Oneof:
+				walk(v.Elements)
+			}
+		}
+	}
+	walk(m.Elements)
+	return fields
+}
+
+// Field numbers which can't be used for fields.
+const (
+	maxFieldNumber = 536870911
+	// firstImplementationReserved and lastImplementationReserved are reserved for the implementation of protocol buffers.
+	firstImplementationReserved = 19000
+	lastImplementationReserved  = 19999
+)
+
+// fieldNumbers hands out the numbers which are neither used nor reserved in a message,
+// so that every duplicate field number in the message is given a distinct one.
+type fieldNumbers struct {
+	used     map[int]bool
+	reserved []protobuf.Range
+	// last is the number handed out last, or the largest one used or reserved up to a bound at first.
+	last int
+	// wrapped is true if numbers are searched from 1 after reaching the maximum.
+	wrapped bool
+}
+
+func newFieldNumbers(m *protobuf.Message) *fieldNumbers {
+	n := &fieldNumbers{used: make(map[int]bool)}
+	for _, f := range messageFields(m) {
+		n.used[f.Sequence] = true
+		n.last = maxInt(n.last, f.Sequence)
+	}
+	for _, el := range m.Elements {
+		r, ok := el.(*protobuf.Reserved)
+		if !ok {
+			continue
+		}
+		n.reserved = append(n.reserved, r.Ranges...)
+		for _, rng := range r.Ranges {
+			if !rng.Max {
+				n.last = maxInt(n.last, maxInt(rng.From, rng.To))
+			}
+		}
+	}
+	return n
+}
+
+// next returns the smallest free number following the one returned last, which follows the largest number
+// used or reserved up to a bound at first. Numbers below it are searched if it reaches the maximum
+// such as by "reserved 100 to max". It returns 0 if no numbers are free.
+func (n *fieldNumbers) next() int {
+	i := n.last + 1
+	for {
+		if i > maxFieldNumber {
+			if n.wrapped {
+				return 0
+			}
+			n.wrapped = true
+			i = 1
+		}
+		if end, ok := n.reservedEnd(i); ok {
+			i = end + 1
+			continue
+		}
+		if n.used[i] {
+			i++
+			continue
+		}
+		n.last = i
+		n.used[i] = true
+		return i
+	}
+}
+
+// reservedEnd returns the end of the reserved range which a number is in.
+func (n *fieldNumbers) reservedEnd(i int) (int, bool) {
+	if firstImplementationReserved <= i && i <= lastImplementationReserved {
+		return lastImplementationReserved, true
+	}
+	for _, rng := range n.reserved {
+		to := maxInt(rng.From, rng.To)
+		if rng.Max {
+			to = maxFieldNumber
+		}
+		if rng.From <= i && i <= to {
+			return to, true
+		}
+	}
+	return 0, false
+}
+
+// fieldNumberRange returns the range of the number of a field.
+func fieldNumberRange(lines []string, f *protobuf.Field) protocol.Range {
+	line, column := f.Position.Line, f.Position.Column
+	if l, c, ok := findWord(lines, line, column, f.Name); ok {
+		line, column = l, c+len(f.Name)
+	}
+	number := strconv.Itoa(f.Sequence)
+	if l, c, ok := findWord(lines, line, column, number); ok {
+		return protocol.Range{
+			Start: protocol.Position{Line: float64(l - 1), Character: float64(c - 1)},
+			End:   protocol.Position{Line: float64(l - 1), Character: float64(c - 1 + len(number))},
+		}
+	}
+	return nameRange(lines, line, column, "")
+}
+
+// relPath returns the path of a file relative to the folder of a view if it is in the folder.
+func relPath(view View, u uri.URI) string {
+	rel, err := filepath.Rel(view.Folder().Filename(), u.Filename())
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return u.Filename()
+	}
+	return filepath.ToSlash(rel)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
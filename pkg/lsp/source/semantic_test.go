@@ -0,0 +1,161 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestSemanticProblems(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	files := map[string]string{
+		"a.proto": `syntax = "proto3";
+package foo;
+message A {}
+`,
+		"b.proto": `syntax = "proto3";
+package foo;
+message B {}
+`,
+		"c.proto": `syntax = "proto3";
+package foo;
+message C {}
+`,
+		"d.proto": `syntax = "proto3";
+package foo;
+import public "b.proto";
+`,
+		"main.proto": `syntax = "proto3";
+package foo;
+import "a.proto";
+import "d.proto";
+import "e.proto";
+message Main {
+  A a = 1;
+  B b = 2;
+  C c = 3;
+  Unknown u = 4;
+  int32 x = 5;
+  oneof o {
+    int32 y = 5;
+  }
+  int32 z = 1;
+  reserved 10 to 20;
+}
+`,
+		"e.proto": `syntax = "proto3";
+package bar;
+message E {}
+`,
+	}
+	for name, text := range files {
+		view.DidOpen(uri.File("/workspace/"+name), []byte(text))
+	}
+
+	f, err := view.GetFile(uri.File("/workspace/main.proto"))
+	if err != nil {
+		t.Fatalf("GetFile() error = %v", err)
+	}
+	problems, err := semanticProblems(ctx, view, f.(ProtoFile))
+	if err != nil {
+		t.Fatalf("semanticProblems() error = %v", err)
+	}
+
+	rng := func(line, start, end float64) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: line, Character: start},
+			End:   protocol.Position{Line: line, Character: end},
+		}
+	}
+	want := []semanticProblem{
+		{code: CodeMissingImport, severity: protocol.SeverityError, rng: rng(8, 2, 3), importPath: "c.proto"},
+		{code: CodeUnresolvedType, severity: protocol.SeverityError, rng: rng(9, 2, 9)},
+		{code: CodeUnusedImport, severity: protocol.SeverityWarning, rng: rng(4, 8, 15), importPath: "e.proto"},
+		{code: CodeDuplicateFieldNumber, severity: protocol.SeverityError, rng: rng(12, 14, 15), number: 21},
+		{code: CodeDuplicateFieldNumber, severity: protocol.SeverityError, rng: rng(14, 12, 13), number: 22},
+	}
+	for i := range problems {
+		if problems[i].message == "" {
+			t.Errorf("semanticProblems()[%d].message is empty", i)
+		}
+		problems[i].message = ""
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("semanticProblems() = %+v, want %+v", problems, want)
+	}
+}
+
+func TestFieldNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{
+			name: "empty",
+			text: `message Foo {}`,
+			want: []int{1, 2},
+		},
+		{
+			name: "reserved",
+			text: `message Foo {
+  int32 a = 1;
+  reserved 2, 5 to 7, 100 to max;
+}`,
+			want: []int{8, 9},
+		},
+		{
+			name: "implementation reserved",
+			text: `message Foo {
+  int32 a = 18999;
+}`,
+			want: []int{20000, 20001},
+		},
+		{
+			name: "reserved to max",
+			text: `message Foo {
+  int32 a = 1;
+  int32 b = 8;
+  reserved 3, 5 to max;
+}`,
+			want: []int{2, 4, 0},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			proto, err := parseProto([]byte(tt.text))
+			if err != nil {
+				t.Fatalf("parseProto() error = %v", err)
+			}
+			numbers := newFieldNumbers(proto.Protobuf().Elements[0].(*protobuf.Message))
+			var got []int
+			for range tt.want {
+				got = append(got, numbers.next())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// Types of semantic tokens. Their names are the ones predefined by the protocol,
+// and their values are the indexes in SemanticTokenTypes.
+const (
+	TokenNamespace = iota
+	TokenType
+	TokenStruct
+	TokenEnum
+	TokenInterface
+	TokenMethod
+	TokenProperty
+	TokenEnumMember
+	TokenDecorator
+)
+
+// SemanticTokenTypes is the legend of the types of semantic tokens.
+var SemanticTokenTypes = []string{
+	TokenNamespace:  "namespace",
+	TokenType:       "type",
+	TokenStruct:     "struct",
+	TokenEnum:       "enum",
+	TokenInterface:  "interface",
+	TokenMethod:     "method",
+	TokenProperty:   "property",
+	TokenEnumMember: "enumMember",
+	TokenDecorator:  "decorator",
+}
+
+// Modifiers of semantic tokens, which are bit flags of the indexes in SemanticTokenModifiers.
+const (
+	ModifierDeclaration = 1 << iota
+	ModifierDeprecated
+	ModifierDefaultLibrary
+)
+
+// SemanticTokenModifiers is the legend of the modifiers of semantic tokens.
+var SemanticTokenModifiers = []string{
+	"declaration",
+	"deprecated",
+	"defaultLibrary",
+}
+
+// SemanticToken is a classified identifier in a proto file.
+type SemanticToken struct {
+	// Line and Start are 0-based.
+	Line      int
+	Start     int
+	Length    int
+	Type      int
+	Modifiers int
+}
+
+// tokenizer collects the semantic tokens of a file.
+type tokenizer struct {
+	view  View
+	uri   uri.URI
+	lines []string
+
+	tokens []SemanticToken
+	// deprecated caches whether the messages and the enums referred to are deprecated.
+	deprecated map[string]bool
+}
+
+// SemanticTokens returns the semantic tokens of the file for a given URI in the order of their positions.
+// Type references are classified by the declarations they resolve to.
+func SemanticTokens(ctx context.Context, view View, uri uri.URI) ([]SemanticToken, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	pf, ok := f.(ProtoFile)
+	if !ok || pf.Proto() == nil {
+		return nil, nil
+	}
+	data, _, err := pf.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proto := pf.Proto().Protobuf()
+
+	t := &tokenizer{
+		view:       view,
+		uri:        uri,
+		lines:      strings.Split(string(data), "\n"),
+		deprecated: make(map[string]bool),
+	}
+	pkg := packageName(proto)
+	for _, el := range proto.Elements {
+		if p, ok := el.(*protobuf.Package); ok {
+			t.add(p.Position.Line, p.Position.Column, p.Name, TokenNamespace, ModifierDeclaration)
+		}
+	}
+	t.walk(pkg, pkg, proto.Elements)
+
+	sort.SliceStable(t.tokens, func(i, j int) bool {
+		a, b := t.tokens[i], t.tokens[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Start < b.Start
+	})
+	return t.tokens, nil
+}
+
+// walk collects the tokens of elements declared in a given scope.
+// pkg is the package, which is the scope of the types of RPCs.
+func (t *tokenizer) walk(pkg, scope string, elements []protobuf.Visitee) {
+	for _, el := range elements {
+		switch v := el.(type) {
+		case *protobuf.Message:
+			if v.IsExtend {
+				t.typeRef(v.Position.Line, v.Position.Column, scope, v.Name)
+				t.walkFields(pkg, scope, v.Elements)
+				continue
+			}
+			name := qualify(scope, v.Name)
+			t.add(v.Position.Line, v.Position.Column, v.Name, TokenStruct, ModifierDeclaration|deprecatedModifier(v.Elements))
+			t.walkFields(pkg, name, v.Elements)
+		case *protobuf.Enum:
+			t.add(v.Position.Line, v.Position.Column, v.Name, TokenEnum, ModifierDeclaration|deprecatedModifier(v.Elements))
+			for _, el := range v.Elements {
+				switch f := el.(type) {
+				case *protobuf.EnumField:
+					l, c, _ := t.add(f.Position.Line, f.Position.Column, f.Name, TokenEnumMember, ModifierDeclaration|deprecatedModifier(f.Elements))
+					t.options(l, c, f.Elements)
+				case *protobuf.Option:
+					t.option(f.Position.Line, f.Position.Column, f)
+				}
+			}
+		case *protobuf.Service:
+			t.add(v.Position.Line, v.Position.Column, v.Name, TokenInterface, ModifierDeclaration|deprecatedModifier(v.Elements))
+			for _, el := range v.Elements {
+				switch r := el.(type) {
+				case *protobuf.RPC:
+					l, c, _ := t.add(r.Position.Line, r.Position.Column, r.Name, TokenMethod, ModifierDeclaration|deprecatedModifier(r.Elements))
+					l, c, _ = t.typeRef(l, c, pkg, r.RequestType)
+					if rl, rc, ok := findWord(t.lines, l, c, "returns"); ok {
+						l, c = rl, rc
+					}
+					l, c, _ = t.typeRef(l, c, pkg, r.ReturnsType)
+					t.options(l, c, r.Elements)
+				case *protobuf.Option:
+					t.option(r.Position.Line, r.Position.Column, r)
+				}
+			}
+		case *protobuf.Option:
+			t.option(v.Position.Line, v.Position.Column, v)
+		}
+	}
+}
+
+// walkFields collects the tokens of the elements of a message or an extension declared in a given scope.
+func (t *tokenizer) walkFields(pkg, scope string, elements []protobuf.Visitee) {
+	for _, el := range elements {
+		switch v := el.(type) {
+		case *protobuf.NormalField:
+			t.field(scope, v.Field)
+		case *protobuf.MapField:
+			l, c, _ := t.typeRef(v.Position.Line, v.Position.Column, scope, v.KeyType)
+			l, c, _ = t.typeRef(l, c, scope, v.Type)
+			l, c, _ = t.add(l, c, v.Name, TokenProperty, ModifierDeclaration|deprecatedModifier(optionElements(v.Options)))
+			t.options(l, c, optionElements(v.Options))
+		case *protobuf.Oneof:
+			for _, el := range v.Elements {
+				switch f := el.(type) {
+				case *protobuf.OneOfField:
+					t.field(scope, f.Field)
+				case *protobuf.Option:
+					t.option(f.Position.Line, f.Position.Column, f)
+				}
+			}
+		case *protobuf.Message, *protobuf.Enum:
+			t.walk(pkg, scope, []protobuf.Visitee{v})
+		case *protobuf.Option:
+			t.option(v.Position.Line, v.Position.Column, v)
+		}
+	}
+}
+
+// field collects the tokens of a field, which are the type, the name and the options.
+func (t *tokenizer) field(scope string, f *protobuf.Field) {
+	l, c, _ := t.typeRef(f.Position.Line, f.Position.Column, scope, f.Type)
+	options := optionElements(f.Options)
+	l, c, _ = t.add(l, c, f.Name, TokenProperty, ModifierDeclaration|deprecatedModifier(options))
+	t.options(l, c, options)
+}
+
+// options collects the tokens of the names of the options in elements at or after a given 1-based position.
+func (t *tokenizer) options(line, column int, elements []protobuf.Visitee) {
+	for _, el := range elements {
+		if o, ok := el.(*protobuf.Option); ok {
+			line, column = t.option(line, column, o)
+		}
+	}
+}
+
+// option adds the token of the name of an option at or after a given 1-based position.
+// Only the part in parentheses is added for a custom option like "(foo.bar).baz".
+func (t *tokenizer) option(line, column int, o *protobuf.Option) (int, int) {
+	name := o.Name
+	if strings.HasPrefix(name, "(") {
+		if i := strings.Index(name, ")"); i > 0 {
+			name = name[1:i]
+		}
+	}
+	l, c, _ := t.add(line, column, name, TokenDecorator, 0)
+	return l, c
+}
+
+// typeRef adds the token of a type reference at or after a given 1-based position.
+func (t *tokenizer) typeRef(line, column int, scope, typ string) (int, int, bool) {
+	if isScalar(typ) {
+		return t.add(line, column, typ, TokenType, ModifierDefaultLibrary)
+	}
+	sym, ok := ResolveType(t.view, t.uri, scope, typ)
+	if !ok {
+		return t.add(line, column, typ, TokenType, 0)
+	}
+	typeKind := TokenStruct
+	if sym.Kind == SymbolKindEnum {
+		typeKind = TokenEnum
+	}
+	modifiers := 0
+	if t.symbolDeprecated(sym) {
+		modifiers |= ModifierDeprecated
+	}
+	return t.add(line, column, typ, typeKind, modifiers)
+}
+
+// add adds the token of a word found at or after a given 1-based position,
+// and returns the 1-based position after the word. If the word is not found, the position is returned as it is.
+func (t *tokenizer) add(line, column int, word string, typ, modifiers int) (int, int, bool) {
+	l, c, ok := findWord(t.lines, line, column, word)
+	if !ok {
+		return line, column, false
+	}
+	t.tokens = append(t.tokens, SemanticToken{
+		Line:      l - 1,
+		Start:     c - 1,
+		Length:    len(word),
+		Type:      typ,
+		Modifiers: modifiers,
+	})
+	return l, c + len(word), true
+}
+
+// symbolDeprecated returns true if a message or an enum is declared with the deprecated option.
+func (t *tokenizer) symbolDeprecated(sym Symbol) bool {
+	if deprecated, ok := t.deprecated[sym.Name]; ok {
+		return deprecated
+	}
+	deprecated := false
+	if f, err := t.view.GetFile(sym.URI); err == nil {
+		if pf, ok := f.(ProtoFile); ok && pf.Proto() != nil {
+			proto := pf.Proto().Protobuf()
+			if m, ok := findMessage(proto, packageName(proto), sym.Name); ok {
+				deprecated = deprecatedModifier(m.Elements) != 0
+			} else if e, ok := findEnum(proto, packageName(proto), sym.Name); ok {
+				deprecated = deprecatedModifier(e.Elements) != 0
+			}
+		}
+	}
+	t.deprecated[sym.Name] = deprecated
+	return deprecated
+}
+
+// deprecatedModifier returns ModifierDeprecated if elements have the deprecated option set to true.
+func deprecatedModifier(elements []protobuf.Visitee) int {
+	for _, el := range elements {
+		if o, ok := el.(*protobuf.Option); ok && o.Name == "deprecated" && o.Constant.Source == "true" {
+			return ModifierDeprecated
+		}
+	}
+	return 0
+}
+
+func optionElements(options []*protobuf.Option) []protobuf.Visitee {
+	elements := make([]protobuf.Visitee, 0, len(options))
+	for _, o := range options {
+		elements = append(elements, o)
+	}
+	return elements
+}
+
+// EncodeSemanticTokens encodes semantic tokens into the relative format of the protocol,
+// where each token is five integers: the line relative to the previous token, the start character
+// relative to the previous token if it is on the same line, the length, the type and the modifiers.
+// If rng is not nil, only the tokens which start in the range are encoded.
+func EncodeSemanticTokens(tokens []SemanticToken, rng *protocol.Range) []uint32 {
+	data := []uint32{}
+	line, start := 0, 0
+	for _, tok := range tokens {
+		if rng != nil && !inRange(*rng, tok.Line, tok.Start) {
+			continue
+		}
+		deltaStart := tok.Start
+		if tok.Line == line {
+			deltaStart = tok.Start - start
+		}
+		data = append(data, uint32(tok.Line-line), uint32(deltaStart), uint32(tok.Length), uint32(tok.Type), uint32(tok.Modifiers))
+		line, start = tok.Line, tok.Start
+	}
+	return data
+}
+
+// inRange returns true if a 0-based position is in a range.
+func inRange(rng protocol.Range, line, character int) bool {
+	start, end := rng.Start, rng.End
+	if line < int(start.Line) || (line == int(start.Line) && character < int(start.Character)) {
+		return false
+	}
+	return line < int(end.Line) || (line == int(end.Line) && character < int(end.Character))
+}
+
+// SemanticTokensEdit is an edit of encoded semantic tokens, which replaces DeleteCount integers
+// from Start with Data.
+type SemanticTokensEdit struct {
+	Start       uint32   `json:"start"`
+	DeleteCount uint32   `json:"deleteCount"`
+	Data        []uint32 `json:"data,omitempty"`
+}
+
+// DiffSemanticTokens returns the edits which change previous encoded semantic tokens into current ones.
+// The changed part between the common prefix and suffix is replaced with a single edit.
+func DiffSemanticTokens(prev, cur []uint32) []SemanticTokensEdit {
+	prefix := 0
+	for prefix < len(prev) && prefix < len(cur) && prev[prefix] == cur[prefix] {
+		prefix++
+	}
+	if prefix == len(prev) && prefix == len(cur) {
+		return []SemanticTokensEdit{}
+	}
+	suffix := 0
+	for suffix < len(prev)-prefix && suffix < len(cur)-prefix && prev[len(prev)-1-suffix] == cur[len(cur)-1-suffix] {
+		suffix++
+	}
+	return []SemanticTokensEdit{
+		{
+			Start:       uint32(prefix),
+			DeleteCount: uint32(len(prev) - prefix - suffix),
+			Data:        cur[prefix : len(cur)-suffix],
+		},
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+
+	fileURI := uri.File("/workspace/foo.proto")
+	view.DidOpen(fileURI, []byte(`syntax = "proto3";
+package foo;
+message Foo {
+  option deprecated = true;
+  Bar bar = 1 [deprecated = true];
+  map<string, Foo> foos = 2;
+}
+enum Bar {
+  BAR_UNSPECIFIED = 0;
+}
+service FooService {
+  rpc GetFoo(Foo) returns (stream Foo);
+}
+`))
+
+	got, err := SemanticTokens(ctx, view, fileURI)
+	if err != nil {
+		t.Fatalf("SemanticTokens() error = %v", err)
+	}
+	want := []SemanticToken{
+		{Line: 1, Start: 8, Length: 3, Type: TokenNamespace, Modifiers: ModifierDeclaration},
+		{Line: 2, Start: 8, Length: 3, Type: TokenStruct, Modifiers: ModifierDeclaration | ModifierDeprecated},
+		{Line: 3, Start: 9, Length: 10, Type: TokenDecorator},
+		{Line: 4, Start: 2, Length: 3, Type: TokenEnum},
+		{Line: 4, Start: 6, Length: 3, Type: TokenProperty, Modifiers: ModifierDeclaration | ModifierDeprecated},
+		{Line: 4, Start: 15, Length: 10, Type: TokenDecorator},
+		{Line: 5, Start: 6, Length: 6, Type: TokenType, Modifiers: ModifierDefaultLibrary},
+		{Line: 5, Start: 14, Length: 3, Type: TokenStruct, Modifiers: ModifierDeprecated},
+		{Line: 5, Start: 19, Length: 4, Type: TokenProperty, Modifiers: ModifierDeclaration},
+		{Line: 7, Start: 5, Length: 3, Type: TokenEnum, Modifiers: ModifierDeclaration},
+		{Line: 8, Start: 2, Length: 15, Type: TokenEnumMember, Modifiers: ModifierDeclaration},
+		{Line: 10, Start: 8, Length: 10, Type: TokenInterface, Modifiers: ModifierDeclaration},
+		{Line: 11, Start: 6, Length: 6, Type: TokenMethod, Modifiers: ModifierDeclaration},
+		{Line: 11, Start: 13, Length: 3, Type: TokenStruct, Modifiers: ModifierDeprecated},
+		{Line: 11, Start: 34, Length: 3, Type: TokenStruct, Modifiers: ModifierDeprecated},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SemanticTokens() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEncodeSemanticTokens(t *testing.T) {
+	tokens := []SemanticToken{
+		{Line: 1, Start: 8, Length: 3, Type: TokenNamespace, Modifiers: ModifierDeclaration},
+		{Line: 4, Start: 2, Length: 3, Type: TokenEnum},
+		{Line: 4, Start: 6, Length: 3, Type: TokenProperty, Modifiers: ModifierDeclaration},
+	}
+
+	tests := []struct {
+		name string
+		rng  *protocol.Range
+		want []uint32
+	}{
+		{
+			name: "full",
+			want: []uint32{
+				1, 8, 3, TokenNamespace, ModifierDeclaration,
+				3, 2, 3, TokenEnum, 0,
+				0, 4, 3, TokenProperty, ModifierDeclaration,
+			},
+		},
+		{
+			name: "range",
+			rng: &protocol.Range{
+				Start: protocol.Position{Line: 4, Character: 4},
+				End:   protocol.Position{Line: 5},
+			},
+			want: []uint32{4, 6, 3, TokenProperty, ModifierDeclaration},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodeSemanticTokens(tokens, tt.rng); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EncodeSemanticTokens() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffSemanticTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		prev []uint32
+		cur  []uint32
+		want []SemanticTokensEdit
+	}{
+		{
+			name: "same",
+			prev: []uint32{1, 2, 3},
+			cur:  []uint32{1, 2, 3},
+			want: []SemanticTokensEdit{},
+		},
+		{
+			name: "replace",
+			prev: []uint32{1, 2, 3, 4},
+			cur:  []uint32{1, 5, 6, 4},
+			want: []SemanticTokensEdit{{Start: 1, DeleteCount: 2, Data: []uint32{5, 6}}},
+		},
+		{
+			name: "delete",
+			prev: []uint32{1, 2, 3},
+			cur:  []uint32{1, 3},
+			want: []SemanticTokensEdit{{Start: 1, DeleteCount: 1, Data: []uint32{}}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffSemanticTokens(tt.prev, tt.cur); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffSemanticTokens() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -41,8 +41,14 @@ type Session interface {
 	View(name string) (View, bool)
 
 	// ViewOf returns a view corresponding to the given URI.
+	// It returns nil if no view contains the URI.
 	ViewOf(uri uri.URI) View
 
+	// ViewOrAdd returns a view corresponding to the given URI, or adds the view returned by newView
+	// if no view contains the URI. added is true if the view is added.
+	// The lookup and the addition are done at once so that concurrent calls add only one view.
+	ViewOrAdd(ctx context.Context, uri uri.URI, newView func() View) (view View, added bool)
+
 	// Views returns the set of active views built by this session.
 	Views() []View
 
@@ -99,11 +105,32 @@ func (s *session) ViewOf(uri uri.URI) View {
 	}
 
 	v = s.bestView(uri)
-	s.viewMap[uri] = v
+	if v != nil {
+		s.viewMap[uri] = v
+	}
 
 	return v
 }
 
+func (s *session) ViewOrAdd(ctx context.Context, fileURI uri.URI, newView func() View) (View, bool) {
+	s.viewMu.Lock()
+	defer s.viewMu.Unlock()
+
+	if v, ok := s.viewMap[fileURI]; ok {
+		return v, false
+	}
+	if v := s.bestView(fileURI); v != nil {
+		s.viewMap[fileURI] = v
+		return v, false
+	}
+
+	v := newView()
+	s.views = append(s.views, v)
+	// we always need to drop the view map
+	s.viewMap = make(map[uri.URI]View)
+	return v, true
+}
+
 func (s *session) Views() (views []View) {
 	s.viewMu.RLock()
 	views = s.views
@@ -145,7 +172,8 @@ func (s *session) Shutdown(context.Context) {
 	s.viewMap = nil
 }
 
-// bestView finds the best view to associate a given URI with.
+// bestView finds the best view to associate a given URI with.
+// It returns nil if no view contains the URI.
 // viewMu must be held when calling this method.
 func (s *session) bestView(uri uri.URI) View {
 	// we need to find the best view for this file
@@ -154,15 +182,12 @@ func (s *session) bestView(uri uri.URI) View {
 		if longest != nil && len(longest.Folder()) > len(view.Folder()) {
 			continue
 		}
-		if strings.HasPrefix(string(uri), string(view.Folder())) {
+		folder := strings.TrimSuffix(string(view.Folder()), "/")
+		if string(uri) == folder || strings.HasPrefix(string(uri), folder+"/") {
 			longest = view
 		}
 	}
-	if longest != nil {
-		return longest
-	}
-	// TODO: are there any more heuristics we can use?
-	return s.views[0]
+	return longest
 }
 
 func hashContent(content []byte) string {
@@ -13,3 +13,107 @@
 // limitations under the License.
 
 package source
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-language-server/uri"
+)
+
+func TestSession_ViewOf(t *testing.T) {
+	ctx := context.Background()
+	session := NewSession()
+	for _, folder := range []string{"/workspace/foo", "/workspace/foo/bar", "/workspace/foobar"} {
+		session.AddView(ctx, NewView(session, folder, uri.File(folder)))
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{
+			name:     "folder",
+			filename: "/workspace/foo/a.proto",
+			want:     "/workspace/foo",
+		},
+		{
+			name:     "nested folder",
+			filename: "/workspace/foo/bar/a.proto",
+			want:     "/workspace/foo/bar",
+		},
+		{
+			name:     "folder with common prefix",
+			filename: "/workspace/foobar/a.proto",
+			want:     "/workspace/foobar",
+		},
+		{
+			name:     "outside of folders",
+			filename: "/tmp/a.proto",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			view := session.ViewOf(uri.File(tt.filename))
+			if tt.want == "" {
+				if view != nil {
+					t.Errorf("ViewOf() = %v, want nil", view.Name())
+				}
+				return
+			}
+			if view == nil {
+				t.Fatalf("ViewOf() = nil, want %v", tt.want)
+			}
+			if got := view.Name(); got != tt.want {
+				t.Errorf("ViewOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_ViewOrAdd(t *testing.T) {
+	ctx := context.Background()
+	session := NewSession()
+	session.AddView(ctx, NewView(session, "/workspace", uri.File("/workspace")))
+
+	if view, added := session.ViewOrAdd(ctx, uri.File("/workspace/a.proto"), func() View {
+		t.Error("ViewOrAdd() called newView for a file in a folder")
+		return nil
+	}); added || view.Name() != "/workspace" {
+		t.Errorf("ViewOrAdd() = %v, %v, want /workspace, false", view.Name(), added)
+	}
+
+	// Concurrent calls for files in the same directory add only one view.
+	const n = 10
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		views = make(map[View]bool)
+		adds  int
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			view, added := session.ViewOrAdd(ctx, uri.File("/tmp/a.proto"), func() View {
+				return NewAdHocView(session, uri.File("/tmp"))
+			})
+			mu.Lock()
+			views[view] = true
+			if added {
+				adds++
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(views) != 1 || adds != 1 {
+		t.Errorf("ViewOrAdd() returned %d views and added %d, want 1 and 1", len(views), adds)
+	}
+	if got := len(session.Views()); got != 2 {
+		t.Errorf("len(Views()) = %d, want 2", got)
+	}
+}
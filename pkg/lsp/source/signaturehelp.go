@@ -0,0 +1,277 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+// SignatureHelp is the signatures shown while writing a declaration.
+// It replaces protocol.SignatureHelp, which can't represent the labels of signatures.
+type SignatureHelp struct {
+	Signatures      []Signature `json:"signatures"`
+	ActiveSignature int         `json:"activeSignature"`
+	ActiveParameter int         `json:"activeParameter"`
+}
+
+// Signature is a signature with its parameters, whose labels are substrings of the label of the signature.
+type Signature struct {
+	Label         string               `json:"label"`
+	Documentation string               `json:"documentation,omitempty"`
+	Parameters    []SignatureParameter `json:"parameters"`
+}
+
+// SignatureParameter is a parameter of a signature.
+type SignatureParameter struct {
+	Label         string `json:"label"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+var (
+	// rpcPattern matches an RPC declaration which is being written up to its response type.
+	rpcPattern = regexp.MustCompile(`\brpc\s+(\w+)\s*\(([^()]*)(\)\s*(returns\s*(\(([^()]*))?)?)?$`)
+	// optionPattern matches the name of a custom option followed by an assignment, such as "(foo.bar).baz =".
+	optionPattern = regexp.MustCompile(`(\([\w.]+\)(?:\.[\w.]+)?)\s*=\s*$`)
+	// aggregateFieldPattern matches the name of a message field in an aggregate value, such as "baz:" or "baz".
+	aggregateFieldPattern = regexp.MustCompile(`\b(\w+)\s*:?\s*$`)
+	// aggregateKeyPattern matches the name of a field assigned in an aggregate value.
+	aggregateKeyPattern = regexp.MustCompile(`\b(\w+)\s*[:{]`)
+	// packagePattern matches a package statement.
+	packagePattern = regexp.MustCompile(`\bpackage\s+([\w.]+)\s*;`)
+)
+
+// SignatureHelps returns the signature help at a given position, which is nil if there are no signatures.
+// It shows the shape of an RPC declaration in its parentheses and the fields of the message type of
+// a custom option in its aggregate value. The content is read as text since it is usually incomplete.
+func SignatureHelps(ctx context.Context, view View, uri uri.URI, pos protocol.Position) (*SignatureHelp, error) {
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := f.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := stripComments(textBefore(string(data), pos))
+
+	if m := rpcPattern.FindStringSubmatch(prefix); m != nil {
+		if m[3] != "" && m[5] == "" {
+			// The request type is closed but the response type is not opened.
+			return nil, nil
+		}
+		active := 0
+		if m[5] != "" {
+			active = 1
+		}
+		return rpcSignature(m[1], active), nil
+	}
+
+	option, path, content, ok := aggregateAt(prefix)
+	if !ok {
+		return nil, nil
+	}
+	t, ok := optionType(view, packageOf(prefix), option, path)
+	if !ok || !t.isMessage {
+		return nil, nil
+	}
+	return optionSignature(t.name, t.fields, content), nil
+}
+
+// aggregateAt returns the name of the custom option whose aggregate value is not closed at the end of a text,
+// the names of the fields of the nested aggregate values which are not closed from the outermost,
+// and the content of the innermost aggregate value.
+func aggregateAt(text string) (option string, path []string, content string, ok bool) {
+	braces := unclosedBraces(text)
+	for i := len(braces) - 1; i >= 0; i-- {
+		before := text[:braces[i]]
+		if m := optionPattern.FindStringSubmatch(before); m != nil {
+			return m[1], path, text[braces[len(braces)-1]+1:], true
+		}
+		m := aggregateFieldPattern.FindStringSubmatch(before)
+		if m == nil {
+			return "", nil, "", false
+		}
+		path = append([]string{m[1]}, path...)
+	}
+	return "", nil, "", false
+}
+
+func rpcSignature(name string, active int) *SignatureHelp {
+	request, response := "[stream] Request", "[stream] Response"
+	return &SignatureHelp{
+		Signatures: []Signature{
+			{
+				Label:         fmt.Sprintf("rpc %s(%s) returns (%s)", name, request, response),
+				Documentation: "An RPC takes a request message and returns a response message. Either of them can be a stream.",
+				Parameters: []SignatureParameter{
+					{Label: request, Documentation: "The request message type, prefixed with stream if the client streams messages."},
+					{Label: response, Documentation: "The response message type, prefixed with stream if the server streams messages."},
+				},
+			},
+		},
+		ActiveParameter: active,
+	}
+}
+
+// optionSignature returns the signature of an aggregate value of a message with given fields.
+// The active parameter is the field assigned last in the content of the value.
+func optionSignature(name string, fields []*protobuf.Field, content string) *SignatureHelp {
+	sig := Signature{Documentation: name}
+	labels := make([]string, 0, len(fields))
+	for _, f := range fields {
+		label := f.Name + ": " + f.Type
+		sig.Parameters = append(sig.Parameters, SignatureParameter{Label: label})
+		labels = append(labels, label)
+	}
+	sig.Label = name[strings.LastIndex(name, ".")+1:] + " { " + strings.Join(labels, ", ") + " }"
+
+	help := &SignatureHelp{Signatures: []Signature{sig}}
+	keys := aggregateKeyPattern.FindAllStringSubmatch(topLevel(content), -1)
+	if len(keys) > 0 {
+		for i, f := range fields {
+			if f.Name == keys[len(keys)-1][1] {
+				help.ActiveParameter = i
+			}
+		}
+	}
+	return help
+}
+
+// optionType returns the type of a custom option like "(foo.bar).baz" used in a given scope,
+// or the type of a field in its aggregate value followed by a given path.
+func optionType(view View, scope, option string, path []string) (valueType, bool) {
+	end := strings.Index(option, ")")
+	if !strings.HasPrefix(option, "(") || end < 0 {
+		return valueType{}, false
+	}
+	ext, ok := lookupExtension(collectExtensions(view), scope, option[1:end])
+	if !ok {
+		return valueType{}, false
+	}
+	if rest := strings.TrimPrefix(option[end+1:], "."); rest != "" {
+		path = append(strings.Split(rest, "."), path...)
+	}
+
+	t, ok := resolveValueType(view, ext.uri, ext.scope, ext.field.Type)
+	for _, name := range path {
+		if !ok {
+			return valueType{}, false
+		}
+		f, found := t.field(name)
+		if !found {
+			return valueType{}, false
+		}
+		t, ok = t.fieldType(view, f)
+	}
+	return t, ok
+}
+
+// textBefore returns the text before a 0-based position in a content.
+func textBefore(content string, pos protocol.Position) string {
+	lines := strings.Split(content, "\n")
+	line := int(pos.Line)
+	if line >= len(lines) {
+		return content
+	}
+	last := lines[line]
+	if c := int(pos.Character); c < len(last) {
+		last = last[:c]
+	}
+	return strings.Join(append(lines[:line:line], last), "\n")
+}
+
+// stripComments replaces comments and string literals in a text with spaces,
+// keeping the offsets and the line breaks.
+func stripComments(text string) string {
+	b := []byte(text)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '/':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '*':
+			for ; i < len(b) && !(b[i] == '*' && i+1 < len(b) && b[i+1] == '/'); i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+			if i+1 < len(b) {
+				b[i], b[i+1] = ' ', ' '
+				i++
+			}
+		case b[i] == '"' || b[i] == '\'':
+			quote := b[i]
+			for i++; i < len(b) && b[i] != quote && b[i] != '\n'; i++ {
+				if b[i] == '\\' && i+1 < len(b) {
+					b[i] = ' '
+					i++
+				}
+				b[i] = ' '
+			}
+		}
+	}
+	return string(b)
+}
+
+// unclosedBraces returns the offsets of the braces which are not closed in a text from the outermost.
+func unclosedBraces(text string) []int {
+	var braces []int
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			braces = append(braces, i)
+		case '}':
+			if len(braces) > 0 {
+				braces = braces[:len(braces)-1]
+			}
+		}
+	}
+	return braces
+}
+
+// topLevel returns a text without the contents of the braces in it.
+func topLevel(text string) string {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '}' && depth > 0 {
+			depth--
+			continue
+		}
+		if depth == 0 {
+			b.WriteByte(c)
+		}
+		if c == '{' {
+			depth++
+		}
+	}
+	return b.String()
+}
+
+func packageOf(text string) string {
+	if m := packagePattern.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+func TestSignatureHelps(t *testing.T) {
+	const annotations = `syntax = "proto3";
+package google.api;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MethodOptions {
+  HttpRule http = 72295728;
+}
+message HttpRule {
+  string get = 2;
+  string post = 4;
+  string body = 7;
+  repeated HttpRule additional_bindings = 11;
+}
+`
+	rpc := func(active int) *SignatureHelp {
+		return &SignatureHelp{
+			Signatures: []Signature{
+				{
+					Label:         "rpc GetFoo([stream] Request) returns ([stream] Response)",
+					Documentation: "An RPC takes a request message and returns a response message. Either of them can be a stream.",
+					Parameters: []SignatureParameter{
+						{Label: "[stream] Request", Documentation: "The request message type, prefixed with stream if the client streams messages."},
+						{Label: "[stream] Response", Documentation: "The response message type, prefixed with stream if the server streams messages."},
+					},
+				},
+			},
+			ActiveParameter: active,
+		}
+	}
+	httpRule := func(active int) *SignatureHelp {
+		return &SignatureHelp{
+			Signatures: []Signature{
+				{
+					Label:         "HttpRule { get: string, post: string, body: string, additional_bindings: HttpRule }",
+					Documentation: "google.api.HttpRule",
+					Parameters: []SignatureParameter{
+						{Label: "get: string"},
+						{Label: "post: string"},
+						{Label: "body: string"},
+						{Label: "additional_bindings: HttpRule"},
+					},
+				},
+			},
+			ActiveParameter: active,
+		}
+	}
+
+	tests := []struct {
+		name string
+		// text is the content of the file, where the cursor is at "|".
+		text string
+		want *SignatureHelp
+	}{
+		{
+			name: "request type",
+			text: "service Foo {\n  rpc GetFoo(|",
+			want: rpc(0),
+		},
+		{
+			name: "response type",
+			text: "service Foo {\n  rpc GetFoo(stream Req) returns (|",
+			want: rpc(1),
+		},
+		{
+			name: "between request and response",
+			text: "service Foo {\n  rpc GetFoo(Req) |",
+		},
+		{
+			name: "after a closed rpc",
+			text: "service Foo {\n  rpc GetFoo(Req) returns (Res);\n  |",
+		},
+		{
+			name: "option aggregate",
+			text: "service Foo {\n  rpc GetFoo(Req) returns (Res) {\n    option (google.api.http) = {\n      post: \"/v1/{name}\"\n      body: \"*\",|",
+			want: httpRule(2),
+		},
+		{
+			name: "nested aggregate",
+			text: "service Foo {\n  rpc GetFoo(Req) returns (Res) {\n    option (google.api.http) = {\n      get: \"/v1\"\n      additional_bindings {|",
+			want: httpRule(0),
+		},
+		{
+			name: "unknown option",
+			text: "service Foo {\n  rpc GetFoo(Req) returns (Res) {\n    option (unknown) = {|",
+		},
+		{
+			name: "message",
+			text: "message Foo {|",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+			view.DidOpen(uri.File("/workspace/google/api/annotations.proto"), []byte(annotations))
+
+			text := "syntax = \"proto3\";\npackage foo.v1;\nimport \"google/api/annotations.proto\";\n" + tt.text
+			i := strings.Index(text, "|")
+			lines := strings.Split(text[:i], "\n")
+			pos := protocol.Position{Line: float64(len(lines) - 1), Character: float64(len(lines[len(lines)-1]))}
+			fileURI := uri.File("/workspace/foo.proto")
+			view.DidOpen(fileURI, []byte(text[:i]+text[i+1:]))
+
+			got, err := SignatureHelps(ctx, view, fileURI, pos)
+			if err != nil {
+				t.Fatalf("SignatureHelps() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SignatureHelps() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"github.com/go-language-server/uri"
+)
+
+// SymbolKind is a kind of a symbol declared in a proto file.
+type SymbolKind int
+
+const (
+	SymbolKindPackage SymbolKind = iota + 1
+	SymbolKindMessage
+	SymbolKindEnum
+	SymbolKindService
+	SymbolKindRPC
+)
+
+// Symbol represents a declaration in a proto file.
+type Symbol struct {
+	// Name is the fully-qualified name without a leading dot, e.g. "foo.bar.Baz".
+	Name string
+	Kind SymbolKind
+	URI  uri.URI
+
+	// Line and Column are 1-based.
+	Line   int
+	Column int
+}
+
+// fileSymbols returns the symbols declared in a proto file including the nested ones.
+func fileSymbols(uri uri.URI, proto *protobuf.Proto) []Symbol {
+	if proto == nil {
+		return nil
+	}
+
+	pkg := ""
+	var symbols []Symbol
+	for _, el := range proto.Elements {
+		if p, ok := el.(*protobuf.Package); ok {
+			pkg = p.Name
+			symbols = append(symbols, Symbol{
+				Name:   p.Name,
+				Kind:   SymbolKindPackage,
+				URI:    uri,
+				Line:   p.Position.Line,
+				Column: p.Position.Column,
+			})
+			break
+		}
+	}
+
+	var walk func(scope string, elements []protobuf.Visitee)
+	walk = func(scope string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Message:
+				// An extend block declares no message but fields of the extended one.
+				if v.IsExtend {
+					continue
+				}
+				name := qualify(scope, v.Name)
+				symbols = append(symbols, Symbol{Name: name, Kind: SymbolKindMessage, URI: uri, Line: v.Position.Line, Column: v.Position.Column})
+				walk(name, v.Elements)
+			case *protobuf.Enum:
+				symbols = append(symbols, Symbol{Name: qualify(scope, v.Name), Kind: SymbolKindEnum, URI: uri, Line: v.Position.Line, Column: v.Position.Column})
+			case *protobuf.Service:
+				name := qualify(scope, v.Name)
+				symbols = append(symbols, Symbol{Name: name, Kind: SymbolKindService, URI: uri, Line: v.Position.Line, Column: v.Position.Column})
+				walk(name, v.Elements)
+			case *protobuf.RPC:
+				symbols = append(symbols, Symbol{Name: qualify(scope, v.Name), Kind: SymbolKindRPC, URI: uri, Line: v.Position.Line, Column: v.Position.Column})
+			}
+		}
+	}
+	walk(pkg, proto.Elements)
+
+	return symbols
+}
+
+// typeCandidates returns the fully-qualified names which a type reference may refer to
+// from a given scope in the order of the protobuf scoping rules.
+func typeCandidates(scope, typ string) []string {
+	if strings.HasPrefix(typ, ".") {
+		return []string{typ[1:]}
+	}
+
+	var candidates []string
+	for {
+		candidates = append(candidates, qualify(scope, typ))
+		if scope == "" {
+			return candidates
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// ResolveType returns the symbol which a type reference in a given file refers to.
+// scope is the fully-qualified name of the message the reference appears in, or the package.
+// Symbols declared in the file itself take precedence over the ones in other files.
+func ResolveType(view View, from uri.URI, scope, typ string) (Symbol, bool) {
+	for _, name := range typeCandidates(scope, typ) {
+		var symbols []Symbol
+		for _, s := range view.LookupSymbol(name) {
+			if s.Kind == SymbolKindMessage || s.Kind == SymbolKindEnum {
+				symbols = append(symbols, s)
+			}
+		}
+		if len(symbols) == 0 {
+			continue
+		}
+		for _, s := range symbols {
+			if s.URI == from {
+				return s, true
+			}
+		}
+		return symbols[0], true
+	}
+	return Symbol{}, false
+}
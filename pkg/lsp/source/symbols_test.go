@@ -0,0 +1,56 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTypeCandidates(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		typ   string
+		want  []string
+	}{
+		{
+			name:  "fully-qualified",
+			scope: "foo.bar",
+			typ:   ".baz.Qux",
+			want:  []string{"baz.Qux"},
+		},
+		{
+			name:  "relative",
+			scope: "foo.bar",
+			typ:   "Qux",
+			want:  []string{"foo.bar.Qux", "foo.Qux", "Qux"},
+		},
+		{
+			name:  "no scope",
+			scope: "",
+			typ:   "Qux.Quux",
+			want:  []string{"Qux.Quux"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typeCandidates(tt.scope, tt.typ); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("typeCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,587 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/compiler"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/textformat"
+)
+
+// textProtoExtensions are the extensions of files in protobuf text format.
+var textProtoExtensions = map[string]struct{}{
+	".textproto": {},
+	".txtpb":     {},
+	".pbtxt":     {},
+}
+
+// IsTextProto returns true if a file is in protobuf text format by its extension.
+func IsTextProto(uri uri.URI) bool {
+	_, ok := textProtoExtensions[filepath.Ext(uri.Filename())]
+	return ok
+}
+
+// textProtoSchema is the message which a text format file declares in its header,
+// with the compiled files which extensions and the types in Any are resolved against.
+type textProtoSchema struct {
+	message protoreflect.MessageDescriptor
+	files   *protoregistry.Files
+}
+
+// resolveTextProtoSchema compiles the proto files declared in the header of a text format file
+// and returns the message. The file declaring the message is looked up in the index if "proto-file" is missing.
+// The error is *textformat.Error at the header value which can't be resolved.
+func resolveTextProtoSchema(ctx context.Context, view View, from uri.URI, h textformat.Header) (*textProtoSchema, error) {
+	fileErr := func(format string, args ...interface{}) error {
+		pos := h.ProtoFilePos
+		if h.ProtoFile == "" {
+			pos = h.ProtoMessagePos
+		}
+		return &textformat.Error{Line: pos.Line, Column: pos.Column, Message: fmt.Sprintf(format, args...)}
+	}
+	messageErr := func(format string, args ...interface{}) error {
+		return &textformat.Error{Line: h.ProtoMessagePos.Line, Column: h.ProtoMessagePos.Column, Message: fmt.Sprintf(format, args...)}
+	}
+
+	var uris []uri.URI
+	if h.ProtoFile != "" {
+		u, ok := ResolveImport(view, from, h.ProtoFile)
+		if !ok {
+			return nil, fileErr("proto file %q not found", h.ProtoFile)
+		}
+		uris = append(uris, u)
+	} else {
+		for _, s := range view.LookupSymbol(h.ProtoMessage) {
+			if s.Kind == SymbolKindMessage {
+				uris = append(uris, s.URI)
+				break
+			}
+		}
+		if len(uris) == 0 {
+			return nil, messageErr("message %q not found", h.ProtoMessage)
+		}
+	}
+	for _, path := range h.ProtoImports {
+		if u, ok := ResolveImport(view, from, path); ok {
+			uris = append(uris, u)
+		}
+	}
+
+	set, err := Compile(ctx, view, uris, compiler.Options{IncludeImports: true, IncludeSourceInfo: true})
+	if err != nil {
+		return nil, fileErr("failed to compile: %v", err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fileErr("failed to compile: %v", err)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(h.ProtoMessage))
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if err != nil || !ok {
+		return nil, messageErr("message %q not found", h.ProtoMessage)
+	}
+	return &textProtoSchema{message: md, files: files}, nil
+}
+
+// field returns the field of a message with a name in text format, which is the name of a field,
+// the name of the type of a group, or the full name of an extension in brackets.
+func (s *textProtoSchema) field(md protoreflect.MessageDescriptor, name string) (protoreflect.FieldDescriptor, bool) {
+	if !strings.HasPrefix(name, "[") {
+		fd := md.Fields().ByTextName(name)
+		return fd, fd != nil
+	}
+	d, err := s.files.FindDescriptorByName(protoreflect.FullName(strings.Trim(name, "[]")))
+	if err != nil {
+		return nil, false
+	}
+	xd, ok := d.(protoreflect.ExtensionDescriptor)
+	if !ok || xd.ContainingMessage().FullName() != md.FullName() {
+		return nil, false
+	}
+	return xd, true
+}
+
+// anyType returns the message type which a type URL in brackets like "[type.googleapis.com/foo.Bar]"
+// expands a google.protobuf.Any to. It returns false for other names.
+func (s *textProtoSchema) anyType(md protoreflect.MessageDescriptor, name string) (protoreflect.MessageDescriptor, bool, error) {
+	if md.FullName() != "google.protobuf.Any" || !strings.HasPrefix(name, "[") || !strings.Contains(name, "/") {
+		return nil, false, nil
+	}
+	typ := strings.TrimSuffix(name[strings.LastIndex(name, "/")+1:], "]")
+	d, err := s.files.FindDescriptorByName(protoreflect.FullName(typ))
+	if m, ok := d.(protoreflect.MessageDescriptor); err == nil && ok {
+		return m, true, nil
+	}
+	return nil, true, fmt.Errorf("unknown type %q", typ)
+}
+
+// descend returns the message type of the value of the innermost field in a path of field names
+// starting with the declared message.
+func (s *textProtoSchema) descend(path []string) (protoreflect.MessageDescriptor, bool) {
+	md := s.message
+	for _, name := range path {
+		if m, ok, err := s.anyType(md, name); ok {
+			if err != nil {
+				return nil, false
+			}
+			md = m
+			continue
+		}
+		fd, ok := s.field(md, name)
+		if !ok || fd.Message() == nil {
+			return nil, false
+		}
+		md = fd.Message()
+	}
+	return md, true
+}
+
+// TextProtoDiagnostics returns the syntax errors of a text format file and the errors of the fields
+// checked against the message declared in its header. Fields are not checked without the header.
+func TextProtoDiagnostics(ctx context.Context, view View, uri uri.URI) ([]protocol.Diagnostic, error) {
+	data, err := readTextProto(ctx, view, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	file, err := textformat.Parse(data)
+	if err != nil {
+		return append(diagnostics, textProtoErrorDiagnostic(err)), nil
+	}
+	if file.Header.ProtoMessage == "" {
+		return diagnostics, nil
+	}
+	schema, err := resolveTextProtoSchema(ctx, view, uri, file.Header)
+	if err != nil {
+		return append(diagnostics, textProtoErrorDiagnostic(err)), nil
+	}
+
+	c := &textProtoChecker{schema: schema, diagnostics: diagnostics}
+	c.message(schema.message, file.Fields)
+	return c.diagnostics, nil
+}
+
+func textProtoErrorDiagnostic(err error) protocol.Diagnostic {
+	var rng protocol.Range
+	var terr *textformat.Error
+	if errors.As(err, &terr) {
+		line, column := terr.Line-1, terr.Column-1
+		if line < 0 {
+			line = 0
+		}
+		if column < 0 {
+			column = 0
+		}
+		rng = protocol.Range{
+			Start: protocol.Position{Line: float64(line), Character: float64(column)},
+			End:   protocol.Position{Line: float64(line), Character: float64(column + 1)},
+		}
+		err = errors.New(terr.Message)
+	}
+	return protocol.Diagnostic{
+		Range:    rng,
+		Severity: protocol.SeverityError,
+		Source:   DiagnosticSource,
+		Message:  err.Error(),
+	}
+}
+
+// textProtoChecker checks the fields of a text format file against their types.
+type textProtoChecker struct {
+	schema      *textProtoSchema
+	diagnostics []protocol.Diagnostic
+}
+
+func (c *textProtoChecker) errorf(start, end textformat.Position, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, protocol.Diagnostic{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: float64(start.Line - 1), Character: float64(start.Column - 1)},
+			End:   protocol.Position{Line: float64(end.Line - 1), Character: float64(end.Column - 1)},
+		},
+		Severity: protocol.SeverityError,
+		Source:   DiagnosticSource,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// message checks the fields of a message value.
+func (c *textProtoChecker) message(md protoreflect.MessageDescriptor, fields []*textformat.Field) {
+	set := make(map[protoreflect.FullName]bool)
+	// oneofs maps the oneofs to the names of the fields set in them.
+	oneofs := make(map[protoreflect.FullName]string)
+	for _, f := range fields {
+		name := f.Name
+		if f.Extension {
+			name = "[" + f.Name + "]"
+		}
+
+		if typ, ok, err := c.schema.anyType(md, name); ok {
+			if err != nil {
+				c.errorf(f.Pos, f.End, "%v", err)
+				continue
+			}
+			for _, v := range f.Values {
+				if v.Kind != textformat.ValueMessage {
+					c.errorf(v.Pos, v.End, "field %s needs a message value", name)
+					continue
+				}
+				c.message(typ, v.Fields)
+			}
+			continue
+		}
+
+		fd, ok := c.schema.field(md, name)
+		if !ok {
+			c.errorf(f.Pos, f.End, "unknown field %s in message %s", name, md.FullName())
+			continue
+		}
+		if fd.Cardinality() != protoreflect.Repeated {
+			switch {
+			case f.List:
+				c.errorf(f.Pos, f.End, "field %s is not repeated", name)
+			case set[fd.FullName()]:
+				c.errorf(f.Pos, f.End, "field %s is set more than once", name)
+			}
+		}
+		set[fd.FullName()] = true
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			if other, ok := oneofs[od.FullName()]; ok && other != name {
+				c.errorf(f.Pos, f.End, "field %s and %s of oneof %s are both set", other, name, od.Name())
+			}
+			oneofs[od.FullName()] = name
+		}
+
+		for _, v := range f.Values {
+			c.value(fd, name, v)
+		}
+	}
+}
+
+// value checks a value of a field.
+func (c *textProtoChecker) value(fd protoreflect.FieldDescriptor, name string, v *textformat.Value) {
+	if fd.Message() != nil {
+		if v.Kind != textformat.ValueMessage {
+			c.errorf(v.Pos, v.End, "field %s needs a message value", name)
+			return
+		}
+		c.message(fd.Message(), v.Fields)
+		return
+	}
+	if v.Kind == textformat.ValueMessage {
+		c.errorf(v.Pos, v.End, "field %s of type %s needs a scalar value", name, textProtoType(fd))
+		return
+	}
+	if msg := checkScalar(fd, v); msg != "" {
+		c.errorf(v.Pos, v.End, "%s", msg)
+	}
+}
+
+// checkScalar returns the error message if a value is not valid for a scalar or an enum field,
+// or an empty string otherwise.
+func checkScalar(fd protoreflect.FieldDescriptor, v *textformat.Value) string {
+	invalid := fmt.Sprintf("invalid value %s for field %s of type %s", v.Text, fd.TextName(), textProtoType(fd))
+	parseInt := func(bits int) string {
+		if _, err := strconv.ParseInt(v.Text, 0, bits); v.Kind != textformat.ValueNumber || err != nil {
+			return invalid
+		}
+		return ""
+	}
+	parseUint := func(bits int) string {
+		if _, err := strconv.ParseUint(v.Text, 0, bits); v.Kind != textformat.ValueNumber || err != nil {
+			return invalid
+		}
+		return ""
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		switch v.Text {
+		case "true", "True", "t", "false", "False", "f", "1", "0":
+			return ""
+		}
+		return invalid
+	case protoreflect.EnumKind:
+		ed := fd.Enum()
+		switch v.Kind {
+		case textformat.ValueIdent:
+			if ed.Values().ByName(protoreflect.Name(v.Text)) == nil {
+				return fmt.Sprintf("unknown value %s of enum %s", v.Text, ed.FullName())
+			}
+			return ""
+		case textformat.ValueNumber:
+			n, err := strconv.ParseInt(v.Text, 0, 32)
+			if err != nil {
+				return invalid
+			}
+			// Closed enums of proto2 accept only the declared numbers.
+			if ed.ParentFile().Syntax() == protoreflect.Proto2 && ed.Values().ByNumber(protoreflect.EnumNumber(n)) == nil {
+				return fmt.Sprintf("unknown value %s of enum %s", v.Text, ed.FullName())
+			}
+			return ""
+		}
+		return invalid
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return parseInt(32)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return parseInt(64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return parseUint(32)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return parseUint(64)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		switch v.Kind {
+		case textformat.ValueIdent:
+			switch strings.ToLower(strings.TrimPrefix(v.Text, "-")) {
+			case "inf", "infinity", "nan":
+				return ""
+			}
+		case textformat.ValueNumber:
+			text := v.Text
+			if !strings.HasPrefix(strings.TrimPrefix(text, "-"), "0x") {
+				text = strings.TrimRight(text, "fF")
+			}
+			if _, err := strconv.ParseFloat(text, 64); err == nil {
+				return ""
+			}
+		}
+		return invalid
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		if v.Kind != textformat.ValueString {
+			return invalid
+		}
+	}
+	return ""
+}
+
+// textProtoType returns the type of a field as written in proto files.
+func textProtoType(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsMap():
+		return fmt.Sprintf("map<%s, %s>", textProtoType(fd.MapKey()), textProtoType(fd.MapValue()))
+	case fd.Message() != nil:
+		return string(fd.Message().FullName())
+	case fd.Enum() != nil:
+		return string(fd.Enum().FullName())
+	}
+	return fd.Kind().String()
+}
+
+// textProtoLabel returns the type of a field with its label.
+func textProtoLabel(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsList():
+		return "repeated " + textProtoType(fd)
+	case fd.Cardinality() == protoreflect.Required:
+		return "required " + textProtoType(fd)
+	case fd.HasOptionalKeyword():
+		return "optional " + textProtoType(fd)
+	}
+	return textProtoType(fd)
+}
+
+// descriptorComments returns the leading comments of a declaration, or the trailing ones if it has none.
+func descriptorComments(d protoreflect.Descriptor) string {
+	loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+	text := loc.LeadingComments
+	if strings.TrimSpace(text) == "" {
+		text = loc.TrailingComments
+	}
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TextProtoCompletions returns the completion items at a given position in a text format file:
+// the fields of the enclosing message where a field name is expected,
+// and the values of an enum or a bool field after the name of it.
+func TextProtoCompletions(ctx context.Context, view View, uri uri.URI, pos protocol.Position) ([]protocol.CompletionItem, error) {
+	data, err := readTextProto(ctx, view, uri)
+	if err != nil {
+		return nil, err
+	}
+	header := textformat.ParseHeader(data)
+	if header.ProtoMessage == "" {
+		return nil, nil
+	}
+	schema, err := resolveTextProtoSchema(ctx, view, uri, header)
+	if err != nil {
+		return nil, err
+	}
+
+	c := textformat.ContextAt(data, textformat.Position{Line: int(pos.Line) + 1, Column: int(pos.Character) + 1})
+	md, ok := schema.descend(c.Path)
+	if !ok {
+		return nil, nil
+	}
+
+	var items []protocol.CompletionItem
+	if c.Field == "" {
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			name := fd.TextName()
+			item := protocol.CompletionItem{
+				Label:      name,
+				Kind:       float64(protocol.FieldCompletion),
+				Detail:     textProtoLabel(fd),
+				InsertText: name + ": ",
+			}
+			if fd.Message() != nil {
+				item.InsertText = name + " "
+			}
+			if doc := descriptorComments(fd); doc != "" {
+				item.Documentation = doc
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	fd, ok := schema.field(md, c.Field)
+	if !ok {
+		return nil, nil
+	}
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			ev := values.Get(i)
+			item := protocol.CompletionItem{
+				Label:  string(ev.Name()),
+				Kind:   float64(protocol.EnumMemberCompletion),
+				Detail: strconv.Itoa(int(ev.Number())),
+			}
+			if doc := descriptorComments(ev); doc != "" {
+				item.Documentation = doc
+			}
+			items = append(items, item)
+		}
+	case protoreflect.BoolKind:
+		for _, b := range []string{"true", "false"} {
+			items = append(items, protocol.CompletionItem{
+				Label: b,
+				Kind:  float64(protocol.KeywordCompletion),
+			})
+		}
+	}
+	return items, nil
+}
+
+// TextProtoHover returns the declaration and the comments of the field or the enum value
+// at a given position in a text format file.
+func TextProtoHover(ctx context.Context, view View, uri uri.URI, pos protocol.Position) (*protocol.Hover, error) {
+	data, err := readTextProto(ctx, view, uri)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	if int(pos.Line) >= len(lines) {
+		return nil, nil
+	}
+	line := lines[int(pos.Line)]
+	start, end := int(pos.Character), int(pos.Character)
+	if end > len(line) {
+		return nil, nil
+	}
+	for start > 0 && isTextProtoIdentChar(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isTextProtoIdentChar(line[end]) {
+		end++
+	}
+	if start == end {
+		return nil, nil
+	}
+	word := line[start:end]
+
+	header := textformat.ParseHeader(data)
+	if header.ProtoMessage == "" {
+		return nil, nil
+	}
+	schema, err := resolveTextProtoSchema(ctx, view, uri, header)
+	if err != nil {
+		return nil, err
+	}
+	c := textformat.ContextAt(data, textformat.Position{Line: int(pos.Line) + 1, Column: start + 1})
+	md, ok := schema.descend(c.Path)
+	if !ok {
+		return nil, nil
+	}
+
+	var decl, doc string
+	if c.Field == "" {
+		fd, ok := schema.field(md, word)
+		if !ok {
+			return nil, nil
+		}
+		decl = fmt.Sprintf("%s %s = %d;", textProtoLabel(fd), fd.Name(), fd.Number())
+		doc = descriptorComments(fd)
+	} else {
+		fd, ok := schema.field(md, c.Field)
+		if !ok || fd.Enum() == nil {
+			return nil, nil
+		}
+		ev := fd.Enum().Values().ByName(protoreflect.Name(word))
+		if ev == nil {
+			return nil, nil
+		}
+		decl = fmt.Sprintf("%s = %d;", ev.Name(), ev.Number())
+		doc = descriptorComments(ev)
+	}
+
+	value := "```proto\n" + decl + "\n```"
+	if doc != "" {
+		value += "\n\n" + doc
+	}
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: value},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: pos.Line, Character: float64(start)},
+			End:   protocol.Position{Line: pos.Line, Character: float64(end)},
+		},
+	}, nil
+}
+
+func isTextProtoIdentChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// readTextProto returns the content of a text format file known to a view.
+func readTextProto(ctx context.Context, view View, uri uri.URI) ([]byte, error) {
+	if !IsTextProto(uri) {
+		return nil, fmt.Errorf("%s is not a text format file", uri.Filename())
+	}
+	f, err := view.GetFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := f.Read(ctx)
+	return data, err
+}
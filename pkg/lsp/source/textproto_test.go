@@ -0,0 +1,320 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-language-server/protocol"
+	"github.com/go-language-server/uri"
+)
+
+const textProtoSchemaFixture = `syntax = "proto2";
+package config;
+
+// Server is the configuration of a server.
+message Server {
+  // The name of the server.
+  optional string name = 1;
+  optional int32 port = 2;
+  repeated string hosts = 3;
+  optional Mode mode = 4;
+  optional bool debug = 5;
+  optional Limits limits = 6;
+  oneof auth {
+    string token = 7;
+    string password = 8;
+  }
+}
+
+message Limits {
+  optional uint32 connections = 1;
+  optional double ratio = 2;
+}
+
+enum Mode {
+  // Serve only reads.
+  READ_ONLY = 0;
+  READ_WRITE = 1;
+}
+`
+
+func newTextProtoView(t *testing.T) View {
+	t.Helper()
+
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	view.DidOpen(uri.File("/workspace/config/server.proto"), []byte(textProtoSchemaFixture))
+	return view
+}
+
+func TestIsTextProto(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     bool
+	}{
+		{filename: "/workspace/server.textproto", want: true},
+		{filename: "/workspace/server.txtpb", want: true},
+		{filename: "/workspace/server.pbtxt", want: true},
+		{filename: "/workspace/server.proto", want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := IsTextProto(uri.File(tt.filename)); got != tt.want {
+				t.Errorf("IsTextProto() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextProtoDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "valid",
+			data: `# proto-file: config/server.proto
+# proto-message: config.Server
+name: "api"
+port: 8080
+hosts: ["a", "b"]
+mode: READ_WRITE
+debug: true
+limits { connections: 10 ratio: 0.5 }
+token: "secret"
+`,
+			want: []string{},
+		},
+		{
+			name: "message looked up without proto-file",
+			data: `# proto-message: config.Server
+name: "api"
+`,
+			want: []string{},
+		},
+		{
+			name: "no header",
+			data: `unknown: 1
+`,
+			want: []string{},
+		},
+		{
+			name: "syntax error",
+			data: `# proto-message: config.Server
+name "api"
+`,
+			want: []string{`1:5: expected ":" after field "name"`},
+		},
+		{
+			name: "unknown proto file",
+			data: `# proto-file: config/missing.proto
+# proto-message: config.Server
+`,
+			want: []string{`0:14: proto file "config/missing.proto" not found`},
+		},
+		{
+			name: "unknown message",
+			data: `# proto-file: config/server.proto
+# proto-message: config.Client
+`,
+			want: []string{`1:17: message "config.Client" not found`},
+		},
+		{
+			name: "type errors",
+			data: `# proto-message: config.Server
+nmae: "api"
+port: "8080"
+port: 80
+mode: READ
+debug: yes
+limits: 1
+name { }
+limits { connections: -1 ratio: inf }
+token: "a"
+password: "b"
+`,
+			want: []string{
+				"1:0: unknown field nmae in message config.Server",
+				"2:6: invalid value \"8080\" for field port of type int32",
+				"3:0: field port is set more than once",
+				"4:6: unknown value READ of enum config.Mode",
+				"5:7: invalid value yes for field debug of type bool",
+				"6:8: field limits needs a message value",
+				"7:5: field name of type string needs a scalar value",
+				"8:0: field limits is set more than once",
+				"8:22: invalid value -1 for field connections of type uint32",
+				"10:0: field token and password of oneof auth are both set",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := newTextProtoView(t)
+			u := uri.File("/workspace/server.textproto")
+			view.DidOpen(u, []byte(tt.data))
+
+			diagnostics, err := Diagnostics(ctx, view, u)
+			if err != nil {
+				t.Fatalf("Diagnostics() error = %v", err)
+			}
+			got := []string{}
+			for _, d := range diagnostics {
+				got = append(got, fmt.Sprintf("%d:%d: %s", int(d.Range.Start.Line), int(d.Range.Start.Character), d.Message))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diagnostics() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextProtoCompletions(t *testing.T) {
+	const data = `# proto-message: config.Server
+name: "api"
+mode: READ_ONLY
+limits {
+  
+}
+`
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want []string
+	}{
+		{
+			name: "fields",
+			pos:  protocol.Position{Line: 2, Character: 0},
+			want: []string{"name: ", "port: ", "hosts: ", "mode: ", "debug: ", "limits ", "token: ", "password: "},
+		},
+		{
+			name: "enum values",
+			pos:  protocol.Position{Line: 2, Character: 6},
+			want: []string{"READ_ONLY", "READ_WRITE"},
+		},
+		{
+			name: "nested fields",
+			pos:  protocol.Position{Line: 4, Character: 2},
+			want: []string{"connections: ", "ratio: "},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := newTextProtoView(t)
+			u := uri.File("/workspace/server.txtpb")
+			view.DidOpen(u, []byte(data))
+
+			items, err := TextProtoCompletions(ctx, view, u, tt.pos)
+			if err != nil {
+				t.Fatalf("TextProtoCompletions() error = %v", err)
+			}
+			var got []string
+			for _, item := range items {
+				text := item.InsertText
+				if text == "" {
+					text = item.Label
+				}
+				got = append(got, text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TextProtoCompletions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextProtoHover(t *testing.T) {
+	const data = `# proto-message: config.Server
+name: "api"
+mode: READ_ONLY
+hosts: "a"
+`
+	tests := []struct {
+		name string
+		pos  protocol.Position
+		want string
+	}{
+		{
+			name: "field",
+			pos:  protocol.Position{Line: 1, Character: 2},
+			want: "```proto\noptional string name = 1;\n```\n\nThe name of the server.",
+		},
+		{
+			name: "repeated field",
+			pos:  protocol.Position{Line: 3, Character: 0},
+			want: "```proto\nrepeated string hosts = 3;\n```",
+		},
+		{
+			name: "enum value",
+			pos:  protocol.Position{Line: 2, Character: 8},
+			want: "```proto\nREAD_ONLY = 0;\n```\n\nServe only reads.",
+		},
+		{
+			name: "string value",
+			pos:  protocol.Position{Line: 1, Character: 8},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			view := newTextProtoView(t)
+			u := uri.File("/workspace/server.pbtxt")
+			view.DidOpen(u, []byte(data))
+
+			hover, err := TextProtoHover(ctx, view, u, tt.pos)
+			if err != nil {
+				t.Fatalf("TextProtoHover() error = %v", err)
+			}
+			var got string
+			if hover != nil {
+				got = hover.Contents.Value
+			}
+			if got != tt.want {
+				t.Errorf("TextProtoHover() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTextProto(t *testing.T) {
+	ctx := context.Background()
+	view := newTextProtoView(t)
+	u := uri.File("/workspace/server.textproto")
+	view.DidOpen(u, []byte("name:\"api\"\nlimits{connections:1}\n"))
+
+	_, formatted, err := Format(ctx, view, u)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	want := "name: \"api\"\nlimits { connections: 1 }\n"
+	if string(formatted) != want {
+		t.Errorf("Format() = %q, want %q", formatted, want)
+	}
+
+	view.DidOpen(u, []byte("name {\n"))
+	if _, _, err := Format(ctx, view, u); err == nil {
+		t.Error("Format() error = nil, want a parse error")
+	}
+}
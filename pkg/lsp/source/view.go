@@ -21,12 +21,14 @@ package source
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/go-language-server/uri"
 
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
 	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/parser"
 	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/registry"
 )
@@ -45,6 +47,23 @@ type View interface {
 	// Folder returns the root folder for this view.
 	Folder() uri.URI
 
+	// AdHoc returns true if this view was created for files opened outside of any workspace folder.
+	AdHoc() bool
+
+	// Config returns the project configuration which applies to the given URI.
+	// The configuration sent by the client is merged into it.
+	Config(uri uri.URI) config.Project
+
+	// Options returns the configuration sent by the client for this view.
+	Options() config.LSP
+
+	// SetOptions sets the configuration sent by the client for this view.
+	SetOptions(options config.LSP)
+
+	// ReloadConfig discovers the project configuration files in the folder
+	// of this view and replaces the current configurations with them.
+	ReloadConfig(ctx context.Context) error
+
 	// Called to set the effective contents of a file from this view.
 	SetContent(ctx context.Context, uri uri.URI, content []byte)
 
@@ -65,6 +84,34 @@ type View interface {
 
 	// IsOpen can be called to check if the editor has a file currently open.
 	IsOpen(uri uri.URI) bool
+
+	// OpenFiles returns the URIs of the files currently open in the editor.
+	OpenFiles() []uri.URI
+
+	// DidChangeOnDisk is invoked each time a file is created, changed or deleted on disk.
+	// Files open in the editor keep their contents since the editor owns them.
+	// It returns the URIs of the open files which import the file directly or indirectly.
+	DidChangeOnDisk(ctx context.Context, uri uri.URI, deleted bool) ([]uri.URI, error)
+
+	// Index walks the folder of this view and parses all proto files in it in the background.
+	// progress is called each time a file is indexed with the number of indexed files and the total.
+	Index(ctx context.Context, progress func(indexed, total int)) error
+
+	// LookupSymbol returns the symbols declared with a given fully-qualified name
+	// in the files known to this view.
+	LookupSymbol(name string) []Symbol
+
+	// KnownFiles returns the URIs of the files known to this view,
+	// which are the open, indexed and imported ones.
+	KnownFiles() []uri.URI
+
+	// DidChangeGoFile forgets the Go packages parsed from the folder for implementations
+	// since a Go file is changed on disk.
+	DidChangeGoFile(uri uri.URI)
+
+	// ClearCache forgets the files which are not open in the editor and the ones read from git,
+	// so that they are read again when they are needed.
+	ClearCache()
 }
 
 type view struct {
@@ -77,6 +124,10 @@ type view struct {
 	// folder is the root of this view.
 	folder uri.URI
 
+	// adHoc is true if this view is rooted at the directory of a file opened
+	// outside of any workspace folder. Such a view never walks its folder.
+	adHoc bool
+
 	// keep track of files by uri and by basename, a single file may be mapped
 	// to multiple uris, and the same basename may map to multiple files
 	filesByURI  map[uri.URI]File
@@ -89,6 +140,21 @@ type view struct {
 	// ignoredURIs is the set of URIs of files that we ignore.
 	ignoredURIs  map[uri.URI]struct{}
 	ignoredURIMu *sync.RWMutex
+
+	// projects is the set of project configurations keyed by their directories.
+	projects  map[string]config.Project
+	projectMu *sync.RWMutex
+
+	// options is the configuration sent by the client.
+	options   config.LSP
+	optionsMu *sync.RWMutex
+
+	// goPackages is the Go packages in the folder parsed for implementations, or nil if they aren't parsed yet.
+	goPackages  map[goPackageKey]*goPackage
+	goPackageMu *sync.Mutex
+
+	// revisions caches the git revisions and the files at them compared with for breaking changes.
+	revisions *revisionCache
 }
 
 var _ View = (*view)(nil)
@@ -106,9 +172,25 @@ func NewView(session Session, name string, folder uri.URI) View {
 		openFileMu:   &sync.RWMutex{},
 		ignoredURIs:  make(map[uri.URI]struct{}),
 		ignoredURIMu: &sync.RWMutex{},
+		projects:     make(map[string]config.Project),
+		projectMu:    &sync.RWMutex{},
+		options:      config.DefaultLSPConfig,
+		optionsMu:    &sync.RWMutex{},
+		goPackageMu:  &sync.Mutex{},
+		revisions:    newRevisionCache(),
 	}
 }
 
+// NewAdHocView returns a view for files opened outside of any workspace folder,
+// which is rooted at a given directory.
+// Unlike a view for a workspace folder, it doesn't walk the directory to index files or
+// to discover configuration files but only looks up the nearest configuration file in its ancestors.
+func NewAdHocView(session Session, folder uri.URI) View {
+	v := NewView(session, folder.Filename(), folder).(*view)
+	v.adHoc = true
+	return v
+}
+
 func (v *view) Session() Session {
 	return v.session
 }
@@ -121,6 +203,73 @@ func (v *view) Folder() uri.URI {
 	return v.folder
 }
 
+func (v *view) AdHoc() bool {
+	return v.adHoc
+}
+
+func (v *view) Config(uri uri.URI) config.Project {
+	v.projectMu.RLock()
+	p, ok := nearestProject(v.projects, uri.Filename())
+	v.projectMu.RUnlock()
+	if !ok {
+		p = config.DefaultProjectConfig
+		p.Dir = v.folder.Filename()
+	}
+
+	options := v.Options()
+
+	includePaths := append([]string(nil), p.IncludePaths...)
+	for _, path := range options.IncludePaths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(v.folder.Filename(), path)
+		}
+		includePaths = append(includePaths, path)
+	}
+	p.IncludePaths = includePaths
+
+	p.Lint.Enable = append(append([]string(nil), p.Lint.Enable...), options.Lint.Enable...)
+	p.Lint.Disable = append(append([]string(nil), p.Lint.Disable...), options.Lint.Disable...)
+
+	if options.Format != nil {
+		p.Format = *options.Format
+	}
+	if options.Breaking.Against != "" {
+		p.Breaking.Against = options.Breaking.Against
+	}
+
+	return p
+}
+
+func (v *view) Options() (options config.LSP) {
+	v.optionsMu.RLock()
+	options = v.options
+	v.optionsMu.RUnlock()
+	return
+}
+
+func (v *view) SetOptions(options config.LSP) {
+	v.optionsMu.Lock()
+	v.options = options
+	v.optionsMu.Unlock()
+}
+
+func (v *view) ReloadConfig(context.Context) error {
+	load := loadProjects
+	if v.adHoc {
+		load = loadNearestProjects
+	}
+	projects, err := load(v.folder.Filename())
+	if projects == nil {
+		return err
+	}
+
+	v.projectMu.Lock()
+	v.projects = projects
+	v.projectMu.Unlock()
+
+	return err
+}
+
 func (v *view) GetFile(uri uri.URI) (File, error) {
 	f, err := v.findFile(uri)
 	if err != nil {
@@ -130,21 +279,52 @@ func (v *view) GetFile(uri uri.URI) (File, error) {
 		return f, nil
 	}
 
-	file := &protoFile{
-		File: &file{
-			session: v.Session(),
-			view:    v,
-			uri:     uri,
-		},
+	// The file is not known to the view yet, so read it from disk if it exists.
+	if err := v.loadFile(uri); err == nil {
+		if f, err := v.findFile(uri); err == nil && f != nil {
+			return f, nil
+		}
+	}
+
+	var file File = &file{
+		session: v.Session(),
+		view:    v,
+		uri:     uri,
+	}
+	if !IsTextProto(uri) {
+		file = &protoFile{File: file}
 	}
 	v.mapFile(uri, file)
 
 	return file, nil
 }
 
+// newFile returns a file with given content, which is parsed if it is a proto file.
+// Files in text format are not parsed here but on every request since they depend on the proto files.
+func (v *view) newFile(uri uri.URI, data []byte, saved bool) File {
+	f := &file{
+		session: v.Session(),
+		view:    v,
+		uri:     uri,
+		data:    data,
+		hash:    hashContent(data),
+		saved:   saved,
+	}
+	if IsTextProto(uri) {
+		return f
+	}
+
+	pf := &protoFile{File: f}
+	// TODO:
+	//  Control times of parse of proto.
+	//  Currently it parses every time of file change.
+	pf.parse(data)
+	return pf
+}
+
 // SetContent sets the file contents for a file.
 func (v *view) SetContent(ctx context.Context, uri uri.URI, data []byte) {
-	if !v.Ignore(uri) {
+	if v.Ignore(uri) {
 		return
 	}
 
@@ -152,25 +332,11 @@ func (v *view) SetContent(ctx context.Context, uri uri.URI, data []byte) {
 	defer v.fileMu.Unlock()
 
 	if data == nil {
-		delete(v.filesByURI, uri)
+		v.unmapFile(uri)
 		return
 	}
 
-	pf := &protoFile{
-		File: &file{
-			session: v.Session(),
-			uri:     uri,
-			data:    data,
-			hash:    hashContent(data),
-		},
-	}
-
-	// TODO:
-	//  Control times of parse of proto.
-	//  Currently it parses every time of file change.
-	pf.proto = parseProto(data)
-
-	v.filesByURI[uri] = pf
+	v.storeFile(uri, v.newFile(uri, data, false))
 }
 
 func (v *view) Ignore(uri uri.URI) (ok bool) {
@@ -181,6 +347,7 @@ func (v *view) Ignore(uri uri.URI) (ok bool) {
 }
 
 func (v *view) Shutdown(ctx context.Context) error {
+	v.revisions.clear()
 	return v.session.RemoveView(ctx, v)
 }
 
@@ -192,6 +359,8 @@ func (v *view) DidOpen(uri uri.URI, text []byte) {
 }
 
 func (v *view) DidSave(uri uri.URI) {
+	v.revisions.forgetCommits()
+
 	v.fileMu.Lock()
 	if file, ok := v.filesByURI[uri]; ok {
 		file.SetSaved(true)
@@ -216,20 +385,159 @@ func (v *view) IsOpen(uri uri.URI) bool {
 	return open
 }
 
-func (v *view) openFile(uri uri.URI, data []byte) {
+func (v *view) OpenFiles() []uri.URI {
+	v.openFileMu.RLock()
+	defer v.openFileMu.RUnlock()
+
+	uris := make([]uri.URI, 0, len(v.openFiles))
+	for uri, open := range v.openFiles {
+		if open {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+func (v *view) DidChangeOnDisk(ctx context.Context, uri uri.URI, deleted bool) ([]uri.URI, error) {
+	v.revisions.forgetCommits()
+	if !v.IsOpen(uri) {
+		if deleted {
+			v.forgetFile(uri)
+		} else if err := v.loadFile(uri); err != nil {
+			v.forgetFile(uri)
+			return nil, err
+		}
+	}
+	return v.openDependents(uri), nil
+}
+
+func (v *view) DidChangeGoFile(uri uri.URI) {
+	v.goPackageMu.Lock()
+	v.goPackages = nil
+	v.goPackageMu.Unlock()
+}
+
+func (v *view) ClearCache() {
+	v.DidChangeGoFile("")
+	v.revisions.clear()
+
 	v.fileMu.Lock()
+	defer v.fileMu.Unlock()
+
+	for uri, f := range v.filesByURI {
+		if !v.IsOpen(f.URI()) {
+			v.unmapFile(uri)
+		}
+	}
+}
+
+// openDependents returns the URIs of the open files which import a given file directly or indirectly.
+func (v *view) openDependents(target uri.URI) []uri.URI {
+	v.fileMu.RLock()
+	files := make(map[uri.URI]File, len(v.filesByURI))
+	for uri, f := range v.filesByURI {
+		files[uri] = f
+	}
+	v.fileMu.RUnlock()
 
-	pf := &protoFile{
-		File: &file{
-			view: v,
-			uri:  uri,
-			data: data,
-			hash: hashContent(data),
-		},
+	importers := make(map[uri.URI][]uri.URI)
+	for uri, f := range files {
+		pf, ok := f.(ProtoFile)
+		if !ok {
+			continue
+		}
+		for _, path := range Imports(pf) {
+			if imported, ok := ResolveImport(v, uri, path); ok {
+				importers[imported] = append(importers[imported], uri)
+				continue
+			}
+			// The target may be deleted already, so it is matched by the candidates.
+			for _, candidate := range importCandidates(v, uri, path) {
+				if candidate == target {
+					importers[target] = append(importers[target], uri)
+					break
+				}
+			}
+		}
 	}
 
-	pf.proto = parseProto(data)
-	v.filesByURI[uri] = pf
+	var dependents []uri.URI
+	visited := map[uri.URI]struct{}{target: {}}
+	queue := []uri.URI{target}
+	for len(queue) > 0 {
+		uri := queue[0]
+		queue = queue[1:]
+		for _, importer := range importers[uri] {
+			if _, ok := visited[importer]; ok {
+				continue
+			}
+			visited[importer] = struct{}{}
+			queue = append(queue, importer)
+			if v.IsOpen(importer) {
+				dependents = append(dependents, importer)
+			}
+		}
+	}
+	return dependents
+}
+
+// loadFile reads a file from disk and replaces the one the view has.
+func (v *view) loadFile(uri uri.URI) error {
+	data, err := ioutil.ReadFile(uri.Filename())
+	if err != nil {
+		return err
+	}
+
+	f := v.newFile(uri, data, true)
+
+	v.fileMu.Lock()
+	defer v.fileMu.Unlock()
+	// The file may have been opened while reading it.
+	if v.IsOpen(uri) {
+		return nil
+	}
+	v.storeFile(uri, f)
+	return nil
+}
+
+// forgetFile removes a file from the view.
+func (v *view) forgetFile(uri uri.URI) {
+	v.fileMu.Lock()
+	v.unmapFile(uri)
+	v.fileMu.Unlock()
+}
+
+// storeFile replaces a file in the maps of the view.
+// fileMu must be held when calling this method.
+func (v *view) storeFile(uri uri.URI, f File) {
+	v.unmapFile(uri)
+	v.filesByURI[uri] = f
+	basename := filepath.Base(uri.Filename())
+	v.filesByBase[basename] = append(v.filesByBase[basename], f)
+}
+
+// unmapFile removes a file from the maps of the view.
+// fileMu must be held when calling this method.
+func (v *view) unmapFile(uri uri.URI) {
+	delete(v.filesByURI, uri)
+	basename := filepath.Base(uri.Filename())
+	files := v.filesByBase[basename][:0]
+	for _, f := range v.filesByBase[basename] {
+		if f.URI() != uri {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		delete(v.filesByBase, basename)
+		return
+	}
+	v.filesByBase[basename] = files
+}
+
+func (v *view) openFile(uri uri.URI, data []byte) {
+	v.fileMu.Lock()
+
+	v.storeFile(uri, v.newFile(uri, data, false))
 
 	v.fileMu.Unlock()
 }
@@ -257,7 +565,9 @@ func (v *view) findFile(uri uri.URI) (File, error) {
 			continue
 		}
 		if os.SameFile(targetStat, stat) {
-			v.mapFile(uri, f)
+			// fileMu is already held, so map the file without calling mapFile.
+			v.filesByURI[uri] = f
+			v.filesByBase[basename] = append(v.filesByBase[basename], f)
 			return f, nil
 		}
 	}
@@ -274,11 +584,11 @@ func (v *view) mapFile(uri uri.URI, f File) {
 	v.fileMu.Unlock()
 }
 
-func parseProto(data []byte) registry.Proto {
+func parseProto(data []byte) (registry.Proto, error) {
 	buf := bytes.NewBuffer(data)
 	proto, err := parser.ParseProto(buf)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return proto
+	return proto, nil
 }
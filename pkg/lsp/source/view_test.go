@@ -13,3 +13,120 @@
 // limitations under the License.
 
 package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-language-server/uri"
+)
+
+func TestView_DidChangeOnDisk(t *testing.T) {
+	root, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"a.proto": `syntax = "proto3";
+message A {}
+`,
+		"b.proto": `syntax = "proto3";
+import "a.proto";
+message B { A a = 1; }
+`,
+		"c/c.proto": `syntax = "proto3";
+import "b.proto";
+message C { B b = 1; }
+`,
+		"d.proto": `syntax = "proto3";
+message D {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	view := NewView(NewSession(), "root", uri.File(root))
+	for _, name := range []string{"b.proto", "c/c.proto", "d.proto"} {
+		path := filepath.Join(root, name)
+		view.DidOpen(uri.File(path), []byte(files[name]))
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		deleted bool
+		want    []string
+	}{
+		{
+			name: "transitive dependents",
+			file: "a.proto",
+			want: []string{"b.proto", "c/c.proto"},
+		},
+		{
+			name: "no dependents",
+			file: "d.proto",
+		},
+		{
+			name:    "deleted",
+			file:    "a.proto",
+			deleted: true,
+			want:    []string{"b.proto", "c/c.proto"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(root, tt.file)
+			if tt.deleted {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			uris, err := view.DidChangeOnDisk(ctx, uri.File(path), tt.deleted)
+			if err != nil {
+				t.Fatalf("DidChangeOnDisk() error = %v", err)
+			}
+			var got []string
+			for _, u := range uris {
+				rel, err := filepath.Rel(root, u.Filename())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DidChangeOnDisk() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestView_ClearCache(t *testing.T) {
+	ctx := context.Background()
+	view := NewView(NewSession(), "workspace", uri.File("/workspace"))
+	openURI := uri.File("/workspace/open.proto")
+	view.DidOpen(openURI, []byte("syntax = \"proto3\";\n"))
+	view.SetContent(ctx, uri.File("/workspace/closed.proto"), []byte("syntax = \"proto3\";\n"))
+
+	view.ClearCache()
+	if got, want := view.KnownFiles(), []uri.URI{openURI}; !reflect.DeepEqual(got, want) {
+		t.Errorf("KnownFiles() = %v, want %v", got, want)
+	}
+}
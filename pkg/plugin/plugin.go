@@ -0,0 +1,131 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// File is a file generated by a plugin.
+type File struct {
+	// Name is the path to the file relative to the output directory.
+	Name    string
+	Content string
+}
+
+// Run runs the executable of a plugin with a request on stdin and returns the files in the response on stdout.
+// Contents for insertion points are inserted into the files generated before them in the same response.
+// The plugin is killed when the context is done.
+func Run(ctx context.Context, path string, req *pluginpb.CodeGeneratorRequest) ([]File, error) {
+	in, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// The plugin is killed when the context is done, which is more useful to report than the exit status.
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s: %w", path, ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", path, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return nil, fmt.Errorf("%s: invalid response: %w", path, err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s: %s", path, resp.GetError())
+	}
+	return Files(resp)
+}
+
+// Files returns the files in a response in the same way as protoc writes them.
+// A file without a name continues the previous one.
+func Files(resp *pluginpb.CodeGeneratorResponse) ([]File, error) {
+	var files []File
+	for _, f := range resp.File {
+		switch {
+		case f.GetName() == "":
+			if len(files) == 0 {
+				return nil, fmt.Errorf("the first file has no name")
+			}
+			files[len(files)-1].Content += f.GetContent()
+		case f.GetInsertionPoint() != "":
+			i := indexFile(files, f.GetName())
+			if i < 0 {
+				return nil, fmt.Errorf("%s: the file to insert into is not generated", f.GetName())
+			}
+			content, ok := insert(files[i].Content, f.GetInsertionPoint(), f.GetContent())
+			if !ok {
+				return nil, fmt.Errorf("%s: insertion point %q is not found", f.GetName(), f.GetInsertionPoint())
+			}
+			files[i].Content = content
+		default:
+			files = append(files, File{Name: f.GetName(), Content: f.GetContent()})
+		}
+	}
+	return files, nil
+}
+
+func indexFile(files []File, name string) int {
+	for i, f := range files {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// insert inserts a text before the line of an insertion point, indenting each line of the text
+// as deep as the insertion point.
+func insert(content, point, text string) (string, bool) {
+	marker := "@@protoc_insertion_point(" + point + ")"
+	i := strings.Index(content, marker)
+	if i < 0 {
+		return "", false
+	}
+	start := strings.LastIndex(content[:i], "\n") + 1
+	indent := content[start:i]
+	indent = indent[:len(indent)-len(strings.TrimLeft(indent, " \t"))]
+
+	var sb strings.Builder
+	sb.WriteString(content[:start])
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if line != "\n" {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(line)
+	}
+	sb.WriteString(content[start:])
+	return sb.String(), true
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// fakePluginEnv makes the test binary behave as a plugin which generates a file for each file to generate.
+const fakePluginEnv = "PLUGIN_TEST_FAKE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakePluginEnv); mode != "" {
+		fakePlugin(mode)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func fakePlugin(mode string) {
+	in, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Exit(2)
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(in, req); err != nil {
+		os.Exit(2)
+	}
+	resp := &pluginpb.CodeGeneratorResponse{}
+	switch mode {
+	case "error":
+		resp.Error = proto.String("bad parameter")
+	case "hang":
+		time.Sleep(time.Hour)
+	case "exit":
+		os.Stderr.WriteString("plugin crashed\n")
+		os.Exit(1)
+	default:
+		for _, name := range req.FileToGenerate {
+			resp.File = append(resp.File,
+				&pluginpb.CodeGeneratorResponse_File{
+					Name:    proto.String(name + ".txt"),
+					Content: proto.String("// " + req.GetParameter() + "\n  // @@protoc_insertion_point(body)\n"),
+				},
+				&pluginpb.CodeGeneratorResponse_File{
+					Content: proto.String("// end\n"),
+				},
+				&pluginpb.CodeGeneratorResponse_File{
+					Name:           proto.String(name + ".txt"),
+					InsertionPoint: proto.String("body"),
+					Content:        proto.String("inserted\n"),
+				},
+			)
+		}
+	}
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		os.Exit(2)
+	}
+	os.Stdout.Write(out)
+}
+
+func TestRun(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo.proto"},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{{Name: proto.String("foo.proto")}},
+	}
+	tests := []struct {
+		name    string
+		mode    string
+		want    []File
+		wantErr bool
+		// wantTimeout is true if the plugin is killed by the deadline of the context.
+		wantTimeout bool
+	}{
+		{
+			name: "files",
+			mode: "files",
+			want: []File{
+				{
+					Name:    "foo.proto.txt",
+					Content: "// paths=source_relative\n  inserted\n  // @@protoc_insertion_point(body)\n// end\n",
+				},
+			},
+		},
+		{
+			name:    "error in response",
+			mode:    "error",
+			wantErr: true,
+		},
+		{
+			name:    "exit status",
+			mode:    "exit",
+			wantErr: true,
+		},
+		{
+			name:        "timeout",
+			mode:        "hang",
+			wantErr:     true,
+			wantTimeout: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(fakePluginEnv, tt.mode)
+			defer os.Unsetenv(fakePluginEnv)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			got, err := Run(ctx, os.Args[0], req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, context.DeadlineExceeded); got != tt.wantTimeout {
+				t.Errorf("Run() error = %v, wantTimeout %v", err, tt.wantTimeout)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
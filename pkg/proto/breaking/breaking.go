@@ -0,0 +1,207 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breaking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/registry"
+)
+
+// DefaultAgainst is the git revision which files are compared with unless configured otherwise.
+const DefaultAgainst = "HEAD"
+
+// Problem is a breaking change.
+type Problem struct {
+	RuleID  string
+	Message string
+
+	// Name is the name the problem is about, which appears at or after the position.
+	// It is empty if the problem is about the whole file.
+	Name string
+
+	// Field is the field the problem is about, which is nil if the problem is not about a field.
+	// It is the previous one for a deleted field.
+	Field *Field
+
+	// Line and Column are 1-based.
+	// The problems about deleted elements are placed at their parents in the current file.
+	Line   int
+	Column int
+}
+
+// Field is a field of a message.
+type Field struct {
+	Name   string
+	Number int
+}
+
+// Check compares the current version of a proto file with the previous one
+// against the rules enabled by a given project configuration.
+// filename is the absolute path to the file, which is used to match the ignore patterns.
+// A nil prev means the file is new and has no breaking change.
+func Check(prev, curr registry.Proto, filename string, cfg config.Project) []Problem {
+	if prev == nil || prev.Protobuf() == nil || curr == nil || curr.Protobuf() == nil {
+		return nil
+	}
+	if cfg.Match(cfg.Breaking.Ignore, filename) {
+		return nil
+	}
+
+	p, c := newFile(prev.Protobuf()), newFile(curr.Protobuf())
+
+	var problems []Problem
+	for _, rule := range EnabledRules(cfg.Breaking) {
+		rule := rule
+		rule.check(p, c, func(pos scanner.Position, name string, field *Field, format string, args ...interface{}) {
+			line, column := pos.Line, pos.Column
+			if line == 0 {
+				line, column = 1, 1
+			}
+			problems = append(problems, Problem{
+				RuleID:  rule.ID,
+				Message: fmt.Sprintf(format, args...),
+				Name:    name,
+				Field:   field,
+				Line:    line,
+				Column:  column,
+			})
+		})
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems
+}
+
+// EnabledRules returns the rules enabled by a given configuration sorted by ID.
+// Unknown rule IDs are ignored. The opt-in rules are enabled only if they are used explicitly.
+func EnabledRules(cfg config.Breaking) []Rule {
+	enabled := make(map[string]bool)
+	if cfg.Use == nil {
+		for _, r := range rules {
+			enabled[r.ID] = !r.optIn
+		}
+	}
+	for _, id := range cfg.Use {
+		enabled[id] = true
+	}
+	for _, id := range cfg.Disable {
+		enabled[id] = false
+	}
+
+	var rs []Rule
+	for _, r := range rules {
+		if enabled[r.ID] {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// file is a proto file whose declarations are keyed by name.
+type file struct {
+	pkg string
+	// messages and enums are keyed by the names qualified with the ones of their parent messages.
+	messages map[string]*protobuf.Message
+	enums    map[string]*protobuf.Enum
+	services map[string]*protobuf.Service
+}
+
+func newFile(proto *protobuf.Proto) *file {
+	f := &file{
+		messages: make(map[string]*protobuf.Message),
+		enums:    make(map[string]*protobuf.Enum),
+		services: make(map[string]*protobuf.Service),
+	}
+	var walk func(prefix string, elements []protobuf.Visitee)
+	walk = func(prefix string, elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Message:
+				if v.IsExtend {
+					continue
+				}
+				f.messages[prefix+v.Name] = v
+				walk(prefix+v.Name+".", v.Elements)
+			case *protobuf.Enum:
+				f.enums[prefix+v.Name] = v
+			case *protobuf.Service:
+				f.services[v.Name] = v
+			case *protobuf.Package:
+				f.pkg = v.Name
+			}
+		}
+	}
+	walk("", proto.Elements)
+	return f
+}
+
+// resolveType returns the fully-qualified names which a type reference in a message may refer to.
+// It is only one name if the reference is fully-qualified or refers to a type declared in the file.
+// Otherwise the type may be declared in an import, so all the names which the protobuf scoping rules
+// try are returned, from the innermost scope to the outermost one.
+func (f *file) resolveType(message, typ string) []string {
+	if strings.HasPrefix(typ, ".") {
+		return []string{typ[1:]}
+	}
+	var names []string
+	scope := qualify(f.pkg, message)
+	for {
+		name := qualify(scope, typ)
+		if f.declares(name) {
+			return []string{name}
+		}
+		names = append(names, name)
+		if scope == "" {
+			return names
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// declares returns true if the file declares a message or an enum with a fully-qualified name.
+func (f *file) declares(name string) bool {
+	if f.pkg != "" {
+		if !strings.HasPrefix(name, f.pkg+".") {
+			return false
+		}
+		name = name[len(f.pkg)+1:]
+	}
+	_, isMessage := f.messages[name]
+	_, isEnum := f.enums[name]
+	return isMessage || isEnum
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
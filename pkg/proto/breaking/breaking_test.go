@@ -0,0 +1,253 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breaking
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/parser"
+)
+
+const prevProto = `syntax = "proto3";
+
+package foo.v1;
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OK = 1;
+}
+
+message SearchRequest {
+  string query = 1;
+  int32 page_size = 2;
+  repeated string tags = 3;
+  string deprecated = 4;
+  string removed = 5;
+  message Inner {
+    string value = 1;
+  }
+}
+
+service Searcher {
+  rpc Search(SearchRequest) returns (SearchRequest);
+  rpc Watch(SearchRequest) returns (stream SearchRequest);
+  rpc Upload(stream SearchRequest) returns (SearchRequest);
+}
+`
+
+// prevTypesProto refers to types declared in the file and in imports to check that
+// requalified type references are not reported.
+const prevTypesProto = `syntax = "proto3";
+package foo.v1;
+import "foo/v1/imported.proto";
+import "bar/v1/bar.proto";
+message Foo {
+  Bar bar = 1;
+  Inner inner = 2;
+  map<string, Bar> bars = 3;
+  Imported imported = 4;
+  bar.v1.Baz baz = 5;
+  message Inner {}
+}
+message Bar {}
+`
+
+const currProto = `syntax = "proto3";
+
+package foo.v1;
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_SUCCEEDED = 1;
+}
+
+message SearchRequest {
+  reserved 4;
+  string query = 1;
+  int64 page_size = 2;
+  string tags = 3;
+  message Inner {
+    string value = 2;
+  }
+}
+
+service Searcher {
+  rpc Watch(SearchRequest) returns (SearchRequest);
+  rpc Upload(SearchRequest) returns (SearchRequest);
+}
+`
+
+func TestCheck(t *testing.T) {
+	filename := filepath.Join(filepath.FromSlash("/workspace"), "foo", "v1", "foo.proto")
+
+	tests := []struct {
+		name string
+		prev string
+		curr string
+		cfg  config.Breaking
+		want []string
+	}{
+		{
+			name: "all rules",
+			prev: prevProto,
+			curr: currProto,
+			want: []string{
+				"7:ENUM_VALUE_SAME_NAME",
+				"10:FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED",
+				"13:FIELD_SAME_TYPE",
+				"14:FIELD_SAME_CARDINALITY",
+				"15:FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED",
+				"16:FIELD_SAME_NUMBER",
+				"20:RPC_NO_DELETE",
+				"21:RPC_SAME_SERVER_STREAMING",
+				"22:RPC_SAME_CLIENT_STREAMING",
+			},
+		},
+		{
+			name: "use and disable",
+			prev: prevProto,
+			curr: currProto,
+			cfg: config.Breaking{
+				Use:     []string{"RPC_NO_DELETE", "FIELD_SAME_TYPE"},
+				Disable: []string{"FIELD_SAME_TYPE"},
+			},
+			want: []string{"20:RPC_NO_DELETE"},
+		},
+		{
+			name: "strict field deletion",
+			prev: prevProto,
+			curr: currProto,
+			cfg: config.Breaking{
+				Use: []string{"FIELD_NO_DELETE"},
+			},
+			want: []string{
+				"10:FIELD_NO_DELETE",
+				"10:FIELD_NO_DELETE",
+				"15:FIELD_NO_DELETE",
+			},
+		},
+		{
+			name: "ignore",
+			prev: prevProto,
+			curr: currProto,
+			cfg: config.Breaking{
+				Ignore: []string{"foo/v1"},
+			},
+		},
+		{
+			name: "deleted service",
+			prev: prevProto,
+			curr: "syntax = \"proto3\";\n",
+			want: []string{
+				"1:RPC_NO_DELETE",
+				"1:RPC_NO_DELETE",
+				"1:RPC_NO_DELETE",
+			},
+		},
+		{
+			name: "requalified types",
+			prev: prevTypesProto,
+			curr: strings.NewReplacer(
+				"  Bar bar", "  foo.v1.Bar bar",
+				"  Inner inner", "  .foo.v1.Foo.Inner inner",
+				"map<string, Bar>", "map<string, .foo.v1.Bar>",
+				"  Imported imported", "  foo.v1.Imported imported",
+				"  bar.v1.Baz", "  .bar.v1.Baz",
+			).Replace(prevTypesProto),
+		},
+		{
+			name: "changed types",
+			prev: prevTypesProto,
+			curr: strings.NewReplacer(
+				"  Bar bar", "  Inner bar",
+				"map<string, Bar>", "map<int32, Bar>",
+				"  Imported imported", "  bar.v1.Imported imported",
+			).Replace(prevTypesProto),
+			want: []string{
+				"6:FIELD_SAME_TYPE",
+				"8:FIELD_SAME_TYPE",
+				"9:FIELD_SAME_TYPE",
+			},
+		},
+		{
+			name: "no change",
+			prev: prevProto,
+			curr: prevProto,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prev, err := parser.ParseProto(strings.NewReader(tt.prev))
+			if err != nil {
+				t.Fatalf("ParseProto() error = %v", err)
+			}
+			curr, err := parser.ParseProto(strings.NewReader(tt.curr))
+			if err != nil {
+				t.Fatalf("ParseProto() error = %v", err)
+			}
+			cfg := config.Project{
+				Dir:      filepath.FromSlash("/workspace"),
+				Breaking: tt.cfg,
+			}
+
+			var got []string
+			for _, p := range Check(prev, curr, filename, cfg) {
+				got = append(got, strconv.Itoa(p.Line)+":"+p.RuleID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck_NewFile(t *testing.T) {
+	curr, err := parser.ParseProto(strings.NewReader(currProto))
+	if err != nil {
+		t.Fatalf("ParseProto() error = %v", err)
+	}
+	if got := Check(nil, curr, "foo.proto", config.Project{}); got != nil {
+		t.Errorf("Check() = %v, want nil", got)
+	}
+}
+
+func TestCheck_Field(t *testing.T) {
+	prev, err := parser.ParseProto(strings.NewReader(prevProto))
+	if err != nil {
+		t.Fatalf("ParseProto() error = %v", err)
+	}
+	curr, err := parser.ParseProto(strings.NewReader(currProto))
+	if err != nil {
+		t.Fatalf("ParseProto() error = %v", err)
+	}
+	cfg := config.Project{
+		Breaking: config.Breaking{Use: []string{"FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED"}},
+	}
+
+	var got []Field
+	for _, p := range Check(prev, curr, "foo.proto", cfg) {
+		got = append(got, *p.Field)
+	}
+	want := []Field{{Name: "removed", Number: 5}, {Name: "value", Number: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() fields = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breaking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+)
+
+// Rule is a breaking change detection rule.
+type Rule struct {
+	// ID is the identifier of the rule, which is the same as the one of buf if buf has the rule.
+	ID string
+
+	// Description describes what the rule checks.
+	Description string
+
+	// optIn is true if the rule is enabled only when it is used explicitly, such as a stricter variant
+	// of another rule which would report the same problems twice.
+	optIn bool
+
+	check func(prev, curr *file, report reportFunc)
+}
+
+// reportFunc reports a problem at a given position about a given name.
+// field is the field the problem is about, which is nil if the problem is not about a field.
+type reportFunc func(pos scanner.Position, name string, field *Field, format string, args ...interface{})
+
+// rules is the list of all rules sorted by ID.
+var rules = []Rule{
+	{
+		ID:          "ENUM_VALUE_SAME_NAME",
+		Description: "Checks that enum values have the same names for the same numbers.",
+		check:       checkEnumValueSameName,
+	},
+	{
+		ID:          "FIELD_NO_DELETE",
+		Description: "Checks that fields are not deleted even if their numbers are reserved.",
+		optIn:       true,
+		check:       checkFieldNoDelete,
+	},
+	{
+		ID:          "FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED",
+		Description: "Checks that fields are not deleted unless their numbers are reserved.",
+		check:       checkFieldNoDeleteUnlessNumberReserved,
+	},
+	{
+		ID:          "FIELD_SAME_CARDINALITY",
+		Description: "Checks that fields have the same cardinalities for the same numbers.",
+		check:       checkFieldSameCardinality,
+	},
+	{
+		ID:          "FIELD_SAME_NUMBER",
+		Description: "Checks that fields have the same numbers for the same names.",
+		check:       checkFieldSameNumber,
+	},
+	{
+		ID:          "FIELD_SAME_TYPE",
+		Description: "Checks that fields have the same types for the same numbers.",
+		check:       checkFieldSameType,
+	},
+	{
+		ID:          "RPC_NO_DELETE",
+		Description: "Checks that RPCs are not deleted from services.",
+		check:       checkRPCNoDelete,
+	},
+	{
+		ID:          "RPC_SAME_CLIENT_STREAMING",
+		Description: "Checks that RPCs keep streaming requests or not.",
+		check:       checkRPCSameClientStreaming,
+	},
+	{
+		ID:          "RPC_SAME_SERVER_STREAMING",
+		Description: "Checks that RPCs keep streaming responses or not.",
+		check:       checkRPCSameServerStreaming,
+	},
+}
+
+// Rules returns all rules sorted by ID.
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+// LookupRule returns the rule with a given ID.
+func LookupRule(id string) (Rule, bool) {
+	for _, r := range rules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func checkEnumValueSameName(prev, curr *file, report reportFunc) {
+	for _, name := range sortedKeys(prev.enums) {
+		e, ok := curr.enums[name]
+		if !ok {
+			continue
+		}
+		currValues := make(map[int][]*protobuf.EnumField)
+		for _, v := range enumValues(e) {
+			currValues[v.Integer] = append(currValues[v.Integer], v)
+		}
+		for _, pv := range enumValues(prev.enums[name]) {
+			cvs := currValues[pv.Integer]
+			if len(cvs) == 0 || hasEnumValue(cvs, pv.Name) {
+				continue
+			}
+			report(cvs[0].Position, cvs[0].Name, nil, "Enum value %d on enum %q changed name from %q to %q.", pv.Integer, name, pv.Name, cvs[0].Name)
+		}
+	}
+}
+
+func hasEnumValue(values []*protobuf.EnumField, name string) bool {
+	for _, v := range values {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkFieldNoDelete(prev, curr *file, report reportFunc) {
+	for _, name := range sortedKeys(prev.messages) {
+		m, ok := curr.messages[name]
+		if !ok {
+			continue
+		}
+		currFields := fieldsByNumber(m)
+		for _, pf := range messageFields(prev.messages[name]) {
+			if _, ok := currFields[pf.number]; ok {
+				continue
+			}
+			report(m.Position, m.Name, &Field{Name: pf.name, Number: pf.number}, "Previously present field %d %q on message %q was deleted.", pf.number, pf.name, name)
+		}
+	}
+}
+
+func checkFieldNoDeleteUnlessNumberReserved(prev, curr *file, report reportFunc) {
+	for _, name := range sortedKeys(prev.messages) {
+		m, ok := curr.messages[name]
+		if !ok {
+			continue
+		}
+		currFields := fieldsByNumber(m)
+		reserved := reservedRanges(m)
+		for _, pf := range messageFields(prev.messages[name]) {
+			if _, ok := currFields[pf.number]; ok || reserved.contains(pf.number) {
+				continue
+			}
+			report(m.Position, m.Name, &Field{Name: pf.name, Number: pf.number}, "Previously present field %d %q on message %q was deleted without reserving the number %d.", pf.number, pf.name, name, pf.number)
+		}
+	}
+}
+
+func checkFieldSameCardinality(prev, curr *file, report reportFunc) {
+	compareFields(prev, curr, func(name string, pf, cf field) {
+		if pf.cardinality != cf.cardinality {
+			report(cf.position, cf.name, cf.export(), "Field %d %q on message %q changed cardinality from %q to %q.", cf.number, cf.name, name, pf.cardinality, cf.cardinality)
+		}
+	})
+}
+
+func checkFieldSameNumber(prev, curr *file, report reportFunc) {
+	for _, name := range sortedKeys(prev.messages) {
+		m, ok := curr.messages[name]
+		if !ok {
+			continue
+		}
+		currFields := make(map[string]field)
+		for _, f := range messageFields(m) {
+			currFields[f.name] = f
+		}
+		for _, pf := range messageFields(prev.messages[name]) {
+			if cf, ok := currFields[pf.name]; ok && cf.number != pf.number {
+				report(cf.position, cf.name, cf.export(), "Field %q on message %q changed number from %d to %d.", cf.name, name, pf.number, cf.number)
+			}
+		}
+	}
+}
+
+func checkFieldSameType(prev, curr *file, report reportFunc) {
+	compareFields(prev, curr, func(name string, pf, cf field) {
+		if !sameType(prev, curr, name, pf, cf) {
+			report(cf.position, cf.name, cf.export(), "Field %d %q on message %q changed type from %q to %q.", cf.number, cf.name, name, pf.typ, cf.typ)
+		}
+	})
+}
+
+func checkRPCNoDelete(prev, curr *file, report reportFunc) {
+	for _, name := range sortedKeys(prev.services) {
+		// The problems are placed at the top of the file if the whole service was deleted.
+		var pos scanner.Position
+		var posName string
+		currRPCs := make(map[string]*protobuf.RPC)
+		if s, ok := curr.services[name]; ok {
+			pos, posName = s.Position, s.Name
+			for _, rpc := range serviceRPCs(s) {
+				currRPCs[rpc.Name] = rpc
+			}
+		}
+		for _, rpc := range serviceRPCs(prev.services[name]) {
+			if _, ok := currRPCs[rpc.Name]; !ok {
+				report(pos, posName, nil, "Previously present RPC %q on service %q was deleted.", rpc.Name, name)
+			}
+		}
+	}
+}
+
+func checkRPCSameClientStreaming(prev, curr *file, report reportFunc) {
+	compareRPCs(prev, curr, func(service string, prpc, crpc *protobuf.RPC) {
+		if prpc.StreamsRequest != crpc.StreamsRequest {
+			report(crpc.Position, crpc.Name, nil, "RPC %q on service %q changed from %s to %s.", crpc.Name, service, streaming("client", prpc.StreamsRequest), streaming("client", crpc.StreamsRequest))
+		}
+	})
+}
+
+func checkRPCSameServerStreaming(prev, curr *file, report reportFunc) {
+	compareRPCs(prev, curr, func(service string, prpc, crpc *protobuf.RPC) {
+		if prpc.StreamsReturns != crpc.StreamsReturns {
+			report(crpc.Position, crpc.Name, nil, "RPC %q on service %q changed from %s to %s.", crpc.Name, service, streaming("server", prpc.StreamsReturns), streaming("server", crpc.StreamsReturns))
+		}
+	})
+}
+
+func streaming(side string, streams bool) string {
+	if streams {
+		return side + " streaming"
+	}
+	return "non-" + side + " streaming"
+}
+
+// field is a field of a message with its properties to compare.
+type field struct {
+	name   string
+	number int
+	// typ is the type as written without the leading dot, such as "pkg.Foo" or "map<string, Foo>".
+	typ string
+	// keyType is the key type of a map field.
+	keyType string
+	// valueType is the type reference as written, or the value type of a map field.
+	valueType   string
+	cardinality string
+	position    scanner.Position
+}
+
+// messageFields returns the fields declared directly in a message including the ones in oneofs.
+func messageFields(m *protobuf.Message) (fields []field) {
+	for _, el := range m.Elements {
+		switch v := el.(type) {
+		case *protobuf.NormalField:
+			cardinality := "singular"
+			switch {
+			case v.Repeated:
+				cardinality = "repeated"
+			case v.Optional:
+				cardinality = "optional"
+			case v.Required:
+				cardinality = "required"
+			}
+			fields = append(fields, newField(v.Field, "", cardinality))
+		case *protobuf.MapField:
+			fields = append(fields, newField(v.Field, v.KeyType, "repeated"))
+		case *protobuf.Oneof:
+			for _, el := range v.Elements {
+				if f, ok := el.(*protobuf.OneOfField); ok {
+					fields = append(fields, newField(f.Field, "", "oneof"))
+				}
+			}
+		}
+	}
+	return
+}
+
+func (f field) export() *Field {
+	return &Field{Name: f.name, Number: f.number}
+}
+
+func newField(f *protobuf.Field, keyType, cardinality string) field {
+	typ := strings.TrimPrefix(f.Type, ".")
+	if keyType != "" {
+		typ = fmt.Sprintf("map<%s, %s>", keyType, typ)
+	}
+	return field{
+		name:        f.Name,
+		number:      f.Sequence,
+		typ:         typ,
+		keyType:     keyType,
+		valueType:   f.Type,
+		cardinality: cardinality,
+		position:    f.Position,
+	}
+}
+
+// sameType returns true if the previous and the current fields of a message may have the same type.
+// The type references are resolved against the packages and the messages they appear in,
+// so that requalifying a type such as "Foo" to "pkg.Foo" is not a change.
+func sameType(prev, curr *file, message string, pf, cf field) bool {
+	if pf.keyType != cf.keyType {
+		return false
+	}
+	currNames := make(map[string]bool)
+	for _, name := range curr.resolveType(message, cf.valueType) {
+		currNames[name] = true
+	}
+	for _, name := range prev.resolveType(message, pf.valueType) {
+		if currNames[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldsByNumber(m *protobuf.Message) map[int]field {
+	fields := make(map[int]field)
+	for _, f := range messageFields(m) {
+		fields[f.number] = f
+	}
+	return fields
+}
+
+// compareFields calls fn for all pairs of the previous and the current fields with the same numbers
+// in the messages with the same names.
+func compareFields(prev, curr *file, fn func(message string, prev, curr field)) {
+	for _, name := range sortedKeys(prev.messages) {
+		m, ok := curr.messages[name]
+		if !ok {
+			continue
+		}
+		currFields := fieldsByNumber(m)
+		for _, pf := range messageFields(prev.messages[name]) {
+			if cf, ok := currFields[pf.number]; ok {
+				fn(name, pf, cf)
+			}
+		}
+	}
+}
+
+// ranges is a list of reserved field numbers.
+type ranges []protobuf.Range
+
+func reservedRanges(m *protobuf.Message) (rs ranges) {
+	for _, el := range m.Elements {
+		if r, ok := el.(*protobuf.Reserved); ok {
+			rs = append(rs, r.Ranges...)
+		}
+	}
+	return
+}
+
+func (rs ranges) contains(number int) bool {
+	for _, r := range rs {
+		if number >= r.From && (r.Max || number <= r.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareRPCs calls fn for all pairs of the previous and the current RPCs with the same names
+// in the services with the same names.
+func compareRPCs(prev, curr *file, fn func(service string, prev, curr *protobuf.RPC)) {
+	for _, name := range sortedKeys(prev.services) {
+		s, ok := curr.services[name]
+		if !ok {
+			continue
+		}
+		currRPCs := make(map[string]*protobuf.RPC)
+		for _, rpc := range serviceRPCs(s) {
+			currRPCs[rpc.Name] = rpc
+		}
+		for _, prpc := range serviceRPCs(prev.services[name]) {
+			if crpc, ok := currRPCs[prpc.Name]; ok {
+				fn(name, prpc, crpc)
+			}
+		}
+	}
+}
+
+func serviceRPCs(s *protobuf.Service) (rpcs []*protobuf.RPC) {
+	for _, el := range s.Elements {
+		if rpc, ok := el.(*protobuf.RPC); ok {
+			rpcs = append(rpcs, rpc)
+		}
+	}
+	return
+}
+
+func enumValues(e *protobuf.Enum) (values []*protobuf.EnumField) {
+	for _, el := range e.Elements {
+		if v, ok := el.(*protobuf.EnumField); ok {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+// sortedKeys returns the keys of a map keyed by names in order
+// so that problems are reported deterministically.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*protobuf.Message:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*protobuf.Enum:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*protobuf.Service:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
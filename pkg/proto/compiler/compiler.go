@@ -0,0 +1,417 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// The well-known files bundled with protoc are registered to be imported without their sources.
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/apipb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/sourcecontextpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/typepb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+	_ "google.golang.org/protobuf/types/pluginpb"
+)
+
+// File is a proto file to compile.
+type File struct {
+	// Path is the import path of the file, which becomes the name of its descriptor.
+	Path string
+	// Proto is the parsed file.
+	Proto *protobuf.Proto
+	// Content is the source of the file from which spans of source code info are computed.
+	Content []byte
+}
+
+// Options configures how files are compiled.
+type Options struct {
+	// IncludeImports includes the files imported by the root files directly or indirectly
+	// like protoc --include_imports does.
+	IncludeImports bool
+	// IncludeSourceInfo includes source code info like protoc --include_source_info does.
+	IncludeSourceInfo bool
+}
+
+// Error represents an error which occurs while compiling a file.
+type Error struct {
+	// Path is the import path of the file.
+	Path string
+	// Line and Column are 1-based. They are zero if the position is unknown.
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+}
+
+// kind is the kind of a declared symbol.
+type kind int
+
+const (
+	kindPackage kind = iota
+	kindMessage
+	kindEnum
+	kindExtension
+	kindOther
+)
+
+// symbol is a declared name.
+type symbol struct {
+	kind kind
+	// path is the import path of the file which declares the symbol.
+	path string
+}
+
+// unit is a file being compiled.
+type unit struct {
+	path string
+	// file is nil for the well-known files.
+	file *File
+	desc *descriptorpb.FileDescriptorProto
+
+	refs      []reference
+	options   []option
+	messages  []proto.Message
+	locations []*descriptorpb.SourceCodeInfo_Location
+}
+
+// reference is a type name waiting to be resolved after all symbols visible from a file are declared.
+type reference struct {
+	scope string
+	name  string
+	pos   scanner.Position
+	kinds []kind
+	// set is called with the fully-qualified name and the kind of the symbol which the name refers to.
+	set func(full string, kind kind)
+}
+
+type compiler struct {
+	files    map[string]*File
+	units    map[string]*unit
+	order    []*unit
+	symbols  map[string]symbol
+	registry *protoregistry.Files
+	types    map[protoreflect.FullName]protoreflect.ExtensionType
+	opts     Options
+}
+
+// Compile converts root files and the files imported by them into file descriptors.
+// files must contain every imported file except for the well-known ones bundled with protoc,
+// whose descriptors linked into the binary are used instead.
+// The files in the returned set are ordered so that each file follows the ones it imports.
+func Compile(files []*File, roots []string, opts Options) (*descriptorpb.FileDescriptorSet, error) {
+	c := &compiler{
+		files:    make(map[string]*File, len(files)),
+		units:    make(map[string]*unit),
+		symbols:  make(map[string]symbol),
+		registry: new(protoregistry.Files),
+		types:    make(map[protoreflect.FullName]protoreflect.ExtensionType),
+		opts:     opts,
+	}
+	for _, f := range files {
+		if _, ok := c.files[f.Path]; !ok {
+			c.files[f.Path] = f
+		}
+	}
+
+	isRoot := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		if _, ok := c.files[root]; !ok {
+			return nil, &Error{Path: root, Message: "file not found"}
+		}
+		if err := c.load(root, nil); err != nil {
+			return nil, err
+		}
+		isRoot[root] = true
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, u := range c.order {
+		if opts.IncludeImports || isRoot[u.path] {
+			set.File = append(set.File, u.desc)
+		}
+	}
+	return set, nil
+}
+
+// load compiles a file after the files imported by it.
+// stack is the import paths of the files importing the file to detect cycles.
+func (c *compiler) load(path string, stack []string) error {
+	if _, ok := c.units[path]; ok {
+		return nil
+	}
+	for i, p := range stack {
+		if p == path {
+			return &Error{Path: path, Message: fmt.Sprintf("import cycle: %s", strings.Join(append(stack[i:], path), " -> "))}
+		}
+	}
+	stack = append(stack, path)
+
+	f, ok := c.files[path]
+	if !ok {
+		return c.loadWellKnown(path, stack)
+	}
+	for _, el := range f.Proto.Elements {
+		imp, ok := el.(*protobuf.Import)
+		if !ok {
+			continue
+		}
+		if _, ok := c.files[imp.Filename]; !ok {
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(imp.Filename); err != nil {
+				return errorAt(path, imp.Position, "import %q was not found", imp.Filename)
+			}
+		}
+		if err := c.load(imp.Filename, stack); err != nil {
+			return err
+		}
+	}
+
+	u := &unit{path: path, file: f}
+	if err := c.build(u); err != nil {
+		return err
+	}
+	for _, ref := range u.refs {
+		if err := c.resolve(u, ref); err != nil {
+			return err
+		}
+	}
+	fd, err := protodesc.NewFile(u.desc, c.registry)
+	if err != nil {
+		return &Error{Path: path, Message: err.Error()}
+	}
+	if err := c.registry.RegisterFile(fd); err != nil {
+		return &Error{Path: path, Message: err.Error()}
+	}
+	for _, o := range u.options {
+		if err := c.setOption(u, o); err != nil {
+			return err
+		}
+	}
+	// Custom options are kept as unknown fields in the same way as descriptors parsed from protoc outputs.
+	for _, m := range u.messages {
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+		if err != nil {
+			return &Error{Path: path, Message: err.Error()}
+		}
+		proto.Reset(m)
+		if err := proto.Unmarshal(b, m); err != nil {
+			return &Error{Path: path, Message: err.Error()}
+		}
+	}
+	if c.opts.IncludeSourceInfo {
+		u.desc.SourceCodeInfo = &descriptorpb.SourceCodeInfo{Location: u.locations}
+	}
+
+	c.units[path] = u
+	c.order = append(c.order, u)
+	return nil
+}
+
+// loadWellKnown loads a file bundled with protoc from the descriptors linked into the binary.
+func (c *compiler) loadWellKnown(path string, stack []string) error {
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+	if err != nil {
+		return &Error{Path: path, Message: "file not found"}
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := c.load(imports.Get(i).Path(), stack); err != nil {
+			return err
+		}
+	}
+	if err := c.registry.RegisterFile(fd); err != nil {
+		return &Error{Path: path, Message: err.Error()}
+	}
+
+	u := &unit{path: path, desc: protodesc.ToFileDescriptorProto(fd)}
+	c.declarePackage(u.desc.GetPackage(), path)
+	c.declareMessages(u.desc.GetPackage(), u.desc.MessageType, path)
+	c.declareEnums(u.desc.GetPackage(), u.desc.EnumType, path)
+	c.declareExtensions(u.desc.GetPackage(), u.desc.Extension, path)
+	for _, s := range u.desc.Service {
+		c.symbols[qualify(u.desc.GetPackage(), s.GetName())] = symbol{kind: kindOther, path: path}
+	}
+	c.units[path] = u
+	c.order = append(c.order, u)
+	return nil
+}
+
+func (c *compiler) declareMessages(scope string, messages []*descriptorpb.DescriptorProto, path string) {
+	for _, m := range messages {
+		full := qualify(scope, m.GetName())
+		c.symbols[full] = symbol{kind: kindMessage, path: path}
+		for _, f := range m.Field {
+			c.symbols[qualify(full, f.GetName())] = symbol{kind: kindOther, path: path}
+		}
+		c.declareMessages(full, m.NestedType, path)
+		c.declareEnums(full, m.EnumType, path)
+		c.declareExtensions(full, m.Extension, path)
+	}
+}
+
+func (c *compiler) declareEnums(scope string, enums []*descriptorpb.EnumDescriptorProto, path string) {
+	for _, e := range enums {
+		c.symbols[qualify(scope, e.GetName())] = symbol{kind: kindEnum, path: path}
+		// Enum values are siblings of their enum.
+		for _, v := range e.Value {
+			c.symbols[qualify(scope, v.GetName())] = symbol{kind: kindOther, path: path}
+		}
+	}
+}
+
+func (c *compiler) declareExtensions(scope string, extensions []*descriptorpb.FieldDescriptorProto, path string) {
+	for _, f := range extensions {
+		c.symbols[qualify(scope, f.GetName())] = symbol{kind: kindExtension, path: path}
+	}
+}
+
+// declarePackage declares a package and its parents.
+func (c *compiler) declarePackage(pkg, path string) {
+	for pkg != "" {
+		if _, ok := c.symbols[pkg]; !ok {
+			c.symbols[pkg] = symbol{kind: kindPackage, path: path}
+		}
+		i := strings.LastIndex(pkg, ".")
+		if i < 0 {
+			break
+		}
+		pkg = pkg[:i]
+	}
+}
+
+// resolve resolves a reference following the scoping rules of protobuf where the innermost scope is searched first.
+func (c *compiler) resolve(u *unit, ref reference) error {
+	full, sym, ok := c.lookup(ref.scope, ref.name, ref.kinds)
+	if !ok {
+		return errorAt(u.path, ref.pos, "%q is not defined", ref.name)
+	}
+	if !c.visible(u)[sym.path] {
+		return errorAt(u.path, ref.pos, "%q seems to be defined in %q, which is not imported by %q", ref.name, sym.path, u.path)
+	}
+	ref.set(full, sym.kind)
+	return nil
+}
+
+// lookup returns the fully-qualified name and the symbol of the given kinds which a name refers to from a scope.
+func (c *compiler) lookup(scope, name string, kinds []kind) (string, symbol, bool) {
+	if strings.HasPrefix(name, ".") {
+		sym, ok := c.symbols[name[1:]]
+		return name[1:], sym, ok && hasKind(kinds, sym.kind)
+	}
+	for {
+		full := qualify(scope, name)
+		if sym, ok := c.symbols[full]; ok && hasKind(kinds, sym.kind) {
+			return full, sym, true
+		}
+		if scope == "" {
+			return "", symbol{}, false
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// visible returns the import paths of the files whose symbols can be referred to from a file,
+// which are the file itself, the files imported by it and the files publicly imported by them.
+func (c *compiler) visible(u *unit) map[string]bool {
+	visible := map[string]bool{u.path: true}
+	var add func(path string)
+	add = func(path string) {
+		if visible[path] {
+			return
+		}
+		visible[path] = true
+		dep, ok := c.units[path]
+		if !ok {
+			return
+		}
+		for _, i := range dep.desc.PublicDependency {
+			add(dep.desc.Dependency[i])
+		}
+	}
+	for _, path := range u.desc.Dependency {
+		add(path)
+	}
+	return visible
+}
+
+// extensionType returns the type of an extension field declared in the files compiled so far.
+func (c *compiler) extensionType(full string) (protoreflect.ExtensionType, bool) {
+	name := protoreflect.FullName(full)
+	if xt, ok := c.types[name]; ok {
+		return xt, true
+	}
+	d, err := c.registry.FindDescriptorByName(name)
+	if err != nil {
+		return nil, false
+	}
+	xd, ok := d.(protoreflect.ExtensionDescriptor)
+	if !ok {
+		return nil, false
+	}
+	xt := dynamicpb.NewExtensionType(xd)
+	c.types[name] = xt
+	return xt, true
+}
+
+func hasKind(kinds []kind, k kind) bool {
+	for _, kind := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// qualify returns the fully-qualified name of a name declared in a scope.
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func errorAt(path string, pos scanner.Position, format string, args ...interface{}) *Error {
+	return &Error{
+		Path:    path,
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,478 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/parser"
+)
+
+const fooProto = `syntax = "proto3";
+
+package foo.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// Status is a status.
+enum Status {
+  option allow_alias = true;
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OK = 1;
+  STATUS_FINE = 1 [deprecated = true];
+  reserved 10 to max;
+}
+
+message SearchRequest {
+  string query = 1; // The query.
+  int32 page_size = 2 [json_name = "size"];
+  map<string, Status> statuses = 3;
+  optional uint64 offset = 4;
+  oneof filter {
+    string tag = 5;
+    google.protobuf.Timestamp since = 6;
+  }
+  Inner inner = 7 [deprecated = true];
+  reserved 8, 20 to 30;
+  reserved "removed";
+  message Inner {
+    Status status = 1;
+  }
+}
+
+service Searcher {
+  rpc Search(SearchRequest) returns (SearchRequest.Inner);
+  rpc Watch(SearchRequest) returns (stream SearchRequest) {
+    option deprecated = true;
+  }
+}
+`
+
+const wantFooProto = `
+name: "foo.proto"
+package: "foo.v1"
+dependency: "google/protobuf/timestamp.proto"
+message_type: {
+  name: "SearchRequest"
+  field: {name: "query" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "query"}
+  field: {name: "page_size" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "size"}
+  field: {name: "statuses" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".foo.v1.SearchRequest.StatusesEntry" json_name: "statuses"}
+  field: {name: "offset" number: 4 label: LABEL_OPTIONAL type: TYPE_UINT64 oneof_index: 1 json_name: "offset" proto3_optional: true}
+  field: {name: "tag" number: 5 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "tag"}
+  field: {name: "since" number: 6 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" oneof_index: 0 json_name: "since"}
+  field: {name: "inner" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".foo.v1.SearchRequest.Inner" json_name: "inner" options: {deprecated: true}}
+  nested_type: {
+    name: "StatusesEntry"
+    field: {name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key"}
+    field: {name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".foo.v1.Status" json_name: "value"}
+    options: {map_entry: true}
+  }
+  nested_type: {
+    name: "Inner"
+    field: {name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".foo.v1.Status" json_name: "status"}
+  }
+  oneof_decl: {name: "filter"}
+  oneof_decl: {name: "_offset"}
+  reserved_range: {start: 8 end: 9}
+  reserved_range: {start: 20 end: 31}
+  reserved_name: "removed"
+}
+enum_type: {
+  name: "Status"
+  value: {name: "STATUS_UNSPECIFIED" number: 0}
+  value: {name: "STATUS_OK" number: 1}
+  value: {name: "STATUS_FINE" number: 1 options: {deprecated: true}}
+  options: {allow_alias: true}
+  reserved_range: {start: 10 end: 2147483647}
+}
+service: {
+  name: "Searcher"
+  method: {name: "Search" input_type: ".foo.v1.SearchRequest" output_type: ".foo.v1.SearchRequest.Inner"}
+  method: {name: "Watch" input_type: ".foo.v1.SearchRequest" output_type: ".foo.v1.SearchRequest" options: {deprecated: true} server_streaming: true}
+}
+syntax: "proto3"
+`
+
+const legacyProto = `syntax = "proto2";
+
+package legacy;
+
+message Legacy {
+  required int32 id = 1 [default = 0x10];
+  optional string name = 2 [default = "a\tb"];
+  optional Kind kind = 3 [default = KIND_B];
+  repeated group Item = 4 {
+    optional int32 count = 1;
+  }
+  extensions 100 to max;
+  enum Kind {
+    KIND_A = 1;
+    KIND_B = 2;
+  }
+}
+
+extend Legacy {
+  optional bool flag = 100;
+}
+`
+
+const wantLegacyProto = `
+name: "legacy.proto"
+package: "legacy"
+message_type: {
+  name: "Legacy"
+  field: {name: "id" number: 1 label: LABEL_REQUIRED type: TYPE_INT32 default_value: "16" json_name: "id"}
+  field: {name: "name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING default_value: "a\tb" json_name: "name"}
+  field: {name: "kind" number: 3 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".legacy.Legacy.Kind" default_value: "KIND_B" json_name: "kind"}
+  field: {name: "item" number: 4 label: LABEL_REPEATED type: TYPE_GROUP type_name: ".legacy.Legacy.Item" json_name: "item"}
+  nested_type: {
+    name: "Item"
+    field: {name: "count" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "count"}
+  }
+  enum_type: {
+    name: "Kind"
+    value: {name: "KIND_A" number: 1}
+    value: {name: "KIND_B" number: 2}
+  }
+  extension_range: {start: 100 end: 536870912}
+}
+extension: {name: "flag" number: 100 label: LABEL_OPTIONAL type: TYPE_BOOL extendee: ".legacy.Legacy" json_name: "flag"}
+`
+
+const optionsProto = `syntax = "proto3";
+
+package opts;
+
+import "google/protobuf/descriptor.proto";
+
+message Rules {
+  int32 min = 1;
+  repeated string tags = 2;
+}
+
+extend google.protobuf.FieldOptions {
+  Rules rules = 50000;
+}
+
+extend google.protobuf.MessageOptions {
+  string label = 50001;
+}
+`
+
+const barProto = `syntax = "proto3";
+
+package bar;
+
+import "options.proto";
+
+message Bar {
+  option (opts.label) = "bar";
+  string name = 1 [(opts.rules).min = 3];
+  string id = 2 [(opts.rules) = { min: 1 tags: ["a", "b"] }];
+}
+`
+
+func compile(t *testing.T, sources map[string]string, roots []string, opts Options) (*descriptorpb.FileDescriptorSet, error) {
+	t.Helper()
+	files := make([]*File, 0, len(sources))
+	for path, src := range sources {
+		p, err := parser.ParseProto(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("ParseProto() error = %v", err)
+		}
+		files = append(files, &File{Path: path, Proto: p.Protobuf(), Content: []byte(src)})
+	}
+	return Compile(files, roots, opts)
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		src  string
+		want string
+	}{
+		{name: "proto3", path: "foo.proto", src: fooProto, want: wantFooProto},
+		{name: "proto2", path: "legacy.proto", src: legacyProto, want: wantLegacyProto},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := compile(t, map[string]string{tt.path: tt.src}, []string{tt.path}, Options{})
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			want := &descriptorpb.FileDescriptorProto{}
+			if err := prototext.Unmarshal([]byte(tt.want), want); err != nil {
+				t.Fatal(err)
+			}
+			if len(set.File) != 1 {
+				t.Fatalf("Compile() = %d files, want 1", len(set.File))
+			}
+			if !proto.Equal(set.File[0], want) {
+				t.Errorf("Compile() = %v, want %v", prototext.Format(set.File[0]), prototext.Format(want))
+			}
+		})
+	}
+}
+
+func TestCompileIncludeImports(t *testing.T) {
+	set, err := compile(t, map[string]string{"foo.proto": fooProto}, []string{"foo.proto"}, Options{IncludeImports: true})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	var names []string
+	for _, f := range set.File {
+		names = append(names, f.GetName())
+	}
+	if want := []string{"google/protobuf/timestamp.proto", "foo.proto"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Compile() = %v, want %v", names, want)
+	}
+	if _, err := protodesc.NewFiles(set); err != nil {
+		t.Errorf("NewFiles() error = %v", err)
+	}
+}
+
+func TestCompileOptions(t *testing.T) {
+	sources := map[string]string{"options.proto": optionsProto, "bar.proto": barProto}
+	set, err := compile(t, sources, []string{"bar.proto"}, Options{IncludeImports: true})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if _, err := protodesc.NewFiles(set); err != nil {
+		t.Errorf("NewFiles() error = %v", err)
+	}
+	bar := set.File[len(set.File)-1]
+
+	var label []byte
+	label = protowire.AppendTag(label, 50001, protowire.BytesType)
+	label = protowire.AppendString(label, "bar")
+
+	var min []byte
+	min = protowire.AppendTag(min, 50000, protowire.BytesType)
+	min = protowire.AppendBytes(min, protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 3))
+
+	var rules []byte
+	rules = protowire.AppendVarint(protowire.AppendTag(rules, 1, protowire.VarintType), 1)
+	rules = protowire.AppendString(protowire.AppendTag(rules, 2, protowire.BytesType), "a")
+	rules = protowire.AppendString(protowire.AppendTag(rules, 2, protowire.BytesType), "b")
+	var aggregate []byte
+	aggregate = protowire.AppendTag(aggregate, 50000, protowire.BytesType)
+	aggregate = protowire.AppendBytes(aggregate, rules)
+
+	message := bar.MessageType[0]
+	tests := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{name: "message option", got: message.Options.ProtoReflect().GetUnknown(), want: label},
+		{name: "option path", got: message.Field[0].Options.ProtoReflect().GetUnknown(), want: min},
+		{name: "aggregate option", got: message.Field[1].Options.ProtoReflect().GetUnknown(), want: aggregate},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("options = %x, want %x", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources map[string]string
+		want    string
+	}{
+		{
+			name:    "undefined type",
+			sources: map[string]string{"foo.proto": "syntax = \"proto3\";\nmessage Foo {\n  Bar bar = 1;\n}\n"},
+			want:    `foo.proto:3:3: "Bar" is not defined`,
+		},
+		{
+			name: "not imported",
+			sources: map[string]string{
+				"foo.proto": "syntax = \"proto3\";\nimport \"bar.proto\";\nmessage Foo {\n  Baz baz = 1;\n}\n",
+				"bar.proto": "syntax = \"proto3\";\nimport \"baz.proto\";\n",
+				"baz.proto": "syntax = \"proto3\";\nmessage Baz {}\n",
+			},
+			want: `foo.proto:4:3: "Baz" seems to be defined in "baz.proto", which is not imported by "foo.proto"`,
+		},
+		{
+			name:    "missing import",
+			sources: map[string]string{"foo.proto": "syntax = \"proto3\";\nimport \"bar.proto\";\n"},
+			want:    `foo.proto:2:1: import "bar.proto" was not found`,
+		},
+		{
+			name: "import cycle",
+			sources: map[string]string{
+				"foo.proto": "syntax = \"proto3\";\nimport \"bar.proto\";\n",
+				"bar.proto": "syntax = \"proto3\";\nimport \"foo.proto\";\n",
+			},
+			want: "foo.proto: import cycle: foo.proto -> bar.proto -> foo.proto",
+		},
+		{
+			name:    "duplicate",
+			sources: map[string]string{"foo.proto": "syntax = \"proto3\";\nmessage Foo {}\nenum Foo {\n  FOO = 0;\n}\n"},
+			want:    `foo.proto:3:1: "Foo" is already defined`,
+		},
+		{
+			name:    "unknown option",
+			sources: map[string]string{"foo.proto": "syntax = \"proto3\";\nmessage Foo {\n  string bar = 1 [lazy_load = true];\n}\n"},
+			want:    `foo.proto:3:18: option "lazy_load" is unknown for google.protobuf.FieldOptions`,
+		},
+		{
+			name:    "invalid option value",
+			sources: map[string]string{"foo.proto": "syntax = \"proto3\";\noption java_multiple_files = 1;\n"},
+			want:    `foo.proto:2:1: option java_multiple_files: invalid value "1" for bool field java_multiple_files`,
+		},
+		{
+			name:    "undefined custom option",
+			sources: map[string]string{"foo.proto": "syntax = \"proto3\";\noption (foo) = 1;\n"},
+			want:    `foo.proto:2:1: option "foo" is not defined`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(t, tt.sources, []string{"foo.proto"}, Options{})
+			if err == nil {
+				t.Fatalf("Compile() error = nil, want %q", tt.want)
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("Compile() error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileSourceInfo(t *testing.T) {
+	set, err := compile(t, map[string]string{"foo.proto": fooProto}, []string{"foo.proto"}, Options{IncludeSourceInfo: true})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	locations := make(map[string]*descriptorpb.SourceCodeInfo_Location)
+	for _, loc := range set.File[0].GetSourceCodeInfo().GetLocation() {
+		locations[pathKey(loc.Path)] = loc
+	}
+
+	tests := []struct {
+		name     string
+		path     []int32
+		span     []int32
+		leading  string
+		trailing string
+	}{
+		{name: "package", path: []int32{2}, span: []int32{2, 0, 15}},
+		{name: "enum", path: []int32{5, 0}, span: []int32{7, 0, 13, 1}, leading: " Status is a status.\n"},
+		{name: "enum value", path: []int32{5, 0, 2, 2}, span: []int32{11, 2, 38}},
+		{name: "field", path: []int32{4, 0, 2, 0}, span: []int32{16, 2, 19}, trailing: " The query.\n"},
+		{name: "oneof field", path: []int32{4, 0, 2, 5}, span: []int32{22, 4, 40}},
+		{name: "nested message", path: []int32{4, 0, 3, 1}, span: []int32{27, 2, 29, 3}},
+		{name: "method", path: []int32{6, 0, 2, 1}, span: []int32{34, 2, 36, 3}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			loc, ok := locations[pathKey(tt.path)]
+			if !ok {
+				t.Fatalf("no location for %v", tt.path)
+			}
+			if !reflect.DeepEqual(loc.Span, tt.span) {
+				t.Errorf("span = %v, want %v", loc.Span, tt.span)
+			}
+			if got := loc.GetLeadingComments(); got != tt.leading {
+				t.Errorf("leading comments = %q, want %q", got, tt.leading)
+			}
+			if got := loc.GetTrailingComments(); got != tt.trailing {
+				t.Errorf("trailing comments = %q, want %q", got, tt.trailing)
+			}
+		})
+	}
+}
+
+// TestCompileProtoc cross-checks the descriptors with the ones protoc produces.
+func TestCompileProtoc(t *testing.T) {
+	if _, err := exec.LookPath("protoc"); err != nil {
+		t.Skip("protoc is not installed")
+	}
+	dir, err := ioutil.TempDir("", "compiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sources := map[string]string{"foo.proto": fooProto, "options.proto": optionsProto, "bar.proto": barProto}
+	for path, src := range sources {
+		if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	roots := []string{"foo.proto", "bar.proto"}
+	out := filepath.Join(dir, "set.pb")
+	cmd := exec.Command("protoc", append([]string{"--include_imports", "--descriptor_set_out=" + out, "-I."}, roots...)...)
+	cmd.Dir = dir
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("protoc: %v: %s", err, b)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := compile(t, sources, roots, Options{IncludeImports: true})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	wantFiles := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, f := range want.File {
+		wantFiles[f.GetName()] = f
+	}
+	for _, f := range got.File {
+		if strings.HasPrefix(f.GetName(), "google/protobuf/") {
+			continue
+		}
+		if !proto.Equal(f, wantFiles[f.GetName()]) {
+			t.Errorf("Compile() = %v, want %v", prototext.Format(f), prototext.Format(wantFiles[f.GetName()]))
+		}
+	}
+}
+
+func pathKey(path []int32) string {
+	var sb strings.Builder
+	for _, p := range path {
+		sb.WriteString(strconv.Itoa(int(p)) + ".")
+	}
+	return sb.String()
+}
@@ -0,0 +1,614 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compiler
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Field numbers of descriptor.proto which make up the paths of source code info.
+const (
+	filePackageTag     = 2
+	fileDependencyTag  = 3
+	fileMessageTypeTag = 4
+	fileEnumTypeTag    = 5
+	fileServiceTag     = 6
+	fileExtensionTag   = 7
+	fileSyntaxTag      = 12
+
+	messageFieldTag      = 2
+	messageNestedTypeTag = 3
+	messageEnumTypeTag   = 4
+	messageExtensionTag  = 6
+	messageOneofTag      = 8
+
+	enumValueTag     = 2
+	serviceMethodTag = 2
+)
+
+// maxFieldNumber is the exclusive upper bound of field numbers used for "max" in ranges.
+const maxFieldNumber = 536870912
+
+var scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"double":   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"float":    descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"int64":    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint64":   descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	"int32":    descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"fixed64":  descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	"fixed32":  descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	"bool":     descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"string":   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":    descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	"uint32":   descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	"sfixed32": descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	"sfixed64": descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	"sint32":   descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64":   descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+}
+
+// builder builds the descriptor of a file from its syntax tree.
+// It keeps the first error so that the tree can be walked without checking errors at each step.
+type builder struct {
+	*compiler
+	u      *unit
+	lines  []int
+	proto3 bool
+	err    error
+}
+
+// build builds the descriptor of a file and declares its symbols.
+// Type names and custom options are left to be resolved by the caller.
+func (c *compiler) build(u *unit) error {
+	b := &builder{compiler: c, u: u, lines: lineOffsets(u.file.Content)}
+	u.desc = &descriptorpb.FileDescriptorProto{Name: proto.String(u.path)}
+
+	elements := u.file.Proto.Elements
+	for _, el := range elements {
+		if s, ok := el.(*protobuf.Syntax); ok && s.Value == "proto3" {
+			b.proto3 = true
+			u.desc.Syntax = proto.String("proto3")
+		}
+	}
+	if len(u.file.Content) > 0 {
+		b.addLocation([]int32{}, 0, len(u.file.Content), nil, nil)
+	}
+
+	pkg := ""
+	for _, el := range elements {
+		if p, ok := el.(*protobuf.Package); ok {
+			pkg = p.Name
+			u.desc.Package = proto.String(pkg)
+			c.declarePackage(pkg, u.path)
+			b.locate([]int32{filePackageTag}, p.Position, p.Comment, p.InlineComment)
+		}
+	}
+
+	for _, el := range elements {
+		switch el := el.(type) {
+		case *protobuf.Syntax:
+			b.locate([]int32{fileSyntaxTag}, el.Position, el.Comment, el.InlineComment)
+		case *protobuf.Import:
+			i := int32(len(u.desc.Dependency))
+			u.desc.Dependency = append(u.desc.Dependency, el.Filename)
+			switch el.Kind {
+			case "public":
+				u.desc.PublicDependency = append(u.desc.PublicDependency, i)
+			case "weak":
+				u.desc.WeakDependency = append(u.desc.WeakDependency, i)
+			}
+			b.locate([]int32{fileDependencyTag, i}, el.Position, el.Comment, el.InlineComment)
+		case *protobuf.Option:
+			if u.desc.Options == nil {
+				u.desc.Options = &descriptorpb.FileOptions{}
+			}
+			b.option(u.desc.Options, el, pkg)
+		case *protobuf.Message:
+			if el.IsExtend {
+				u.desc.Extension = b.extend(u.desc.Extension, el, pkg, []int32{fileExtensionTag})
+				continue
+			}
+			path := []int32{fileMessageTypeTag, int32(len(u.desc.MessageType))}
+			u.desc.MessageType = append(u.desc.MessageType, b.message(el, pkg, path))
+		case *protobuf.Enum:
+			path := []int32{fileEnumTypeTag, int32(len(u.desc.EnumType))}
+			u.desc.EnumType = append(u.desc.EnumType, b.enum(el, pkg, path))
+		case *protobuf.Service:
+			path := []int32{fileServiceTag, int32(len(u.desc.Service))}
+			u.desc.Service = append(u.desc.Service, b.service(el, pkg, path))
+		}
+	}
+	return b.err
+}
+
+func (b *builder) message(m *protobuf.Message, scope string, path []int32) *descriptorpb.DescriptorProto {
+	full := qualify(scope, m.Name)
+	b.declare(full, kindMessage, m.Position)
+	b.locate(path, m.Position, m.Comment, nil)
+
+	desc := &descriptorpb.DescriptorProto{Name: proto.String(m.Name)}
+	b.messageElements(desc, m.Elements, full, path)
+
+	// Synthetic oneofs of proto3 optional fields follow the declared ones.
+	for _, f := range desc.Field {
+		if !f.GetProto3Optional() {
+			continue
+		}
+		f.OneofIndex = proto.Int32(int32(len(desc.OneofDecl)))
+		desc.OneofDecl = append(desc.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + f.GetName())})
+	}
+	return desc
+}
+
+func (b *builder) messageElements(desc *descriptorpb.DescriptorProto, elements []protobuf.Visitee, full string, path []int32) {
+	for _, el := range elements {
+		switch el := el.(type) {
+		case *protobuf.NormalField:
+			f := b.field(el.Field, full, b.label(el.Repeated, el.Optional, el.Required), b.fieldPath(desc, path))
+			if b.proto3 && el.Optional {
+				f.Proto3Optional = proto.Bool(true)
+			}
+			desc.Field = append(desc.Field, f)
+		case *protobuf.MapField:
+			desc.Field = append(desc.Field, b.mapField(desc, el, full, path))
+		case *protobuf.Group:
+			desc.Field = append(desc.Field, b.group(desc, el, full, path))
+		case *protobuf.Oneof:
+			i := int32(len(desc.OneofDecl))
+			oneof := &descriptorpb.OneofDescriptorProto{Name: proto.String(el.Name)}
+			desc.OneofDecl = append(desc.OneofDecl, oneof)
+			b.declare(qualify(full, el.Name), kindOther, el.Position)
+			b.locate(appendPath(path, messageOneofTag, i), el.Position, el.Comment, nil)
+			for _, el := range el.Elements {
+				var f *descriptorpb.FieldDescriptorProto
+				switch el := el.(type) {
+				case *protobuf.OneOfField:
+					f = b.field(el.Field, full, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, b.fieldPath(desc, path))
+				case *protobuf.Group:
+					f = b.group(desc, el, full, path)
+				case *protobuf.Option:
+					if oneof.Options == nil {
+						oneof.Options = &descriptorpb.OneofOptions{}
+					}
+					b.option(oneof.Options, el, full)
+					continue
+				default:
+					continue
+				}
+				f.OneofIndex = proto.Int32(i)
+				desc.Field = append(desc.Field, f)
+			}
+		case *protobuf.Message:
+			if el.IsExtend {
+				desc.Extension = b.extend(desc.Extension, el, full, appendPath(path, messageExtensionTag))
+				continue
+			}
+			nestedPath := appendPath(path, messageNestedTypeTag, int32(len(desc.NestedType)))
+			desc.NestedType = append(desc.NestedType, b.message(el, full, nestedPath))
+		case *protobuf.Enum:
+			enumPath := appendPath(path, messageEnumTypeTag, int32(len(desc.EnumType)))
+			desc.EnumType = append(desc.EnumType, b.enum(el, full, enumPath))
+		case *protobuf.Option:
+			if desc.Options == nil {
+				desc.Options = &descriptorpb.MessageOptions{}
+			}
+			b.option(desc.Options, el, full)
+		case *protobuf.Reserved:
+			for _, r := range el.Ranges {
+				desc.ReservedRange = append(desc.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+					Start: proto.Int32(int32(r.From)),
+					End:   proto.Int32(rangeEnd(r)),
+				})
+			}
+			desc.ReservedName = append(desc.ReservedName, el.FieldNames...)
+		case *protobuf.Extensions:
+			for _, r := range el.Ranges {
+				desc.ExtensionRange = append(desc.ExtensionRange, &descriptorpb.DescriptorProto_ExtensionRange{
+					Start: proto.Int32(int32(r.From)),
+					End:   proto.Int32(rangeEnd(r)),
+				})
+			}
+		}
+	}
+}
+
+// field builds a field whose type is resolved later. path is the path of the field for source code info.
+func (b *builder) field(f *protobuf.Field, scope string, label descriptorpb.FieldDescriptorProto_Label, path []int32) *descriptorpb.FieldDescriptorProto {
+	b.declare(qualify(scope, f.Name), kindOther, f.Position)
+	b.locate(path, f.Position, f.Comment, f.InlineComment)
+
+	desc := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(f.Name),
+		Number:   proto.Int32(int32(f.Sequence)),
+		Label:    label.Enum(),
+		JsonName: proto.String(jsonName(f.Name)),
+	}
+	b.fieldType(desc, f.Type, scope, f.Position)
+	for _, o := range f.Options {
+		switch o.Name {
+		case "default":
+			desc.DefaultValue = proto.String(defaultValue(desc.Type, &o.Constant))
+		case "json_name":
+			desc.JsonName = proto.String(o.Constant.Source)
+		default:
+			if desc.Options == nil {
+				desc.Options = &descriptorpb.FieldOptions{}
+			}
+			b.option(desc.Options, o, scope)
+		}
+	}
+	return desc
+}
+
+// fieldType sets the type of a field, or leaves it to be resolved if the type isn't a scalar one.
+func (b *builder) fieldType(desc *descriptorpb.FieldDescriptorProto, name, scope string, pos scanner.Position) {
+	if t, ok := scalarTypes[name]; ok {
+		desc.Type = t.Enum()
+		return
+	}
+	b.u.refs = append(b.u.refs, reference{
+		scope: scope,
+		name:  name,
+		pos:   pos,
+		kinds: []kind{kindMessage, kindEnum},
+		set: func(full string, k kind) {
+			desc.TypeName = proto.String("." + full)
+			if k == kindEnum {
+				desc.Type = descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+			} else {
+				desc.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			}
+		},
+	})
+}
+
+// mapField builds a map field and its entry message nested in the message.
+func (b *builder) mapField(desc *descriptorpb.DescriptorProto, f *protobuf.MapField, scope string, path []int32) *descriptorpb.FieldDescriptorProto {
+	name := mapEntryName(f.Name)
+	b.declare(qualify(scope, name), kindMessage, f.Position)
+
+	key := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("key"),
+		Number:   proto.Int32(1),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String("key"),
+	}
+	b.fieldType(key, f.KeyType, scope, f.Position)
+	value := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("value"),
+		Number:   proto.Int32(2),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String("value"),
+	}
+	b.fieldType(value, f.Type, scope, f.Position)
+	desc.NestedType = append(desc.NestedType, &descriptorpb.DescriptorProto{
+		Name:    proto.String(name),
+		Field:   []*descriptorpb.FieldDescriptorProto{key, value},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	})
+
+	// The type of the field is the entry rather than the value.
+	field := *f.Field
+	field.Type = "." + qualify(scope, name)
+	return b.field(&field, scope, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, b.fieldPath(desc, path))
+}
+
+// group builds a group field and its message nested in the message.
+func (b *builder) group(desc *descriptorpb.DescriptorProto, g *protobuf.Group, scope string, path []int32) *descriptorpb.FieldDescriptorProto {
+	name := strings.ToLower(g.Name)
+	fieldPath := b.fieldPath(desc, path)
+	b.declare(qualify(scope, name), kindOther, g.Position)
+	b.locate(fieldPath, g.Position, g.Comment, nil)
+
+	nestedPath := appendPath(path, messageNestedTypeTag, int32(len(desc.NestedType)))
+	b.declare(qualify(scope, g.Name), kindMessage, g.Position)
+	nested := &descriptorpb.DescriptorProto{Name: proto.String(g.Name)}
+	b.messageElements(nested, g.Elements, qualify(scope, g.Name), nestedPath)
+	desc.NestedType = append(desc.NestedType, nested)
+
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(int32(g.Sequence)),
+		Label:    b.label(g.Repeated, g.Optional, g.Required).Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum(),
+		TypeName: proto.String("." + qualify(scope, g.Name)),
+		JsonName: proto.String(jsonName(name)),
+	}
+}
+
+// extend builds the fields declared in an extend block and appends them to extensions.
+func (b *builder) extend(extensions []*descriptorpb.FieldDescriptorProto, m *protobuf.Message, scope string, path []int32) []*descriptorpb.FieldDescriptorProto {
+	for _, el := range m.Elements {
+		f, ok := el.(*protobuf.NormalField)
+		if !ok {
+			continue
+		}
+		fieldPath := appendPath(path, int32(len(extensions)))
+		desc := b.field(f.Field, scope, b.label(f.Repeated, f.Optional, f.Required), fieldPath)
+		if b.proto3 && f.Optional {
+			desc.Proto3Optional = proto.Bool(true)
+		}
+		b.symbols[qualify(scope, f.Name)] = symbol{kind: kindExtension, path: b.u.path}
+		b.u.refs = append(b.u.refs, reference{
+			scope: scope,
+			name:  m.Name,
+			pos:   m.Position,
+			kinds: []kind{kindMessage},
+			set: func(full string, _ kind) {
+				desc.Extendee = proto.String("." + full)
+			},
+		})
+		extensions = append(extensions, desc)
+	}
+	return extensions
+}
+
+func (b *builder) enum(e *protobuf.Enum, scope string, path []int32) *descriptorpb.EnumDescriptorProto {
+	full := qualify(scope, e.Name)
+	b.declare(full, kindEnum, e.Position)
+	b.locate(path, e.Position, e.Comment, nil)
+
+	desc := &descriptorpb.EnumDescriptorProto{Name: proto.String(e.Name)}
+	for _, el := range e.Elements {
+		switch el := el.(type) {
+		case *protobuf.EnumField:
+			valuePath := appendPath(path, enumValueTag, int32(len(desc.Value)))
+			// Enum values are siblings of their enum.
+			b.declare(qualify(scope, el.Name), kindOther, el.Position)
+			b.locate(valuePath, el.Position, el.Comment, el.InlineComment)
+			value := &descriptorpb.EnumValueDescriptorProto{
+				Name:   proto.String(el.Name),
+				Number: proto.Int32(int32(el.Integer)),
+			}
+			options := enumValueOptions(el)
+			if len(options) > 0 {
+				value.Options = &descriptorpb.EnumValueOptions{}
+			}
+			for _, o := range options {
+				b.option(value.Options, o, scope)
+			}
+			desc.Value = append(desc.Value, value)
+		case *protobuf.Option:
+			if desc.Options == nil {
+				desc.Options = &descriptorpb.EnumOptions{}
+			}
+			b.option(desc.Options, el, full)
+		case *protobuf.Reserved:
+			for _, r := range el.Ranges {
+				end := int32(r.To)
+				if r.Max {
+					end = math.MaxInt32
+				}
+				desc.ReservedRange = append(desc.ReservedRange, &descriptorpb.EnumDescriptorProto_EnumReservedRange{
+					Start: proto.Int32(int32(r.From)),
+					End:   proto.Int32(end),
+				})
+			}
+			desc.ReservedName = append(desc.ReservedName, el.FieldNames...)
+		}
+	}
+	return desc
+}
+
+func (b *builder) service(s *protobuf.Service, scope string, path []int32) *descriptorpb.ServiceDescriptorProto {
+	full := qualify(scope, s.Name)
+	b.declare(full, kindOther, s.Position)
+	b.locate(path, s.Position, s.Comment, nil)
+
+	desc := &descriptorpb.ServiceDescriptorProto{Name: proto.String(s.Name)}
+	for _, el := range s.Elements {
+		switch el := el.(type) {
+		case *protobuf.RPC:
+			desc.Method = append(desc.Method, b.method(el, full, appendPath(path, serviceMethodTag, int32(len(desc.Method)))))
+		case *protobuf.Option:
+			if desc.Options == nil {
+				desc.Options = &descriptorpb.ServiceOptions{}
+			}
+			b.option(desc.Options, el, full)
+		}
+	}
+	return desc
+}
+
+func (b *builder) method(r *protobuf.RPC, scope string, path []int32) *descriptorpb.MethodDescriptorProto {
+	b.declare(qualify(scope, r.Name), kindOther, r.Position)
+	b.locate(path, r.Position, r.Comment, r.InlineComment)
+
+	desc := &descriptorpb.MethodDescriptorProto{Name: proto.String(r.Name)}
+	if r.StreamsRequest {
+		desc.ClientStreaming = proto.Bool(true)
+	}
+	if r.StreamsReturns {
+		desc.ServerStreaming = proto.Bool(true)
+	}
+	b.methodType(&desc.InputType, r.RequestType, scope, r.Position)
+	b.methodType(&desc.OutputType, r.ReturnsType, scope, r.Position)
+	for _, el := range r.Elements {
+		if o, ok := el.(*protobuf.Option); ok {
+			if desc.Options == nil {
+				desc.Options = &descriptorpb.MethodOptions{}
+			}
+			b.option(desc.Options, o, scope)
+		}
+	}
+	return desc
+}
+
+func (b *builder) methodType(dst **string, name, scope string, pos scanner.Position) {
+	b.u.refs = append(b.u.refs, reference{
+		scope: scope,
+		name:  name,
+		pos:   pos,
+		kinds: []kind{kindMessage},
+		set: func(full string, _ kind) {
+			*dst = proto.String("." + full)
+		},
+	})
+}
+
+// option sets a built-in option to an options message or leaves a custom one to be set after types are resolved.
+func (b *builder) option(message proto.Message, o *protobuf.Option, scope string) {
+	b.u.messages = appendMessage(b.u.messages, message)
+	opt := option{message: message, option: o, scope: scope}
+	if strings.HasPrefix(o.Name, "(") {
+		b.u.options = append(b.u.options, opt)
+		return
+	}
+	if err := b.setOption(b.u, opt); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// declare declares a symbol defined in the file and reports an error if it's already defined.
+func (b *builder) declare(full string, k kind, pos scanner.Position) {
+	if sym, ok := b.symbols[full]; ok && b.err == nil {
+		if sym.path == b.u.path {
+			b.err = errorAt(b.u.path, pos, "%q is already defined", full)
+		} else {
+			b.err = errorAt(b.u.path, pos, "%q is already defined in %q", full, sym.path)
+		}
+		return
+	}
+	b.symbols[full] = symbol{kind: k, path: b.u.path}
+}
+
+// label returns the label of a field. Fields without labels are optional.
+func (b *builder) label(repeated, optional, required bool) descriptorpb.FieldDescriptorProto_Label {
+	switch {
+	case repeated:
+		return descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	case required:
+		return descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
+	default:
+		return descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	}
+}
+
+// fieldPath returns the path of the next field of a message.
+func (b *builder) fieldPath(desc *descriptorpb.DescriptorProto, path []int32) []int32 {
+	return appendPath(path, messageFieldTag, int32(len(desc.Field)))
+}
+
+// enumValueOptions returns the options of an enum value.
+// The parser keeps them in the elements of the value, or in ValueOption for older versions.
+func enumValueOptions(v *protobuf.EnumField) []*protobuf.Option {
+	var options []*protobuf.Option
+	for _, el := range v.Elements {
+		if o, ok := el.(*protobuf.Option); ok {
+			options = append(options, o)
+		}
+	}
+	if len(options) == 0 && v.ValueOption != nil {
+		options = append(options, v.ValueOption)
+	}
+	return options
+}
+
+// jsonName returns the JSON name of a field in the same way as protoc does.
+func jsonName(name string) string {
+	var sb strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && 'a' <= r && r <= 'z':
+			sb.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			sb.WriteRune(r)
+			upper = false
+		}
+	}
+	return sb.String()
+}
+
+// mapEntryName returns the name of the entry message of a map field in the same way as protoc does.
+func mapEntryName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && 'a' <= r && r <= 'z':
+			sb.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			sb.WriteRune(r)
+			upper = false
+		}
+	}
+	return sb.String() + "Entry"
+}
+
+// defaultValue returns the default value of a field in the form of descriptors.
+// The type is nil for enum fields, whose default values are the names of the values as written.
+func defaultValue(t *descriptorpb.FieldDescriptorProto_Type, lit *protobuf.Literal) string {
+	if t == nil {
+		return lit.Source
+	}
+	switch *t {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING:
+		return unescape(lit)
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		if v, err := strconv.ParseFloat(lit.Source, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		if v, err := strconv.ParseInt(lit.Source, 0, 64); err == nil {
+			return strconv.FormatInt(v, 10)
+		}
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED32, descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+		if v, err := strconv.ParseUint(lit.Source, 0, 64); err == nil {
+			return strconv.FormatUint(v, 10)
+		}
+	}
+	return lit.Source
+}
+
+// rangeEnd returns the exclusive end of a range of field numbers.
+func rangeEnd(r protobuf.Range) int32 {
+	if r.Max {
+		return maxFieldNumber
+	}
+	return int32(r.To + 1)
+}
+
+// appendPath returns a new path so that paths sharing a prefix don't share the backing array.
+func appendPath(path []int32, elems ...int32) []int32 {
+	p := make([]int32, 0, len(path)+len(elems))
+	p = append(p, path...)
+	return append(p, elems...)
+}
+
+func appendMessage(messages []proto.Message, m proto.Message) []proto.Message {
+	for _, msg := range messages {
+		if msg == m {
+			return messages
+		}
+	}
+	return append(messages, m)
+}
@@ -0,0 +1,262 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	protobuf "github.com/emicklei/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// option is an option to be set to an options message such as *descriptorpb.FieldOptions.
+type option struct {
+	message proto.Message
+	option  *protobuf.Option
+	// scope is the scope from which the names of custom options are resolved.
+	scope string
+}
+
+// setOption sets an option following its name, which may be a path into a message like "(foo.bar).baz".
+func (c *compiler) setOption(u *unit, o option) error {
+	components, ok := splitOptionName(o.option.Name)
+	if !ok {
+		return errorAt(u.path, o.option.Position, "invalid option name %q", o.option.Name)
+	}
+	m := o.message.ProtoReflect()
+	for i, component := range components {
+		fd, err := c.optionField(u, m, component, o)
+		if err != nil {
+			return err
+		}
+		if i == len(components)-1 {
+			if err := setValue(m, fd, &o.option.Constant); err != nil {
+				return errorAt(u.path, o.option.Position, "option %s: %v", o.option.Name, err)
+			}
+			break
+		}
+		if fd.Message() == nil || fd.IsList() {
+			return errorAt(u.path, o.option.Position, "option %s: %s is not a message", o.option.Name, component)
+		}
+		m = m.Mutable(fd).Message()
+	}
+	return nil
+}
+
+// optionField returns the field of an options message which a component of an option name refers to.
+// Components in parentheses refer to extensions.
+func (c *compiler) optionField(u *unit, m protoreflect.Message, component string, o option) (protoreflect.FieldDescriptor, error) {
+	md := m.Descriptor()
+	if !strings.HasPrefix(component, "(") {
+		fd := md.Fields().ByName(protoreflect.Name(component))
+		if fd == nil {
+			return nil, errorAt(u.path, o.option.Position, "option %q is unknown for %s", component, md.FullName())
+		}
+		return fd, nil
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(component, "("), ")")
+	full, sym, ok := c.lookup(o.scope, name, []kind{kindExtension})
+	if !ok {
+		return nil, errorAt(u.path, o.option.Position, "option %q is not defined", name)
+	}
+	if !c.visible(u)[sym.path] {
+		return nil, errorAt(u.path, o.option.Position, "option %q seems to be defined in %q, which is not imported by %q", name, sym.path, u.path)
+	}
+	xt, ok := c.extensionType(full)
+	if !ok {
+		return nil, errorAt(u.path, o.option.Position, "option %q is not defined", name)
+	}
+	xd := xt.TypeDescriptor()
+	if xd.ContainingMessage().FullName() != md.FullName() {
+		return nil, errorAt(u.path, o.option.Position, "option %q extends %s rather than %s", name, xd.ContainingMessage().FullName(), md.FullName())
+	}
+	return xd, nil
+}
+
+// setValue sets a literal to a field of a message.
+// Repeated fields accept lists and are appended to by each literal.
+func setValue(m protoreflect.Message, fd protoreflect.FieldDescriptor, lit *protobuf.Literal) error {
+	if fd.IsMap() {
+		return fmt.Errorf("map field %s is not supported", fd.Name())
+	}
+	if fd.IsList() {
+		elems := lit.Array
+		if elems == nil {
+			elems = []*protobuf.Literal{lit}
+		}
+		list := m.Mutable(fd).List()
+		for _, elem := range elems {
+			if fd.Message() != nil {
+				v := list.NewElement()
+				if err := setFields(v.Message(), elem); err != nil {
+					return err
+				}
+				list.Append(v)
+				continue
+			}
+			v, err := scalarValue(fd, elem)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+	if lit.Array != nil {
+		return fmt.Errorf("%s is not repeated", fd.Name())
+	}
+	if fd.Message() != nil {
+		return setFields(m.Mutable(fd).Message(), lit)
+	}
+	v, err := scalarValue(fd, lit)
+	if err != nil {
+		return err
+	}
+	m.Set(fd, v)
+	return nil
+}
+
+// setFields sets the fields of an aggregate literal to a message.
+func setFields(m protoreflect.Message, lit *protobuf.Literal) error {
+	if lit.OrderedMap == nil && lit.Source != "" {
+		return fmt.Errorf("%s must be an aggregate value", m.Descriptor().Name())
+	}
+	for _, nl := range lit.OrderedMap {
+		fields := m.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(nl.Name))
+		if fd == nil {
+			// Groups are referred to by their type names.
+			if g := fields.ByName(protoreflect.Name(strings.ToLower(nl.Name))); g != nil && g.Kind() == protoreflect.GroupKind {
+				fd = g
+			}
+		}
+		if fd == nil {
+			return fmt.Errorf("%s has no field %q", m.Descriptor().FullName(), nl.Name)
+		}
+		if err := setValue(m, fd, nl.Literal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scalarValue converts a literal into a value of a field which isn't a message.
+func scalarValue(fd protoreflect.FieldDescriptor, lit *protobuf.Literal) (protoreflect.Value, error) {
+	invalid := fmt.Errorf("invalid value %q for %s field %s", lit.SourceRepresentation(), fd.Kind(), fd.Name())
+	if lit.OrderedMap != nil {
+		return protoreflect.Value{}, invalid
+	}
+	if fd.Kind() == protoreflect.StringKind || fd.Kind() == protoreflect.BytesKind {
+		if !lit.IsString {
+			return protoreflect.Value{}, invalid
+		}
+		if fd.Kind() == protoreflect.BytesKind {
+			return protoreflect.ValueOfBytes([]byte(unescape(lit))), nil
+		}
+		return protoreflect.ValueOfString(unescape(lit)), nil
+	}
+	if lit.IsString {
+		return protoreflect.Value{}, invalid
+	}
+
+	s := lit.Source
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		switch s {
+		case "true":
+			return protoreflect.ValueOfBool(true), nil
+		case "false":
+			return protoreflect.ValueOfBool(false), nil
+		}
+	case protoreflect.EnumKind:
+		if v := fd.Enum().Values().ByName(protoreflect.Name(s)); v != nil {
+			return protoreflect.ValueOfEnum(v.Number()), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if v, err := strconv.ParseInt(s, 0, 32); err == nil {
+			return protoreflect.ValueOfInt32(int32(v)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if v, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return protoreflect.ValueOfInt64(v), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if v, err := strconv.ParseUint(s, 0, 32); err == nil {
+			return protoreflect.ValueOfUint32(uint32(v)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if v, err := strconv.ParseUint(s, 0, 64); err == nil {
+			return protoreflect.ValueOfUint64(v), nil
+		}
+	case protoreflect.FloatKind:
+		if v, err := strconv.ParseFloat(s, 32); err == nil {
+			return protoreflect.ValueOfFloat32(float32(v)), nil
+		}
+	case protoreflect.DoubleKind:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return protoreflect.ValueOfFloat64(v), nil
+		}
+	}
+	return protoreflect.Value{}, invalid
+}
+
+// splitOptionName splits an option name into its components keeping the parentheses of extension names.
+func splitOptionName(name string) ([]string, bool) {
+	var components []string
+	for name != "" {
+		var component string
+		if strings.HasPrefix(name, "(") {
+			i := strings.Index(name, ")")
+			if i < 0 {
+				return nil, false
+			}
+			component, name = name[:i+1], name[i+1:]
+		} else {
+			i := strings.Index(name, ".")
+			if i < 0 {
+				i = len(name)
+			}
+			component, name = name[:i], name[i:]
+		}
+		if component == "" || component == "()" {
+			return nil, false
+		}
+		components = append(components, component)
+		if name == "" {
+			break
+		}
+		if !strings.HasPrefix(name, ".") {
+			return nil, false
+		}
+		name = name[1:]
+	}
+	return components, len(components) > 0
+}
+
+// unescape returns the value of a string literal whose escape sequences are kept as written by the parser.
+func unescape(lit *protobuf.Literal) string {
+	s := lit.Source
+	if lit.QuoteRune == '\'' {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	if v, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return v
+	}
+	return lit.Source
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compiler
+
+import (
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// locate adds the location of an element starting at a position.
+func (b *builder) locate(path []int32, pos scanner.Position, leading, trailing *protobuf.Comment) {
+	start := b.offset(pos)
+	if start < 0 {
+		return
+	}
+	b.addLocation(path, start, statementEnd(b.u.file.Content, start), leading, trailing)
+}
+
+// addLocation adds the location of an element which spans between offsets.
+func (b *builder) addLocation(path []int32, start, end int, leading, trailing *protobuf.Comment) {
+	startLine, startColumn := b.position(start)
+	endLine, endColumn := b.position(end)
+	span := []int32{startLine, startColumn, endLine, endColumn}
+	if startLine == endLine {
+		span = []int32{startLine, startColumn, endColumn}
+	}
+	b.u.locations = append(b.u.locations, &descriptorpb.SourceCodeInfo_Location{
+		Path:             path,
+		Span:             span,
+		LeadingComments:  commentText(leading),
+		TrailingComments: commentText(trailing),
+	})
+}
+
+// offset returns the byte offset of a position, or -1 if the position is unknown.
+func (b *builder) offset(pos scanner.Position) int {
+	if pos.Line < 1 || pos.Line > len(b.lines) {
+		return -1
+	}
+	return b.lines[pos.Line-1] + pos.Column - 1
+}
+
+// position returns the 0-based line and column of an offset.
+func (b *builder) position(offset int) (int32, int32) {
+	line := len(b.lines) - 1
+	for line > 0 && b.lines[line] > offset {
+		line--
+	}
+	return int32(line), int32(offset - b.lines[line])
+}
+
+// lineOffsets returns the offsets at which the lines of a content start.
+func lineOffsets(content []byte) []int {
+	offsets := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// statementEnd returns the offset just after a statement or a declaration starting at an offset,
+// which ends with a semicolon or the closing brace of its body.
+// Braces of aggregate values, which follow '=' or ':', don't make bodies.
+func statementEnd(content []byte, start int) int {
+	depth := 0
+	block := false
+	last := byte(0)
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '"', '\'':
+			for i++; i < len(content) && content[i] != c && content[i] != '\n'; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if i+1 < len(content) && content[i+1] == '/' {
+				for i < len(content) && content[i] != '\n' {
+					i++
+				}
+				continue
+			}
+			if i+1 < len(content) && content[i+1] == '*' {
+				if end := strings.Index(string(content[i+2:]), "*/"); end >= 0 {
+					i += end + 3
+				} else {
+					i = len(content)
+				}
+				continue
+			}
+		case '{':
+			if depth == 0 && last != '=' && last != ':' {
+				block = true
+			}
+			depth++
+		case '}':
+			depth--
+			if depth <= 0 && block {
+				return i + 1
+			}
+		case ';':
+			if depth <= 0 {
+				return i + 1
+			}
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			last = c
+		}
+	}
+	return len(content)
+}
+
+// commentText returns the text of a comment in the form of source code info.
+func commentText(c *protobuf.Comment) *string {
+	if c == nil || len(c.Lines) == 0 {
+		return nil
+	}
+	lines := c.Lines
+	if c.ExtraSlash {
+		lines = make([]string, len(c.Lines))
+		for i, line := range c.Lines {
+			lines[i] = "/" + line
+		}
+	}
+	text := strings.Join(lines, "\n")
+	if !c.Cstyle {
+		text += "\n"
+	}
+	return proto.String(text)
+}
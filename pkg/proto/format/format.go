@@ -0,0 +1,274 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"strings"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/printer"
+)
+
+// syntax is the lexical syntax of proto files.
+var syntax = printer.Syntax{
+	LineComment:   "//",
+	BlockComments: true,
+	LeadingDot:    true,
+}
+
+// brackets are the brackets of blocks, around which blank lines are removed.
+var brackets = printer.Brackets{
+	Open:  func(t printer.Token) bool { return t.Text == "{" },
+	Close: func(t printer.Token) bool { return t.Text == "}" },
+}
+
+// bracket is an open bracket.
+type bracket struct {
+	char byte
+
+	// literal is true if the bracket opens a message literal of an option value
+	// rather than a block of declarations.
+	literal bool
+}
+
+// Format formats a proto file with given options.
+//
+// Line breaks and blank lines are kept as printer.Print does.
+// Each line is indented by its nesting level, and statements spanning multiple lines are
+// indented by one more level after their first lines. Spaces between tokens are normalized,
+// while comments and string literals are kept as they are.
+func Format(src []byte, opts config.Format) ([]byte, error) {
+	tokens, err := printer.Tokenize(string(src), syntax)
+	if err != nil {
+		return nil, err
+	}
+	lines := printer.SplitLines(tokens)
+	alignable := layout(lines)
+
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = join(l.Tokens)
+	}
+	if opts.AlignFields {
+		align(lines, alignable, texts)
+	}
+	return printer.Print(lines, texts, brackets, opts), nil
+}
+
+// layout computes the indentation levels of lines, and returns whether each line is a single field
+// or enum value declaration whose name and number can be aligned with the ones of the neighboring lines.
+func layout(lines []*printer.Line) []bool {
+	alignable := make([]bool, len(lines))
+	var (
+		stack []bracket
+		// last is the last token other than comments.
+		last *printer.Token
+	)
+	for i, l := range lines {
+		first := l.Tokens[0]
+		l.Indent = len(stack)
+		if isClosing(first.Text) && l.Indent > 0 {
+			l.Indent--
+		}
+
+		inBlock := len(stack) == 0 || (stack[len(stack)-1].char == '{' && !stack[len(stack)-1].literal)
+		if inBlock && last != nil && !isClosing(first.Text) {
+			switch last.Text {
+			case ";", "{", "}":
+			default:
+				// The line continues the statement of the previous line.
+				l.Indent++
+			}
+		}
+		alignable[i] = inBlock && l.Indent == len(stack) && isDeclaration(l.Tokens)
+
+		for j := range l.Tokens {
+			t := &l.Tokens[j]
+			if t.Kind == printer.Comment {
+				continue
+			}
+			if t.Kind == printer.Punct {
+				switch t.Text {
+				case "{", "[", "(":
+					literal := t.Text == "{" && last != nil && (last.Text == ":" || last.Text == "=")
+					if len(stack) > 0 {
+						top := stack[len(stack)-1]
+						literal = literal || top.literal || top.char != '{'
+					}
+					stack = append(stack, bracket{char: t.Text[0], literal: literal})
+				case "}", "]", ")":
+					if len(stack) > 0 {
+						stack = stack[:len(stack)-1]
+					}
+				}
+			}
+			last = t
+		}
+	}
+	return alignable
+}
+
+func isClosing(text string) bool {
+	return text == "}" || text == "]" || text == ")"
+}
+
+// statementKeywords are the keywords which start statements other than fields and enum values.
+var statementKeywords = map[string]bool{
+	"syntax":     true,
+	"edition":    true,
+	"package":    true,
+	"import":     true,
+	"option":     true,
+	"reserved":   true,
+	"extensions": true,
+	"rpc":        true,
+	"message":    true,
+	"enum":       true,
+	"service":    true,
+	"oneof":      true,
+	"extend":     true,
+}
+
+// isDeclaration returns true if tokens are a single field or enum value declaration like "NAME = 1;".
+func isDeclaration(tokens []printer.Token) bool {
+	code := printer.CodeTokens(tokens)
+	if len(code) < 4 || code[len(code)-1].Text != ";" || statementKeywords[code[0].Text] {
+		return false
+	}
+	eq := equalIndex(code)
+	if eq < 1 || code[eq-1].Kind != printer.Ident {
+		return false
+	}
+	for _, t := range code[:len(code)-1] {
+		if t.Text == ";" || t.Text == "{" || t.Kind == printer.Comment {
+			return false
+		}
+	}
+	return true
+}
+
+func equalIndex(tokens []printer.Token) int {
+	for i, t := range tokens {
+		if t.Text == "=" {
+			return i
+		}
+	}
+	return -1
+}
+
+// join joins tokens on a line with normalized spaces.
+func join(tokens []printer.Token) string {
+	return printer.Join(tokens, func(i int) bool { return needSpace(tokens[i-1], tokens[i]) })
+}
+
+// needSpace returns true if a space is placed between two adjacent tokens.
+func needSpace(prev, cur printer.Token) bool {
+	switch {
+	case prev.Kind == printer.Comment || cur.Kind == printer.Comment:
+		return true
+	case prev.Text == "-":
+		// Minus signs are always unary.
+		return false
+	case cur.Text == ";" || cur.Text == "," || cur.Text == ")" || cur.Text == "]" || cur.Text == ">" || cur.Text == ":":
+		return false
+	case prev.Text == "(" || prev.Text == "[" || prev.Text == "<":
+		return false
+	case cur.Text == "<":
+		return false
+	case cur.Text == "(":
+		// "rpc Method(Request) returns (Response)" and "option (name) = value".
+		switch prev.Text {
+		case "returns", "option", "=", ",", ":", "{":
+			return true
+		}
+		return false
+	case cur.Kind == printer.Ident && strings.HasPrefix(cur.Text, ".") && prev.Text == ")":
+		// "(custom).field"
+		return false
+	case prev.Text == "{" && cur.Text == "}":
+		return false
+	}
+	return true
+}
+
+// align aligns the names and the numbers of consecutive field or enum value declarations
+// at the same level, and the comments trailing them.
+func align(lines []*printer.Line, alignable []bool, texts []string) {
+	for i := 0; i < len(lines); {
+		if !alignable[i] {
+			i++
+			continue
+		}
+		kind := equalIndex(printer.CodeTokens(lines[i].Tokens)) > 1
+		j := i + 1
+		for j < len(lines) && alignable[j] && !lines[j].BlankBefore &&
+			lines[j].Indent == lines[i].Indent && (equalIndex(printer.CodeTokens(lines[j].Tokens)) > 1) == kind {
+			j++
+		}
+		if j-i > 1 {
+			alignRun(lines[i:j], texts[i:j])
+		}
+		i = j
+	}
+}
+
+func alignRun(lines []*printer.Line, texts []string) {
+	type parts struct {
+		typ, name, rest, comment string
+	}
+	ps := make([]parts, len(lines))
+	var typeWidth, nameWidth int
+	for i, l := range lines {
+		code := printer.CodeTokens(l.Tokens)
+		eq := equalIndex(code)
+		ps[i] = parts{
+			typ:     join(code[:eq-1]),
+			name:    code[eq-1].Text,
+			rest:    join(code[eq:]),
+			comment: join(l.Tokens[len(code):]),
+		}
+		typeWidth = maxInt(typeWidth, len(ps[i].typ))
+		nameWidth = maxInt(nameWidth, len(ps[i].name))
+	}
+
+	codes := make([]string, len(lines))
+	var codeWidth int
+	for i, p := range ps {
+		code := pad(p.name, nameWidth) + " " + p.rest
+		if typeWidth > 0 {
+			code = pad(p.typ, typeWidth) + " " + code
+		}
+		codes[i] = code
+		codeWidth = maxInt(codeWidth, len(code))
+	}
+	for i, p := range ps {
+		text := codes[i]
+		if p.comment != "" {
+			text = pad(text, codeWidth) + " " + p.comment
+		}
+		texts[i] = text
+	}
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
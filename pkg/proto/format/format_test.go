@@ -0,0 +1,175 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		opts config.Format
+		want string
+	}{
+		{
+			name: "spaces and indentation",
+			src: `syntax="proto3";
+message Foo{
+string name=1;   // the name
+    repeated   int64 ids = 2 [packed=true];
+ map<string,int32> counts=3;
+  option (my.opt).value = {a:1 b:-2 c {d:"x"}};
+}
+`,
+			opts: config.Format{IndentSize: 2},
+			want: `syntax = "proto3";
+message Foo {
+  string name = 1; // the name
+  repeated int64 ids = 2 [packed = true];
+  map<string, int32> counts = 3;
+  option (my.opt).value = { a: 1 b: -2 c { d: "x" } };
+}
+`,
+		},
+		{
+			name: "blank lines",
+			src: `
+
+syntax = "proto3";
+
+
+message Foo {
+
+  string name = 1;
+
+}
+`,
+			opts: config.Format{IndentSize: 2},
+			want: `syntax = "proto3";
+
+message Foo {
+  string name = 1;
+}
+`,
+		},
+		{
+			name: "continuation and tabs",
+			src: `service S {
+rpc A(Foo)returns(Foo);
+rpc B (stream Foo)
+returns (stream Foo) {
+option (x) = true;
+}
+rpc C(Foo) returns (Foo) {}
+}`,
+			opts: config.Format{UseTabs: true},
+			want: "service S {\n" +
+				"\trpc A(Foo) returns (Foo);\n" +
+				"\trpc B(stream Foo)\n" +
+				"\t\treturns (stream Foo) {\n" +
+				"\t\toption (x) = true;\n" +
+				"\t}\n" +
+				"\trpc C(Foo) returns (Foo) {}\n" +
+				"}\n",
+		},
+		{
+			name: "comments",
+			src: `message Foo {
+    /* block
+       comment   
+     */
+  string name = 1; /* inline */ // trailing
+}
+`,
+			opts: config.Format{IndentSize: 4},
+			want: `message Foo {
+    /* block
+       comment
+     */
+    string name = 1; /* inline */ // trailing
+}
+`,
+		},
+		{
+			name: "align fields",
+			src: `message Foo {
+  string name = 1; // the name
+  repeated int64 ids = 2;
+  oneof kind {
+    string a = 10;
+    Bar b = 11;
+  }
+
+  int32 c = 3;
+}
+enum E {
+  E_UNSPECIFIED = 0;
+  E_A = 1;
+}
+`,
+			opts: config.Format{IndentSize: 2, AlignFields: true},
+			want: `message Foo {
+  string         name = 1; // the name
+  repeated int64 ids  = 2;
+  oneof kind {
+    string a = 10;
+    Bar    b = 11;
+  }
+
+  int32 c = 3;
+}
+enum E {
+  E_UNSPECIFIED = 0;
+  E_A           = 1;
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]byte(tt.src), tt.opts)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Format() = \n%s\nwant\n%s", got, tt.want)
+			}
+
+			again, err := Format(got, tt.opts)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("Format() is not idempotent: \n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormat_Error(t *testing.T) {
+	for _, src := range []string{
+		"message Foo { /* unterminated",
+		"option foo = \"unterminated;\n",
+	} {
+		if _, err := Format([]byte(src), config.Format{}); err == nil {
+			t.Errorf("Format(%q) error = nil, want an error", src)
+		}
+	}
+}
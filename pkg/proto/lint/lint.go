@@ -0,0 +1,286 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/registry"
+)
+
+// Severity is a severity of a problem.
+type Severity int
+
+const (
+	SeverityError Severity = iota + 1
+	SeverityWarning
+	SeverityInfo
+	SeverityHint
+)
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// ParseSeverity parses a case-insensitive name of severity.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "error":
+		return SeverityError, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "info", "information":
+		return SeverityInfo, nil
+	case "hint":
+		return SeverityHint, nil
+	default:
+		return 0, fmt.Errorf("undefined severity: %v", s)
+	}
+}
+
+// Problem is a violation of a rule.
+type Problem struct {
+	RuleID   string
+	Severity Severity
+	Message  string
+
+	// Name is the name or the type the problem is about, which appears at or after the position.
+	Name string
+
+	// Suggestion is a name which fixes the problem, which is empty if there is no such name.
+	// It is the name to replace Name with for the naming rules, and the name of the zero value
+	// to add for ENUM_FIRST_VALUE_ZERO.
+	Suggestion string
+
+	// Line and Column are 1-based.
+	Line   int
+	Column int
+}
+
+// ignoreDirective is the prefix of a comment which suppresses rules.
+const ignoreDirective = "lint:ignore"
+
+// Lint checks a proto file against the rules enabled by a given project configuration.
+// filename is the absolute path to the file, which is used to match the ignore patterns
+// and to check the package directory.
+//
+// Problems can be suppressed by a comment like "// lint:ignore RULE_ID".
+// A comment attached to a declaration suppresses the problems of the declaration,
+// while a standalone comment at the top level suppresses the problems in the whole file.
+// Without any rule ID, all rules are suppressed.
+func Lint(proto registry.Proto, filename string, cfg config.Project) []Problem {
+	if proto == nil || proto.Protobuf() == nil {
+		return nil
+	}
+	if cfg.Match(cfg.Lint.Ignore, filename) {
+		return nil
+	}
+
+	f := &file{
+		proto:    proto.Protobuf(),
+		filename: filename,
+		dir:      cfg.Dir,
+	}
+	suppressions := collectSuppressions(f.proto)
+
+	var problems []Problem
+	for _, rule := range EnabledRules(cfg.Lint) {
+		if cfg.Match(cfg.Lint.IgnoreOnly[rule.ID], filename) {
+			continue
+		}
+		severity := rule.Severity
+		if s, ok := cfg.Lint.Severity[rule.ID]; ok {
+			if parsed, err := ParseSeverity(s); err == nil {
+				severity = parsed
+			}
+		}
+
+		rule := rule
+		rule.check(f, func(pos scanner.Position, name, suggestion string, format string, args ...interface{}) {
+			if suppressions.suppressed(rule.ID, pos.Line) {
+				return
+			}
+			problems = append(problems, Problem{
+				RuleID:     rule.ID,
+				Severity:   severity,
+				Message:    fmt.Sprintf(format, args...),
+				Name:       name,
+				Suggestion: suggestion,
+				Line:       pos.Line,
+				Column:     pos.Column,
+			})
+		})
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems
+}
+
+// EnabledRules returns the rules enabled by a given configuration sorted by ID.
+// Unknown rule IDs are ignored.
+func EnabledRules(cfg config.Lint) []Rule {
+	enabled := make(map[string]bool)
+	if cfg.Use == nil {
+		for _, r := range rules {
+			enabled[r.ID] = r.Default
+		}
+	}
+	for _, id := range cfg.Use {
+		enabled[id] = true
+	}
+	for _, id := range cfg.Enable {
+		enabled[id] = true
+	}
+	for _, id := range cfg.Disable {
+		enabled[id] = false
+	}
+
+	var rs []Rule
+	for _, r := range rules {
+		if enabled[r.ID] {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// suppressions holds the rule IDs suppressed by comments.
+// The empty ID means all rules.
+type suppressions struct {
+	file   map[string]bool
+	byLine map[int]map[string]bool
+}
+
+func (s *suppressions) suppressed(id string, line int) bool {
+	if s.file[""] || s.file[id] {
+		return true
+	}
+	ids := s.byLine[line]
+	return ids[""] || ids[id]
+}
+
+func collectSuppressions(proto *protobuf.Proto) *suppressions {
+	s := &suppressions{
+		file:   make(map[string]bool),
+		byLine: make(map[int]map[string]bool),
+	}
+	addFile := func(comments ...*protobuf.Comment) {
+		for _, c := range comments {
+			for _, id := range ignoredIDs(c) {
+				s.file[id] = true
+			}
+		}
+	}
+	addLine := func(line int, comments ...*protobuf.Comment) {
+		for _, c := range comments {
+			for _, id := range ignoredIDs(c) {
+				if s.byLine[line] == nil {
+					s.byLine[line] = make(map[string]bool)
+				}
+				s.byLine[line][id] = true
+			}
+		}
+	}
+
+	var walk func(elements []protobuf.Visitee)
+	walk = func(elements []protobuf.Visitee) {
+		for _, el := range elements {
+			switch v := el.(type) {
+			case *protobuf.Syntax:
+				// The top of a file is where a directive for the whole file is usually placed.
+				addFile(v.Comment, v.InlineComment)
+			case *protobuf.Package:
+				addFile(v.Comment, v.InlineComment)
+			case *protobuf.Message:
+				addLine(v.Position.Line, v.Comment)
+				walk(v.Elements)
+			case *protobuf.Enum:
+				addLine(v.Position.Line, v.Comment)
+				walk(v.Elements)
+			case *protobuf.EnumField:
+				addLine(v.Position.Line, v.Comment, v.InlineComment)
+			case *protobuf.Service:
+				addLine(v.Position.Line, v.Comment)
+				walk(v.Elements)
+			case *protobuf.RPC:
+				addLine(v.Position.Line, v.Comment, v.InlineComment)
+			case *protobuf.NormalField:
+				addLine(v.Position.Line, v.Comment, v.InlineComment)
+			case *protobuf.MapField:
+				addLine(v.Position.Line, v.Comment, v.InlineComment)
+			case *protobuf.Oneof:
+				addLine(v.Position.Line, v.Comment)
+				walk(v.Elements)
+			case *protobuf.OneOfField:
+				addLine(v.Position.Line, v.Comment, v.InlineComment)
+			}
+		}
+	}
+	walk(proto.Elements)
+
+	for _, el := range proto.Elements {
+		if c, ok := el.(*protobuf.Comment); ok {
+			addFile(c)
+		}
+	}
+	return s
+}
+
+// ignoredIDs returns the rule IDs in the ignore directives of a comment.
+// It returns a slice with the empty ID if the directive has no rule ID.
+func ignoredIDs(c *protobuf.Comment) []string {
+	if c == nil {
+		return nil
+	}
+	var ids []string
+	for _, line := range c.Lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, ignoreDirective) {
+			continue
+		}
+		fields := strings.FieldsFunc(line[len(ignoreDirective):], func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(fields) == 0 {
+			ids = append(ids, "")
+			continue
+		}
+		ids = append(ids, fields...)
+	}
+	return ids
+}
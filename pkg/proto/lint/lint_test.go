@@ -0,0 +1,225 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/parser"
+)
+
+const testProto = `syntax = "proto3";
+
+package foo.v1;
+
+enum status {
+  OK = 1;
+  STATUS_failed = 2;
+}
+
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+}
+
+message search_request {
+  string Query = 1;
+  map<string, int32> pageSize = 2;
+  oneof filter {
+    string byName = 3;
+  }
+  message inner {}
+}
+
+service Searcher {
+  rpc search(search_request) returns (SearchResult);
+  rpc Get(GetRequest) returns (SearcherGetResponse);
+}
+`
+
+func TestLint(t *testing.T) {
+	root := filepath.FromSlash("/workspace")
+	filename := filepath.Join(root, "foo", "v1", "foo.proto")
+
+	tests := []struct {
+		name     string
+		text     string
+		filename string
+		cfg      config.Lint
+		want     []string
+	}{
+		{
+			name:     "default rules",
+			text:     testProto,
+			filename: filename,
+			want: []string{
+				"5:ENUM_PASCAL_CASE",
+				"6:ENUM_FIRST_VALUE_ZERO",
+				"6:ENUM_VALUE_PREFIX",
+				"7:ENUM_VALUE_UPPER_SNAKE_CASE",
+				"14:MESSAGE_PASCAL_CASE",
+				"15:FIELD_LOWER_SNAKE_CASE",
+				"16:FIELD_LOWER_SNAKE_CASE",
+				"18:FIELD_LOWER_SNAKE_CASE",
+				"20:MESSAGE_PASCAL_CASE",
+				"24:RPC_PASCAL_CASE",
+				"24:RPC_REQUEST_STANDARD_NAME",
+				"24:RPC_RESPONSE_STANDARD_NAME",
+			},
+		},
+		{
+			name:     "package directory mismatch",
+			text:     "syntax = \"proto3\";\npackage foo.v2;\n",
+			filename: filename,
+			want:     []string{"2:PACKAGE_DIRECTORY_MATCH"},
+		},
+		{
+			name:     "use and enable and disable",
+			text:     testProto,
+			filename: filename,
+			cfg: config.Lint{
+				Use:     []string{"MESSAGE_PASCAL_CASE", "FIELD_LOWER_SNAKE_CASE"},
+				Enable:  []string{"SERVICE_PASCAL_CASE"},
+				Disable: []string{"FIELD_LOWER_SNAKE_CASE"},
+			},
+			want: []string{
+				"14:MESSAGE_PASCAL_CASE",
+				"20:MESSAGE_PASCAL_CASE",
+			},
+		},
+		{
+			name:     "ignore",
+			text:     testProto,
+			filename: filename,
+			cfg: config.Lint{
+				Ignore: []string{"foo/**"},
+			},
+		},
+		{
+			name:     "ignore only",
+			text:     testProto,
+			filename: filename,
+			cfg: config.Lint{
+				Use:        []string{"MESSAGE_PASCAL_CASE", "ENUM_PASCAL_CASE"},
+				IgnoreOnly: map[string][]string{"MESSAGE_PASCAL_CASE": {"foo/v1"}},
+			},
+			want: []string{"5:ENUM_PASCAL_CASE"},
+		},
+		{
+			name: "comment suppression",
+			text: `syntax = "proto3";
+
+// lint:ignore ENUM_PASCAL_CASE
+
+// lint:ignore MESSAGE_PASCAL_CASE
+message foo {
+  string Bar = 1; // lint:ignore
+  string Baz = 2;
+}
+
+enum bar {
+  BAR_UNSPECIFIED = 0;
+}
+`,
+			filename: filepath.Join(root, "foo.proto"),
+			want:     []string{"8:FIELD_LOWER_SNAKE_CASE"},
+		},
+		{
+			name: "syntax comment suppression",
+			text: `// lint:ignore MESSAGE_PASCAL_CASE
+syntax = "proto3";
+package legacy; // lint:ignore ENUM_PASCAL_CASE
+
+message foo {
+  string Bar = 1;
+}
+
+enum bar {
+  BAR_UNSPECIFIED = 0;
+}
+`,
+			filename: filepath.Join(root, "foo.proto"),
+			want:     []string{"3:PACKAGE_DIRECTORY_MATCH", "6:FIELD_LOWER_SNAKE_CASE"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			proto, err := parser.ParseProto(strings.NewReader(tt.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := config.DefaultProjectConfig
+			cfg.Dir = root
+			cfg.Lint = tt.cfg
+
+			var got []string
+			for _, p := range Lint(proto, tt.filename, cfg) {
+				if p.Severity != SeverityWarning {
+					t.Errorf("Lint() severity = %v, want %v", p.Severity, SeverityWarning)
+				}
+				got = append(got, strconv.Itoa(p.Line)+":"+p.RuleID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLint_Severity(t *testing.T) {
+	proto, err := parser.ParseProto(strings.NewReader("syntax = \"proto3\";\nmessage foo {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultProjectConfig
+	cfg.Lint.Severity = map[string]string{"MESSAGE_PASCAL_CASE": "error"}
+
+	problems := Lint(proto, "", cfg)
+	if len(problems) != 1 {
+		t.Fatalf("len(Lint()) = %d, want 1", len(problems))
+	}
+	if got := problems[0].Severity; got != SeverityError {
+		t.Errorf("Lint() severity = %v, want %v", got, SeverityError)
+	}
+}
+
+func TestLint_Suggestion(t *testing.T) {
+	proto, err := parser.ParseProto(strings.NewReader(testProto))
+	if err != nil {
+		t.Fatalf("ParseProto() error = %v", err)
+	}
+	cfg := config.Project{
+		Lint: config.Lint{Use: []string{"MESSAGE_PASCAL_CASE", "ENUM_VALUE_PREFIX", "ENUM_FIRST_VALUE_ZERO"}},
+	}
+
+	got := make(map[string]string)
+	for _, p := range Lint(proto, "foo.proto", cfg) {
+		got[p.Name+":"+p.RuleID] = p.Suggestion
+	}
+	want := map[string]string{
+		"search_request:MESSAGE_PASCAL_CASE": "SearchRequest",
+		"inner:MESSAGE_PASCAL_CASE":          "Inner",
+		"OK:ENUM_VALUE_PREFIX":               "STATUS_OK",
+		"OK:ENUM_FIRST_VALUE_ZERO":           "STATUS_UNSPECIFIED",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() suggestions = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isPascalCase returns true if a given name is like "FooBar".
+func isPascalCase(name string) bool {
+	if name == "" || !unicode.IsUpper(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !isASCIILetter(r) && !isASCIIDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLowerSnakeCase returns true if a given name is like "foo_bar".
+func isLowerSnakeCase(name string) bool {
+	if name == "" || !unicode.IsLower(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLower(r) && !isASCIIDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return !strings.Contains(name, "__") && !strings.HasSuffix(name, "_")
+}
+
+// isUpperSnakeCase returns true if a given name is like "FOO_BAR".
+func isUpperSnakeCase(name string) bool {
+	if name == "" || !unicode.IsUpper(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsUpper(r) && !isASCIIDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return !strings.Contains(name, "__") && !strings.HasSuffix(name, "_")
+}
+
+// toUpperSnakeCase converts a given name to UPPER_SNAKE_CASE, e.g. "HTTPStatus" to "HTTP_STATUS".
+func toUpperSnakeCase(name string) string {
+	return strings.ToUpper(toSnakeCase(name))
+}
+
+// toLowerSnakeCase converts a given name to lower_snake_case, e.g. "fooBar" to "foo_bar".
+func toLowerSnakeCase(name string) string {
+	return strings.ToLower(toSnakeCase(name))
+}
+
+// toPascalCase converts a given name to PascalCase, e.g. "foo_bar" to "FooBar".
+func toPascalCase(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toSnakeCase inserts underscores at the word boundaries of a given name without changing the case.
+func toSnakeCase(name string) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, r := range rs {
+		if r == '_' {
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || isASCIIDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				if !strings.HasSuffix(b.String(), "_") {
+					b.WriteRune('_')
+				}
+			}
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+func isASCIILetter(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+func isASCIIDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
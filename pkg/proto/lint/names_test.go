@@ -0,0 +1,93 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import "testing"
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name           string
+		pascal         bool
+		lowerSnake     bool
+		upperSnake     bool
+		wantUpperSnake string
+		wantLowerSnake string
+		wantPascal     string
+	}{
+		{
+			name:           "FooBar",
+			pascal:         true,
+			wantUpperSnake: "FOO_BAR",
+			wantLowerSnake: "foo_bar",
+			wantPascal:     "FooBar",
+		},
+		{
+			name:           "HTTPStatus",
+			pascal:         true,
+			wantUpperSnake: "HTTP_STATUS",
+			wantLowerSnake: "http_status",
+			wantPascal:     "HTTPStatus",
+		},
+		{
+			name:           "foo_bar2",
+			lowerSnake:     true,
+			wantUpperSnake: "FOO_BAR2",
+			wantLowerSnake: "foo_bar2",
+			wantPascal:     "FooBar2",
+		},
+		{
+			name:           "FOO_BAR",
+			upperSnake:     true,
+			wantUpperSnake: "FOO_BAR",
+			wantLowerSnake: "foo_bar",
+			wantPascal:     "FOOBAR",
+		},
+		{
+			name:           "fooBar",
+			wantUpperSnake: "FOO_BAR",
+			wantLowerSnake: "foo_bar",
+			wantPascal:     "FooBar",
+		},
+		{
+			name:           "foo__bar",
+			wantUpperSnake: "FOO_BAR",
+			wantLowerSnake: "foo_bar",
+			wantPascal:     "FooBar",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPascalCase(tt.name); got != tt.pascal {
+				t.Errorf("isPascalCase() = %v, want %v", got, tt.pascal)
+			}
+			if got := isLowerSnakeCase(tt.name); got != tt.lowerSnake {
+				t.Errorf("isLowerSnakeCase() = %v, want %v", got, tt.lowerSnake)
+			}
+			if got := isUpperSnakeCase(tt.name); got != tt.upperSnake {
+				t.Errorf("isUpperSnakeCase() = %v, want %v", got, tt.upperSnake)
+			}
+			if got := toUpperSnakeCase(tt.name); got != tt.wantUpperSnake {
+				t.Errorf("toUpperSnakeCase() = %v, want %v", got, tt.wantUpperSnake)
+			}
+			if got := toLowerSnakeCase(tt.name); got != tt.wantLowerSnake {
+				t.Errorf("toLowerSnakeCase() = %v, want %v", got, tt.wantLowerSnake)
+			}
+			if got := toPascalCase(tt.name); got != tt.wantPascal {
+				t.Errorf("toPascalCase() = %v, want %v", got, tt.wantPascal)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"path/filepath"
+	"strings"
+	"text/scanner"
+
+	protobuf "github.com/emicklei/proto"
+)
+
+// Rule is a lint rule.
+type Rule struct {
+	// ID is the identifier of the rule, which is the same as the one of buf.
+	ID string
+
+	// Description describes what the rule checks.
+	Description string
+
+	// Severity is the default severity of the rule.
+	Severity Severity
+
+	// Default is true if the rule is enabled unless configured otherwise.
+	Default bool
+
+	check func(f *file, report reportFunc)
+}
+
+// reportFunc reports a problem at a given position about a given name.
+// suggestion is a name which fixes the problem, which is empty if there is no such name.
+type reportFunc func(pos scanner.Position, name, suggestion string, format string, args ...interface{})
+
+// file is a proto file to lint.
+type file struct {
+	proto    *protobuf.Proto
+	filename string
+
+	// dir is the absolute path to the root directory of the project.
+	dir string
+}
+
+// rules is the list of all rules sorted by ID.
+var rules = []Rule{
+	{
+		ID:          "ENUM_FIRST_VALUE_ZERO",
+		Description: "Checks that the first value of each enum is zero.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkEnumFirstValueZero,
+	},
+	{
+		ID:          "ENUM_PASCAL_CASE",
+		Description: "Checks that enum names are PascalCase.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkEnumPascalCase,
+	},
+	{
+		ID:          "ENUM_VALUE_PREFIX",
+		Description: "Checks that enum values are prefixed with the UPPER_SNAKE_CASE name of the enum.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkEnumValuePrefix,
+	},
+	{
+		ID:          "ENUM_VALUE_UPPER_SNAKE_CASE",
+		Description: "Checks that enum values are UPPER_SNAKE_CASE.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkEnumValueUpperSnakeCase,
+	},
+	{
+		ID:          "ENUM_ZERO_VALUE_SUFFIX",
+		Description: `Checks that the zero value of each enum is suffixed with "_UNSPECIFIED".`,
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkEnumZeroValueSuffix,
+	},
+	{
+		ID:          "FIELD_LOWER_SNAKE_CASE",
+		Description: "Checks that field names are lower_snake_case.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkFieldLowerSnakeCase,
+	},
+	{
+		ID:          "MESSAGE_PASCAL_CASE",
+		Description: "Checks that message names are PascalCase.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkMessagePascalCase,
+	},
+	{
+		ID:          "PACKAGE_DIRECTORY_MATCH",
+		Description: "Checks that the package name matches the directory of the file relative to the project root.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkPackageDirectoryMatch,
+	},
+	{
+		ID:          "RPC_PASCAL_CASE",
+		Description: "Checks that RPC names are PascalCase.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkRPCPascalCase,
+	},
+	{
+		ID:          "RPC_REQUEST_STANDARD_NAME",
+		Description: `Checks that RPC request types are named "MethodRequest" or "ServiceMethodRequest".`,
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkRPCRequestStandardName,
+	},
+	{
+		ID:          "RPC_RESPONSE_STANDARD_NAME",
+		Description: `Checks that RPC response types are named "MethodResponse" or "ServiceMethodResponse".`,
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkRPCResponseStandardName,
+	},
+	{
+		ID:          "SERVICE_PASCAL_CASE",
+		Description: "Checks that service names are PascalCase.",
+		Severity:    SeverityWarning,
+		Default:     true,
+		check:       checkServicePascalCase,
+	},
+}
+
+// Rules returns all rules sorted by ID.
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+// LookupRule returns the rule with a given ID.
+func LookupRule(id string) (Rule, bool) {
+	for _, r := range rules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func checkEnumFirstValueZero(f *file, report reportFunc) {
+	walkEnums(f.proto.Elements, func(e *protobuf.Enum) {
+		if values := enumValues(e); len(values) > 0 && values[0].Integer != 0 {
+			report(values[0].Position, values[0].Name, toUpperSnakeCase(e.Name)+"_UNSPECIFIED", "The first value of enum %q should be zero.", e.Name)
+		}
+	})
+}
+
+func checkEnumPascalCase(f *file, report reportFunc) {
+	walkEnums(f.proto.Elements, func(e *protobuf.Enum) {
+		if !isPascalCase(e.Name) {
+			report(e.Position, e.Name, toPascalCase(e.Name), "Enum name %q should be PascalCase, such as %q.", e.Name, toPascalCase(e.Name))
+		}
+	})
+}
+
+func checkEnumValuePrefix(f *file, report reportFunc) {
+	walkEnums(f.proto.Elements, func(e *protobuf.Enum) {
+		prefix := toUpperSnakeCase(e.Name) + "_"
+		for _, v := range enumValues(e) {
+			if !strings.HasPrefix(v.Name, prefix) {
+				report(v.Position, v.Name, prefix+toUpperSnakeCase(v.Name), "Enum value name %q should be prefixed with %q.", v.Name, prefix)
+			}
+		}
+	})
+}
+
+func checkEnumValueUpperSnakeCase(f *file, report reportFunc) {
+	walkEnums(f.proto.Elements, func(e *protobuf.Enum) {
+		for _, v := range enumValues(e) {
+			if !isUpperSnakeCase(v.Name) {
+				report(v.Position, v.Name, toUpperSnakeCase(v.Name), "Enum value name %q should be UPPER_SNAKE_CASE, such as %q.", v.Name, toUpperSnakeCase(v.Name))
+			}
+		}
+	})
+}
+
+func checkEnumZeroValueSuffix(f *file, report reportFunc) {
+	walkEnums(f.proto.Elements, func(e *protobuf.Enum) {
+		for _, v := range enumValues(e) {
+			if v.Integer == 0 && !strings.HasSuffix(v.Name, "_UNSPECIFIED") {
+				report(v.Position, v.Name, toUpperSnakeCase(e.Name)+"_UNSPECIFIED", "Enum zero value name %q should be suffixed with \"_UNSPECIFIED\".", v.Name)
+			}
+		}
+	})
+}
+
+func checkFieldLowerSnakeCase(f *file, report reportFunc) {
+	walkMessages(f.proto.Elements, func(m *protobuf.Message) {
+		for _, field := range messageFields(m) {
+			if !isLowerSnakeCase(field.Name) {
+				report(field.Position, field.Name, toLowerSnakeCase(field.Name), "Field name %q should be lower_snake_case, such as %q.", field.Name, toLowerSnakeCase(field.Name))
+			}
+		}
+	})
+}
+
+func checkMessagePascalCase(f *file, report reportFunc) {
+	walkMessages(f.proto.Elements, func(m *protobuf.Message) {
+		if !m.IsExtend && !isPascalCase(m.Name) {
+			report(m.Position, m.Name, toPascalCase(m.Name), "Message name %q should be PascalCase, such as %q.", m.Name, toPascalCase(m.Name))
+		}
+	})
+}
+
+func checkPackageDirectoryMatch(f *file, report reportFunc) {
+	if f.dir == "" || f.filename == "" {
+		return
+	}
+	rel, err := filepath.Rel(f.dir, filepath.Dir(f.filename))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	dir := filepath.ToSlash(rel)
+
+	for _, el := range f.proto.Elements {
+		pkg, ok := el.(*protobuf.Package)
+		if !ok {
+			continue
+		}
+		want := strings.Replace(pkg.Name, ".", "/", -1)
+		if dir != want {
+			report(pkg.Position, pkg.Name, "", "Files with package %q should be within a directory %q relative to the root, but were in %q.", pkg.Name, want, dir)
+		}
+		return
+	}
+}
+
+func checkRPCPascalCase(f *file, report reportFunc) {
+	walkRPCs(f.proto.Elements, func(s *protobuf.Service, rpc *protobuf.RPC) {
+		if !isPascalCase(rpc.Name) {
+			report(rpc.Position, rpc.Name, toPascalCase(rpc.Name), "RPC name %q should be PascalCase, such as %q.", rpc.Name, toPascalCase(rpc.Name))
+		}
+	})
+}
+
+func checkRPCRequestStandardName(f *file, report reportFunc) {
+	walkRPCs(f.proto.Elements, func(s *protobuf.Service, rpc *protobuf.RPC) {
+		checkRPCTypeStandardName(s, rpc, rpc.RequestType, "request", "Request", report)
+	})
+}
+
+func checkRPCResponseStandardName(f *file, report reportFunc) {
+	walkRPCs(f.proto.Elements, func(s *protobuf.Service, rpc *protobuf.RPC) {
+		checkRPCTypeStandardName(s, rpc, rpc.ReturnsType, "response", "Response", report)
+	})
+}
+
+func checkRPCTypeStandardName(s *protobuf.Service, rpc *protobuf.RPC, typ, kind, suffix string, report reportFunc) {
+	name := typ
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	want := rpc.Name + suffix
+	if name == want || name == s.Name+want {
+		return
+	}
+	report(rpc.Position, typ, "", "RPC %s type %q should be named %q or %q.", kind, typ, want, s.Name+want)
+}
+
+func checkServicePascalCase(f *file, report reportFunc) {
+	for _, el := range f.proto.Elements {
+		if s, ok := el.(*protobuf.Service); ok && !isPascalCase(s.Name) {
+			report(s.Position, s.Name, toPascalCase(s.Name), "Service name %q should be PascalCase, such as %q.", s.Name, toPascalCase(s.Name))
+		}
+	}
+}
+
+// walkMessages calls fn for all messages including the nested ones.
+func walkMessages(elements []protobuf.Visitee, fn func(*protobuf.Message)) {
+	for _, el := range elements {
+		if m, ok := el.(*protobuf.Message); ok {
+			fn(m)
+			walkMessages(m.Elements, fn)
+		}
+	}
+}
+
+// walkEnums calls fn for all enums including the nested ones.
+func walkEnums(elements []protobuf.Visitee, fn func(*protobuf.Enum)) {
+	for _, el := range elements {
+		switch v := el.(type) {
+		case *protobuf.Enum:
+			fn(v)
+		case *protobuf.Message:
+			walkEnums(v.Elements, fn)
+		}
+	}
+}
+
+// walkRPCs calls fn for all RPCs with the services they belong to.
+func walkRPCs(elements []protobuf.Visitee, fn func(*protobuf.Service, *protobuf.RPC)) {
+	for _, el := range elements {
+		s, ok := el.(*protobuf.Service)
+		if !ok {
+			continue
+		}
+		for _, el := range s.Elements {
+			if rpc, ok := el.(*protobuf.RPC); ok {
+				fn(s, rpc)
+			}
+		}
+	}
+}
+
+func enumValues(e *protobuf.Enum) (values []*protobuf.EnumField) {
+	for _, el := range e.Elements {
+		if v, ok := el.(*protobuf.EnumField); ok {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+// messageFields returns the fields declared directly in a message including the ones in oneofs.
+func messageFields(m *protobuf.Message) (fields []*protobuf.Field) {
+	for _, el := range m.Elements {
+		switch v := el.(type) {
+		case *protobuf.NormalField:
+			fields = append(fields, v.Field)
+		case *protobuf.MapField:
+			fields = append(fields, v.Field)
+		case *protobuf.Oneof:
+			for _, el := range v.Elements {
+				if f, ok := el.(*protobuf.OneOfField); ok {
+					fields = append(fields, f.Field)
+				}
+			}
+		}
+	}
+	return
+}
@@ -15,19 +15,70 @@
 package parser
 
 import (
+	"fmt"
 	"io"
+	"regexp"
+	"strconv"
+	"strings"
 
 	protobuf "github.com/emicklei/proto"
 
 	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/registry"
 )
 
-// ParseProtos parses protobuf files from filenames and return registry.ProtoSet.
+// Error represents an error which occurs while parsing a proto file.
+type Error struct {
+	// Line and Column are 1-based. They are zero if the position is unknown.
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+var (
+	// parseErrorRegexp matches errors like "<input>:3:5: found ..." returned by the parser.
+	parseErrorRegexp = regexp.MustCompile(`^[^:]*:(\d+):(\d+):\s*(.*)$`)
+	// scanErrorRegexp matches errors like "go scanner error at <input>:3:5 = ..." returned by the scanner.
+	scanErrorRegexp = regexp.MustCompile(`^go scanner error at [^:]*:(\d+):(\d+) = (.*)$`)
+)
+
 func ParseProto(r io.Reader) (registry.Proto, error) {
 	parser := protobuf.NewParser(r)
 	p, err := parser.Parse()
 	if err != nil {
-		return nil, err
+		return nil, newError(err)
 	}
 	return registry.NewProto(p), nil
 }
+
+// newError converts an error returned by the parser into *Error.
+// Only the first error is kept if the scanner reports multiple errors.
+func newError(err error) *Error {
+	msg := strings.TrimSpace(err.Error())
+	if i := strings.Index(msg, "\n"); i >= 0 {
+		msg = msg[:i]
+	}
+	for _, re := range []*regexp.Regexp{scanErrorRegexp, parseErrorRegexp} {
+		m := re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		line, lineErr := strconv.Atoi(m[1])
+		column, columnErr := strconv.Atoi(m[2])
+		if lineErr != nil || columnErr != nil {
+			continue
+		}
+		return &Error{
+			Line:    line,
+			Column:  column,
+			Message: m[3],
+		}
+	}
+	return &Error{Message: msg}
+}
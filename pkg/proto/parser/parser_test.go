@@ -13,3 +13,56 @@
 // limitations under the License.
 
 package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProto(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr *Error
+	}{
+		{
+			name: "valid",
+			text: `syntax = "proto3";
+message Foo {
+  string bar = 1;
+}
+`,
+		},
+		{
+			name: "syntax error",
+			text: `syntax = "proto3";
+message Foo {
+  string bar = ;
+}
+`,
+			wantErr: &Error{Line: 3, Column: 16},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProto(strings.NewReader(tt.text))
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ParseProto() error = %v", err)
+				}
+				return
+			}
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("ParseProto() error = %#v, want *Error", err)
+			}
+			if e.Line != tt.wantErr.Line || e.Column != tt.wantErr.Column {
+				t.Errorf("ParseProto() error position = %d:%d, want %d:%d", e.Line, e.Column, tt.wantErr.Line, tt.wantErr.Column)
+			}
+			if e.Message == "" {
+				t.Error("ParseProto() error message is empty")
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"strings"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+// Line is a line of output made of the tokens starting on the same line of input.
+type Line struct {
+	Tokens []Token
+
+	// BlankBefore is true if the line was preceded by blank lines in input.
+	BlankBefore bool
+
+	// Indent is the indentation level.
+	Indent int
+}
+
+// Brackets classifies the tokens opening and closing blocks.
+type Brackets struct {
+	Open  func(t Token) bool
+	Close func(t Token) bool
+}
+
+// SplitLines groups tokens by the lines they start on.
+// A token following a block comment spanning multiple lines is placed on the line the comment ends on.
+func SplitLines(tokens []Token) []*Line {
+	var lines []*Line
+	lastLine := 0
+	for _, t := range tokens {
+		if len(lines) == 0 || t.Line > lastLine {
+			lines = append(lines, &Line{
+				BlankBefore: len(lines) > 0 && t.Line > lastLine+1,
+			})
+		}
+		l := lines[len(lines)-1]
+		l.Tokens = append(l.Tokens, t)
+		lastLine = t.EndLine
+	}
+	return lines
+}
+
+// Print writes lines of texts indented by their levels with given options.
+//
+// Line breaks are kept as they are except for blank lines: consecutive blank lines are
+// collapsed into one and the ones at the start and the end of blocks are removed.
+func Print(lines []*Line, texts []string, brackets Brackets, opts config.Format) []byte {
+	indent := strings.Repeat(" ", opts.IndentSize)
+	if opts.UseTabs {
+		indent = "\t"
+	}
+
+	var sb strings.Builder
+	for i, l := range lines {
+		if i > 0 && l.BlankBefore && !opens(lines[i-1], brackets) && !brackets.Close(l.Tokens[0]) {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(strings.Repeat(indent, l.Indent))
+		sb.WriteString(texts[i])
+		sb.WriteString("\n")
+	}
+	return []byte(sb.String())
+}
+
+// opens returns true if a line ends with an open bracket ignoring comments.
+func opens(l *Line, brackets Brackets) bool {
+	code := CodeTokens(l.Tokens)
+	return len(code) > 0 && brackets.Open(code[len(code)-1])
+}
+
+// CodeTokens returns tokens without the trailing comments.
+func CodeTokens(tokens []Token) []Token {
+	end := len(tokens)
+	for end > 0 && tokens[end-1].Kind == Comment {
+		end--
+	}
+	return tokens[:end]
+}
+
+// Join joins tokens on a line, placing a space before the i-th token if space(i) returns true.
+// Trailing spaces of block comments spanning multiple lines are trimmed.
+func Join(tokens []Token, space func(i int) bool) string {
+	var sb strings.Builder
+	for i, t := range tokens {
+		if i > 0 && space(i) {
+			sb.WriteByte(' ')
+		}
+		if t.Kind == Comment && t.Line != t.EndLine {
+			ls := strings.Split(t.Text, "\n")
+			for j := range ls {
+				ls[j] = strings.TrimRight(ls[j], " \t\r")
+			}
+			sb.WriteString(strings.Join(ls, "\n"))
+			continue
+		}
+		sb.WriteString(t.Text)
+	}
+	return sb.String()
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		syntax  Syntax
+		want    []Token
+		wantErr string
+	}{
+		{
+			name:   "proto",
+			src:    "int32 .foo.Bar = 1; // comment  \n/* a\n b */ x",
+			syntax: Syntax{LineComment: "//", BlockComments: true, LeadingDot: true},
+			want: []Token{
+				{Kind: Ident, Text: "int32", Line: 1, Column: 1, EndLine: 1},
+				{Kind: Ident, Text: ".foo.Bar", Line: 1, Column: 7, EndLine: 1},
+				{Kind: Punct, Text: "=", Line: 1, Column: 16, EndLine: 1},
+				{Kind: Number, Text: "1", Line: 1, Column: 18, EndLine: 1},
+				{Kind: Punct, Text: ";", Line: 1, Column: 19, EndLine: 1},
+				{Kind: Comment, Text: "// comment", Line: 1, Column: 21, EndLine: 1},
+				{Kind: Comment, Text: "/* a\n b */", Line: 2, Column: 1, EndLine: 3},
+				{Kind: Ident, Text: "x", Line: 3, Column: 7, EndLine: 3},
+			},
+		},
+		{
+			name:   "text format",
+			src:    "f: 1e-3 # comment\ng: 0x1e-1 .x",
+			syntax: Syntax{LineComment: "#"},
+			want: []Token{
+				{Kind: Ident, Text: "f", Line: 1, Column: 1, EndLine: 1},
+				{Kind: Punct, Text: ":", Line: 1, Column: 2, EndLine: 1},
+				{Kind: Number, Text: "1e-3", Line: 1, Column: 4, EndLine: 1},
+				{Kind: Comment, Text: "# comment", Line: 1, Column: 9, EndLine: 1},
+				{Kind: Ident, Text: "g", Line: 2, Column: 1, EndLine: 2},
+				{Kind: Punct, Text: ":", Line: 2, Column: 2, EndLine: 2},
+				{Kind: Number, Text: "0x1e", Line: 2, Column: 4, EndLine: 2},
+				{Kind: Punct, Text: "-", Line: 2, Column: 8, EndLine: 2},
+				{Kind: Number, Text: "1", Line: 2, Column: 9, EndLine: 2},
+				{Kind: Punct, Text: ".", Line: 2, Column: 11, EndLine: 2},
+				{Kind: Ident, Text: "x", Line: 2, Column: 12, EndLine: 2},
+			},
+		},
+		{
+			name:   "unterminated string",
+			src:    "a\n  'b\n'",
+			syntax: Syntax{LineComment: "#"},
+			want: []Token{
+				{Kind: Ident, Text: "a", Line: 1, Column: 1, EndLine: 1},
+			},
+			wantErr: "2:3: unterminated string",
+		},
+		{
+			name:    "unterminated comment",
+			src:     "/* a",
+			syntax:  Syntax{BlockComments: true},
+			wantErr: "1:1: unterminated comment",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Tokenize(tt.src, tt.syntax)
+			if err != nil && err.Error() != tt.wantErr || err == nil && tt.wantErr != "" {
+				t.Fatalf("Tokenize() error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	src := "\n\na {\n\n  b\n\n\n  /* c\n  */ d\n\n}\n\ne\n"
+	tokens, err := Tokenize(src, Syntax{BlockComments: true})
+	if err != nil {
+		t.Fatalf("Tokenize() error = %v", err)
+	}
+	lines := SplitLines(tokens)
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		if i > 0 && i < len(lines)-2 {
+			l.Indent = 1
+		}
+		texts[i] = Join(l.Tokens, func(int) bool { return true })
+	}
+	brackets := Brackets{
+		Open:  func(t Token) bool { return t.Text == "{" },
+		Close: func(t Token) bool { return t.Text == "}" },
+	}
+
+	got := string(Print(lines, texts, brackets, config.Format{UseTabs: true}))
+	want := "a {\n\tb\n\n\t/* c\n  */ d\n}\n\ne\n"
+	if got != want {
+		t.Errorf("Print() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind is the kind of a token.
+type Kind int
+
+const (
+	Ident Kind = iota + 1
+	Number
+	String
+	Comment
+	Punct
+)
+
+// Token is a lexical token.
+// Comments are tokens as well so that they are kept by the formatters.
+type Token struct {
+	Kind Kind
+	Text string
+
+	// Line and Column are the 1-based position the token starts at.
+	Line   int
+	Column int
+
+	// EndLine is the line the token ends on.
+	// It differs from Line only for block comments spanning multiple lines.
+	EndLine int
+}
+
+// Syntax is the lexical syntax specific to a file type.
+type Syntax struct {
+	// LineComment is the prefix of the comments running to the end of lines.
+	LineComment string
+
+	// BlockComments is true if comments enclosed in "/*" and "*/" are allowed.
+	BlockComments bool
+
+	// LeadingDot is true if identifiers can start with a dot like fully-qualified names.
+	LeadingDot bool
+}
+
+// Error represents an error which occurs while tokenizing a file.
+type Error struct {
+	// Line and Column are 1-based.
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Tokenize splits a file into tokens.
+// It returns the tokens before the error as well if the file can't be split.
+func Tokenize(src string, syntax Syntax) ([]Token, error) {
+	var tokens []Token
+	line, lineStart := 1, 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		start, startLine, column := i, line, i-lineStart+1
+		kind := Punct
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+			continue
+		case syntax.LineComment != "" && strings.HasPrefix(src[i:], syntax.LineComment):
+			kind = Comment
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			// Trailing spaces of comments are trimmed since they are invisible.
+			for i > start+len(syntax.LineComment) && (src[i-1] == ' ' || src[i-1] == '\t' || src[i-1] == '\r') {
+				i--
+			}
+		case syntax.BlockComments && strings.HasPrefix(src[i:], "/*"):
+			kind = Comment
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return tokens, &Error{Line: startLine, Column: column, Message: "unterminated comment"}
+			}
+			i += 2 + end + 2
+			if n := strings.Count(src[start:i], "\n"); n > 0 {
+				line, lineStart = line+n, start+strings.LastIndex(src[start:i], "\n")+1
+			}
+		case c == '"' || c == '\'':
+			kind = String
+			i++
+			for i < len(src) && src[i] != c && src[i] != '\n' {
+				if src[i] == '\\' && i+1 < len(src) && src[i+1] != '\n' {
+					i++
+				}
+				i++
+			}
+			if i >= len(src) || src[i] != c {
+				return tokens, &Error{Line: startLine, Column: column, Message: "unterminated string"}
+			}
+			i++
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			kind = Number
+			for i < len(src) && (isIdentChar(src[i]) || src[i] == '.' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E') && !strings.HasPrefix(src[start:], "0x"))) {
+				i++
+			}
+		case isIdentChar(c) || (syntax.LeadingDot && c == '.' && i+1 < len(src) && isIdentChar(src[i+1])):
+			// Qualified names including dots are single tokens.
+			kind = Ident
+			for i < len(src) && (isIdentChar(src[i]) || src[i] == '.') {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, Token{Kind: kind, Text: src[start:i], Line: startLine, Column: column, EndLine: line})
+	}
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c) || c == '_'
+}
@@ -105,10 +105,29 @@ func NewMessage(protoMessage *protobuf.Message) Message {
 			f := NewMapField(v)
 			m.mapFields = append(m.mapFields, f)
 
+		case *protobuf.Message:
+			if v.IsExtend {
+				continue
+			}
+			nested := NewMessage(v)
+			m.nestedMessages = append(m.nestedMessages, nested)
+
+		case *protobuf.Enum:
+			e := NewEnum(v)
+			m.nestedEnums = append(m.nestedEnums, e)
+
 		default:
 		}
 	}
 
+	for _, nested := range m.nestedMessages {
+		m.nestedMessageNameToMessage[nested.Protobuf().Name] = nested
+	}
+
+	for _, e := range m.nestedEnums {
+		m.nestedEnumNameToEnum[e.Protobuf().Name] = e
+	}
+
 	for _, f := range m.fields {
 		m.fieldNameToField[f.ProtoField.Name] = f
 		m.lineToField[f.ProtoField.Position.Line] = f
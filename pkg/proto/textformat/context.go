@@ -0,0 +1,139 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textformat
+
+import (
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/printer"
+)
+
+// Context is the syntactic context at a position in a text format file.
+// It is computed from the tokens before the position so that it is available in incomplete files.
+type Context struct {
+	// Path is the names of the fields whose message values enclose the position from the outermost.
+	// The names of extensions and type URLs are enclosed in brackets.
+	Path []string
+	// Field is the name of the field whose scalar value is at the position,
+	// or empty if a field name is expected at the position.
+	Field string
+	// Prefix is the part of the identifier before the position.
+	Prefix string
+}
+
+// state is what is expected next while computing a context.
+type state int
+
+const (
+	stateName state = iota
+	stateAfterName
+	stateValue
+	stateList
+	stateExtension
+)
+
+// frame is a message value enclosing a position.
+type frame struct {
+	name string
+	// inList is true if the message is an element of a list.
+	inList bool
+}
+
+// ContextAt returns the context at a given 1-based position in a text format file.
+func ContextAt(src []byte, pos Position) Context {
+	tokens, _ := tokenize(string(src))
+
+	var (
+		c     Context
+		stack []frame
+		st    = stateName
+		name  string
+		ext   string
+	)
+	for _, t := range tokens {
+		if !before(t.pos(), pos) {
+			break
+		}
+		if t.Kind == printer.Comment {
+			continue
+		}
+		if !before(t.end(), pos) {
+			// The position is in or at the end of the token.
+			if t.Kind == printer.Ident || t.Kind == printer.Number {
+				c.Prefix = t.Text[:pos.Column-t.Column]
+				break
+			}
+			if t.Kind == printer.String {
+				break
+			}
+		}
+
+		switch st {
+		case stateName:
+			switch {
+			case t.Kind == printer.Ident:
+				name, st = t.Text, stateAfterName
+			case t.Text == "[":
+				ext, st = "", stateExtension
+			case t.Text == "}" || t.Text == ">":
+				if len(stack) > 0 {
+					if f := stack[len(stack)-1]; f.inList {
+						name, st = f.name, stateList
+					}
+					stack = stack[:len(stack)-1]
+				}
+			}
+		case stateExtension:
+			if t.Text == "]" {
+				name, st = "["+ext+"]", stateAfterName
+			} else {
+				ext += t.Text
+			}
+		case stateAfterName, stateValue, stateList:
+			switch {
+			case t.Text == ":" && st == stateAfterName:
+				st = stateValue
+			case t.Text == "{" || t.Text == "<":
+				stack = append(stack, frame{name: name, inList: st == stateList})
+				st = stateName
+			case t.Text == "[" && st != stateList:
+				st = stateList
+			case t.Text == "]" && st == stateList:
+				st = stateName
+			case t.Kind == printer.Ident && st == stateAfterName:
+				// The previous field has no value yet, which is common while editing.
+				name = t.Text
+			case t.Text == "-":
+				// The sign of a number.
+			case st == stateList:
+				// Scalar values in a list and the commas between them.
+			default:
+				// A scalar value ends the field.
+				st = stateName
+			}
+		}
+	}
+
+	for _, f := range stack {
+		c.Path = append(c.Path, f.name)
+	}
+	if st == stateValue || st == stateList {
+		c.Field = name
+	}
+	return c
+}
+
+// before returns true if a position is before another one.
+func before(a, b Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textformat
+
+import (
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/printer"
+)
+
+// brackets are the brackets of messages and lists, around which blank lines are removed.
+var brackets = printer.Brackets{
+	Open:  isOpening,
+	Close: isClosing,
+}
+
+// Format formats a text format file with given options in the same way as proto files.
+//
+// Line breaks and blank lines are kept as printer.Print does, where messages and lists are blocks.
+// Each line is indented by its nesting level, and a value on the line after its field name
+// is indented by one more level. Spaces between tokens are normalized,
+// while comments and string literals are kept as they are.
+func Format(src []byte, opts config.Format) ([]byte, error) {
+	tokens, err := printer.Tokenize(string(src), syntax)
+	if err != nil {
+		return nil, err
+	}
+	lines := printer.SplitLines(tokens)
+	layout(lines)
+
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = join(l.Tokens)
+	}
+	return printer.Print(lines, texts, brackets, opts), nil
+}
+
+// layout computes the indentation levels of lines.
+func layout(lines []*printer.Line) {
+	var (
+		depth int
+		// last is the last token other than comments, and prev is the line of it.
+		last *printer.Token
+		prev *printer.Line
+	)
+	for _, l := range lines {
+		l.Indent = depth
+		if isClosing(l.Tokens[0]) && l.Indent > 0 {
+			l.Indent--
+		}
+		switch {
+		case last != nil && last.Kind == printer.Punct && last.Text == ":":
+			// The line continues the field of the previous line.
+			l.Indent++
+		case last != nil && last.Kind == printer.String && l.Tokens[0].Kind == printer.String:
+			// Concatenated strings are aligned.
+			l.Indent = prev.Indent
+		}
+
+		for i := range l.Tokens {
+			t := &l.Tokens[i]
+			if t.Kind == printer.Comment {
+				continue
+			}
+			if isOpening(*t) {
+				depth++
+			} else if isClosing(*t) && depth > 0 {
+				depth--
+			}
+			last, prev = t, l
+		}
+	}
+}
+
+// isOpening returns true if a token opens a message or a list.
+// The brackets of extension names are closed on the same line, so they don't affect the indentation.
+func isOpening(t printer.Token) bool {
+	return t.Kind == printer.Punct && (t.Text == "{" || t.Text == "<" || t.Text == "[")
+}
+
+func isClosing(t printer.Token) bool {
+	return t.Kind == printer.Punct && (t.Text == "}" || t.Text == ">" || t.Text == "]")
+}
+
+// join joins tokens on a line with normalized spaces.
+func join(tokens []printer.Token) string {
+	return printer.Join(tokens, func(i int) bool {
+		return !inExtension(tokens, i) && needSpace(tokens[i-1], tokens[i])
+	})
+}
+
+// inExtension returns true if the i-th token is in the brackets of an extension name or a type URL,
+// or is the closing bracket of them.
+func inExtension(tokens []printer.Token, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch tokens[j].Text {
+		case "]":
+			return false
+		case "[":
+			return (j == 0 || tokens[j-1].Text != ":") && isExtensionName(tokens[j+1:])
+		}
+	}
+	return false
+}
+
+// isExtensionName returns true if tokens after an opening bracket are an extension name or a type URL
+// closed with a bracket, which are made only of identifiers and slashes unlike lists.
+func isExtensionName(tokens []printer.Token) bool {
+	for i, t := range tokens {
+		switch {
+		case t.Kind == printer.Punct && t.Text == "]":
+			return i > 0
+		case t.Kind != printer.Ident && t.Text != "/":
+			return false
+		}
+	}
+	return false
+}
+
+// needSpace returns true if a space is placed between two adjacent tokens.
+func needSpace(prev, cur printer.Token) bool {
+	switch {
+	case prev.Kind == printer.Comment || cur.Kind == printer.Comment:
+		return true
+	case prev.Kind == printer.Punct && prev.Text == "-":
+		// Minus signs are always unary.
+		return false
+	case cur.Kind == printer.Punct && (cur.Text == ":" || cur.Text == ";" || cur.Text == "," || cur.Text == "]"):
+		return false
+	case prev.Kind == printer.Punct && prev.Text == "[":
+		return false
+	case (prev.Text == "{" && cur.Text == "}") || (prev.Text == "<" && cur.Text == ">"):
+		return false
+	}
+	return true
+}
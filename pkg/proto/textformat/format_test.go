@@ -0,0 +1,116 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textformat
+
+import (
+	"testing"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/config"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		opts config.Format
+		want string
+	}{
+		{
+			name: "spaces and indentation",
+			src: `# proto-message: foo.v1.Config
+name:"foo"   # the name
+count : - 1
+child{
+enabled:true
+    ids:[1,2 ,3]
+  inner < id:1 >
+}
+[ foo.v1.ext ]:{ }
+any {
+[type.googleapis.com / foo.v1.Child] {id:1}
+}
+`,
+			opts: config.Format{IndentSize: 2},
+			want: `# proto-message: foo.v1.Config
+name: "foo" # the name
+count: -1
+child {
+  enabled: true
+  ids: [1, 2, 3]
+  inner < id: 1 >
+}
+[foo.v1.ext]: {}
+any {
+  [type.googleapis.com/foo.v1.Child] { id: 1 }
+}
+`,
+		},
+		{
+			name: "blank lines",
+			src: `
+
+name: "foo"
+
+
+child {
+
+  id: 1
+
+}
+
+`,
+			opts: config.Format{IndentSize: 2},
+			want: `name: "foo"
+
+child {
+  id: 1
+}
+`,
+		},
+		{
+			name: "lists and continuation",
+			src: `children [
+{
+id: 1
+},
+{ id: 2 }
+]
+description:
+"a long"
+"description"
+`,
+			opts: config.Format{UseTabs: true},
+			want: "children [\n\t{\n\t\tid: 1\n\t},\n\t{ id: 2 }\n]\ndescription:\n\t\"a long\"\n\t\"description\"\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]byte(tt.src), tt.opts)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	if _, err := Format([]byte("name: 'foo\n"), config.Format{IndentSize: 2}); err == nil {
+		t.Error("Format() error = nil, want an error")
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textformat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/printer"
+)
+
+// Position is a 1-based position in a text format file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// File is a parsed text format file.
+type File struct {
+	Header Header
+	// Fields is the fields of the top-level message.
+	Fields []*Field
+}
+
+// Header is the schema of a file declared with the comments at the start of it.
+// https://protobuf.dev/reference/protobuf/textformat-spec/#header
+type Header struct {
+	// ProtoFile is the path of the proto file declaring the message, given in "# proto-file:".
+	ProtoFile string
+	// ProtoMessage is the fully-qualified name of the message, given in "# proto-message:".
+	ProtoMessage string
+	// ProtoImports is the paths of the proto files declaring the extensions in the file, given in "# proto-import:".
+	ProtoImports []string
+
+	// ProtoFilePos and ProtoMessagePos are the positions of the values, which are zero if they are not given.
+	ProtoFilePos    Position
+	ProtoMessagePos Position
+}
+
+// Field is a field of a message.
+type Field struct {
+	// Name is the name of the field, or the name of an extension or the type URL of an Any
+	// without the brackets.
+	Name string
+	// Extension is true if the name is enclosed in brackets.
+	Extension bool
+	// Pos and End are the positions of the start and the end of the name including the brackets.
+	Pos Position
+	End Position
+
+	// Values is the values of the field. There is more than one value only in a list.
+	Values []*Value
+	// List is true if the values are enclosed in brackets.
+	List bool
+}
+
+// ValueKind is a kind of a value.
+type ValueKind int
+
+const (
+	// ValueIdent is an identifier such as an enum value, true or inf.
+	ValueIdent ValueKind = iota + 1
+	ValueNumber
+	ValueString
+	ValueMessage
+)
+
+// Value is a value of a field.
+type Value struct {
+	Kind ValueKind
+	// Text is the value as written without spaces, such as "-1", or the concatenated literals of a string
+	// joined with spaces. It is empty for a message.
+	Text string
+	// Pos and End are the positions of the start and the end of the value including the braces of a message.
+	Pos Position
+	End Position
+	// Fields is the fields of a message.
+	Fields []*Field
+}
+
+// Parse parses a text format file.
+func Parse(src []byte) (*File, error) {
+	tokens, err := tokenize(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{}
+	for _, t := range tokens {
+		if t.Kind != printer.Comment {
+			p.tokens = append(p.tokens, t)
+		}
+	}
+	if len(p.tokens) > 0 {
+		p.last = p.tokens[len(p.tokens)-1]
+	}
+
+	fields, err := p.fields("")
+	if err != nil {
+		return nil, err
+	}
+	return &File{Header: parseHeader(tokens), Fields: fields}, nil
+}
+
+// ParseHeader parses the header of a text format file, which is available even if the rest can't be parsed.
+func ParseHeader(src []byte) Header {
+	tokens, _ := tokenize(string(src))
+	return parseHeader(tokens)
+}
+
+// headerKeys are the keys of the header comments.
+const (
+	headerProtoFile    = "proto-file:"
+	headerProtoMessage = "proto-message:"
+	headerProtoImport  = "proto-import:"
+)
+
+// parseHeader parses the comments before the first field.
+func parseHeader(tokens []token) Header {
+	var h Header
+	for _, t := range tokens {
+		if t.Kind != printer.Comment {
+			break
+		}
+		body := strings.TrimSpace(strings.TrimLeft(t.Text, "#"))
+		for _, key := range []string{headerProtoFile, headerProtoMessage, headerProtoImport} {
+			if !strings.HasPrefix(body, key) {
+				continue
+			}
+			rest := t.Text[strings.Index(t.Text, key)+len(key):]
+			value := strings.TrimSpace(rest)
+			pos := Position{Line: t.Line, Column: t.Column + len(t.Text) - len(strings.TrimLeft(rest, " \t"))}
+			switch key {
+			case headerProtoFile:
+				h.ProtoFile, h.ProtoFilePos = value, pos
+			case headerProtoMessage:
+				h.ProtoMessage, h.ProtoMessagePos = value, pos
+			case headerProtoImport:
+				h.ProtoImports = append(h.ProtoImports, value)
+			}
+		}
+	}
+	return h
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	// last is the last token, where errors at the end of the file are reported.
+	last token
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.i >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.i], true
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	p.i++
+	return t
+}
+
+// errorf returns an error at the current token, or after the last token at the end of the file.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t, ok := p.peek()
+	if !ok {
+		end := p.last.end()
+		return &Error{Line: end.Line, Column: end.Column, Message: fmt.Sprintf(format, args...)}
+	}
+	return &Error{Line: t.Line, Column: t.Column, Message: fmt.Sprintf(format, args...)}
+}
+
+// fields parses fields until a given closing bracket, or the end of the file if it is empty.
+func (p *parser) fields(closing string) ([]*Field, error) {
+	var fields []*Field
+	for {
+		t, ok := p.peek()
+		if !ok {
+			if closing != "" {
+				return nil, p.errorf("expected %q", closing)
+			}
+			return fields, nil
+		}
+		if t.Text == closing && t.Kind == printer.Punct {
+			return fields, nil
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+		if t, ok := p.peek(); ok && t.Kind == printer.Punct && (t.Text == ";" || t.Text == ",") {
+			p.next()
+		}
+	}
+}
+
+// field parses a field.
+func (p *parser) field() (*Field, error) {
+	t := p.next()
+	f := &Field{Pos: t.pos(), End: t.end()}
+	switch {
+	case t.Kind == printer.Ident && !strings.Contains(t.Text, "."):
+		f.Name = t.Text
+	case t.Kind == printer.Punct && t.Text == "[":
+		// An extension name or a type URL like "type.googleapis.com/foo.Bar".
+		var sb strings.Builder
+		for {
+			t, ok := p.peek()
+			if !ok {
+				return nil, p.errorf("expected \"]\"")
+			}
+			p.next()
+			if t.Text == "]" {
+				f.End = t.end()
+				break
+			}
+			if t.Kind != printer.Ident && t.Text != "/" {
+				p.i--
+				return nil, p.errorf("unexpected %q in extension name", t.Text)
+			}
+			sb.WriteString(t.Text)
+		}
+		f.Name, f.Extension = sb.String(), true
+		if f.Name == "" {
+			return nil, &Error{Line: f.Pos.Line, Column: f.Pos.Column, Message: "empty extension name"}
+		}
+	default:
+		p.i--
+		return nil, p.errorf("unexpected %q, expected a field name", t.Text)
+	}
+
+	colon := false
+	if t, ok := p.peek(); ok && t.Kind == printer.Punct && t.Text == ":" {
+		p.next()
+		colon = true
+	}
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("expected a value of field %q", f.Name)
+	}
+	if t.Kind == printer.Punct && t.Text == "[" {
+		p.next()
+		f.List = true
+		for {
+			if t, ok := p.peek(); ok && t.Kind == printer.Punct && t.Text == "]" {
+				p.next()
+				break
+			}
+			v, err := p.value(f.Name, colon)
+			if err != nil {
+				return nil, err
+			}
+			f.Values = append(f.Values, v)
+			if t, ok := p.peek(); ok && t.Kind == printer.Punct && t.Text == "," {
+				p.next()
+			} else if !ok || t.Text != "]" {
+				return nil, p.errorf("expected \",\" or \"]\"")
+			}
+		}
+		return f, nil
+	}
+	v, err := p.value(f.Name, colon)
+	if err != nil {
+		return nil, err
+	}
+	f.Values = []*Value{v}
+	return f, nil
+}
+
+// value parses a value of a field. A scalar value requires a colon after the field name.
+func (p *parser) value(name string, colon bool) (*Value, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("expected a value of field %q", name)
+	}
+	if t.Kind == printer.Punct && (t.Text == "{" || t.Text == "<") {
+		p.next()
+		closing := "}"
+		if t.Text == "<" {
+			closing = ">"
+		}
+		fields, err := p.fields(closing)
+		if err != nil {
+			return nil, err
+		}
+		end := p.next()
+		return &Value{Kind: ValueMessage, Pos: t.pos(), End: end.end(), Fields: fields}, nil
+	}
+	if !colon {
+		return nil, p.errorf("expected \":\" after field %q", name)
+	}
+
+	p.next()
+	v := &Value{Pos: t.pos(), End: t.end(), Text: t.Text}
+	switch {
+	case t.Kind == printer.String:
+		v.Kind = ValueString
+		// Adjacent string literals are concatenated.
+		var texts []string
+		texts = append(texts, t.Text)
+		for {
+			t, ok := p.peek()
+			if !ok || t.Kind != printer.String {
+				break
+			}
+			p.next()
+			texts = append(texts, t.Text)
+			v.End = t.end()
+		}
+		v.Text = strings.Join(texts, " ")
+	case t.Kind == printer.Punct && t.Text == "-":
+		n, ok := p.peek()
+		if !ok || (n.Kind != printer.Number && n.Kind != printer.Ident) {
+			return nil, p.errorf("expected a number after \"-\"")
+		}
+		p.next()
+		v.Kind = ValueNumber
+		if n.Kind == printer.Ident {
+			v.Kind = ValueIdent
+		}
+		v.Text, v.End = "-"+n.Text, n.end()
+	case t.Kind == printer.Number:
+		v.Kind = ValueNumber
+	case t.Kind == printer.Ident:
+		v.Kind = ValueIdent
+	default:
+		p.i--
+		return nil, p.errorf("unexpected %q, expected a value of field %q", t.Text, name)
+	}
+	return v, nil
+}
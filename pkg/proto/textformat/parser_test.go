@@ -0,0 +1,243 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textformat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `# proto-file: foo/v1/foo.proto
+# proto-message: foo.v1.Config
+# proto-import: foo/v1/ext.proto
+
+name: "foo" "bar"  # the name
+count: -1
+ratio: -inf
+kinds: [KIND_A, KIND_B]
+child {
+  enabled: true
+}
+children: [<id: 1>, {id: 2}];
+[foo.v1.ext] {}
+any {
+  [type.googleapis.com/foo.v1.Child] { id: 3 }
+}
+`
+	got, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	pos := func(line, column int) Position {
+		return Position{Line: line, Column: column}
+	}
+	want := &File{
+		Header: Header{
+			ProtoFile:       "foo/v1/foo.proto",
+			ProtoMessage:    "foo.v1.Config",
+			ProtoImports:    []string{"foo/v1/ext.proto"},
+			ProtoFilePos:    pos(1, 15),
+			ProtoMessagePos: pos(2, 18),
+		},
+		Fields: []*Field{
+			{
+				Name: "name", Pos: pos(5, 1), End: pos(5, 5),
+				Values: []*Value{{Kind: ValueString, Text: `"foo" "bar"`, Pos: pos(5, 7), End: pos(5, 18)}},
+			},
+			{
+				Name: "count", Pos: pos(6, 1), End: pos(6, 6),
+				Values: []*Value{{Kind: ValueNumber, Text: "-1", Pos: pos(6, 8), End: pos(6, 10)}},
+			},
+			{
+				Name: "ratio", Pos: pos(7, 1), End: pos(7, 6),
+				Values: []*Value{{Kind: ValueIdent, Text: "-inf", Pos: pos(7, 8), End: pos(7, 12)}},
+			},
+			{
+				Name: "kinds", Pos: pos(8, 1), End: pos(8, 6), List: true,
+				Values: []*Value{
+					{Kind: ValueIdent, Text: "KIND_A", Pos: pos(8, 9), End: pos(8, 15)},
+					{Kind: ValueIdent, Text: "KIND_B", Pos: pos(8, 17), End: pos(8, 23)},
+				},
+			},
+			{
+				Name: "child", Pos: pos(9, 1), End: pos(9, 6),
+				Values: []*Value{{
+					Kind: ValueMessage, Pos: pos(9, 7), End: pos(11, 2),
+					Fields: []*Field{{
+						Name: "enabled", Pos: pos(10, 3), End: pos(10, 10),
+						Values: []*Value{{Kind: ValueIdent, Text: "true", Pos: pos(10, 12), End: pos(10, 16)}},
+					}},
+				}},
+			},
+			{
+				Name: "children", Pos: pos(12, 1), End: pos(12, 9), List: true,
+				Values: []*Value{
+					{
+						Kind: ValueMessage, Pos: pos(12, 12), End: pos(12, 19),
+						Fields: []*Field{{
+							Name: "id", Pos: pos(12, 13), End: pos(12, 15),
+							Values: []*Value{{Kind: ValueNumber, Text: "1", Pos: pos(12, 17), End: pos(12, 18)}},
+						}},
+					},
+					{
+						Kind: ValueMessage, Pos: pos(12, 21), End: pos(12, 28),
+						Fields: []*Field{{
+							Name: "id", Pos: pos(12, 22), End: pos(12, 24),
+							Values: []*Value{{Kind: ValueNumber, Text: "2", Pos: pos(12, 26), End: pos(12, 27)}},
+						}},
+					},
+				},
+			},
+			{
+				Name: "foo.v1.ext", Extension: true, Pos: pos(13, 1), End: pos(13, 13),
+				Values: []*Value{{Kind: ValueMessage, Pos: pos(13, 14), End: pos(13, 16)}},
+			},
+			{
+				Name: "any", Pos: pos(14, 1), End: pos(14, 4),
+				Values: []*Value{{
+					Kind: ValueMessage, Pos: pos(14, 5), End: pos(16, 2),
+					Fields: []*Field{{
+						Name: "type.googleapis.com/foo.v1.Child", Extension: true, Pos: pos(15, 3), End: pos(15, 37),
+						Values: []*Value{{
+							Kind: ValueMessage, Pos: pos(15, 38), End: pos(15, 47),
+							Fields: []*Field{{
+								Name: "id", Pos: pos(15, 40), End: pos(15, 42),
+								Values: []*Value{{Kind: ValueNumber, Text: "3", Pos: pos(15, 44), End: pos(15, 45)}},
+							}},
+						}},
+					}},
+				}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+		for i := range want.Fields {
+			if i < len(got.Fields) && !reflect.DeepEqual(got.Fields[i], want.Fields[i]) {
+				t.Errorf("field %d = %+v, want %+v", i, got.Fields[i], want.Fields[i])
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "missing colon",
+			src:  "name \"foo\"\n",
+			want: `1:6: expected ":" after field "name"`,
+		},
+		{
+			name: "unclosed message",
+			src:  "child {\n  id: 1\n",
+			want: `2:8: expected "}"`,
+		},
+		{
+			name: "unterminated string",
+			src:  "name: \"foo\n",
+			want: "1:7: unterminated string",
+		},
+		{
+			name: "missing value",
+			src:  "name:",
+			want: `1:6: expected a value of field "name"`,
+		},
+		{
+			name: "unexpected token",
+			src:  "name: 1\n}\n",
+			want: `2:1: unexpected "}", expected a field name`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	src := `#proto-message: Config
+# Other comment.
+child {
+`
+	want := Header{ProtoMessage: "Config", ProtoMessagePos: Position{Line: 1, Column: 17}}
+	if got := ParseHeader([]byte(src)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHeader() = %+v, want %+v", got, want)
+	}
+}
+
+func TestContextAt(t *testing.T) {
+	src := `name: "foo"
+child {
+  na
+  kind: KI
+  items: [{ id: 1 }, { i
+}
+`
+	tests := []struct {
+		name string
+		pos  Position
+		want Context
+	}{
+		{
+			name: "top level",
+			pos:  Position{Line: 1, Column: 1},
+			want: Context{},
+		},
+		{
+			name: "field name",
+			pos:  Position{Line: 3, Column: 5},
+			want: Context{Path: []string{"child"}, Prefix: "na"},
+		},
+		{
+			name: "scalar value",
+			pos:  Position{Line: 4, Column: 11},
+			want: Context{Path: []string{"child"}, Field: "kind", Prefix: "KI"},
+		},
+		{
+			name: "after colon",
+			pos:  Position{Line: 4, Column: 9},
+			want: Context{Path: []string{"child"}, Field: "kind"},
+		},
+		{
+			name: "message in list",
+			pos:  Position{Line: 5, Column: 25},
+			want: Context{Path: []string{"child", "items"}, Prefix: "i"},
+		},
+		{
+			name: "in string",
+			pos:  Position{Line: 1, Column: 9},
+			want: Context{Field: "name"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContextAt([]byte(src), tt.pos); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ContextAt() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 The Protocol Buffers Language Server Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textformat
+
+import (
+	"github.com/micnncim/protocol-buffers-language-server/pkg/proto/printer"
+)
+
+// syntax is the lexical syntax of text format files.
+var syntax = printer.Syntax{LineComment: "#"}
+
+// token is a lexical token of a text format file.
+type token struct {
+	printer.Token
+}
+
+func (t token) pos() Position {
+	return Position{Line: t.Line, Column: t.Column}
+}
+
+func (t token) end() Position {
+	return Position{Line: t.Line, Column: t.Column + len(t.Text)}
+}
+
+// Error represents an error which occurs while parsing a text format file.
+type Error = printer.Error
+
+// tokenize splits a text format file into tokens.
+// It returns the tokens before the error as well if the file can't be split.
+func tokenize(src string) ([]token, error) {
+	ts, err := printer.Tokenize(src, syntax)
+	tokens := make([]token, len(ts))
+	for i, t := range ts {
+		tokens[i] = token{t}
+	}
+	return tokens, err
+}